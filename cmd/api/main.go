@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"net/http"
 	"os"
@@ -11,6 +13,7 @@ import (
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 
+	"courier/internal/feed"
 	"courier/internal/httpx"
 	"courier/internal/logx"
 	"courier/internal/search"
@@ -49,12 +52,21 @@ func main() {
 	}
 
 	store := store.New(db, metrics)
+	if err := bootstrapAdmin(ctx, svc, store, runtimeCfg.Auth); err != nil {
+		fatal(svc, "bootstrap admin", err, nil)
+	}
 	srv := httpx.NewServer(httpx.Config{
 		Store:   store,
 		Search:  searchClient,
 		DB:      db,
 		Service: svc,
 		Metrics: metrics,
+		// Discovery shares the fetcher's HTTP stack so submitted URLs are
+		// probed the same way they will later be crawled.
+		Discoverer:      feed.NewFetcher(runtimeCfg.Fetcher.AllowedNetworks),
+		Auth:            store,
+		SessionTTL:      runtimeCfg.Auth.SessionTTL,
+		AllowedNetworks: runtimeCfg.Fetcher.AllowedNetworks,
 	})
 	srv.HTTPErrorHandler = httpx.HTTPErrorHandler(svc)
 	httpx.RegisterConfigRoute(srv, runtimeCfg)
@@ -97,6 +109,50 @@ func main() {
 	}
 }
 
+// bootstrapAdmin creates the configured admin account when no users exist
+// yet, so a fresh install can be signed in to. The admin adopts the feeds
+// and read state of an install that predates accounts. Without a configured
+// password one is generated and logged, once, for the operator to sign in
+// with.
+func bootstrapAdmin(ctx context.Context, svc string, repo *store.Store, cfg httpx.AuthConfig) error {
+	count, err := repo.CountUsers(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if cfg.AdminUser == "" {
+		logx.Info(svc, "no users yet; set COURIER_ADMIN_USER and COURIER_ADMIN_PASSWORD to create an admin", nil)
+		return nil
+	}
+	password := cfg.AdminPassword
+	generated := password == ""
+	if generated {
+		buf := make([]byte, 18)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		password = base64.RawURLEncoding.EncodeToString(buf)
+	}
+	user, err := httpx.CreateUser(ctx, repo, cfg.AdminUser, password, true)
+	if err != nil {
+		return err
+	}
+	adopted, err := repo.AdoptFeeds(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	extra := map[string]any{"username": user.Username, "subscriptions": adopted}
+	if generated {
+		extra["password"] = password
+		logx.Info(svc, "admin user created with a generated password; it is not shown again", extra)
+		return nil
+	}
+	logx.Info(svc, "admin user created", extra)
+	return nil
+}
+
 func fatal(service, msg string, err error, extra map[string]any) {
 	logx.Error(service, msg, err, extra)
 	os.Exit(1)
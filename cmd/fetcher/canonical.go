@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"courier/internal/search"
+	"courier/internal/store"
+)
+
+type canonicalStore interface {
+	ListFeedsWithoutCanonicalURL(context.Context) ([]store.Feed, error)
+	GetFeedByCanonicalURL(context.Context, string) (store.Feed, error)
+	SetFeedCanonicalURL(context.Context, string, string) error
+	MergeFeeds(context.Context, string, string) (store.MergeFeedsResult, error)
+}
+
+// canonicalizeFeeds backfills canonical URLs for feeds stored before feed
+// URLs were canonicalized. A feed whose canonical URL is already taken is a
+// duplicate subscription and is merged into the feed holding it. Feeds are
+// visited active and recently healthy first, so those keep their IDs. Once
+// every feed has a canonical URL this is a no-op.
+func canonicalizeFeeds(ctx context.Context, repo canonicalStore, indexer documentIndexer) (int, error) {
+	feeds, err := repo.ListFeedsWithoutCanonicalURL(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list feeds: %w", err)
+	}
+
+	merged := 0
+	for _, f := range feeds {
+		existing, err := repo.GetFeedByCanonicalURL(ctx, f.URL)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if err := repo.SetFeedCanonicalURL(ctx, f.ID, f.URL); err != nil {
+				return merged, fmt.Errorf("set canonical url: %w", err)
+			}
+			continue
+		case err != nil:
+			return merged, fmt.Errorf("lookup canonical url: %w", err)
+		}
+
+		result, err := repo.MergeFeeds(ctx, existing.ID, f.ID)
+		if err != nil {
+			return merged, fmt.Errorf("merge feeds: %w", err)
+		}
+		merged++
+		if err := indexer.DeleteDocuments(ctx, result.Dropped); err != nil {
+			return merged, fmt.Errorf("delete merged documents: %w", err)
+		}
+		docs := make([]search.Document, 0, len(result.Moved))
+		for _, it := range result.Moved {
+			docs = append(docs, itemDocument(it))
+		}
+		if err := indexer.UpsertBatch(ctx, docs); err != nil {
+			return merged, fmt.Errorf("index merged items: %w", err)
+		}
+	}
+	return merged, nil
+}
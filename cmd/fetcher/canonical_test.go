@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"courier/internal/item/urlcanon"
+	"courier/internal/store"
+)
+
+type stubCanonicalStore struct {
+	feeds     []store.Feed
+	canonical map[string]string
+	merges    [][2]string
+}
+
+func (s *stubCanonicalStore) ListFeedsWithoutCanonicalURL(context.Context) ([]store.Feed, error) {
+	return s.feeds, nil
+}
+
+func (s *stubCanonicalStore) GetFeedByCanonicalURL(ctx context.Context, url string) (store.Feed, error) {
+	for id, canonical := range s.canonical {
+		if canonical == urlcanon.Normalize(url) {
+			return store.Feed{ID: id}, nil
+		}
+	}
+	return store.Feed{}, sql.ErrNoRows
+}
+
+func (s *stubCanonicalStore) SetFeedCanonicalURL(ctx context.Context, id, url string) error {
+	if s.canonical == nil {
+		s.canonical = map[string]string{}
+	}
+	s.canonical[id] = urlcanon.Normalize(url)
+	return nil
+}
+
+func (s *stubCanonicalStore) MergeFeeds(ctx context.Context, keepID, dropID string) (store.MergeFeedsResult, error) {
+	s.merges = append(s.merges, [2]string{keepID, dropID})
+	return store.MergeFeedsResult{
+		Moved:   []store.Item{{ID: "item-moved", FeedID: keepID}},
+		Dropped: []string{"item-dup"},
+	}, nil
+}
+
+func TestCanonicalizeFeedsMergesDuplicates(t *testing.T) {
+	repo := &stubCanonicalStore{feeds: []store.Feed{
+		{ID: "feed-1", URL: "https://www.example.com/feed/"},
+		{ID: "feed-2", URL: "https://example.org/rss"},
+		{ID: "feed-3", URL: "https://example.com/feed"},
+	}}
+	indexer := &stubSearchClient{}
+
+	merged, err := canonicalizeFeeds(context.Background(), repo, indexer)
+	if err != nil {
+		t.Fatalf("canonicalizeFeeds: %v", err)
+	}
+	if merged != 1 {
+		t.Fatalf("expected 1 merge, got %d", merged)
+	}
+	if len(repo.merges) != 1 || repo.merges[0] != [2]string{"feed-1", "feed-3"} {
+		t.Fatalf("expected feed-3 merged into feed-1, got %v", repo.merges)
+	}
+	if got := repo.canonical["feed-1"]; got != "https://example.com/feed" {
+		t.Fatalf("expected canonical url for feed-1, got %q", got)
+	}
+	if _, ok := repo.canonical["feed-3"]; ok {
+		t.Fatalf("merged feed should not be canonicalized")
+	}
+	if len(indexer.deleted) != 1 || indexer.deleted[0] != "item-dup" {
+		t.Fatalf("expected duplicate documents deleted, got %v", indexer.deleted)
+	}
+	if len(indexer.batchCalls) != 1 || indexer.batchCalls[0][0].FeedID != "feed-1" {
+		t.Fatalf("expected moved items reindexed under feed-1, got %+v", indexer.batchCalls)
+	}
+}
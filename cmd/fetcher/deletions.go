@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+const feedDeletionBatch = 100
+
+type feedDeletionStore interface {
+	ListFeedDeletions(context.Context, int32) ([]string, error)
+	ClearFeedDeletions(context.Context, []string) error
+}
+
+type feedDocumentDeleter interface {
+	DeleteFeedDocuments(context.Context, string) error
+}
+
+// purgeDeletedFeeds removes the search documents of feeds deleted since the
+// last tick. The API deletes them too, but a feed is only forgotten here, so
+// documents a failed request or an in-flight crawl left behind still go. It
+// returns how many feeds were purged.
+func purgeDeletedFeeds(ctx context.Context, repo feedDeletionStore, indexer feedDocumentDeleter) (int, error) {
+	purged := 0
+	for {
+		ids, err := repo.ListFeedDeletions(ctx, feedDeletionBatch)
+		if err != nil {
+			return purged, fmt.Errorf("list feed deletions: %w", err)
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+		for _, id := range ids {
+			if err := indexer.DeleteFeedDocuments(ctx, id); err != nil {
+				return purged, fmt.Errorf("delete feed documents: %w", err)
+			}
+		}
+		if err := repo.ClearFeedDeletions(ctx, ids); err != nil {
+			return purged, fmt.Errorf("clear feed deletions: %w", err)
+		}
+		purged += len(ids)
+		if len(ids) < feedDeletionBatch {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type stubDeletionStore struct {
+	pending []string
+	cleared []string
+}
+
+func (s *stubDeletionStore) ListFeedDeletions(ctx context.Context, limit int32) ([]string, error) {
+	if int(limit) < len(s.pending) {
+		return s.pending[:limit], nil
+	}
+	return s.pending, nil
+}
+
+func (s *stubDeletionStore) ClearFeedDeletions(ctx context.Context, ids []string) error {
+	s.cleared = append(s.cleared, ids...)
+	s.pending = s.pending[len(ids):]
+	return nil
+}
+
+type stubFeedDocumentDeleter struct {
+	deleted []string
+	err     error
+}
+
+func (s *stubFeedDocumentDeleter) DeleteFeedDocuments(ctx context.Context, feedID string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.deleted = append(s.deleted, feedID)
+	return nil
+}
+
+func TestPurgeDeletedFeeds(t *testing.T) {
+	repo := &stubDeletionStore{pending: []string{"feed-1", "feed-2"}}
+	indexer := &stubFeedDocumentDeleter{}
+
+	purged, err := purgeDeletedFeeds(context.Background(), repo, indexer)
+	if err != nil {
+		t.Fatalf("purgeDeletedFeeds: %v", err)
+	}
+	if purged != 2 || len(indexer.deleted) != 2 || len(repo.cleared) != 2 {
+		t.Fatalf("expected both feeds purged, got %d (documents %v, cleared %v)", purged, indexer.deleted, repo.cleared)
+	}
+}
+
+func TestPurgeDeletedFeedsKeepsFeedsWhenIndexFails(t *testing.T) {
+	repo := &stubDeletionStore{pending: []string{"feed-1"}}
+	indexer := &stubFeedDocumentDeleter{err: errors.New("meilisearch unavailable")}
+
+	if _, err := purgeDeletedFeeds(context.Background(), repo, indexer); err == nil {
+		t.Fatalf("expected the index error to be returned")
+	}
+	if len(repo.cleared) != 0 || len(repo.pending) != 1 {
+		t.Fatalf("expected the deletion to be kept for the next tick, cleared %v", repo.cleared)
+	}
+}
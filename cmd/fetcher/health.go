@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"courier/internal/feed"
+	"courier/internal/store"
+)
+
+// fetchLogRetention is how many fetch attempts are kept per feed in
+// feed_fetch_log.
+const fetchLogRetention = 50
+
+// recordFeedHealth stores the outcome of a fetch attempt. Feeds skipped
+// because a backoff is still active or their host is blocked were not
+// fetched and are not recorded.
+func recordFeedHealth(ctx context.Context, repo feedRepository, f store.Feed, result FetchFeedResult, started time.Time) error {
+	if fetchSkipped(result.Err) {
+		return nil
+	}
+
+	params := store.RecordFeedFetchParams{
+		FeedID:    f.ID,
+		FetchedAt: started.UTC(),
+		Status:    result.Status,
+		Items:     result.Items,
+		Duration:  time.Since(started),
+		Keep:      fetchLogRetention,
+	}
+	if result.Err != nil {
+		params.Err = result.Err.Error()
+	}
+	return repo.RecordFeedFetch(ctx, params)
+}
+
+// fetchSkipped reports whether err means no request was made for the feed.
+func fetchSkipped(err error) bool {
+	var blocked *feed.BlockedError
+	return errors.Is(err, ErrBackoffActive) || errors.As(err, &blocked)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"courier/internal/feed"
+	"courier/internal/store"
+)
+
+func TestRunRecordsFeedHealth(t *testing.T) {
+	repo := &stubFeedStore{
+		feeds: []store.Feed{
+			{ID: "feed-1", URL: "http://example.com/ok", Active: true},
+			{ID: "feed-2", URL: "http://example.com/broken", Active: true},
+			{ID: "feed-3", URL: "http://example.com/waiting", Active: true},
+			{ID: "feed-4", URL: "http://example.com/sibling", Active: true},
+		},
+	}
+	blocked := &feed.BlockedError{Host: "example.com", Until: time.Now().Add(time.Minute)}
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{Items: []*gofeed.Item{{Title: "a", Link: "http://example.com/a"}}}}},
+		{result: feed.Result{Status: http.StatusNotFound}, err: fmt.Errorf("unexpected status 404")},
+		{err: fmt.Errorf("%w: %w", feed.ErrRetryLater, blocked)},
+	}}
+	rateLimitBackoffs := newBackoffTracker()
+	rateLimitBackoffs.Schedule("feed-3", time.Now(), 5*time.Minute)
+
+	run(context.Background(), "fetcher", repo, &stubSearchClient{}, fetcher, rateLimitBackoffs, newBackoffTracker(), testSchedule, testLifecycle, 10, 1)
+
+	if len(repo.fetches) != 2 {
+		t.Fatalf("expected 2 recorded fetches, got %d: %+v", len(repo.fetches), repo.fetches)
+	}
+
+	ok := repo.fetches[0]
+	if ok.FeedID != "feed-1" || ok.Status != http.StatusOK || ok.Err != "" || ok.Items != 1 {
+		t.Fatalf("unexpected success record: %+v", ok)
+	}
+	if ok.Keep != fetchLogRetention {
+		t.Fatalf("Keep = %d, want %d", ok.Keep, fetchLogRetention)
+	}
+
+	failed := repo.fetches[1]
+	if failed.FeedID != "feed-2" || failed.Status != http.StatusNotFound || failed.Err == "" {
+		t.Fatalf("unexpected failure record: %+v", failed)
+	}
+}
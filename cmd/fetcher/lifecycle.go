@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"courier/internal/feed"
+	"courier/internal/search"
+	"courier/internal/store"
+)
+
+// feedLifecycle decides when a feed moves to a new URL or stops being
+// crawled.
+type feedLifecycle struct {
+	// notFoundLimit is how many consecutive 404 responses deactivate a
+	// feed.
+	notFoundLimit int
+}
+
+func newFeedLifecycle(notFoundLimit int) feedLifecycle {
+	if notFoundLimit <= 0 {
+		notFoundLimit = 1
+	}
+	return feedLifecycle{notFoundLimit: notFoundLimit}
+}
+
+// deactivationReason returns why f should stop being crawled after result, or
+// "" when it should stay active.
+func (l feedLifecycle) deactivationReason(f store.Feed, result FetchFeedResult) string {
+	switch {
+	case errors.Is(result.Err, feed.ErrGone):
+		return "gone: http status 410"
+	case errors.Is(result.Err, feed.ErrNotFound):
+		if misses := int(f.ConsecutiveNotFound) + 1; misses >= l.notFoundLimit {
+			return fmt.Sprintf("not found: %d consecutive 404 responses", misses)
+		}
+	}
+	return ""
+}
+
+// applyFeedLifecycle follows permanent redirects by moving the feed to its new
+// URL and deactivates feeds that are gone. A feed redirected to a URL that is
+// already subscribed absorbs that feed, as with in-band relocations, so items
+// stay under f's ID. It returns search documents for items that changed feeds.
+func applyFeedLifecycle(ctx context.Context, repo feedRepository, indexer documentIndexer, lifecycle feedLifecycle, f store.Feed, result *FetchFeedResult) ([]search.Document, error) {
+	reason := lifecycle.deactivationReason(f, *result)
+
+	// An in-band relocation already moved the feed during the fetch.
+	var docs []search.Document
+	if result.MovedTo != "" && result.MovedTo != f.URL && result.Relocated == "" && reason == "" {
+		existing, err := repo.GetFeedByURL(ctx, result.MovedTo)
+		switch {
+		case err == nil && existing.ID != f.ID:
+			merged, err := repo.MergeFeeds(ctx, f.ID, existing.ID)
+			if err != nil {
+				return nil, fmt.Errorf("merge feeds: %w", err)
+			}
+			if err := indexer.DeleteDocuments(ctx, merged.Dropped); err != nil {
+				return nil, fmt.Errorf("delete merged documents: %w", err)
+			}
+			for _, it := range merged.Moved {
+				docs = append(docs, itemDocument(it))
+			}
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("lookup redirect target: %w", err)
+		}
+
+		if _, err := repo.UpdateFeedURL(ctx, f.ID, result.MovedTo); err != nil {
+			if !errors.Is(err, store.ErrFeedExists) {
+				return docs, fmt.Errorf("update feed url: %w", err)
+			}
+			// Only another feed's URL history can still hold the target.
+			reason = fmt.Sprintf("duplicate: redirected to %s, a former url of another feed", result.MovedTo)
+		}
+	}
+
+	if reason == "" {
+		return docs, nil
+	}
+	if err := repo.DeactivateFeed(ctx, f.ID, reason); err != nil {
+		return docs, fmt.Errorf("deactivate feed: %w", err)
+	}
+	result.Deactivated = reason
+	return docs, nil
+}
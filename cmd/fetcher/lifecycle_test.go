@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+
+	"courier/internal/feed"
+	"courier/internal/store"
+)
+
+func TestRunAppliesFeedLifecycle(t *testing.T) {
+	repo := &stubFeedStore{
+		feeds: []store.Feed{
+			{ID: "feed-1", URL: "http://example.com/old", Active: true},
+			{ID: "feed-2", URL: "http://example.com/gone", Active: true},
+			{ID: "feed-3", URL: "http://example.com/missing", Active: true, ConsecutiveNotFound: 2},
+			{ID: "feed-4", URL: "http://example.com/flaky", Active: true},
+		},
+	}
+	notFound := fmt.Errorf("%w: http status 404", feed.ErrNotFound)
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{}, PermanentURL: "http://example.com/new"}},
+		{result: feed.Result{Status: http.StatusGone}, err: fmt.Errorf("%w: http status 410", feed.ErrGone)},
+		{result: feed.Result{Status: http.StatusNotFound}, err: notFound},
+		{result: feed.Result{Status: http.StatusNotFound}, err: notFound},
+	}}
+
+	run(context.Background(), "fetcher", repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, 1)
+
+	if got := repo.moved["feed-1"]; got != "http://example.com/new" {
+		t.Fatalf("expected feed-1 to move to the redirect target, got %q", got)
+	}
+	if _, ok := repo.deactivated["feed-1"]; ok {
+		t.Fatalf("expected redirected feed to stay active")
+	}
+	if reason := repo.deactivated["feed-2"]; !strings.Contains(reason, "410") {
+		t.Fatalf("expected feed-2 to be deactivated as gone, got %q", reason)
+	}
+	if reason := repo.deactivated["feed-3"]; !strings.Contains(reason, "3 consecutive 404") {
+		t.Fatalf("expected feed-3 to be deactivated after 3 misses, got %q", reason)
+	}
+	if _, ok := repo.deactivated["feed-4"]; ok {
+		t.Fatalf("expected a single 404 to leave feed-4 active")
+	}
+}
+
+func TestApplyFeedLifecycleMergesRedirectToExistingFeed(t *testing.T) {
+	repo := &stubFeedStore{
+		feeds: []store.Feed{{ID: "feed-2", URL: "http://example.com/existing", Active: true}},
+		mergeResult: store.MergeFeedsResult{
+			Moved:   []store.Item{{ID: "item-1", FeedID: "feed-1"}},
+			Dropped: []string{"item-2"},
+		},
+	}
+	indexer := &stubSearchClient{}
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/old"}
+	result := FetchFeedResult{Status: http.StatusOK, MovedTo: "http://example.com/existing"}
+
+	docs, err := applyFeedLifecycle(context.Background(), repo, indexer, testLifecycle, f, &result)
+	if err != nil {
+		t.Fatalf("apply lifecycle: %v", err)
+	}
+	if len(repo.merges) != 1 || repo.merges[0] != [2]string{"feed-1", "feed-2"} {
+		t.Fatalf("expected feed-2 to be merged into feed-1, got %v", repo.merges)
+	}
+	if got := repo.moved["feed-1"]; got != "http://example.com/existing" {
+		t.Fatalf("expected feed-1 to move to the redirect target, got %q", got)
+	}
+	if len(repo.deactivated) != 0 || result.Deactivated != "" {
+		t.Fatalf("expected no deactivation, got %v", repo.deactivated)
+	}
+	if len(indexer.deleted) != 1 || indexer.deleted[0] != "item-2" {
+		t.Fatalf("expected duplicate item documents to be deleted, got %v", indexer.deleted)
+	}
+	if len(docs) != 1 || docs[0].ID != "item-1" {
+		t.Fatalf("expected moved items to be reindexed, got %v", docs)
+	}
+}
+
+func TestApplyFeedLifecycleDeactivatesRedirectToFormerURL(t *testing.T) {
+	repo := &stubFeedStore{movedErr: store.ErrFeedExists}
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/old"}
+	result := FetchFeedResult{Status: http.StatusOK, MovedTo: "http://example.com/former"}
+
+	if _, err := applyFeedLifecycle(context.Background(), repo, &stubSearchClient{}, testLifecycle, f, &result); err != nil {
+		t.Fatalf("apply lifecycle: %v", err)
+	}
+	if !strings.HasPrefix(repo.deactivated["feed-1"], "duplicate") {
+		t.Fatalf("expected duplicate deactivation, got %q", repo.deactivated["feed-1"])
+	}
+	if result.Deactivated == "" {
+		t.Fatalf("expected result to report the deactivation")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"runtime/debug"
 	"strconv"
+	"sync"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -17,6 +18,7 @@ import (
 	"courier/internal/item"
 	"courier/internal/item/urlcanon"
 	"courier/internal/logx"
+	"courier/internal/netguard"
 	"courier/internal/search"
 	"courier/internal/store"
 )
@@ -41,6 +43,77 @@ func main() {
 		batchSize = n
 	}
 
+	concurrency := 8
+	if v := os.Getenv("COURIER_FETCH_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			fatal(svc, "invalid fetch concurrency", fmt.Errorf("COURIER_FETCH_CONCURRENCY must be a positive integer"), map[string]any{"env": "COURIER_FETCH_CONCURRENCY"})
+		}
+		concurrency = n
+	}
+
+	crawlMin := every
+	if v := os.Getenv("COURIER_CRAWL_MIN_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			fatal(svc, "invalid crawl min interval", fmt.Errorf("COURIER_CRAWL_MIN_INTERVAL must be a positive duration"), map[string]any{"env": "COURIER_CRAWL_MIN_INTERVAL"})
+		}
+		crawlMin = d
+	}
+
+	crawlMax := 6 * time.Hour
+	if v := os.Getenv("COURIER_CRAWL_MAX_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			fatal(svc, "invalid crawl max interval", fmt.Errorf("COURIER_CRAWL_MAX_INTERVAL must be a positive duration"), map[string]any{"env": "COURIER_CRAWL_MAX_INTERVAL"})
+		}
+		crawlMax = d
+	}
+	if crawlMax < crawlMin {
+		fatal(svc, "invalid crawl interval", fmt.Errorf("COURIER_CRAWL_MAX_INTERVAL must be greater than or equal to COURIER_CRAWL_MIN_INTERVAL"), nil)
+	}
+
+	hostInterval := time.Second
+	if v := os.Getenv("COURIER_HOST_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			fatal(svc, "invalid host interval", fmt.Errorf("COURIER_HOST_INTERVAL must be a non-negative duration"), map[string]any{"env": "COURIER_HOST_INTERVAL"})
+		}
+		hostInterval = d
+	}
+
+	notFoundLimit := 5
+	if v := os.Getenv("COURIER_NOT_FOUND_LIMIT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			fatal(svc, "invalid not found limit", fmt.Errorf("COURIER_NOT_FOUND_LIMIT must be a positive integer"), map[string]any{"env": "COURIER_NOT_FOUND_LIMIT"})
+		}
+		notFoundLimit = n
+	}
+
+	hostConcurrency := 2
+	if v := os.Getenv("COURIER_HOST_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			fatal(svc, "invalid host concurrency", fmt.Errorf("COURIER_HOST_CONCURRENCY must be a positive integer"), map[string]any{"env": "COURIER_HOST_CONCURRENCY"})
+		}
+		hostConcurrency = n
+	}
+
+	webhookInterval := 15 * time.Second
+	if v := os.Getenv("COURIER_WEBHOOK_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			fatal(svc, "invalid webhook interval", fmt.Errorf("COURIER_WEBHOOK_INTERVAL must be a positive duration"), map[string]any{"env": "COURIER_WEBHOOK_INTERVAL"})
+		}
+		webhookInterval = d
+	}
+
+	allowedNetworks, err := netguard.ParseAllowlist(os.Getenv("COURIER_ALLOWED_NETWORKS"))
+	if err != nil {
+		fatal(svc, "invalid allowed networks", err, map[string]any{"env": "COURIER_ALLOWED_NETWORKS"})
+	}
+
 	db, err := sql.Open("pgx", dsn)
 	if err != nil {
 		fatal(svc, "open db", err, nil)
@@ -56,20 +129,69 @@ func main() {
 	if err := searchClient.EnsureIndex(ctx); err != nil {
 		fatal(svc, "ensure index", err, nil)
 	}
-	cancel()
 
-	fetcher := feed.NewFetcher()
+	hosts := feed.NewHostScheduler(hostInterval, hostConcurrency)
+	fetcher := feed.NewFetcherWithScheduler(hosts, allowedNetworks)
 	rateLimitBackoffs := newBackoffTracker()
 	transientBackoffs := newBackoffTrackerWith(5*time.Second, 2*time.Minute)
+	schedule := newCrawlSchedule(crawlMin, crawlMax)
+	lifecycle := newFeedLifecycle(notFoundLimit)
+
+	backedOff, err := repo.ListBackedOffFeeds(ctx, time.Now().UTC())
+	if err != nil {
+		fatal(svc, "load backoffs", err, nil)
+	}
+	restored := restoreBackoffs(backedOff, rateLimitBackoffs, transientBackoffs, hosts)
+	cancel()
 
-	logx.Info(svc, "ready", map[string]any{"every": every.String(), "batch_size": batchSize})
+	// Feeds added before URLs were canonicalized are backfilled once, and
+	// duplicates among them merged; later starts find nothing to do.
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Minute)
+	merged, err := canonicalizeFeeds(ctx, repo, searchClient)
+	cancel()
+	if err != nil {
+		logx.Error(svc, "canonicalize feeds", err, map[string]any{"merged": merged})
+	}
+
+	logx.Info(svc, "ready", map[string]any{
+		"every":            every.String(),
+		"batch_size":       batchSize,
+		"concurrency":      concurrency,
+		"host_interval":    hostInterval.String(),
+		"host_concurrency": hostConcurrency,
+		"crawl_min":        crawlMin.String(),
+		"crawl_max":        crawlMax.String(),
+		"not_found_limit":  notFoundLimit,
+		"backoffs":         restored,
+		"merged_feeds":     merged,
+		"webhook_interval": webhookInterval.String(),
+		"allowed_networks": allowedNetworks.Strings(),
+	})
+
+	// Webhooks are delivered on their own, shorter cycle so retries are not
+	// held up by crawl ticks.
+	go func() {
+		client := newWebhookClient(allowedNetworks)
+		ticker := time.NewTicker(webhookInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), webhookLease)
+			deliverWebhooks(ctx, svc, repo, client)
+			cancel()
+		}
+	}()
 
 	ticker := time.NewTicker(every)
 	defer ticker.Stop()
 
 	for {
 		ctx, cancel := context.WithTimeout(context.Background(), every)
-		run(ctx, svc, repo, searchClient, fetcher, rateLimitBackoffs, transientBackoffs, batchSize)
+		if purged, err := purgeDeletedFeeds(ctx, repo, searchClient); err != nil {
+			logx.Error(svc, "purge deleted feeds", err, map[string]any{"purged": purged})
+		} else if purged > 0 {
+			logx.Info(svc, "deleted feeds purged", map[string]any{"purged": purged})
+		}
+		run(ctx, svc, repo, searchClient, fetcher, rateLimitBackoffs, transientBackoffs, schedule, lifecycle, batchSize, concurrency)
 		cancel()
 		<-ticker.C
 	}
@@ -90,96 +212,151 @@ func requireEnv(service, key string) string {
 
 type feedRepository interface {
 	feedStore
-	ListFeeds(context.Context, bool) ([]store.Feed, error)
+	ListDueFeeds(context.Context, time.Time) ([]store.Feed, error)
+	ListFeedPublishTimes(context.Context, string, int32) ([]time.Time, error)
+	ScheduleFeedCrawl(context.Context, string, time.Time) error
+	RecordFeedFetch(context.Context, store.RecordFeedFetchParams) error
+	DeactivateFeed(context.Context, string, string) error
+	savedSearchStore
+	webhookQueue
 }
 
-func run(ctx context.Context, svc string, repo feedRepository, searchClient documentIndexer, fetcher feedFetcher, rateLimitBackoffs, transientBackoffs *backoffTracker, batchSize int) {
+// eventTimeout bounds recording the saved search matches and webhook
+// deliveries for a feed's items. They are recorded on a context detached
+// from the tick, so items already stored keep their events when the tick's
+// deadline passes.
+const eventTimeout = 30 * time.Second
+
+// pendingBatch buffers search documents across feeds so they can be flushed
+// to Meilisearch in batches. Workers share a single batch per tick.
+type pendingBatch struct {
+	mu   sync.Mutex
+	docs []search.Document
+}
+
+func run(ctx context.Context, svc string, repo feedRepository, searchClient documentIndexer, fetcher feedFetcher, rateLimitBackoffs, transientBackoffs *backoffTracker, schedule crawlSchedule, lifecycle feedLifecycle, batchSize, concurrency int) {
 	logx.Info(svc, "crawl tick", nil)
 
-	feeds, err := repo.ListFeeds(ctx, true)
+	feeds, err := repo.ListDueFeeds(ctx, time.Now().UTC())
 	if err != nil {
 		logx.Error(svc, "list feeds", err, nil)
 		return
 	}
 
-	pendingDocs := make([]search.Document, 0, batchSize)
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if concurrency > len(feeds) {
+		concurrency = len(feeds)
+	}
 
-	for _, f := range feeds {
-		result := processFeed(ctx, svc, repo, searchClient, fetcher, rateLimitBackoffs, transientBackoffs, batchSize, &pendingDocs, f)
+	pending := &pendingBatch{docs: make([]search.Document, 0, batchSize)}
+
+	jobs := make(chan store.Feed)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				result := processFeed(ctx, svc, repo, searchClient, fetcher, rateLimitBackoffs, transientBackoffs, schedule, lifecycle, batchSize, pending, f)
+				logFeedResult(svc, f, result)
+			}
+		}()
+	}
 
-		extra := map[string]any{
-			"feed":    f.URL,
-			"feed_id": f.ID,
-		}
-		if result.Status != 0 {
-			extra["status"] = result.Status
-		}
-		if result.Items > 0 {
-			extra["items"] = result.Items
-		}
-		if result.Mutated {
-			extra["mutated"] = true
-		}
-		if result.Reason != "" {
-			extra["reason"] = result.Reason
-		}
-		if result.RetryIn > 0 {
-			extra["retry_in"] = result.RetryIn.String()
+dispatch:
+	for _, f := range feeds {
+		select {
+		case jobs <- f:
+		case <-ctx.Done():
+			logx.Error(svc, "crawl tick interrupted", ctx.Err(), map[string]any{"feeds": len(feeds)})
+			break dispatch
 		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		switch {
-		case result.Err != nil && errors.Is(result.Err, ErrBackoffActive):
-			logx.Info(svc, "feed skipped", extra)
-		case result.Err != nil:
-			logx.Error(svc, "feed error", result.Err, extra)
-		case result.Skipped:
-			logx.Info(svc, "feed skipped", extra)
-		default:
-			logx.Info(svc, "feed processed", extra)
+	for docs := pending.docs; len(docs) > 0; {
+		n := batchSize
+		if n > len(docs) {
+			n = len(docs)
 		}
+		_ = flushDocuments(ctx, svc, searchClient, docs[:n])
+		docs = docs[n:]
 	}
+}
 
-	for len(pendingDocs) > 0 {
-		chunkSize := batchSize
-		if chunkSize > len(pendingDocs) {
-			chunkSize = len(pendingDocs)
-		}
-		chunk := pendingDocs[:chunkSize]
-		logx.Info(svc, "flush search batch", map[string]any{"batch_size": len(chunk)})
-		if err := searchClient.UpsertBatch(ctx, chunk); err != nil {
-			logx.Error(svc, "flush search batch", err, map[string]any{"batch_size": len(chunk)})
-			indexed, fallbackErr := upsertDocumentsIndividually(ctx, searchClient, chunk)
-			pendingDocs = pendingDocs[indexed:]
-			if fallbackErr != nil {
-				if len(pendingDocs) > 0 {
-					skipped := pendingDocs[0]
-					logx.Error(svc, "fallback search upsert", fallbackErr, map[string]any{"document_id": skipped.ID})
-					pendingDocs = pendingDocs[1:]
-				} else {
-					logx.Error(svc, "fallback search upsert", fallbackErr, nil)
-				}
-				continue
-			}
-			continue
-		}
-		pendingDocs = pendingDocs[chunkSize:]
+func logFeedResult(svc string, f store.Feed, result FetchFeedResult) {
+	extra := map[string]any{
+		"feed":    f.URL,
+		"feed_id": f.ID,
+	}
+	if result.Status != 0 {
+		extra["status"] = result.Status
+	}
+	if result.Items > 0 {
+		extra["items"] = result.Items
+	}
+	if result.Mutated {
+		extra["mutated"] = true
+	}
+	if result.Reason != "" {
+		extra["reason"] = result.Reason
+	}
+	if result.RetryIn > 0 {
+		extra["retry_in"] = result.RetryIn.String()
+	}
+	if result.NextCrawlIn > 0 {
+		extra["next_crawl_in"] = result.NextCrawlIn.String()
+	}
+	if result.MovedTo != "" {
+		extra["moved_to"] = result.MovedTo
+	}
+	if result.Deactivated != "" {
+		extra["deactivated"] = result.Deactivated
+	}
+	if result.Relocated != "" {
+		extra["relocated"] = result.Relocated
+	}
+
+	switch {
+	case result.Err != nil && fetchSkipped(result.Err):
+		logx.Info(svc, "feed skipped", extra)
+	case result.Err != nil:
+		logx.Error(svc, "feed error", result.Err, extra)
+	case result.Skipped:
+		logx.Info(svc, "feed skipped", extra)
+	default:
+		logx.Info(svc, "feed processed", extra)
 	}
 }
 
-func processFeed(ctx context.Context, svc string, repo feedRepository, searchClient documentIndexer, fetcher feedFetcher, rateLimitBackoffs, transientBackoffs *backoffTracker, batchSize int, pendingDocs *[]search.Document, f store.Feed) (result FetchFeedResult) {
+func processFeed(ctx context.Context, svc string, repo feedRepository, searchClient documentIndexer, fetcher feedFetcher, rateLimitBackoffs, transientBackoffs *backoffTracker, schedule crawlSchedule, lifecycle feedLifecycle, batchSize int, pending *pendingBatch, f store.Feed) (result FetchFeedResult) {
 	result = FetchFeedResult{FeedID: f.ID, FeedURL: f.URL}
-	initialPending := len(*pendingDocs)
 
-	defer recoverFeedPanic(svc, f, pendingDocs, initialPending, &result)
+	defer recoverFeedPanic(svc, f, pending, &result)
 
-	result = processFeedUnsafe(ctx, svc, repo, searchClient, fetcher, rateLimitBackoffs, transientBackoffs, batchSize, pendingDocs, f)
+	result = processFeedUnsafe(ctx, svc, repo, searchClient, fetcher, rateLimitBackoffs, transientBackoffs, schedule, lifecycle, batchSize, pending, f)
 	return result
 }
 
-func recoverFeedPanic(svc string, f store.Feed, pendingDocs *[]search.Document, initialPending int, result *FetchFeedResult) {
+// recoverFeedPanic turns a panic while processing f into a failed result. Any
+// documents the feed already queued are dropped; other workers may have
+// appended to the batch in the meantime, so the feed's documents are removed
+// by feed ID rather than by truncating the batch.
+func recoverFeedPanic(svc string, f store.Feed, pending *pendingBatch, result *FetchFeedResult) {
 	if r := recover(); r != nil {
-		if pendingDocs != nil && len(*pendingDocs) > initialPending {
-			*pendingDocs = (*pendingDocs)[:initialPending]
+		if pending != nil {
+			pending.mu.Lock()
+			kept := pending.docs[:0]
+			for _, doc := range pending.docs {
+				if doc.FeedID != f.ID {
+					kept = append(kept, doc)
+				}
+			}
+			pending.docs = kept
+			pending.mu.Unlock()
 		}
 
 		panicValue := fmt.Sprintf("%v", r)
@@ -211,73 +388,89 @@ func recoverFeedPanic(svc string, f store.Feed, pendingDocs *[]search.Document,
 	}
 }
 
-func processFeedUnsafe(ctx context.Context, svc string, repo feedRepository, searchClient documentIndexer, fetcher feedFetcher, rateLimitBackoffs, transientBackoffs *backoffTracker, batchSize int, pendingDocs *[]search.Document, f store.Feed) FetchFeedResult {
+func processFeedUnsafe(ctx context.Context, svc string, repo feedRepository, searchClient documentIndexer, fetcher feedFetcher, rateLimitBackoffs, transientBackoffs *backoffTracker, schedule crawlSchedule, lifecycle feedLifecycle, batchSize int, pending *pendingBatch, f store.Feed) FetchFeedResult {
 	result := FetchFeedResult{FeedID: f.ID, FeedURL: f.URL}
 
-	newDocsRemaining := 0
-	removeFront := func(n int) {
-		if n <= 0 {
-			return
-		}
-		pd := *pendingDocs
-		if n > len(pd) {
-			n = len(pd)
-		}
-		start := len(pd) - newDocsRemaining
-		if n > start {
-			removedNew := n - start
-			if removedNew > newDocsRemaining {
-				removedNew = newDocsRemaining
-			}
-			newDocsRemaining -= removedNew
-		}
-		*pendingDocs = pd[n:]
-	}
-
+	started := time.Now()
 	docsResult, docs := FetchFeed(ctx, repo, searchClient, fetcher, rateLimitBackoffs, transientBackoffs, f)
 	result = docsResult
 
+	eventCtx, cancelEvents := context.WithTimeout(context.WithoutCancel(ctx), eventTimeout)
+	defer cancelEvents()
+	enqueueWebhooks(eventCtx, svc, repo, result.Changed)
+
+	if err := recordFeedHealth(ctx, repo, f, result, started); err != nil {
+		logx.Error(svc, "record feed health", err, map[string]any{"feed": f.URL, "feed_id": f.ID})
+	}
+
+	mergedDocs, err := applyFeedLifecycle(ctx, repo, searchClient, lifecycle, f, &result)
+	if err != nil {
+		logx.Error(svc, "feed lifecycle", err, map[string]any{"feed": f.URL, "feed_id": f.ID})
+	}
+	docs = append(docs, mergedDocs...)
+
+	if wait, err := scheduleNextCrawl(ctx, repo, schedule, f, result); err != nil {
+		logx.Error(svc, "schedule feed", err, map[string]any{"feed": f.URL, "feed_id": f.ID})
+	} else {
+		result.NextCrawlIn = wait
+	}
+
+	evaluateSavedSearches(eventCtx, svc, repo, docs)
+
+	// Workers append to the shared batch under its lock and take any full
+	// chunks with them, so the Meilisearch requests run after unlocking and
+	// never hold up other workers.
+	pending.mu.Lock()
 	if len(docs) > 0 {
-		*pendingDocs = append(*pendingDocs, docs...)
-		newDocsRemaining += len(docs)
-	}
-
-	if len(*pendingDocs) >= batchSize {
-		for len(*pendingDocs) >= batchSize {
-			chunk := (*pendingDocs)[:batchSize]
-			logx.Info(svc, "flush search batch", map[string]any{"batch_size": len(chunk)})
-			if err := searchClient.UpsertBatch(ctx, chunk); err != nil {
-				logx.Error(svc, "flush search batch", err, map[string]any{"batch_size": len(chunk)})
-				indexed, fallbackErr := upsertDocumentsIndividually(ctx, searchClient, chunk)
-				removeFront(indexed)
-				if fallbackErr != nil {
-					pd := *pendingDocs
-					if len(pd) > 0 {
-						skipped := pd[0]
-						logx.Error(svc, "fallback search upsert", fallbackErr, map[string]any{"document_id": skipped.ID})
-						removeFront(1)
-					} else {
-						logx.Error(svc, "fallback search upsert", fallbackErr, nil)
-					}
-					if result.Err != nil {
-						result.Err = errors.Join(result.Err, err, fallbackErr)
-					} else {
-						result.Err = errors.Join(err, fallbackErr)
-					}
-					if result.Reason == "" {
-						result.Reason = "search upsert"
-					}
-					continue
-				}
-				continue
+		pending.docs = append(pending.docs, docs...)
+	}
+	var chunks [][]search.Document
+	for len(pending.docs) >= batchSize {
+		chunks = append(chunks, pending.docs[:batchSize:batchSize])
+		pending.docs = pending.docs[batchSize:]
+	}
+	pending.mu.Unlock()
+
+	for _, chunk := range chunks {
+		if err := flushDocuments(ctx, svc, searchClient, chunk); err != nil {
+			if result.Err != nil {
+				result.Err = errors.Join(result.Err, err)
+			} else {
+				result.Err = err
+			}
+			if result.Reason == "" {
+				result.Reason = "search upsert"
 			}
-			removeFront(batchSize)
 		}
 	}
 
 	return result
 }
 
+// flushDocuments upserts docs to the search index as one batch. When the
+// batch is rejected the documents are upserted one at a time; a document
+// that still fails is logged and skipped, and the rest are batched again.
+// The returned error joins the failures behind every skipped document.
+func flushDocuments(ctx context.Context, svc string, indexer documentIndexer, docs []search.Document) error {
+	var errs []error
+	for len(docs) > 0 {
+		logx.Info(svc, "flush search batch", map[string]any{"batch_size": len(docs)})
+		err := indexer.UpsertBatch(ctx, docs)
+		if err == nil {
+			break
+		}
+		logx.Error(svc, "flush search batch", err, map[string]any{"batch_size": len(docs)})
+		indexed, fallbackErr := upsertDocumentsIndividually(ctx, indexer, docs)
+		if fallbackErr == nil {
+			break
+		}
+		logx.Error(svc, "fallback search upsert", fallbackErr, map[string]any{"document_id": docs[indexed].ID})
+		errs = append(errs, err, fallbackErr)
+		docs = docs[indexed+1:]
+	}
+	return errors.Join(errs...)
+}
+
 func upsertDocumentsIndividually(ctx context.Context, indexer documentIndexer, docs []search.Document) (int, error) {
 	for i, doc := range docs {
 		if err := indexer.UpsertDocuments(ctx, []search.Document{doc}); err != nil {
@@ -290,6 +483,12 @@ func upsertDocumentsIndividually(ctx context.Context, indexer documentIndexer, d
 type feedStore interface {
 	UpdateFeedCrawlState(context.Context, store.UpdateFeedCrawlStateParams) (store.Feed, error)
 	UpsertItem(context.Context, store.UpsertItemParams) (store.UpsertItemResult, error)
+	UpdateFeedBackoff(context.Context, store.UpdateFeedBackoffParams) error
+	ClearFeedBackoff(context.Context, string) error
+	UpdateFeedURL(context.Context, string, string) (store.Feed, error)
+	GetFeedByURL(context.Context, string) (store.Feed, error)
+	MergeFeeds(context.Context, string, string) (store.MergeFeedsResult, error)
+	RejectFeedRelocation(context.Context, string, string) error
 }
 
 type feedFetcher interface {
@@ -299,23 +498,43 @@ type feedFetcher interface {
 type documentIndexer interface {
 	UpsertDocuments(ctx context.Context, docs []search.Document) error
 	UpsertBatch(ctx context.Context, docs []search.Document) error
+	DeleteDocuments(ctx context.Context, ids []string) error
 }
 
 type FetchFeedResult struct {
-	FeedID  string
-	FeedURL string
-	Status  int
-	Items   int
-	Mutated bool
-	Err     error
-	RetryIn time.Duration
-	Skipped bool
-	Reason  string
+	FeedID      string
+	FeedURL     string
+	Status      int
+	Items       int
+	Mutated     bool
+	Err         error
+	RetryIn     time.Duration
+	Skipped     bool
+	Reason      string
+	NextCrawlIn time.Duration
+	Hints       feed.RefreshHints
+	// MovedTo is the URL the feed permanently redirected to, if any.
+	MovedTo string
+	// Deactivated holds the reason the feed was deactivated, if it was.
+	Deactivated string
+	// Relocated is the URL the feed moved to after announcing a new
+	// location in-band.
+	Relocated string
+	// Changed holds the upserts that inserted an item or changed its
+	// content.
+	Changed []store.UpsertItemResult
 }
 
 var ErrBackoffActive = errors.New("backoff active")
 
-func FetchFeed(ctx context.Context, repo feedStore, _ documentIndexer, fetcher feedFetcher, rateLimitBackoffs, transientBackoffs *backoffTracker, f store.Feed) (FetchFeedResult, []search.Document) {
+// Backoff reasons persisted with the feed. They select the tracker a stored
+// backoff is restored into.
+const (
+	backoffReasonRateLimit = "rate_limit"
+	backoffReasonTransient = "transient"
+)
+
+func FetchFeed(ctx context.Context, repo feedStore, indexer documentIndexer, fetcher feedFetcher, rateLimitBackoffs, transientBackoffs *backoffTracker, f store.Feed) (FetchFeedResult, []search.Document) {
 	result := FetchFeedResult{FeedID: f.ID, FeedURL: f.URL}
 
 	now := time.Now().UTC()
@@ -346,17 +565,39 @@ func FetchFeed(ctx context.Context, repo feedStore, _ documentIndexer, fetcher f
 
 	res, err := fetcher.Fetch(ctx, f.URL, etag, lastModified)
 	result.Status = res.Status
+	result.Hints = res.Hints
+	result.MovedTo = res.PermanentURL
+	if res.Feed == nil {
+		// Only a parsed document carries feed-level hints; reuse the ones
+		// stored from the last full fetch.
+		result.Hints.TTL = f.RefreshInterval
+		result.Hints.SkipHours = f.SkipHours
+		result.Hints.SkipDays = f.SkipDays
+	}
 	if err != nil {
 		result.Err = err
+		var blocked *feed.BlockedError
 		switch {
+		case errors.As(err, &blocked):
+			// A sibling feed got the host rate limited and no request was
+			// made, so this feed keeps its own backoff unchanged.
+			result.RetryIn = blocked.Until.Sub(now)
+			result.Skipped = true
+			result.Reason = "host blocked"
 		case errors.Is(err, feed.ErrTransientFetch):
 			duration := transientBackoffs.Schedule(f.ID, now, res.RetryAfter)
 			result.RetryIn = duration
 			result.Reason = "transient retry scheduled"
+			if saveErr := saveBackoff(ctx, repo, transientBackoffs, f.ID, backoffReasonTransient); saveErr != nil {
+				result.Err = errors.Join(result.Err, saveErr)
+			}
 		case errors.Is(err, feed.ErrRetryLater):
 			duration := rateLimitBackoffs.Schedule(f.ID, now, res.RetryAfter)
 			result.RetryIn = duration
 			result.Reason = "rate limit retry scheduled"
+			if saveErr := saveBackoff(ctx, repo, rateLimitBackoffs, f.ID, backoffReasonRateLimit); saveErr != nil {
+				result.Err = errors.Join(result.Err, saveErr)
+			}
 		default:
 			result.Reason = "fetch failed"
 		}
@@ -365,6 +606,11 @@ func FetchFeed(ctx context.Context, repo feedStore, _ documentIndexer, fetcher f
 
 	rateLimitBackoffs.Reset(f.ID)
 	transientBackoffs.Reset(f.ID)
+	if f.BackoffUntil.Valid || f.BackoffFailures > 0 {
+		if err := repo.ClearFeedBackoff(ctx, f.ID); err != nil {
+			result.Err = fmt.Errorf("clear backoff: %w", err)
+		}
+	}
 
 	if res.Status == http.StatusNotModified {
 		result.Skipped = true
@@ -385,11 +631,14 @@ func FetchFeed(ctx context.Context, repo feedStore, _ documentIndexer, fetcher f
 	}
 
 	if _, err := repo.UpdateFeedCrawlState(ctx, store.UpdateFeedCrawlStateParams{
-		ID:           f.ID,
-		ETag:         sqlNullString(res.ETag),
-		LastModified: sqlNullString(res.LastModified),
-		LastCrawled:  sqlNullTime(updateTime),
-		Title:        title,
+		ID:              f.ID,
+		ETag:            sqlNullString(res.ETag),
+		LastModified:    sqlNullString(res.LastModified),
+		LastCrawled:     sqlNullTime(updateTime),
+		Title:           title,
+		RefreshInterval: res.Hints.DeclaredInterval(),
+		SkipHours:       res.Hints.SkipHours,
+		SkipDays:        res.Hints.SkipDays,
 	}); err != nil {
 		result.Err = err
 		result.Reason = "update feed"
@@ -418,25 +667,95 @@ func FetchFeed(ctx context.Context, repo feedStore, _ documentIndexer, fetcher f
 		if !output.Indexed {
 			continue
 		}
-		doc := search.Document{
-			ID:          output.Item.ID,
-			FeedID:      output.Item.FeedID,
-			FeedTitle:   output.Item.FeedTitle,
-			Title:       output.Item.Title,
-			ContentText: output.Item.ContentText,
-			URL:         output.Item.URL,
+		docs = append(docs, itemDocument(output.Item))
+		result.Changed = append(result.Changed, output)
+	}
+
+	result.Items = len(docs)
+
+	moved, movedDocs, err := relocateFeed(ctx, repo, indexer, fetcher, f, res)
+	if err != nil {
+		wrapped := fmt.Errorf("relocate feed: %w", err)
+		if result.Err != nil {
+			result.Err = errors.Join(result.Err, wrapped)
+		} else {
+			result.Err = wrapped
 		}
-		if output.Item.PublishedAt.Valid {
-			t := output.Item.PublishedAt.Time.UTC()
-			doc.PublishedAt = &t
+		if result.Reason == "" {
+			result.Reason = "relocate"
 		}
-		docs = append(docs, doc)
 	}
+	result.Relocated = moved
+	docs = append(docs, movedDocs...)
 
-	result.Items = len(docs)
 	return result, docs
 }
 
+func itemDocument(it store.Item) search.Document {
+	doc := search.Document{
+		ID:          it.ID,
+		FeedID:      it.FeedID,
+		FeedTitle:   it.FeedTitle,
+		Title:       it.Title,
+		ContentText: it.ContentText,
+		URL:         it.URL,
+	}
+	if it.PublishedAt.Valid {
+		t := it.PublishedAt.Time.UTC()
+		doc.PublishedAt = &t
+	}
+	return doc
+}
+
+func saveBackoff(ctx context.Context, repo feedStore, tracker *backoffTracker, id, reason string) error {
+	entry, ok := tracker.Entry(id)
+	if !ok {
+		return nil
+	}
+	if err := repo.UpdateFeedBackoff(ctx, store.UpdateFeedBackoffParams{
+		ID:       id,
+		Until:    entry.until,
+		Duration: entry.duration,
+		Reason:   reason,
+		Failures: int32(entry.failures),
+	}); err != nil {
+		return fmt.Errorf("save backoff: %w", err)
+	}
+	return nil
+}
+
+// restoreBackoffs loads backoffs persisted by a previous run into the
+// trackers. Rate limits are also re-applied to the feed's host so sibling
+// feeds keep waiting.
+func restoreBackoffs(feeds []store.Feed, rateLimitBackoffs, transientBackoffs *backoffTracker, hosts *feed.HostScheduler) int {
+	restored := 0
+	for _, f := range feeds {
+		if !f.BackoffUntil.Valid {
+			continue
+		}
+		entry := backoffEntry{
+			until:    f.BackoffUntil.Time,
+			duration: f.BackoffDuration,
+			failures: int(f.BackoffFailures),
+		}
+		switch f.BackoffReason.String {
+		case backoffReasonRateLimit:
+			rateLimitBackoffs.Restore(f.ID, entry)
+			if hosts != nil {
+				if host := feed.HostKey(f.URL); host != "" {
+					hosts.Block(host, entry.until)
+				}
+			}
+		case backoffReasonTransient:
+			transientBackoffs.Restore(f.ID, entry)
+		default:
+			continue
+		}
+		restored++
+	}
+	return restored
+}
+
 func sqlNullString(v string) sql.NullString {
 	if v == "" {
 		return sql.NullString{}
@@ -444,7 +763,9 @@ func sqlNullString(v string) sql.NullString {
 	return sql.NullString{Valid: true, String: v}
 }
 
+// backoffTracker is shared by the crawl workers, so every method takes mu.
 type backoffTracker struct {
+	mu     sync.Mutex
 	min    time.Duration
 	max    time.Duration
 	factor float64
@@ -454,6 +775,7 @@ type backoffTracker struct {
 type backoffEntry struct {
 	until    time.Time
 	duration time.Duration
+	failures int
 }
 
 func newBackoffTracker() *backoffTracker {
@@ -470,18 +792,22 @@ func newBackoffTrackerWith(min, max time.Duration) *backoffTracker {
 }
 
 func (b *backoffTracker) Remaining(id string, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Expired entries are kept until Reset so the next failure keeps growing
+	// the duration instead of starting over at min.
 	entry, ok := b.items[id]
-	if !ok {
-		return 0
-	}
-	if now.After(entry.until) {
-		delete(b.items, id)
+	if !ok || now.After(entry.until) {
 		return 0
 	}
 	return entry.until.Sub(now)
 }
 
 func (b *backoffTracker) Schedule(id string, now time.Time, suggested time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	entry := b.items[id]
 	duration := suggested
 	if duration <= 0 {
@@ -496,11 +822,32 @@ func (b *backoffTracker) Schedule(id string, now time.Time, suggested time.Durat
 	}
 	entry.duration = duration
 	entry.until = now.Add(duration)
+	entry.failures++
 	b.items[id] = entry
 	return duration
 }
 
+func (b *backoffTracker) Entry(id string) (backoffEntry, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.items[id]
+	return entry, ok
+}
+
+// Restore installs a backoff loaded from the store, replacing any entry the
+// tracker already holds for id.
+func (b *backoffTracker) Restore(id string, entry backoffEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.items[id] = entry
+}
+
 func (b *backoffTracker) Reset(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	delete(b.items, id)
 }
 
@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mmcdole/gofeed"
 
@@ -15,13 +18,31 @@ import (
 )
 
 type stubFeedStore struct {
+	mu            sync.Mutex
 	updates       []store.UpdateFeedCrawlStateParams
 	upserts       []store.UpsertItemParams
 	feeds         []store.Feed
 	upsertResults []store.UpsertItemResult
+	publishTimes  map[string][]time.Time
+	scheduled     map[string]time.Time
+	backoffs      []store.UpdateFeedBackoffParams
+	cleared       []string
+	fetches       []store.RecordFeedFetchParams
+	moved         map[string]string
+	deactivated   map[string]string
+	movedErr      error
+	merges        [][2]string
+	mergeResult   store.MergeFeedsResult
+	rejected      map[string]string
+	savedSearches []store.SavedSearch
+	savedMatches  map[string][]string
+	webhooks      []store.Webhook
+	webhookEvents map[string][]store.WebhookEvent
 }
 
 func (s *stubFeedStore) UpdateFeedCrawlState(ctx context.Context, arg store.UpdateFeedCrawlStateParams) (store.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.updates = append(s.updates, arg)
 	return store.Feed{
 		ID:           arg.ID,
@@ -34,7 +55,81 @@ func (s *stubFeedStore) UpdateFeedCrawlState(ctx context.Context, arg store.Upda
 	}, nil
 }
 
+func (s *stubFeedStore) UpdateFeedBackoff(ctx context.Context, arg store.UpdateFeedBackoffParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backoffs = append(s.backoffs, arg)
+	return nil
+}
+
+func (s *stubFeedStore) ClearFeedBackoff(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleared = append(s.cleared, id)
+	return nil
+}
+
+func (s *stubFeedStore) RecordFeedFetch(ctx context.Context, arg store.RecordFeedFetchParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches = append(s.fetches, arg)
+	return nil
+}
+
+func (s *stubFeedStore) UpdateFeedURL(ctx context.Context, id, url string) (store.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.movedErr != nil {
+		return store.Feed{}, s.movedErr
+	}
+	if s.moved == nil {
+		s.moved = make(map[string]string)
+	}
+	s.moved[id] = url
+	return store.Feed{ID: id, URL: url, Active: true}, nil
+}
+
+func (s *stubFeedStore) GetFeedByURL(ctx context.Context, url string) (store.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.feeds {
+		if f.URL == url {
+			return f, nil
+		}
+	}
+	return store.Feed{}, sql.ErrNoRows
+}
+
+func (s *stubFeedStore) MergeFeeds(ctx context.Context, keepID, dropID string) (store.MergeFeedsResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.merges = append(s.merges, [2]string{keepID, dropID})
+	return s.mergeResult, nil
+}
+
+func (s *stubFeedStore) RejectFeedRelocation(ctx context.Context, id, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rejected == nil {
+		s.rejected = make(map[string]string)
+	}
+	s.rejected[id] = url
+	return nil
+}
+
+func (s *stubFeedStore) DeactivateFeed(ctx context.Context, id, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deactivated == nil {
+		s.deactivated = make(map[string]string)
+	}
+	s.deactivated[id] = reason
+	return nil
+}
+
 func (s *stubFeedStore) UpsertItem(ctx context.Context, arg store.UpsertItemParams) (store.UpsertItemResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.upserts = append(s.upserts, arg)
 	result := store.UpsertItemResult{
 		Item: store.Item{
@@ -72,22 +167,80 @@ func (s *stubFeedStore) UpsertItem(ctx context.Context, arg store.UpsertItemPara
 	return result, nil
 }
 
-func (s *stubFeedStore) ListFeeds(ctx context.Context, active bool) ([]store.Feed, error) {
+func (s *stubFeedStore) ListDueFeeds(ctx context.Context, now time.Time) ([]store.Feed, error) {
 	feeds := make([]store.Feed, len(s.feeds))
 	copy(feeds, s.feeds)
 	return feeds, nil
 }
 
+func (s *stubFeedStore) ListFeedPublishTimes(ctx context.Context, feedID string, limit int32) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.publishTimes[feedID], nil
+}
+
+func (s *stubFeedStore) ScheduleFeedCrawl(ctx context.Context, feedID string, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scheduled == nil {
+		s.scheduled = make(map[string]time.Time)
+	}
+	s.scheduled[feedID] = next
+	return nil
+}
+
+func (s *stubFeedStore) ListSavedSearchQueries(ctx context.Context) ([]store.SavedSearch, error) {
+	return s.savedSearches, nil
+}
+
+func (s *stubFeedStore) AddSavedSearchMatches(ctx context.Context, id string, itemIDs []string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.savedMatches == nil {
+		s.savedMatches = make(map[string][]string)
+	}
+	s.savedMatches[id] = append(s.savedMatches[id], itemIDs...)
+	return int64(len(itemIDs)), nil
+}
+
+func (s *stubFeedStore) ListActiveWebhooks(ctx context.Context) ([]store.Webhook, error) {
+	return s.webhooks, nil
+}
+
+func (s *stubFeedStore) EnqueueWebhookDeliveries(ctx context.Context, id string, events []store.WebhookEvent) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.webhookEvents == nil {
+		s.webhookEvents = make(map[string][]store.WebhookEvent)
+	}
+	s.webhookEvents[id] = append(s.webhookEvents[id], events...)
+	return int64(len(events)), nil
+}
+
+var testSchedule = newCrawlSchedule(2*time.Minute, 6*time.Hour)
+
+var testLifecycle = newFeedLifecycle(3)
+
 type stubSearchClient struct {
+	mu            sync.Mutex
 	docCalls      [][]search.Document
 	batchCalls    [][]search.Document
 	batchErr      error
 	batchErrLimit int
 	batchErrCount int
 	docErrs       []error
+	deleted       []string
 }
 
 func (s *stubSearchClient) UpsertDocuments(ctx context.Context, docs []search.Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	copied := make([]search.Document, len(docs))
 	copy(copied, docs)
 	s.docCalls = append(s.docCalls, copied)
@@ -100,6 +253,8 @@ func (s *stubSearchClient) UpsertDocuments(ctx context.Context, docs []search.Do
 }
 
 func (s *stubSearchClient) UpsertBatch(ctx context.Context, docs []search.Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	copied := make([]search.Document, len(docs))
 	copy(copied, docs)
 	s.batchCalls = append(s.batchCalls, copied)
@@ -112,23 +267,34 @@ func (s *stubSearchClient) UpsertBatch(ctx context.Context, docs []search.Docume
 	return nil
 }
 
+func (s *stubSearchClient) DeleteDocuments(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, ids...)
+	return nil
+}
+
 type fetchResponse struct {
 	result feed.Result
 	err    error
 }
 
 type stubFetcher struct {
+	mu        sync.Mutex
 	responses []fetchResponse
 	calls     []fetchCall
 }
 
 type fetchCall struct {
+	url          string
 	etag         string
 	lastModified string
 }
 
 func (s *stubFetcher) Fetch(ctx context.Context, url, etag, lastModified string) (feed.Result, error) {
-	s.calls = append(s.calls, fetchCall{etag: etag, lastModified: lastModified})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, fetchCall{url: url, etag: etag, lastModified: lastModified})
 	if len(s.responses) == 0 {
 		return feed.Result{}, nil
 	}
@@ -361,7 +527,7 @@ func TestRunIndexesOnlyChangedItems(t *testing.T) {
 			}}}
 
 			ctx := context.Background()
-			run(ctx, "fetcher", repo, searchClient, fetcher, newBackoffTracker(), newBackoffTracker(), 10)
+			run(ctx, "fetcher", repo, searchClient, fetcher, newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, 1)
 
 			if len(repo.upserts) != 1 {
 				t.Fatalf("expected one item upsert, got %d", len(repo.upserts))
@@ -425,7 +591,7 @@ func TestRunFallsBackToSingleDocumentUpserts(t *testing.T) {
 		}
 
 		ctx := context.Background()
-		run(ctx, "fetcher", repo, searchClient, makeFetcher(), newBackoffTracker(), newBackoffTracker(), 10)
+		run(ctx, "fetcher", repo, searchClient, makeFetcher(), newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, 1)
 
 		if len(searchClient.batchCalls) != 1 {
 			t.Fatalf("expected one batch attempt, got %d", len(searchClient.batchCalls))
@@ -457,7 +623,7 @@ func TestRunFallsBackToSingleDocumentUpserts(t *testing.T) {
 		}
 
 		ctx := context.Background()
-		run(ctx, "fetcher", repo, searchClient, makeFetcher(), newBackoffTracker(), newBackoffTracker(), 10)
+		run(ctx, "fetcher", repo, searchClient, makeFetcher(), newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, 1)
 
 		if len(searchClient.batchCalls) != 2 {
 			t.Fatalf("expected two batch attempts, got %d", len(searchClient.batchCalls))
@@ -474,8 +640,240 @@ func TestRunFallsBackToSingleDocumentUpserts(t *testing.T) {
 	})
 }
 
+func TestRunProcessesFeedsConcurrently(t *testing.T) {
+	const feedCount = 12
+
+	feeds := make([]store.Feed, 0, feedCount)
+	responses := make([]fetchResponse, 0, feedCount)
+	for i := 0; i < feedCount; i++ {
+		id := fmt.Sprintf("feed-%d", i)
+		feeds = append(feeds, store.Feed{ID: id, URL: "http://example.com/" + id, Active: true})
+		responses = append(responses, fetchResponse{result: feed.Result{
+			Status: http.StatusOK,
+			Feed: &gofeed.Feed{Items: []*gofeed.Item{{
+				Title: "Post",
+				Link:  "http://example.com/post",
+			}}},
+		}})
+	}
+
+	repo := &stubFeedStore{feeds: feeds}
+	searchClient := &stubSearchClient{}
+	fetcher := &stubFetcher{responses: responses}
+
+	run(context.Background(), "fetcher", repo, searchClient, fetcher, newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 5, 4)
+
+	if len(fetcher.calls) != feedCount {
+		t.Fatalf("expected %d fetch calls, got %d", feedCount, len(fetcher.calls))
+	}
+	if len(repo.updates) != feedCount {
+		t.Fatalf("expected %d crawl state updates, got %d", feedCount, len(repo.updates))
+	}
+
+	seen := make(map[string]int)
+	for _, batch := range searchClient.batchCalls {
+		if len(batch) > 5 {
+			t.Fatalf("batch of %d documents exceeds batch size 5", len(batch))
+		}
+		for _, doc := range batch {
+			seen[doc.FeedID]++
+		}
+	}
+	for _, f := range feeds {
+		if seen[f.ID] != 1 {
+			t.Fatalf("feed %s indexed %d documents, want 1", f.ID, seen[f.ID])
+		}
+	}
+}
+
+type panicFetcher struct {
+	stubFetcher
+	panicURL string
+}
+
+func (p *panicFetcher) Fetch(ctx context.Context, url, etag, lastModified string) (feed.Result, error) {
+	if url == p.panicURL {
+		panic("boom")
+	}
+	return p.stubFetcher.Fetch(ctx, url, etag, lastModified)
+}
+
+func TestRunRecoversFromFeedPanic(t *testing.T) {
+	repo := &stubFeedStore{feeds: []store.Feed{
+		{ID: "feed-1", URL: "http://example.com/panic", Active: true},
+		{ID: "feed-2", URL: "http://example.com/ok", Active: true},
+	}}
+	searchClient := &stubSearchClient{}
+	fetcher := &panicFetcher{
+		panicURL: "http://example.com/panic",
+		stubFetcher: stubFetcher{responses: []fetchResponse{{
+			result: feed.Result{
+				Status: http.StatusOK,
+				Feed:   &gofeed.Feed{Items: []*gofeed.Item{{Title: "Post", Link: "http://example.com/post"}}},
+			},
+		}}},
+	}
+
+	run(context.Background(), "fetcher", repo, searchClient, fetcher, newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, 2)
+
+	if len(searchClient.batchCalls) != 1 {
+		t.Fatalf("expected one batch flush, got %d", len(searchClient.batchCalls))
+	}
+	batch := searchClient.batchCalls[0]
+	if len(batch) != 1 || batch[0].FeedID != "feed-2" {
+		t.Fatalf("expected only feed-2 document to be flushed, got %+v", batch)
+	}
+}
+
+func TestRecoverFeedPanicDropsOnlyPanickingFeedDocs(t *testing.T) {
+	pending := &pendingBatch{docs: []search.Document{
+		{ID: "a", FeedID: "feed-1"},
+		{ID: "b", FeedID: "feed-2"},
+		{ID: "c", FeedID: "feed-1"},
+	}}
+
+	var result FetchFeedResult
+	func() {
+		defer recoverFeedPanic("fetcher", store.Feed{ID: "feed-1"}, pending, &result)
+		panic("boom")
+	}()
+
+	if len(pending.docs) != 1 || pending.docs[0].ID != "b" {
+		t.Fatalf("expected only feed-2 document to remain, got %+v", pending.docs)
+	}
+	if result.Err == nil || !result.Skipped || result.Reason != "panic" {
+		t.Fatalf("expected panic result, got %+v", result)
+	}
+	if result.Status != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", result.Status, http.StatusInternalServerError)
+	}
+}
+
 // Ensure stub satisfies interfaces at compile time.
 var _ feedStore = (*stubFeedStore)(nil)
 var _ feedRepository = (*stubFeedStore)(nil)
 var _ documentIndexer = (*stubSearchClient)(nil)
 var _ feedFetcher = (*stubFetcher)(nil)
+
+func TestFetchFeedPersistsBackoff(t *testing.T) {
+	repo := &stubFeedStore{}
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{Status: http.StatusTooManyRequests, RetryAfter: 2 * time.Minute}, err: feed.ErrRetryLater},
+	}}
+	rateLimitBackoffs := newBackoffTracker()
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/feed"}
+
+	result, _ := FetchFeed(context.Background(), repo, &stubSearchClient{}, fetcher, rateLimitBackoffs, newBackoffTracker(), f)
+	if result.RetryIn != 2*time.Minute {
+		t.Fatalf("RetryIn = %s, want 2m", result.RetryIn)
+	}
+	if len(repo.backoffs) != 1 {
+		t.Fatalf("expected backoff to be persisted once, got %d", len(repo.backoffs))
+	}
+	saved := repo.backoffs[0]
+	if saved.ID != f.ID || saved.Reason != backoffReasonRateLimit || saved.Duration != 2*time.Minute || saved.Failures != 1 {
+		t.Fatalf("unexpected persisted backoff: %+v", saved)
+	}
+	if saved.Until.IsZero() {
+		t.Fatalf("expected backoff until to be set")
+	}
+}
+
+func TestFetchFeedSkipsBlockedHostWithoutBackoff(t *testing.T) {
+	repo := &stubFeedStore{}
+	until := time.Now().Add(3 * time.Minute)
+	blocked := &feed.BlockedError{Host: "example.com", Until: until}
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{RetryAfter: time.Until(until)}, err: fmt.Errorf("%w: %w", feed.ErrRetryLater, blocked)},
+	}}
+	rateLimitBackoffs := newBackoffTracker()
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/feed"}
+
+	result, _ := FetchFeed(context.Background(), repo, &stubSearchClient{}, fetcher, rateLimitBackoffs, newBackoffTracker(), f)
+	if !result.Skipped || result.Reason != "host blocked" {
+		t.Fatalf("expected blocked feed to be skipped, got %+v", result)
+	}
+	if result.RetryIn <= 0 || result.RetryIn > 3*time.Minute {
+		t.Fatalf("RetryIn = %s, want the host block", result.RetryIn)
+	}
+	if _, ok := rateLimitBackoffs.Entry(f.ID); ok {
+		t.Fatalf("expected no per-feed backoff to be scheduled")
+	}
+	if len(repo.backoffs) != 0 {
+		t.Fatalf("expected no backoff to be persisted, got %+v", repo.backoffs)
+	}
+}
+
+func TestFetchFeedClearsPersistedBackoffOnSuccess(t *testing.T) {
+	repo := &stubFeedStore{}
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{Status: http.StatusNotModified}},
+	}}
+	f := store.Feed{
+		ID:              "feed-1",
+		URL:             "http://example.com/feed",
+		BackoffUntil:    sql.NullTime{Valid: true, Time: time.Now().Add(-time.Minute)},
+		BackoffFailures: 2,
+	}
+
+	result, _ := FetchFeed(context.Background(), repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), f)
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+	if len(repo.cleared) != 1 || repo.cleared[0] != f.ID {
+		t.Fatalf("expected backoff to be cleared for %s, got %v", f.ID, repo.cleared)
+	}
+}
+
+func TestRestoreBackoffs(t *testing.T) {
+	now := time.Now()
+	rateLimitBackoffs := newBackoffTracker()
+	transientBackoffs := newBackoffTrackerWith(5*time.Second, 2*time.Minute)
+	hosts := feed.NewHostScheduler(0, 1)
+
+	restored := restoreBackoffs([]store.Feed{
+		{
+			ID:              "feed-1",
+			URL:             "http://example.com/a",
+			BackoffUntil:    sql.NullTime{Valid: true, Time: now.Add(10 * time.Minute)},
+			BackoffDuration: 10 * time.Minute,
+			BackoffReason:   sql.NullString{Valid: true, String: backoffReasonRateLimit},
+			BackoffFailures: 4,
+		},
+		{
+			ID:              "feed-2",
+			URL:             "http://other.example.com/b",
+			BackoffUntil:    sql.NullTime{Valid: true, Time: now.Add(time.Minute)},
+			BackoffDuration: time.Minute,
+			BackoffReason:   sql.NullString{Valid: true, String: backoffReasonTransient},
+			BackoffFailures: 1,
+		},
+		{ID: "feed-3", URL: "http://example.com/c"},
+	}, rateLimitBackoffs, transientBackoffs, hosts)
+
+	if restored != 2 {
+		t.Fatalf("restored = %d, want 2", restored)
+	}
+	if wait := rateLimitBackoffs.Remaining("feed-1", now); wait < 9*time.Minute {
+		t.Fatalf("expected rate limit backoff to be restored, got %s", wait)
+	}
+	if wait := transientBackoffs.Remaining("feed-2", now); wait <= 0 {
+		t.Fatalf("expected transient backoff to be restored")
+	}
+	if wait := rateLimitBackoffs.Remaining("feed-2", now); wait != 0 {
+		t.Fatalf("expected transient backoff to stay out of the rate limit tracker, got %s", wait)
+	}
+	if blocked := hosts.Blocked("example.com", now); blocked < 9*time.Minute {
+		t.Fatalf("expected rate limited host to be blocked, got %s", blocked)
+	}
+
+	// The restored duration and failure count carry into the next failure.
+	next := rateLimitBackoffs.Schedule("feed-1", now.Add(11*time.Minute), 0)
+	if next != 10*time.Minute {
+		t.Fatalf("next backoff = %s, want capped 10m", next)
+	}
+	entry, _ := rateLimitBackoffs.Entry("feed-1")
+	if entry.failures != 5 {
+		t.Fatalf("failures = %d, want 5", entry.failures)
+	}
+}
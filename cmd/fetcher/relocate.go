@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"courier/internal/feed"
+	"courier/internal/search"
+	"courier/internal/store"
+)
+
+// relocateFeed follows an in-band relocation announced by the fetched
+// document (itunes:new-feed-url or a changed atom:link rel="self"). The new
+// URL is fetched and must serve the same feed before the subscription moves;
+// a target that is already subscribed is merged into f so items stay under
+// f's ID. It returns the URL the feed moved to, if any, and search documents
+// for items that changed feeds.
+func relocateFeed(ctx context.Context, repo feedStore, indexer documentIndexer, fetcher feedFetcher, f store.Feed, current feed.Result) (string, []search.Document, error) {
+	candidate := feed.Relocation(current.Feed, f.URL)
+	if candidate == "" || candidate == current.PermanentURL {
+		return "", nil, nil
+	}
+	if f.RejectedRelocation.Valid && f.RejectedRelocation.String == candidate {
+		return "", nil, nil
+	}
+
+	res, err := fetcher.Fetch(ctx, candidate, "", "")
+	if err != nil && (errors.Is(err, feed.ErrTransientFetch) || errors.Is(err, feed.ErrRetryLater)) {
+		// Try again on a later crawl rather than rejecting the target.
+		return "", nil, nil
+	}
+	if err != nil || res.Feed == nil || !feed.SameFeed(current.Feed, res.Feed) {
+		return "", nil, rejectRelocation(ctx, repo, f.ID, candidate)
+	}
+
+	target := candidate
+	if res.PermanentURL != "" {
+		target = res.PermanentURL
+	}
+
+	var docs []search.Document
+	existing, err := repo.GetFeedByURL(ctx, target)
+	switch {
+	case err == nil && existing.ID != f.ID:
+		merged, err := repo.MergeFeeds(ctx, f.ID, existing.ID)
+		if err != nil {
+			return "", nil, fmt.Errorf("merge feeds: %w", err)
+		}
+		if err := indexer.DeleteDocuments(ctx, merged.Dropped); err != nil {
+			return "", nil, fmt.Errorf("delete merged documents: %w", err)
+		}
+		for _, it := range merged.Moved {
+			docs = append(docs, itemDocument(it))
+		}
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return "", nil, fmt.Errorf("lookup relocation target: %w", err)
+	}
+
+	if _, err := repo.UpdateFeedURL(ctx, f.ID, target); err != nil {
+		if errors.Is(err, store.ErrFeedExists) {
+			return "", docs, rejectRelocation(ctx, repo, f.ID, candidate)
+		}
+		return "", docs, fmt.Errorf("update feed url: %w", err)
+	}
+	return target, docs, nil
+}
+
+func rejectRelocation(ctx context.Context, repo feedStore, id, candidate string) error {
+	if err := repo.RejectFeedRelocation(ctx, id, candidate); err != nil {
+		return fmt.Errorf("reject relocation: %w", err)
+	}
+	return nil
+}
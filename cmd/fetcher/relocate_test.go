@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+
+	"courier/internal/feed"
+	"courier/internal/store"
+)
+
+func TestFetchFeedFollowsVerifiedRelocation(t *testing.T) {
+	repo := &stubFeedStore{}
+	items := []*gofeed.Item{{GUID: "episode-1", Link: "http://example.com/1", Title: "Episode 1"}}
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{
+			Title:     "Show",
+			Items:     items,
+			ITunesExt: &ext.ITunesFeedExtension{NewFeedURL: "http://podcasts.example.net/show"},
+		}}},
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{Title: "Show", Items: items}}},
+	}}
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/show"}
+
+	result, _ := FetchFeed(context.Background(), repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), f)
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+	if result.Relocated != "http://podcasts.example.net/show" {
+		t.Fatalf("Relocated = %q, want the new feed URL", result.Relocated)
+	}
+	if len(fetcher.calls) != 2 || fetcher.calls[1].url != "http://podcasts.example.net/show" {
+		t.Fatalf("expected the new URL to be fetched for verification, got %+v", fetcher.calls)
+	}
+	if got := repo.moved["feed-1"]; got != "http://podcasts.example.net/show" {
+		t.Fatalf("expected feed URL to be updated, got %q", got)
+	}
+}
+
+func TestFetchFeedRejectsUnverifiedRelocation(t *testing.T) {
+	repo := &stubFeedStore{}
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{
+			Title:    "Blog",
+			FeedLink: "http://example.com/other",
+			Items:    []*gofeed.Item{{GUID: "a", Title: "A"}},
+		}}},
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{Title: "Other", Items: []*gofeed.Item{{GUID: "z"}}}}},
+	}}
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/feed"}
+
+	result, _ := FetchFeed(context.Background(), repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), f)
+	if result.Relocated != "" {
+		t.Fatalf("expected no relocation, got %q", result.Relocated)
+	}
+	if len(repo.moved) != 0 {
+		t.Fatalf("expected feed URL to stay, got %v", repo.moved)
+	}
+	if got := repo.rejected["feed-1"]; got != "http://example.com/other" {
+		t.Fatalf("expected candidate to be rejected, got %q", got)
+	}
+
+	// A rejected candidate is not fetched again.
+	f.RejectedRelocation = sql.NullString{Valid: true, String: "http://example.com/other"}
+	fetcher.responses = []fetchResponse{
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{Title: "Blog", FeedLink: "http://example.com/other"}}},
+	}
+	fetcher.calls = nil
+	FetchFeed(context.Background(), repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), f)
+	if len(fetcher.calls) != 1 {
+		t.Fatalf("expected only the feed itself to be fetched, got %+v", fetcher.calls)
+	}
+}
+
+func TestFetchFeedMergesRelocationIntoExistingFeed(t *testing.T) {
+	repo := &stubFeedStore{
+		feeds: []store.Feed{{ID: "feed-2", URL: "https://example.com/feed"}},
+		mergeResult: store.MergeFeedsResult{
+			Moved:   []store.Item{{ID: "item-9", FeedID: "feed-1", Title: "Only in the old subscription"}},
+			Dropped: []string{"item-8"},
+		},
+	}
+	items := []*gofeed.Item{{GUID: "a", Title: "A"}}
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{Title: "Blog", FeedLink: "https://example.com/feed", Items: items}}},
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{Title: "Blog", Items: items}}},
+	}}
+	searchClient := &stubSearchClient{}
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/rss"}
+
+	result, docs := FetchFeed(context.Background(), repo, searchClient, fetcher, newBackoffTracker(), newBackoffTracker(), f)
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+	if len(repo.merges) != 1 || repo.merges[0] != [2]string{"feed-1", "feed-2"} {
+		t.Fatalf("expected feed-2 to be merged into feed-1, got %v", repo.merges)
+	}
+	if got := repo.moved["feed-1"]; got != "https://example.com/feed" {
+		t.Fatalf("expected feed-1 to take over the URL, got %q", got)
+	}
+	if len(searchClient.deleted) != 1 || searchClient.deleted[0] != "item-8" {
+		t.Fatalf("expected duplicate item document to be deleted, got %v", searchClient.deleted)
+	}
+	found := false
+	for _, doc := range docs {
+		if doc.ID == "item-9" && doc.FeedID == "feed-1" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected moved item to be reindexed under feed-1, got %+v", docs)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"courier/internal/logx"
+	"courier/internal/search"
+	"courier/internal/store"
+)
+
+type savedSearchStore interface {
+	ListSavedSearchQueries(context.Context) ([]store.SavedSearch, error)
+	AddSavedSearchMatches(context.Context, string, []string) (int64, error)
+}
+
+// evaluateSavedSearches records which of the documents a feed indexed each
+// saved search matches. Queries are matched here rather than in
+// Meilisearch, which indexes asynchronously and may not have the documents
+// yet, so parseQuery and matchesQuery, not the search index, define what a
+// saved search matches; GET /search ranks the same query with Meilisearch's
+// looser rules. The store then drops matches outside the search's owner's
+// subscriptions and filters. It returns how many matches were added.
+func evaluateSavedSearches(ctx context.Context, svc string, repo savedSearchStore, docs []search.Document) int64 {
+	if len(docs) == 0 {
+		return 0
+	}
+	searches, err := repo.ListSavedSearchQueries(ctx)
+	if err != nil {
+		logx.Error(svc, "list saved searches", err, nil)
+		return 0
+	}
+
+	var added int64
+	for _, saved := range searches {
+		terms := parseQuery(saved.Query)
+		var ids []string
+		for _, doc := range docs {
+			if matchesQuery(terms, doc) {
+				ids = append(ids, doc.ID)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		n, err := repo.AddSavedSearchMatches(ctx, saved.ID, ids)
+		if err != nil {
+			logx.Error(svc, "record saved search matches", err, map[string]any{"saved_search_id": saved.ID})
+			continue
+		}
+		added += n
+	}
+	if added > 0 {
+		logx.Info(svc, "saved search matches", map[string]any{"searches": len(searches), "documents": len(docs), "added": added})
+	}
+	return added
+}
+
+// queryTerm is a word, or a quoted phrase of consecutive words, that a
+// document must contain.
+type queryTerm struct {
+	words []string
+	// prefix lets the last word match the start of a longer one.
+	prefix bool
+}
+
+// parseQuery reads a query the way Meilisearch does by default: words
+// match case-insensitively, "quoted phrases" match as consecutive words and
+// the final word also matches as a prefix. Unlike Meilisearch, every term
+// is required and typos are not tolerated.
+func parseQuery(query string) []queryTerm {
+	var terms []queryTerm
+	for i, part := range strings.Split(query, `"`) {
+		words := queryWords(part)
+		if len(words) == 0 {
+			continue
+		}
+		if i%2 == 1 {
+			terms = append(terms, queryTerm{words: words})
+			continue
+		}
+		for _, word := range words {
+			terms = append(terms, queryTerm{words: []string{word}})
+		}
+	}
+	if n := len(terms); n > 0 && len(terms[n-1].words) == 1 && !strings.HasSuffix(strings.TrimSpace(query), `"`) {
+		terms[n-1].prefix = true
+	}
+	return terms
+}
+
+// matchesQuery reports whether doc's title or content contains every term.
+// A query without terms matches everything.
+func matchesQuery(terms []queryTerm, doc search.Document) bool {
+	fields := [][]string{queryWords(doc.Title), queryWords(doc.ContentText)}
+	for _, term := range terms {
+		found := false
+		for _, words := range fields {
+			if containsTerm(words, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTerm(words []string, term queryTerm) bool {
+	n := len(term.words)
+	for i := 0; i+n <= len(words); i++ {
+		matched := true
+		for j, want := range term.words {
+			got := words[i+j]
+			if got == want || (term.prefix && j == n-1 && strings.HasPrefix(got, want)) {
+				continue
+			}
+			matched = false
+			break
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func queryWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
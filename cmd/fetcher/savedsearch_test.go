@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"courier/internal/feed"
+	"courier/internal/search"
+	"courier/internal/store"
+)
+
+func TestMatchesQuery(t *testing.T) {
+	doc := search.Document{
+		Title:       "Go 1.22 Released",
+		ContentText: "Range over integers and a new routing pattern syntax in net/http.",
+	}
+	for _, tc := range []struct {
+		query string
+		want  bool
+	}{
+		{"go", true},
+		{"GO released", true},
+		{"routing patterns", false},
+		{"routing patt", true},
+		{`"routing pattern"`, true},
+		{`"pattern routing"`, false},
+		{`"rout"`, false},
+		{"rust", false},
+		{"go rust", false},
+		{"net http", true},
+		{"", true},
+	} {
+		if got := matchesQuery(parseQuery(tc.query), doc); got != tc.want {
+			t.Errorf("matchesQuery(%q) = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestRunRecordsSavedSearchMatches(t *testing.T) {
+	repo := &stubFeedStore{
+		feeds: []store.Feed{{ID: "feed-1", URL: "http://example.com/feed", Active: true}},
+		savedSearches: []store.SavedSearch{
+			{ID: "search-go", Query: "generics"},
+			{ID: "search-rust", Query: "borrow checker"},
+		},
+		upsertResults: []store.UpsertItemResult{
+			{Item: store.Item{ID: "item-1"}, Indexed: true},
+			{Item: store.Item{ID: "item-2"}, Indexed: true},
+			{Item: store.Item{ID: "item-3"}, Indexed: false},
+		},
+	}
+	fetcher := &stubFetcher{responses: []fetchResponse{{result: feed.Result{
+		Status: http.StatusOK,
+		Feed: &gofeed.Feed{Items: []*gofeed.Item{
+			{Title: "Generics in Go", Link: "http://example.com/1"},
+			{Title: "Iterators", Link: "http://example.com/2"},
+			{Title: "More generics", Link: "http://example.com/3"},
+		}},
+	}}}}
+
+	run(context.Background(), "fetcher", repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, 1)
+
+	if got := repo.savedMatches["search-go"]; len(got) != 1 || got[0] != "item-1" {
+		t.Fatalf("expected only the newly indexed match, got %v", got)
+	}
+	if got, ok := repo.savedMatches["search-rust"]; ok {
+		t.Fatalf("expected no matches for an unrelated search, got %v", got)
+	}
+}
+
+func TestProcessFeedRecordsSavedSearchMatchesAfterDeadline(t *testing.T) {
+	repo := &stubFeedStore{
+		savedSearches: []store.SavedSearch{{ID: "search-go", Query: "generics"}},
+		upsertResults: []store.UpsertItemResult{{Item: store.Item{ID: "item-1"}, Indexed: true}},
+	}
+	fetcher := &stubFetcher{responses: []fetchResponse{{result: feed.Result{
+		Status: http.StatusOK,
+		Feed:   &gofeed.Feed{Items: []*gofeed.Item{{Title: "Generics in Go", Link: "http://example.com/1"}}},
+	}}}}
+
+	// The tick ran out while the feed's items were being stored.
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/feed", Active: true}
+	processFeed(ctx, "fetcher", repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, &pendingBatch{}, f)
+
+	if got := repo.savedMatches["search-go"]; len(got) != 1 || got[0] != "item-1" {
+		t.Fatalf("expected the match to be recorded despite the expired tick, got %v", got)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"courier/internal/store"
+)
+
+// defaultCadenceSamples is how many recent publish timestamps are used to
+// estimate a feed's posting cadence.
+const defaultCadenceSamples = 20
+
+// maxHintInterval caps publisher refresh hints so a misconfigured
+// Cache-Control or ttl cannot stop a feed from being polled for weeks.
+const maxHintInterval = 24 * time.Hour
+
+// crawlSchedule derives each feed's polling interval from how often it
+// publishes, clamped to [min, max].
+type crawlSchedule struct {
+	min     time.Duration
+	max     time.Duration
+	samples int32
+}
+
+func newCrawlSchedule(min, max time.Duration) crawlSchedule {
+	return crawlSchedule{min: min, max: max, samples: defaultCadenceSamples}
+}
+
+// interval returns how long to wait before polling a feed whose items were
+// published at the given times. Feeds are polled roughly twice per average
+// posting gap; feeds that have been quiet for longer than their usual gap
+// back off in proportion to the silence.
+func (s crawlSchedule) interval(now time.Time, published []time.Time) time.Duration {
+	times := make([]time.Time, 0, len(published))
+	for _, t := range published {
+		if !t.After(now) {
+			times = append(times, t)
+		}
+	}
+	if len(times) < 2 {
+		return s.clamp(s.min)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].After(times[j]) })
+
+	newest := times[0]
+	oldest := times[len(times)-1]
+	gap := newest.Sub(oldest) / time.Duration(len(times)-1)
+
+	interval := gap / 2
+	if since := now.Sub(newest); since > gap {
+		if quiet := since / 2; quiet > interval {
+			interval = quiet
+		}
+	}
+	return s.clamp(interval)
+}
+
+func (s crawlSchedule) clamp(d time.Duration) time.Duration {
+	if d < s.min {
+		d = s.min
+	}
+	if s.max > 0 && d > s.max {
+		d = s.max
+	}
+	return d
+}
+
+// scheduleNextCrawl persists when f is next due and returns the wait. The
+// publisher's refresh hints are a lower bound on the adaptive interval, and the
+// result is pushed past any skipHours/skipDays window. An active backoff only
+// needs to push the feed past the backoff window, so the cadence lookup is
+// skipped.
+func scheduleNextCrawl(ctx context.Context, repo feedRepository, schedule crawlSchedule, f store.Feed, result FetchFeedResult) (time.Duration, error) {
+	now := time.Now().UTC()
+	wait := result.RetryIn
+
+	if !errors.Is(result.Err, ErrBackoffActive) {
+		published, err := repo.ListFeedPublishTimes(ctx, f.ID, schedule.samples)
+		if err != nil {
+			return 0, err
+		}
+		if d := schedule.interval(now, published); d > wait {
+			wait = d
+		}
+
+		hint := result.Hints.Interval(now)
+		if hint > maxHintInterval {
+			hint = maxHintInterval
+		}
+		if hint > wait {
+			wait = hint
+		}
+	}
+
+	next := result.Hints.Allowed(now.Add(wait))
+	if err := repo.ScheduleFeedCrawl(ctx, f.ID, next); err != nil {
+		return 0, err
+	}
+	return next.Sub(now), nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"courier/internal/feed"
+	"courier/internal/store"
+)
+
+func TestCrawlScheduleInterval(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	schedule := newCrawlSchedule(5*time.Minute, 12*time.Hour)
+
+	hourly := make([]time.Time, 0, 10)
+	for i := 0; i < 10; i++ {
+		hourly = append(hourly, now.Add(-time.Duration(i)*time.Hour))
+	}
+
+	daily := make([]time.Time, 0, 5)
+	for i := 0; i < 5; i++ {
+		daily = append(daily, now.Add(-time.Duration(i)*24*time.Hour))
+	}
+
+	quiet := []time.Time{
+		now.Add(-30 * 24 * time.Hour),
+		now.Add(-31 * 24 * time.Hour),
+	}
+
+	burst := []time.Time{now, now.Add(-time.Minute), now.Add(-2 * time.Minute)}
+
+	cases := []struct {
+		name      string
+		published []time.Time
+		want      time.Duration
+	}{
+		{name: "no history", published: nil, want: 5 * time.Minute},
+		{name: "single item", published: hourly[:1], want: 5 * time.Minute},
+		{name: "hourly", published: hourly, want: 30 * time.Minute},
+		{name: "daily clamped to max", published: daily, want: 12 * time.Hour},
+		{name: "quiet feed backs off", published: quiet, want: 12 * time.Hour},
+		{name: "burst clamped to min", published: burst, want: 5 * time.Minute},
+		{name: "future timestamps ignored", published: []time.Time{now.Add(48 * time.Hour)}, want: 5 * time.Minute},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := schedule.interval(now, tc.published); got != tc.want {
+				t.Fatalf("interval = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunSchedulesNextCrawl(t *testing.T) {
+	now := time.Now().UTC()
+	history := make([]time.Time, 0, 10)
+	for i := 0; i < 10; i++ {
+		history = append(history, now.Add(-time.Duration(i)*time.Hour))
+	}
+
+	repo := &stubFeedStore{
+		feeds: []store.Feed{
+			{ID: "feed-1", URL: "http://example.com/hourly", Active: true},
+			{ID: "feed-2", URL: "http://example.com/limited", Active: true},
+		},
+		publishTimes: map[string][]time.Time{"feed-1": history},
+	}
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{Status: http.StatusOK, Feed: &gofeed.Feed{}}},
+		{
+			result: feed.Result{Status: http.StatusTooManyRequests, RetryAfter: 5 * time.Minute},
+			err:    feed.ErrRetryLater,
+		},
+	}}
+
+	run(context.Background(), "fetcher", repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, 1)
+
+	if len(repo.scheduled) != 2 {
+		t.Fatalf("expected both feeds to be scheduled, got %d", len(repo.scheduled))
+	}
+
+	// With a single worker feeds are fetched in order, so feed-2 receives
+	// the 429.
+	if wait := repo.scheduled["feed-1"].Sub(now); wait < 29*time.Minute || wait > 31*time.Minute {
+		t.Fatalf("feed-1 scheduled in %s, want about 30m", wait)
+	}
+	if wait := repo.scheduled["feed-2"].Sub(now); wait < 5*time.Minute {
+		t.Fatalf("feed-2 scheduled in %s, want at least the 5m Retry-After", wait)
+	}
+}
+
+func TestScheduleNextCrawlSkipsHistoryDuringBackoff(t *testing.T) {
+	repo := &stubFeedStore{publishTimes: map[string][]time.Time{}}
+	f := store.Feed{ID: "feed-1"}
+
+	wait, err := scheduleNextCrawl(context.Background(), repo, testSchedule, f, FetchFeedResult{
+		Err:     errors.Join(ErrBackoffActive),
+		RetryIn: 30 * time.Second,
+		Skipped: true,
+	})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if wait != 30*time.Second {
+		t.Fatalf("wait = %s, want 30s", wait)
+	}
+}
+
+func TestScheduleNextCrawlRespectsRefreshHints(t *testing.T) {
+	repo := &stubFeedStore{}
+	f := store.Feed{ID: "feed-1"}
+
+	wait, err := scheduleNextCrawl(context.Background(), repo, testSchedule, f, FetchFeedResult{
+		Status: http.StatusOK,
+		Hints:  feed.RefreshHints{TTL: 3 * time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if wait < 3*time.Hour {
+		t.Fatalf("wait = %s, want at least the 3h ttl", wait)
+	}
+
+	wait, err = scheduleNextCrawl(context.Background(), repo, testSchedule, f, FetchFeedResult{
+		Status: http.StatusOK,
+		Hints:  feed.RefreshHints{MaxAge: 30 * 24 * time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if wait > maxHintInterval {
+		t.Fatalf("wait = %s, want hints capped at %s", wait, maxHintInterval)
+	}
+}
+
+func TestFetchFeedUsesStoredHintsOnNotModified(t *testing.T) {
+	repo := &stubFeedStore{}
+	fetcher := &stubFetcher{responses: []fetchResponse{
+		{result: feed.Result{Status: http.StatusNotModified}},
+	}}
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/feed", RefreshInterval: time.Hour, SkipDays: 1 << time.Sunday}
+
+	result, _ := FetchFeed(context.Background(), repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), f)
+	if result.Hints.TTL != time.Hour {
+		t.Fatalf("TTL = %s, want stored 1h", result.Hints.TTL)
+	}
+	if result.Hints.SkipDays != 1<<time.Sunday {
+		t.Fatalf("SkipDays = %b, want stored mask", result.Hints.SkipDays)
+	}
+}
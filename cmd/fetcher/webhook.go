@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"courier/internal/logx"
+	"courier/internal/netguard"
+	"courier/internal/search"
+	"courier/internal/store"
+)
+
+// Webhook events. An item is created the first time the fetcher stores it
+// and updated whenever its content changes afterwards.
+const (
+	webhookEventCreated = "item.created"
+	webhookEventUpdated = "item.updated"
+)
+
+const (
+	webhookSignatureHeader = "X-Courier-Signature"
+	webhookEventHeader     = "X-Courier-Event"
+	webhookDeliveryHeader  = "X-Courier-Delivery"
+)
+
+const (
+	webhookBatchSize = 25
+	webhookTimeout   = 10 * time.Second
+	// webhookLease covers sending a whole batch, so a claimed delivery is
+	// only handed out again if its sender died.
+	webhookLease       = 5 * time.Minute
+	webhookMaxAttempts = 8
+	webhookRetryMin    = 30 * time.Second
+	webhookRetryMax    = time.Hour
+)
+
+type webhookQueue interface {
+	ListActiveWebhooks(context.Context) ([]store.Webhook, error)
+	EnqueueWebhookDeliveries(context.Context, string, []store.WebhookEvent) (int64, error)
+}
+
+type webhookDeliveryStore interface {
+	ClaimWebhookDeliveries(context.Context, time.Time, time.Duration, int32) ([]store.ClaimedWebhookDelivery, error)
+	RecordWebhookAttempt(context.Context, store.RecordWebhookAttemptParams) error
+}
+
+type webhookPayload struct {
+	Event string      `json:"event"`
+	Item  webhookItem `json:"item"`
+}
+
+type webhookItem struct {
+	ID          string     `json:"id"`
+	FeedID      string     `json:"feed_id"`
+	FeedTitle   string     `json:"feed_title"`
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Author      string     `json:"author,omitempty"`
+	ContentText string     `json:"content_text"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	RetrievedAt time.Time  `json:"retrieved_at"`
+}
+
+// enqueueWebhooks queues deliveries for the items a feed inserted or
+// changed, as soon as they are stored. Keywords are matched here, like saved search queries; the
+// store drops items outside the webhook owner's subscriptions and feed
+// filter. It returns how many deliveries were queued.
+func enqueueWebhooks(ctx context.Context, svc string, repo webhookQueue, changed []store.UpsertItemResult) int64 {
+	if len(changed) == 0 {
+		return 0
+	}
+	hooks, err := repo.ListActiveWebhooks(ctx)
+	if err != nil {
+		logx.Error(svc, "list webhooks", err, nil)
+		return 0
+	}
+	if len(hooks) == 0 {
+		return 0
+	}
+
+	events := make([]store.WebhookEvent, 0, len(changed))
+	docs := make([]search.Document, 0, len(changed))
+	for _, c := range changed {
+		event := webhookEventUpdated
+		if c.Fresh {
+			event = webhookEventCreated
+		}
+		payload, err := json.Marshal(webhookPayload{Event: event, Item: mapWebhookItem(c.Item)})
+		if err != nil {
+			logx.Error(svc, "encode webhook payload", err, map[string]any{"item_id": c.Item.ID})
+			continue
+		}
+		events = append(events, store.WebhookEvent{ItemID: c.Item.ID, Event: event, Payload: string(payload)})
+		docs = append(docs, itemDocument(c.Item))
+	}
+
+	var queued int64
+	for _, hook := range hooks {
+		var matched []store.WebhookEvent
+		for i, ev := range events {
+			if matchesKeywords(hook.Keywords, docs[i]) {
+				matched = append(matched, ev)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		n, err := repo.EnqueueWebhookDeliveries(ctx, hook.ID, matched)
+		if err != nil {
+			logx.Error(svc, "queue webhook deliveries", err, map[string]any{"webhook_id": hook.ID})
+			continue
+		}
+		queued += n
+	}
+	if queued > 0 {
+		logx.Info(svc, "webhook deliveries queued", map[string]any{"webhooks": len(hooks), "items": len(events), "queued": queued})
+	}
+	return queued
+}
+
+func mapWebhookItem(it store.Item) webhookItem {
+	out := webhookItem{
+		ID:          it.ID,
+		FeedID:      it.FeedID,
+		FeedTitle:   it.FeedTitle,
+		URL:         it.URL,
+		Title:       it.Title,
+		ContentText: it.ContentText,
+		RetrievedAt: it.RetrievedAt.UTC(),
+	}
+	if it.Author.Valid {
+		out.Author = it.Author.String
+	}
+	if it.PublishedAt.Valid {
+		t := it.PublishedAt.Time.UTC()
+		out.PublishedAt = &t
+	}
+	return out
+}
+
+// matchesKeywords reports whether doc's title or content mentions any of
+// keywords as whole words, ignoring case. A keyword of several words must
+// appear as a phrase. No keywords match everything.
+func matchesKeywords(keywords []string, doc search.Document) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	fields := [][]string{queryWords(doc.Title), queryWords(doc.ContentText)}
+	for _, keyword := range keywords {
+		term := queryTerm{words: queryWords(keyword)}
+		if len(term.words) == 0 {
+			continue
+		}
+		for _, words := range fields {
+			if containsTerm(words, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newWebhookClient returns the client deliveries are sent with. Redirects
+// are not followed, since they would turn the POST into a GET; a
+// redirecting endpoint fails like any other non-2xx response. Like feed
+// fetches, deliveries only reach public addresses and the networks in
+// allow.
+func newWebhookClient(allow netguard.Allowlist) *http.Client {
+	return &http.Client{
+		Transport: allow.Transport(),
+		Timeout:   webhookTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// deliverWebhooks sends the deliveries that are due, a batch at a time,
+// until none are left or ctx ends. Failed attempts are retried with
+// exponential backoff until webhookMaxAttempts is reached; one refused for
+// its address fails at once. It returns how many deliveries succeeded and
+// how many gave up.
+func deliverWebhooks(ctx context.Context, svc string, repo webhookDeliveryStore, client *http.Client) (delivered, failed int) {
+	for ctx.Err() == nil {
+		batch, err := repo.ClaimWebhookDeliveries(ctx, time.Now().UTC(), webhookLease, webhookBatchSize)
+		if err != nil {
+			logx.Error(svc, "claim webhook deliveries", err, nil)
+			break
+		}
+		for _, d := range batch {
+			status, sendErr := sendWebhook(ctx, client, d)
+			now := time.Now().UTC()
+			attempt := store.RecordWebhookAttemptParams{
+				ID:             d.ID,
+				Status:         store.WebhookDelivered,
+				AttemptedAt:    now,
+				NextAttemptAt:  now,
+				ResponseStatus: status,
+			}
+			switch {
+			case sendErr == nil:
+				delivered++
+			case d.Attempts+1 >= webhookMaxAttempts, errors.Is(sendErr, netguard.ErrBlocked):
+				attempt.Status = store.WebhookFailed
+				attempt.Error = sendErr.Error()
+				failed++
+			default:
+				attempt.Status = store.WebhookPending
+				attempt.Error = sendErr.Error()
+				attempt.NextAttemptAt = now.Add(webhookRetryDelay(d.Attempts + 1))
+			}
+			if sendErr != nil {
+				logx.Error(svc, "deliver webhook", sendErr, map[string]any{
+					"webhook_id":  d.WebhookID,
+					"delivery_id": d.ID,
+					"attempts":    d.Attempts + 1,
+					"status":      attempt.Status,
+				})
+			}
+			if err := repo.RecordWebhookAttempt(ctx, attempt); err != nil {
+				logx.Error(svc, "record webhook attempt", err, map[string]any{"delivery_id": d.ID})
+			}
+		}
+		if len(batch) < webhookBatchSize {
+			break
+		}
+	}
+	if delivered > 0 || failed > 0 {
+		logx.Info(svc, "webhook deliveries sent", map[string]any{"delivered": delivered, "failed": failed})
+	}
+	return delivered, failed
+}
+
+// sendWebhook posts a delivery's payload, signed with the webhook's secret.
+// It returns the response status, if one arrived, and an error unless the
+// status was 2xx.
+func sendWebhook(ctx context.Context, client *http.Client, d store.ClaimedWebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, strings.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, d.Event)
+	req.Header.Set(webhookDeliveryHeader, d.ID)
+	req.Header.Set(webhookSignatureHeader, signWebhook(d.Secret, []byte(d.Payload)))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhook returns the signature header value for body: "sha256="
+// followed by the hex HMAC-SHA256 of body keyed with secret.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay is how long to wait after a delivery's attempts-th
+// failed attempt: webhookRetryMin, doubling with each attempt up to
+// webhookRetryMax.
+func webhookRetryDelay(attempts int32) time.Duration {
+	delay := webhookRetryMin
+	for i := int32(1); i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
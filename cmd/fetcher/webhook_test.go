@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"courier/internal/feed"
+	"courier/internal/netguard"
+	"courier/internal/store"
+)
+
+// loopback lets deliveries reach the test servers.
+var loopback = netguard.Allowlist{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+func TestRunQueuesWebhookDeliveries(t *testing.T) {
+	repo := &stubFeedStore{
+		feeds: []store.Feed{{ID: "feed-1", URL: "http://example.com/feed", Active: true}},
+		webhooks: []store.Webhook{
+			{ID: "hook-all"},
+			{ID: "hook-generics", Keywords: []string{"generics"}},
+			{ID: "hook-rust", Keywords: []string{"borrow checker"}},
+		},
+		upsertResults: []store.UpsertItemResult{
+			{Item: store.Item{ID: "item-1"}, Fresh: true, Indexed: true},
+			{Item: store.Item{ID: "item-2"}, Indexed: true},
+			{Item: store.Item{ID: "item-3"}, Indexed: false},
+		},
+	}
+	fetcher := &stubFetcher{responses: []fetchResponse{{result: feed.Result{
+		Status: http.StatusOK,
+		Feed: &gofeed.Feed{Items: []*gofeed.Item{
+			{Title: "Generics in Go", Link: "http://example.com/1"},
+			{Title: "Iterators", Link: "http://example.com/2"},
+			{Title: "More generics", Link: "http://example.com/3"},
+		}},
+	}}}}
+
+	run(context.Background(), "fetcher", repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, 1)
+
+	all := repo.webhookEvents["hook-all"]
+	if len(all) != 2 || all[0].Event != webhookEventCreated || all[1].Event != webhookEventUpdated {
+		t.Fatalf("expected a created and an updated event, got %+v", all)
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal([]byte(all[0].Payload), &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if payload.Event != webhookEventCreated || payload.Item.ID != "item-1" || payload.Item.Title != "Generics in Go" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+	if got := repo.webhookEvents["hook-generics"]; len(got) != 1 || got[0].ItemID != "item-1" {
+		t.Fatalf("expected only the item mentioning the keyword, got %+v", got)
+	}
+	if got, ok := repo.webhookEvents["hook-rust"]; ok {
+		t.Fatalf("expected no deliveries for an unrelated keyword, got %+v", got)
+	}
+}
+
+func TestProcessFeedQueuesWebhooksAfterDeadline(t *testing.T) {
+	repo := &stubFeedStore{
+		webhooks:      []store.Webhook{{ID: "hook-all"}},
+		upsertResults: []store.UpsertItemResult{{Item: store.Item{ID: "item-1"}, Fresh: true, Indexed: true}},
+	}
+	fetcher := &stubFetcher{responses: []fetchResponse{{result: feed.Result{
+		Status: http.StatusOK,
+		Feed:   &gofeed.Feed{Items: []*gofeed.Item{{Title: "Generics in Go", Link: "http://example.com/1"}}},
+	}}}}
+
+	// The tick ran out while the feed's items were being stored.
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	f := store.Feed{ID: "feed-1", URL: "http://example.com/feed", Active: true}
+	processFeed(ctx, "fetcher", repo, &stubSearchClient{}, fetcher, newBackoffTracker(), newBackoffTracker(), testSchedule, testLifecycle, 10, &pendingBatch{}, f)
+
+	if got := repo.webhookEvents["hook-all"]; len(got) != 1 || got[0].ItemID != "item-1" {
+		t.Fatalf("expected the delivery to be queued despite the expired tick, got %+v", got)
+	}
+}
+
+type stubWebhookDeliveryStore struct {
+	mu       sync.Mutex
+	pending  []store.ClaimedWebhookDelivery
+	attempts map[string]store.RecordWebhookAttemptParams
+}
+
+func (s *stubWebhookDeliveryStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int32) ([]store.ClaimedWebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	claimed := s.pending
+	s.pending = nil
+	return claimed, nil
+}
+
+func (s *stubWebhookDeliveryStore) RecordWebhookAttempt(ctx context.Context, arg store.RecordWebhookAttemptParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attempts == nil {
+		s.attempts = make(map[string]store.RecordWebhookAttemptParams)
+	}
+	s.attempts[arg.ID] = arg
+	return nil
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	const body = `{"event":"item.created"}`
+	var (
+		mu         sync.Mutex
+		signatures []string
+	)
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		signatures = append(signatures, r.Header.Get(webhookSignatureHeader))
+		mu.Unlock()
+		if string(got) != body || r.Header.Get(webhookEventHeader) != webhookEventCreated || r.Header.Get(webhookDeliveryHeader) != "delivery-ok" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+	moved := httptest.NewServer(http.RedirectHandler(ok.URL, http.StatusFound))
+	defer moved.Close()
+
+	repo := &stubWebhookDeliveryStore{pending: []store.ClaimedWebhookDelivery{
+		{ID: "delivery-ok", Event: webhookEventCreated, Payload: body, URL: ok.URL, Secret: "s3cret"},
+		{ID: "delivery-retry", Event: webhookEventCreated, Payload: body, URL: broken.URL, Attempts: 2},
+		{ID: "delivery-failed", Event: webhookEventCreated, Payload: body, URL: broken.URL, Attempts: webhookMaxAttempts - 1},
+		{ID: "delivery-moved", Event: webhookEventCreated, Payload: body, URL: moved.URL},
+	}}
+
+	started := time.Now().UTC()
+	delivered, failed := deliverWebhooks(context.Background(), "fetcher", repo, newWebhookClient(loopback))
+	if delivered != 1 || failed != 1 {
+		t.Fatalf("expected 1 delivered and 1 failed, got %d and %d", delivered, failed)
+	}
+	if len(signatures) != 1 || signatures[0] != signWebhook("s3cret", []byte(body)) {
+		t.Fatalf("unexpected signatures %v; redirects must not be followed", signatures)
+	}
+
+	if got := repo.attempts["delivery-ok"]; got.Status != store.WebhookDelivered || got.ResponseStatus != http.StatusNoContent || got.Error != "" {
+		t.Fatalf("unexpected attempt for the delivered webhook: %+v", got)
+	}
+	retry := repo.attempts["delivery-retry"]
+	if retry.Status != store.WebhookPending || retry.ResponseStatus != http.StatusInternalServerError || retry.Error == "" {
+		t.Fatalf("unexpected attempt for the retried webhook: %+v", retry)
+	}
+	if wait := retry.NextAttemptAt.Sub(started); wait < webhookRetryDelay(3) || wait > webhookRetryDelay(3)+time.Minute {
+		t.Fatalf("expected a retry in about %s, got %s", webhookRetryDelay(3), wait)
+	}
+	if got := repo.attempts["delivery-failed"]; got.Status != store.WebhookFailed {
+		t.Fatalf("expected the last attempt to fail the delivery, got %+v", got)
+	}
+	if got := repo.attempts["delivery-moved"]; got.Status != store.WebhookPending || got.ResponseStatus != http.StatusFound {
+		t.Fatalf("expected a redirect to be retried, got %+v", got)
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	// Matches: printf 'hello' | openssl dgst -sha256 -hmac key
+	const want = "sha256=9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b"
+	if got := signWebhook("key", []byte("hello")); got != want {
+		t.Fatalf("signWebhook = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	for _, tc := range []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{40, time.Hour},
+	} {
+		if got := webhookRetryDelay(tc.attempts); got != tc.want {
+			t.Errorf("webhookRetryDelay(%d) = %s, want %s", tc.attempts, got, tc.want)
+		}
+	}
+}
+
+func TestDeliverWebhooksRefusesPrivateAddresses(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	repo := &stubWebhookDeliveryStore{pending: []store.ClaimedWebhookDelivery{
+		{ID: "delivery-internal", Event: webhookEventCreated, Payload: "{}", URL: srv.URL},
+	}}
+	delivered, failed := deliverWebhooks(context.Background(), "fetcher", repo, newWebhookClient(nil))
+	if delivered != 0 || failed != 1 || hits != 0 {
+		t.Fatalf("expected the loopback delivery to fail unsent, got %d delivered, %d failed, %d hits", delivered, failed, hits)
+	}
+	if got := repo.attempts["delivery-internal"]; got.Status != store.WebhookFailed || got.Error == "" {
+		t.Fatalf("expected a blocked delivery to fail without retries, got %+v", got)
+	}
+}
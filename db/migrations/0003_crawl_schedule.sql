@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS next_crawl_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS feeds_due_idx ON feeds(next_crawl_at NULLS FIRST) WHERE active;
+CREATE INDEX IF NOT EXISTS items_feed_published_idx ON items(feed_id, published_at DESC) WHERE published_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS items_feed_published_idx;
+DROP INDEX IF EXISTS feeds_due_idx;
+ALTER TABLE feeds DROP COLUMN IF EXISTS next_crawl_at;
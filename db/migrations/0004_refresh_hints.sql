@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS refresh_interval_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS skip_hours INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS skip_days SMALLINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN IF EXISTS skip_days;
+ALTER TABLE feeds DROP COLUMN IF EXISTS skip_hours;
+ALTER TABLE feeds DROP COLUMN IF EXISTS refresh_interval_seconds;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS backoff_until TIMESTAMPTZ NULL;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS backoff_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS backoff_reason TEXT NULL;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS backoff_failures INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS feeds_backoff_idx ON feeds(backoff_until) WHERE backoff_until IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS feeds_backoff_idx;
+ALTER TABLE feeds DROP COLUMN IF EXISTS backoff_failures;
+ALTER TABLE feeds DROP COLUMN IF EXISTS backoff_reason;
+ALTER TABLE feeds DROP COLUMN IF EXISTS backoff_seconds;
+ALTER TABLE feeds DROP COLUMN IF EXISTS backoff_until;
@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_error TEXT NULL;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_error_at TIMESTAMPTZ NULL;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_success_at TIMESTAMPTZ NULL;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_status INTEGER NULL;
+
+CREATE TABLE IF NOT EXISTS feed_fetch_log (
+    id BIGSERIAL PRIMARY KEY,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    status INTEGER NULL,
+    items INTEGER NOT NULL DEFAULT 0,
+    error TEXT NULL,
+    duration_ms INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS feed_fetch_log_feed_idx ON feed_fetch_log(feed_id, fetched_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS feeds_failing_idx ON feeds(consecutive_failures) WHERE consecutive_failures > 0;
+
+-- +goose Down
+DROP INDEX IF EXISTS feeds_failing_idx;
+DROP TABLE IF EXISTS feed_fetch_log;
+ALTER TABLE feeds DROP COLUMN IF EXISTS last_status;
+ALTER TABLE feeds DROP COLUMN IF EXISTS last_success_at;
+ALTER TABLE feeds DROP COLUMN IF EXISTS last_error_at;
+ALTER TABLE feeds DROP COLUMN IF EXISTS last_error;
+ALTER TABLE feeds DROP COLUMN IF EXISTS consecutive_failures;
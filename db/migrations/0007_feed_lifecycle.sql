@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS consecutive_not_found INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ NULL;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS deactivated_reason TEXT NULL;
+
+CREATE TABLE IF NOT EXISTS feed_url_history (
+    url TEXT PRIMARY KEY,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    replaced_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS feed_url_history_feed_idx ON feed_url_history(feed_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS feed_url_history;
+ALTER TABLE feeds DROP COLUMN IF EXISTS deactivated_reason;
+ALTER TABLE feeds DROP COLUMN IF EXISTS deactivated_at;
+ALTER TABLE feeds DROP COLUMN IF EXISTS consecutive_not_found;
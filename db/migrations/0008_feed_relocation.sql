@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS rejected_relocation_url TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN IF EXISTS rejected_relocation_url;
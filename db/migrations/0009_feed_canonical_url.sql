@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS canonical_url TEXT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS feeds_canonical_url_idx ON feeds(canonical_url);
+
+-- +goose Down
+DROP INDEX IF EXISTS feeds_canonical_url_idx;
+ALTER TABLE feeds DROP COLUMN IF EXISTS canonical_url;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS category TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN IF EXISTS category;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tags_name_idx ON tags(lower(name));
+
+CREATE TABLE IF NOT EXISTS feed_tags (
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (feed_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS feed_tags_tag_idx ON feed_tags(tag_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS feed_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS items_retrieved_idx ON items(retrieved_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS items_author_idx ON items(lower(author)) WHERE author IS NOT NULL;
+CREATE INDEX IF NOT EXISTS items_host_idx ON items((substring(lower(url) from '^[a-z][a-z0-9+.-]*://(?:www\.)?([^/:?#]+)')));
+CREATE INDEX IF NOT EXISTS items_title_trgm_idx ON items USING gin (lower(title) gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS items_title_trgm_idx;
+DROP INDEX IF EXISTS items_host_idx;
+DROP INDEX IF EXISTS items_author_idx;
+DROP INDEX IF EXISTS items_retrieved_idx;
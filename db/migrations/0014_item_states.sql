@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS item_states (
+    item_id UUID PRIMARY KEY REFERENCES items(id) ON DELETE CASCADE,
+    read BOOLEAN NOT NULL DEFAULT false,
+    starred BOOLEAN NOT NULL DEFAULT false,
+    read_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS item_states_read_idx ON item_states(item_id) WHERE read;
+CREATE INDEX IF NOT EXISTS item_states_starred_idx ON item_states(item_id) WHERE starred;
+
+-- +goose Down
+DROP TABLE IF EXISTS item_states;
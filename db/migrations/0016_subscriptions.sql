@@ -0,0 +1,47 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    title TEXT NULL,
+    category TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, feed_id)
+);
+
+CREATE INDEX IF NOT EXISTS subscriptions_feed_idx ON subscriptions(feed_id);
+
+-- Existing users keep every feed, with the names and folders set so far.
+INSERT INTO subscriptions (user_id, feed_id, title, category)
+SELECT u.id, f.id, CASE WHEN f.title_locked THEN f.title END, f.category
+FROM users u
+CROSS JOIN feeds f
+ON CONFLICT DO NOTHING;
+
+-- Read state becomes per user. Rows without a user belong to the open API
+-- and are handed to the first user that adopts the existing feeds.
+ALTER TABLE item_states DROP CONSTRAINT IF EXISTS item_states_pkey;
+ALTER TABLE item_states ADD COLUMN IF NOT EXISTS user_id UUID NULL REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE item_states ADD CONSTRAINT item_states_item_user_key UNIQUE NULLS NOT DISTINCT (item_id, user_id);
+
+UPDATE item_states
+SET user_id = (SELECT id FROM users ORDER BY is_admin DESC, created_at ASC LIMIT 1);
+
+DROP INDEX IF EXISTS item_states_read_idx;
+DROP INDEX IF EXISTS item_states_starred_idx;
+CREATE INDEX IF NOT EXISTS item_states_read_idx ON item_states(user_id, item_id) WHERE read;
+CREATE INDEX IF NOT EXISTS item_states_starred_idx ON item_states(user_id, item_id) WHERE starred;
+
+-- +goose Down
+DROP INDEX IF EXISTS item_states_read_idx;
+DROP INDEX IF EXISTS item_states_starred_idx;
+DELETE FROM item_states a
+USING item_states b
+WHERE a.item_id = b.item_id
+  AND a.ctid > b.ctid;
+ALTER TABLE item_states DROP CONSTRAINT IF EXISTS item_states_item_user_key;
+ALTER TABLE item_states DROP COLUMN IF EXISTS user_id;
+ALTER TABLE item_states ADD PRIMARY KEY (item_id);
+CREATE INDEX IF NOT EXISTS item_states_read_idx ON item_states(item_id) WHERE read;
+CREATE INDEX IF NOT EXISTS item_states_starred_idx ON item_states(item_id) WHERE starred;
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Google Reader and Fever clients identify items by integer. The sequence
+-- numbers existing items in no particular order and new items as they land.
+ALTER TABLE items ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+CREATE UNIQUE INDEX IF NOT EXISTS items_seq_idx ON items(seq);
+
+-- +goose Down
+DROP INDEX IF EXISTS items_seq_idx;
+ALTER TABLE items DROP COLUMN IF EXISTS seq;
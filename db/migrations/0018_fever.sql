@@ -0,0 +1,18 @@
+-- +goose Up
+-- Fever clients number feeds. The number belongs to the subscription so
+-- users sharing a feed do not see each other's numbering change.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+CREATE UNIQUE INDEX IF NOT EXISTS subscriptions_seq_idx ON subscriptions(seq);
+
+-- Fever clients sign in with md5("username:password"); only a hash of that
+-- key is kept.
+CREATE TABLE IF NOT EXISTS fever_keys (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    key_hash BYTEA NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS fever_keys;
+DROP INDEX IF EXISTS subscriptions_seq_idx;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- Saved searches without a user belong to the open API, like item_states.
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL,
+    feed_ids UUID[] NOT NULL DEFAULT '{}',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_viewed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_idx ON saved_searches(user_id);
+
+-- Matches found after last_viewed_at are new.
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+    saved_search_id UUID NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    matched_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (saved_search_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS saved_search_matches_item_idx ON saved_search_matches(item_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS saved_search_matches;
+DROP TABLE IF EXISTS saved_searches;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Feeds deleted once their last subscriber left. Their search documents
+-- are removed by the fetcher, which clears the row afterwards, so a failed
+-- index request is retried instead of leaving documents behind.
+CREATE TABLE IF NOT EXISTS feed_deletions (
+    feed_id UUID PRIMARY KEY,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS feed_deletions;
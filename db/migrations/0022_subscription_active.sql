@@ -0,0 +1,30 @@
+-- +goose Up
+-- Pausing belongs to the subscription: a feed is crawled while any of its
+-- subscribers keeps it active, or when it has none, as under the open API.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE subscriptions s
+SET active = FALSE
+FROM feeds f
+WHERE f.id = s.feed_id
+  AND NOT f.active
+  AND f.deactivated_reason = 'paused';
+
+UPDATE feeds f
+SET active = TRUE,
+    deactivated_at = NULL,
+    deactivated_reason = NULL
+WHERE NOT f.active
+  AND f.deactivated_reason = 'paused'
+  AND EXISTS (SELECT 1 FROM subscriptions s WHERE s.feed_id = f.id);
+
+-- +goose Down
+UPDATE feeds f
+SET active = FALSE,
+    deactivated_at = now(),
+    deactivated_reason = 'paused'
+WHERE f.active
+  AND EXISTS (SELECT 1 FROM subscriptions s WHERE s.feed_id = f.id)
+  AND NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.feed_id = f.id AND s.active);
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS active;
@@ -0,0 +1,84 @@
+-- +goose Up
+-- Tags belong to a user, or to the open API when user_id is null, and are
+-- attached to that user's subscriptions rather than to the shared feed.
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS user_id UUID NULL REFERENCES users(id) ON DELETE CASCADE;
+DROP INDEX IF EXISTS tags_name_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS tags_user_name_idx ON tags(user_id, lower(name)) NULLS NOT DISTINCT;
+
+-- Rows with a user follow their subscription: they are removed on
+-- unsubscribe and move with it to another feed. Open API rows have no
+-- subscription to follow.
+CREATE TABLE IF NOT EXISTS subscription_tags (
+    user_id UUID NULL,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (tag_id, feed_id),
+    FOREIGN KEY (user_id, feed_id) REFERENCES subscriptions(user_id, feed_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS subscription_tags_user_feed_idx ON subscription_tags(user_id, feed_id);
+
+-- Every subscriber of a tagged feed gets their own copy of its tags.
+INSERT INTO tags (user_id, name, created_at)
+SELECT DISTINCT s.user_id, t.name, t.created_at
+FROM feed_tags ft
+JOIN tags t ON t.id = ft.tag_id
+JOIN subscriptions s ON s.feed_id = ft.feed_id
+WHERE t.user_id IS NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO subscription_tags (user_id, feed_id, tag_id)
+SELECT s.user_id, ft.feed_id, ut.id
+FROM feed_tags ft
+JOIN tags t ON t.id = ft.tag_id
+JOIN subscriptions s ON s.feed_id = ft.feed_id
+JOIN tags ut ON ut.user_id = s.user_id AND lower(ut.name) = lower(t.name)
+ON CONFLICT DO NOTHING;
+
+-- Feeds nobody subscribes to keep their tags under the open API.
+INSERT INTO subscription_tags (user_id, feed_id, tag_id)
+SELECT NULL, ft.feed_id, ft.tag_id
+FROM feed_tags ft
+WHERE NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.feed_id = ft.feed_id)
+ON CONFLICT DO NOTHING;
+
+DELETE FROM tags t
+WHERE t.user_id IS NULL
+  AND EXISTS (SELECT 1 FROM users)
+  AND NOT EXISTS (SELECT 1 FROM subscription_tags st WHERE st.tag_id = t.id);
+
+DROP TABLE IF EXISTS feed_tags;
+
+-- +goose Down
+CREATE TABLE IF NOT EXISTS feed_tags (
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (feed_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS feed_tags_tag_idx ON feed_tags(tag_id);
+
+-- Tags sharing a name collapse into the oldest one.
+INSERT INTO feed_tags (feed_id, tag_id)
+SELECT st.feed_id, keep.id
+FROM subscription_tags st
+JOIN tags t ON t.id = st.tag_id
+JOIN LATERAL (
+    SELECT k.id
+    FROM tags k
+    WHERE lower(k.name) = lower(t.name)
+    ORDER BY k.created_at ASC, k.id ASC
+    LIMIT 1
+) keep ON TRUE
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS subscription_tags;
+
+DELETE FROM tags t
+USING tags k
+WHERE lower(k.name) = lower(t.name)
+  AND (k.created_at, k.id) < (t.created_at, t.id);
+
+DROP INDEX IF EXISTS tags_user_name_idx;
+ALTER TABLE tags DROP COLUMN IF EXISTS user_id;
+CREATE UNIQUE INDEX IF NOT EXISTS tags_name_idx ON tags(lower(name));
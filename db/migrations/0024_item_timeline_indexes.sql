@@ -0,0 +1,9 @@
+-- +goose Up
+-- Item neighbors are looked up as ranges over the full timeline order, so
+-- both indexes carry every column of it.
+CREATE INDEX IF NOT EXISTS items_timeline_idx ON items(published_at, retrieved_at, id);
+CREATE INDEX IF NOT EXISTS items_feed_timeline_idx ON items(feed_id, published_at, retrieved_at, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS items_feed_timeline_idx;
+DROP INDEX IF EXISTS items_timeline_idx;
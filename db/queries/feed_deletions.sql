@@ -0,0 +1,14 @@
+-- name: RecordFeedDeletion :exec
+INSERT INTO feed_deletions (feed_id)
+VALUES (sqlc.arg(feed_id))
+ON CONFLICT (feed_id) DO NOTHING;
+
+-- name: ListFeedDeletions :many
+SELECT feed_id
+FROM feed_deletions
+ORDER BY deleted_at ASC
+LIMIT sqlc.arg(result_limit)::int;
+
+-- name: ClearFeedDeletions :exec
+DELETE FROM feed_deletions
+WHERE feed_id = ANY(sqlc.arg(feed_ids)::uuid[]);
@@ -0,0 +1,21 @@
+-- name: InsertFeedFetchLog :exec
+INSERT INTO feed_fetch_log (feed_id, fetched_at, status, items, error, duration_ms)
+VALUES (sqlc.arg(feed_id), sqlc.arg(fetched_at), sqlc.arg(status), sqlc.arg(items), sqlc.arg(error), sqlc.arg(duration_ms));
+
+-- name: PruneFeedFetchLog :exec
+DELETE FROM feed_fetch_log
+WHERE feed_fetch_log.feed_id = sqlc.arg(feed_id)
+  AND id NOT IN (
+    SELECT l.id
+    FROM feed_fetch_log l
+    WHERE l.feed_id = sqlc.arg(feed_id)
+    ORDER BY l.fetched_at DESC, l.id DESC
+    LIMIT sqlc.arg(keep)::int
+  );
+
+-- name: ListFeedFetchLog :many
+SELECT id, feed_id, fetched_at, status, items, error, duration_ms
+FROM feed_fetch_log
+WHERE feed_id = sqlc.arg(feed_id)
+ORDER BY fetched_at DESC, id DESC
+LIMIT sqlc.arg(result_limit)::int;
@@ -0,0 +1,20 @@
+-- name: InsertFeedURLHistory :exec
+INSERT INTO feed_url_history (url, feed_id, replaced_at)
+VALUES (sqlc.arg(url), sqlc.arg(feed_id), sqlc.arg(replaced_at))
+ON CONFLICT (url) DO UPDATE SET feed_id = EXCLUDED.feed_id, replaced_at = EXCLUDED.replaced_at;
+
+-- name: DeleteFeedURLHistory :exec
+DELETE FROM feed_url_history
+WHERE url = sqlc.arg(url)
+  AND feed_id = sqlc.arg(feed_id);
+
+-- name: ListFeedURLHistory :many
+SELECT url, feed_id, replaced_at
+FROM feed_url_history
+WHERE feed_id = sqlc.arg(feed_id)
+ORDER BY replaced_at DESC;
+
+-- name: MoveFeedURLHistory :exec
+UPDATE feed_url_history
+SET feed_id = sqlc.arg(keep_feed_id)
+WHERE feed_id = sqlc.arg(drop_feed_id);
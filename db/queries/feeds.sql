@@ -1,20 +1,168 @@
 -- name: InsertFeed :one
-INSERT INTO feeds (url)
-VALUES (sqlc.arg(url))
-ON CONFLICT (url) DO NOTHING
-RETURNING id, url, title, etag, last_modified, last_crawled, active;
+INSERT INTO feeds (url, canonical_url, title, title_locked, category, refresh_interval_seconds, skip_hours, skip_days, last_success_at, last_status)
+SELECT sqlc.arg(url)::text,
+       sqlc.arg(canonical_url)::text,
+       sqlc.arg(title)::text,
+       sqlc.arg(title_locked)::boolean,
+       sqlc.narg(category)::text,
+       sqlc.arg(refresh_interval_seconds)::int,
+       sqlc.arg(skip_hours)::int,
+       sqlc.arg(skip_days)::smallint,
+       sqlc.narg(last_success_at)::timestamptz,
+       sqlc.narg(last_status)::int
+WHERE NOT EXISTS (SELECT 1 FROM feed_url_history h WHERE h.url = sqlc.arg(url)::text)
+ON CONFLICT DO NOTHING
+RETURNING id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category;
 
 -- name: ListFeeds :many
-SELECT id, url, title, etag, last_modified, last_crawled, active
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
 FROM feeds
 WHERE active = sqlc.arg(active)
 ORDER BY title ASC, url ASC;
 
+-- name: ListDueFeeds :many
+-- Feeds every subscriber has paused are skipped; feeds without subscribers
+-- belong to the open API and are still crawled.
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE active
+  AND (next_crawl_at IS NULL OR next_crawl_at <= sqlc.arg(now))
+  AND (
+      EXISTS (SELECT 1 FROM subscriptions s WHERE s.feed_id = feeds.id AND s.active)
+      OR NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.feed_id = feeds.id)
+  )
+ORDER BY next_crawl_at ASC NULLS FIRST, title ASC, url ASC;
+
 -- name: UpdateFeedCrawlState :one
 UPDATE feeds
 SET etag = COALESCE(sqlc.arg(etag), feeds.etag),
     last_modified = COALESCE(sqlc.arg(last_modified), feeds.last_modified),
     last_crawled = COALESCE(sqlc.arg(last_crawled), last_crawled),
-    title = COALESCE(NULLIF(sqlc.arg(new_title)::text, ''), title)
+    title = CASE WHEN title_locked THEN title ELSE COALESCE(NULLIF(sqlc.arg(new_title)::text, ''), title) END,
+    refresh_interval_seconds = sqlc.arg(refresh_interval_seconds),
+    skip_hours = sqlc.arg(skip_hours),
+    skip_days = sqlc.arg(skip_days)
+WHERE id = sqlc.arg(id)
+RETURNING id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category;
+
+-- name: ScheduleFeedCrawl :exec
+UPDATE feeds
+SET next_crawl_at = sqlc.arg(next_crawl_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ListBackedOffFeeds :many
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE active
+  AND backoff_until > sqlc.arg(now)
+ORDER BY backoff_until ASC;
+
+-- name: UpdateFeedBackoff :exec
+UPDATE feeds
+SET backoff_until = sqlc.arg(backoff_until),
+    backoff_seconds = sqlc.arg(backoff_seconds),
+    backoff_reason = sqlc.arg(backoff_reason),
+    backoff_failures = sqlc.arg(backoff_failures)
+WHERE id = sqlc.arg(id);
+
+-- name: ClearFeedBackoff :exec
+UPDATE feeds
+SET backoff_until = NULL,
+    backoff_seconds = 0,
+    backoff_reason = NULL,
+    backoff_failures = 0
+WHERE id = sqlc.arg(id);
+
+-- name: GetFeed :one
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE id = sqlc.arg(id);
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    consecutive_not_found = 0,
+    last_success_at = sqlc.arg(fetched_at),
+    last_status = sqlc.arg(last_status)
+WHERE id = sqlc.arg(id);
+
+-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    consecutive_not_found = CASE WHEN sqlc.arg(last_status)::int = 404 THEN consecutive_not_found + 1 ELSE 0 END,
+    last_error = sqlc.arg(last_error),
+    last_error_at = sqlc.arg(fetched_at),
+    last_status = sqlc.arg(last_status)
+WHERE id = sqlc.arg(id);
+
+-- name: ActivateFeed :exec
+UPDATE feeds
+SET active = TRUE,
+    deactivated_at = NULL,
+    deactivated_reason = NULL,
+    consecutive_not_found = 0,
+    next_crawl_at = NULL
+WHERE id = sqlc.arg(id);
+
+-- name: DeactivateFeed :exec
+UPDATE feeds
+SET active = FALSE,
+    deactivated_at = sqlc.arg(deactivated_at),
+    deactivated_reason = sqlc.arg(reason)
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateFeedURL :one
+UPDATE feeds
+SET url = sqlc.arg(url),
+    canonical_url = sqlc.arg(canonical_url)::text
 WHERE id = sqlc.arg(id)
-RETURNING id, url, title, etag, last_modified, last_crawled, active;
+  AND NOT EXISTS (
+    SELECT 1 FROM feeds other
+    WHERE (other.url = sqlc.arg(url) OR other.canonical_url = sqlc.arg(canonical_url)::text)
+      AND other.id <> sqlc.arg(id)
+  )
+  AND NOT EXISTS (SELECT 1 FROM feed_url_history h WHERE h.url = sqlc.arg(url) AND h.feed_id <> sqlc.arg(id))
+RETURNING id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category;
+
+-- name: GetFeedByURL :one
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE url = sqlc.arg(url) OR canonical_url = sqlc.arg(canonical_url)::text
+ORDER BY url = sqlc.arg(url) DESC
+LIMIT 1;
+
+-- name: GetFeedByCanonicalURL :one
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE canonical_url = sqlc.arg(canonical_url);
+
+-- name: RejectFeedRelocation :exec
+UPDATE feeds
+SET rejected_relocation_url = sqlc.arg(url)
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = sqlc.arg(id);
+
+-- name: ListFeedsWithoutCanonicalURL :many
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE canonical_url IS NULL
+ORDER BY active DESC, last_success_at DESC NULLS LAST, id ASC;
+
+-- name: SetFeedCanonicalURL :exec
+UPDATE feeds
+SET canonical_url = sqlc.arg(canonical_url)
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateFeedTitle :exec
+UPDATE feeds
+SET title = COALESCE(NULLIF(sqlc.arg(title)::text, ''), title),
+    title_locked = sqlc.arg(title)::text <> ''
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateFeedCategory :exec
+UPDATE feeds
+SET category = sqlc.narg(category)
+WHERE id = sqlc.arg(id);
@@ -0,0 +1,48 @@
+-- name: SetItemRead :exec
+INSERT INTO item_states (item_id, user_id, read, read_at)
+VALUES (sqlc.arg(item_id), sqlc.narg(user_id), sqlc.arg(read), CASE WHEN sqlc.arg(read)::boolean THEN sqlc.arg(read_at)::timestamptz END)
+ON CONFLICT (item_id, user_id) DO UPDATE SET
+    read = EXCLUDED.read,
+    read_at = CASE WHEN EXCLUDED.read THEN COALESCE(item_states.read_at, EXCLUDED.read_at) END;
+
+-- name: SetItemStarred :exec
+INSERT INTO item_states (item_id, user_id, starred)
+VALUES (sqlc.arg(item_id), sqlc.narg(user_id), sqlc.arg(starred))
+ON CONFLICT (item_id, user_id) DO UPDATE SET starred = EXCLUDED.starred;
+
+-- name: MarkFeedRead :execrows
+INSERT INTO item_states (item_id, user_id, read, read_at)
+SELECT i.id, sqlc.narg(user_id)::uuid, true, sqlc.arg(read_at)::timestamptz
+FROM items i
+WHERE i.feed_id = sqlc.arg(feed_id)
+  AND i.retrieved_at <= sqlc.arg(before)::timestamptz
+ON CONFLICT (item_id, user_id) DO UPDATE SET read = true, read_at = EXCLUDED.read_at
+WHERE NOT item_states.read;
+
+-- name: ListUnreadCounts :many
+-- Without a user, every feed is counted against the shared read state.
+SELECT i.feed_id, COUNT(*)::bigint AS unread
+FROM items i
+LEFT JOIN item_states s ON s.item_id = i.id AND s.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+WHERE NOT COALESCE(s.read, false)
+  AND (
+      sqlc.narg(user_id)::uuid IS NULL
+      OR i.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = sqlc.narg(user_id)::uuid)
+  )
+GROUP BY i.feed_id;
+
+-- name: ListUnreadItemSeqs :many
+SELECT i.seq
+FROM items i
+JOIN subscriptions sub ON sub.feed_id = i.feed_id AND sub.user_id = sqlc.arg(user_id)
+LEFT JOIN item_states s ON s.item_id = i.id AND s.user_id = sqlc.arg(user_id)
+WHERE NOT COALESCE(s.read, false)
+ORDER BY i.seq;
+
+-- name: ListStarredItemSeqs :many
+SELECT i.seq
+FROM items i
+JOIN subscriptions sub ON sub.feed_id = i.feed_id AND sub.user_id = sqlc.arg(user_id)
+JOIN item_states s ON s.item_id = i.id AND s.user_id = sqlc.arg(user_id)
+WHERE s.starred
+ORDER BY i.seq;
@@ -127,3 +127,227 @@ ORDER BY CASE WHEN sqlc.arg(sort_direction)::text = 'asc' THEN i.published_at EN
          i.retrieved_at DESC
 LIMIT sqlc.arg(result_limit)::int
 OFFSET sqlc.arg(result_offset)::int;
+
+-- name: ListFeedPublishTimes :many
+SELECT i.published_at
+FROM items i
+WHERE i.feed_id = sqlc.arg(feed_id)
+  AND i.published_at IS NOT NULL
+ORDER BY i.published_at DESC
+LIMIT sqlc.arg(result_limit)::int;
+
+-- name: DeleteDuplicateFeedItems :many
+DELETE FROM items d
+WHERE d.feed_id = sqlc.arg(drop_feed_id)
+  AND EXISTS (
+      SELECT 1
+      FROM items k
+      WHERE k.feed_id = sqlc.arg(keep_feed_id)
+        AND COALESCE(k.guid, k.url) = COALESCE(d.guid, d.url)
+  )
+RETURNING d.id;
+
+-- name: MoveFeedItems :many
+UPDATE items i
+SET feed_id = f.id
+FROM feeds f
+WHERE i.feed_id = sqlc.arg(drop_feed_id)
+  AND f.id = sqlc.arg(keep_feed_id)
+RETURNING i.id,
+          i.feed_id,
+          f.title AS feed_title,
+          i.guid,
+          i.url,
+          i.title,
+          i.author,
+          i.content_html,
+          i.content_text,
+          i.published_at,
+          i.retrieved_at;
+
+-- name: GetItem :one
+SELECT i.id,
+       i.feed_id,
+       COALESCE(sub.title, f.title) AS feed_title,
+       i.guid,
+       i.url,
+       i.title,
+       i.author,
+       i.content_html,
+       i.content_text,
+       i.published_at,
+       i.retrieved_at,
+       COALESCE(s.read, false) AS read,
+       COALESCE(s.starred, false) AS starred
+FROM items i
+JOIN feeds f ON f.id = i.feed_id
+LEFT JOIN subscriptions sub ON sub.feed_id = i.feed_id AND sub.user_id = sqlc.narg(user_id)::uuid
+LEFT JOIN item_states s ON s.item_id = i.id AND s.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+WHERE i.id = sqlc.arg(id)
+  AND (sqlc.narg(user_id)::uuid IS NULL OR sub.id IS NOT NULL);
+
+-- name: GetItemNeighbors :one
+-- Neighbors follow the default GET /items order: newest first by
+-- published_at (undated items last), then retrieved_at and id. Each
+-- neighbor is the first row of up to three ranges over the timeline
+-- indexes, one for dated and one for undated items, rather than a single
+-- disjunction that no index can serve.
+WITH cur AS (
+    SELECT id, feed_id, published_at, retrieved_at
+    FROM items
+    WHERE id = sqlc.arg(id)
+)
+SELECT (
+           SELECT b.id
+           FROM (
+               (SELECT n.id, 0 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND (n.published_at, n.retrieved_at, n.id) > (cur.published_at, cur.retrieved_at, cur.id)
+                  AND (sqlc.narg(user_id)::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = sqlc.narg(user_id)::uuid))
+                ORDER BY n.published_at, n.retrieved_at, n.id
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 1 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NULL AND (n.retrieved_at, n.id) > (cur.retrieved_at, cur.id)
+                  AND (sqlc.narg(user_id)::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = sqlc.narg(user_id)::uuid))
+                ORDER BY n.retrieved_at, n.id
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 2 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NOT NULL
+                  AND (sqlc.narg(user_id)::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = sqlc.narg(user_id)::uuid))
+                ORDER BY n.published_at, n.retrieved_at, n.id
+                LIMIT 1)
+           ) b
+           ORDER BY b.branch
+           LIMIT 1
+       )::uuid AS previous_id,
+       (
+           SELECT b.id
+           FROM (
+               (SELECT n.id, 0 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND (n.published_at, n.retrieved_at, n.id) < (cur.published_at, cur.retrieved_at, cur.id)
+                  AND (sqlc.narg(user_id)::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = sqlc.narg(user_id)::uuid))
+                ORDER BY n.published_at DESC, n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 1 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND n.published_at IS NULL
+                  AND (sqlc.narg(user_id)::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = sqlc.narg(user_id)::uuid))
+                ORDER BY n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 2 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NULL AND (n.retrieved_at, n.id) < (cur.retrieved_at, cur.id)
+                  AND (sqlc.narg(user_id)::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = sqlc.narg(user_id)::uuid))
+                ORDER BY n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+           ) b
+           ORDER BY b.branch
+           LIMIT 1
+       )::uuid AS next_id,
+       (
+           SELECT b.id
+           FROM (
+               (SELECT n.id, 0 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND (n.published_at, n.retrieved_at, n.id) > (cur.published_at, cur.retrieved_at, cur.id)
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.published_at, n.retrieved_at, n.id
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 1 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NULL AND (n.retrieved_at, n.id) > (cur.retrieved_at, cur.id)
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.retrieved_at, n.id
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 2 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NOT NULL
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.published_at, n.retrieved_at, n.id
+                LIMIT 1)
+           ) b
+           ORDER BY b.branch
+           LIMIT 1
+       )::uuid AS feed_previous_id,
+       (
+           SELECT b.id
+           FROM (
+               (SELECT n.id, 0 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND (n.published_at, n.retrieved_at, n.id) < (cur.published_at, cur.retrieved_at, cur.id)
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.published_at DESC, n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 1 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND n.published_at IS NULL
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 2 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NULL AND (n.retrieved_at, n.id) < (cur.retrieved_at, cur.id)
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+           ) b
+           ORDER BY b.branch
+           LIMIT 1
+       )::uuid AS feed_next_id
+FROM cur;
+
+-- name: ListItemsBySeq :many
+-- Items come oldest first, or newest first below max_seq.
+SELECT i.id,
+       i.feed_id,
+       COALESCE(sub.title, f.title) AS feed_title,
+       i.guid,
+       i.url,
+       i.title,
+       i.author,
+       i.content_html,
+       i.content_text,
+       i.published_at,
+       i.retrieved_at,
+       i.seq,
+       COALESCE(s.read, false) AS read,
+       COALESCE(s.starred, false) AS starred
+FROM items i
+JOIN feeds f ON f.id = i.feed_id
+JOIN subscriptions sub ON sub.feed_id = i.feed_id AND sub.user_id = sqlc.arg(user_id)
+LEFT JOIN item_states s ON s.item_id = i.id AND s.user_id = sqlc.arg(user_id)
+WHERE (sqlc.narg(since_seq)::bigint IS NULL OR i.seq > sqlc.narg(since_seq)::bigint)
+  AND (sqlc.narg(max_seq)::bigint IS NULL OR i.seq < sqlc.narg(max_seq)::bigint)
+  AND (sqlc.narg(seqs)::bigint[] IS NULL OR i.seq = ANY(sqlc.narg(seqs)::bigint[]))
+ORDER BY CASE WHEN sqlc.narg(max_seq)::bigint IS NULL THEN i.seq END ASC,
+         i.seq DESC
+LIMIT sqlc.arg(result_limit)::int;
+
+-- name: CountUserItems :one
+SELECT COUNT(*)::bigint
+FROM items i
+JOIN subscriptions sub ON sub.feed_id = i.feed_id
+WHERE sub.user_id = sqlc.arg(user_id);
@@ -0,0 +1,147 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (user_id, name, query, feed_ids, tags)
+VALUES (sqlc.narg(user_id), sqlc.arg(name), sqlc.arg(query), sqlc.arg(feed_ids), sqlc.arg(tags))
+RETURNING id, user_id, name, query, feed_ids, tags, created_at, last_viewed_at;
+
+-- name: GetSavedSearch :one
+-- Matches whose feed the owner has since left are not counted, as
+-- ListSavedSearchMatches hides them.
+SELECT ss.id,
+       ss.user_id,
+       ss.name,
+       ss.query,
+       ss.feed_ids,
+       ss.tags,
+       ss.created_at,
+       ss.last_viewed_at,
+       COUNT(m.item_id)::bigint AS match_count,
+       (COUNT(m.item_id) FILTER (WHERE m.matched_at > ss.last_viewed_at))::bigint AS new_count
+FROM saved_searches ss
+LEFT JOIN saved_search_matches m ON m.saved_search_id = ss.id
+    AND (
+        ss.user_id IS NULL
+        OR EXISTS (
+            SELECT 1
+            FROM items i
+            JOIN subscriptions sub ON sub.feed_id = i.feed_id
+            WHERE i.id = m.item_id
+              AND sub.user_id = ss.user_id
+        )
+    )
+WHERE ss.id = sqlc.arg(id)
+  AND ss.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+GROUP BY ss.id;
+
+-- name: ListSavedSearches :many
+-- Matches whose feed the owner has since left are not counted, as
+-- ListSavedSearchMatches hides them.
+SELECT ss.id,
+       ss.user_id,
+       ss.name,
+       ss.query,
+       ss.feed_ids,
+       ss.tags,
+       ss.created_at,
+       ss.last_viewed_at,
+       COUNT(m.item_id)::bigint AS match_count,
+       (COUNT(m.item_id) FILTER (WHERE m.matched_at > ss.last_viewed_at))::bigint AS new_count
+FROM saved_searches ss
+LEFT JOIN saved_search_matches m ON m.saved_search_id = ss.id
+    AND (
+        ss.user_id IS NULL
+        OR EXISTS (
+            SELECT 1
+            FROM items i
+            JOIN subscriptions sub ON sub.feed_id = i.feed_id
+            WHERE i.id = m.item_id
+              AND sub.user_id = ss.user_id
+        )
+    )
+WHERE ss.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+GROUP BY ss.id
+ORDER BY lower(ss.name) ASC, ss.created_at ASC;
+
+-- name: UpdateSavedSearch :one
+UPDATE saved_searches
+SET name = sqlc.arg(name),
+    query = sqlc.arg(query),
+    feed_ids = sqlc.arg(feed_ids),
+    tags = sqlc.arg(tags)
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+RETURNING id, user_id, name, query, feed_ids, tags, created_at, last_viewed_at;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid;
+
+-- name: MarkSavedSearchViewed :execrows
+UPDATE saved_searches
+SET last_viewed_at = now()
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid;
+
+-- name: ListSavedSearchQueries :many
+SELECT id, query
+FROM saved_searches
+ORDER BY created_at ASC;
+
+-- name: AddSavedSearchMatches :execrows
+-- Candidates are kept only when the owner subscribes to their feed and
+-- they pass the search's feed and tag filters.
+INSERT INTO saved_search_matches (saved_search_id, item_id)
+SELECT ss.id, i.id
+FROM saved_searches ss
+JOIN items i ON i.id = ANY(sqlc.arg(item_ids)::uuid[])
+WHERE ss.id = sqlc.arg(saved_search_id)
+  AND (
+      ss.user_id IS NULL
+      OR i.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = ss.user_id)
+  )
+  AND (cardinality(ss.feed_ids) = 0 OR i.feed_id = ANY(ss.feed_ids))
+  AND (
+      cardinality(ss.tags) = 0
+      OR i.feed_id IN (
+          SELECT st.feed_id
+          FROM subscription_tags st
+          JOIN tags t ON t.id = st.tag_id
+          WHERE st.user_id IS NOT DISTINCT FROM ss.user_id
+            AND lower(t.name) = ANY(ss.tags)
+      )
+  )
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteSavedSearchMatches :exec
+DELETE FROM saved_search_matches
+WHERE saved_search_id = sqlc.arg(saved_search_id);
+
+-- name: ListSavedSearchMatches :many
+-- Matches whose feed the owner has since left are hidden.
+SELECT i.id,
+       i.feed_id,
+       COALESCE(sub.title, f.title) AS feed_title,
+       i.guid,
+       i.url,
+       i.title,
+       i.author,
+       i.content_html,
+       i.content_text,
+       i.published_at,
+       i.retrieved_at,
+       COALESCE(s.read, false) AS read,
+       COALESCE(s.starred, false) AS starred,
+       m.matched_at,
+       (m.matched_at > ss.last_viewed_at)::boolean AS is_new
+FROM saved_search_matches m
+JOIN saved_searches ss ON ss.id = m.saved_search_id
+JOIN items i ON i.id = m.item_id
+JOIN feeds f ON f.id = i.feed_id
+LEFT JOIN subscriptions sub ON sub.feed_id = i.feed_id AND sub.user_id = ss.user_id
+LEFT JOIN item_states s ON s.item_id = i.id AND s.user_id IS NOT DISTINCT FROM ss.user_id
+WHERE m.saved_search_id = sqlc.arg(saved_search_id)
+  AND (ss.user_id IS NULL OR sub.id IS NOT NULL)
+  AND (NOT sqlc.arg(only_new)::boolean OR m.matched_at > ss.last_viewed_at)
+ORDER BY m.matched_at DESC, i.id DESC
+LIMIT sqlc.arg(result_limit)::int
+OFFSET sqlc.arg(result_offset)::int;
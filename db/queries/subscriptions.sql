@@ -0,0 +1,113 @@
+-- name: CreateSubscription :execrows
+INSERT INTO subscriptions (user_id, feed_id, title, category)
+VALUES (sqlc.arg(user_id), sqlc.arg(feed_id), sqlc.narg(title), sqlc.narg(category))
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: DeleteSubscription :execrows
+DELETE FROM subscriptions
+WHERE user_id = sqlc.arg(user_id)
+  AND feed_id = sqlc.arg(feed_id);
+
+-- name: FindFeedID :one
+-- Matches the feed a URL belongs to, including URLs the feed moved away from.
+SELECT id
+FROM feeds
+WHERE url = sqlc.arg(url) OR canonical_url = sqlc.arg(canonical_url)::text
+UNION ALL
+SELECT feed_id
+FROM feed_url_history
+WHERE url = sqlc.arg(url)
+LIMIT 1;
+
+-- name: GetUserFeed :one
+SELECT sqlc.embed(f), s.title AS subscription_title, s.category AS subscription_category, s.seq AS subscription_seq, s.active AS subscription_active
+FROM subscriptions s
+JOIN feeds f ON f.id = s.feed_id
+WHERE s.user_id = sqlc.arg(user_id)
+  AND s.feed_id = sqlc.arg(feed_id);
+
+-- name: ListUserFeeds :many
+SELECT sqlc.embed(f), s.title AS subscription_title, s.category AS subscription_category, s.seq AS subscription_seq, s.active AS subscription_active
+FROM subscriptions s
+JOIN feeds f ON f.id = s.feed_id
+WHERE s.user_id = sqlc.arg(user_id)
+  AND (f.active AND s.active) = sqlc.arg(active)
+ORDER BY COALESCE(s.title, f.title) ASC, f.url ASC;
+
+-- name: ListSubscribedFeedIDs :many
+SELECT feed_id
+FROM subscriptions
+WHERE user_id = sqlc.arg(user_id)
+ORDER BY feed_id;
+
+-- name: UpdateSubscriptionTitle :execrows
+UPDATE subscriptions
+SET title = sqlc.narg(title)
+WHERE user_id = sqlc.arg(user_id)
+  AND feed_id = sqlc.arg(feed_id);
+
+-- name: UpdateSubscriptionCategory :execrows
+UPDATE subscriptions
+SET category = sqlc.narg(category)
+WHERE user_id = sqlc.arg(user_id)
+  AND feed_id = sqlc.arg(feed_id);
+
+-- name: UpdateSubscriptionActive :execrows
+UPDATE subscriptions
+SET active = sqlc.arg(active)
+WHERE user_id = sqlc.arg(user_id)
+  AND feed_id = sqlc.arg(feed_id);
+
+-- name: MoveSubscription :execrows
+-- Points one user's subscription, with its name and folder, at another
+-- feed.
+UPDATE subscriptions
+SET feed_id = sqlc.arg(new_feed_id)
+WHERE user_id = sqlc.arg(user_id)
+  AND feed_id = sqlc.arg(feed_id);
+
+-- name: MoveFeedSubscriptions :exec
+-- Users subscribed to both feeds keep the subscription to the kept feed.
+UPDATE subscriptions
+SET feed_id = sqlc.arg(keep_feed_id)
+WHERE feed_id = sqlc.arg(drop_feed_id)
+  AND NOT EXISTS (
+      SELECT 1
+      FROM subscriptions k
+      WHERE k.feed_id = sqlc.arg(keep_feed_id)
+        AND k.user_id = subscriptions.user_id
+  );
+
+-- name: SubscribeToAllFeeds :execrows
+INSERT INTO subscriptions (user_id, feed_id, title, category)
+SELECT sqlc.arg(user_id)::uuid, f.id, CASE WHEN f.title_locked THEN f.title END, f.category
+FROM feeds f
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: ClaimItemStates :execrows
+UPDATE item_states
+SET user_id = sqlc.arg(user_id)::uuid
+WHERE user_id IS NULL
+  AND NOT EXISTS (
+      SELECT 1
+      FROM item_states o
+      WHERE o.item_id = item_states.item_id
+        AND o.user_id = sqlc.arg(user_id)::uuid
+  );
+
+-- name: LockFeed :one
+-- Serializes subscription changes on a feed, so the last subscriber leaving
+-- is seen by exactly one transaction.
+SELECT id
+FROM feeds
+WHERE id = sqlc.arg(id)
+FOR UPDATE;
+
+-- name: DeleteUnsubscribedFeed :execrows
+DELETE FROM feeds
+WHERE id = sqlc.arg(id)
+  AND NOT EXISTS (
+      SELECT 1
+      FROM subscriptions
+      WHERE feed_id = sqlc.arg(id)
+  );
@@ -0,0 +1,82 @@
+-- name: CreateTag :one
+INSERT INTO tags (user_id, name)
+VALUES (sqlc.narg(user_id), sqlc.arg(name))
+ON CONFLICT DO NOTHING
+RETURNING id, name, created_at, user_id;
+
+-- name: GetTag :one
+SELECT id, name, created_at, user_id
+FROM tags
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid;
+
+-- name: GetTagByName :one
+SELECT id, name, created_at, user_id
+FROM tags
+WHERE lower(name) = lower(sqlc.arg(name))
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid;
+
+-- name: ListTags :many
+SELECT t.id, t.name, t.created_at, COUNT(st.feed_id)::bigint AS feed_count
+FROM tags t
+LEFT JOIN subscription_tags st ON st.tag_id = t.id
+WHERE t.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+GROUP BY t.id
+ORDER BY lower(t.name) ASC;
+
+-- name: RenameTag :one
+UPDATE tags
+SET name = sqlc.arg(name)
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+  AND NOT EXISTS (
+      SELECT 1
+      FROM tags other
+      WHERE lower(other.name) = lower(sqlc.arg(name))
+        AND other.id <> sqlc.arg(id)
+        AND other.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+  )
+RETURNING id, name, created_at, user_id;
+
+-- name: DeleteTag :execrows
+DELETE FROM tags
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid;
+
+-- name: ListFeedTags :many
+SELECT t.id, t.name, t.created_at, t.user_id
+FROM tags t
+JOIN subscription_tags st ON st.tag_id = t.id
+WHERE st.feed_id = sqlc.arg(feed_id)
+  AND st.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+ORDER BY lower(t.name) ASC;
+
+-- name: DeleteSubscriptionTags :exec
+DELETE FROM subscription_tags
+WHERE feed_id = sqlc.arg(feed_id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid;
+
+-- name: InsertSubscriptionTag :exec
+INSERT INTO subscription_tags (user_id, feed_id, tag_id)
+VALUES (sqlc.narg(user_id), sqlc.arg(feed_id), sqlc.arg(tag_id))
+ON CONFLICT DO NOTHING;
+
+-- name: ListTaggedFeedIDs :many
+SELECT DISTINCT st.feed_id
+FROM subscription_tags st
+JOIN tags t ON t.id = st.tag_id
+WHERE st.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+  AND lower(t.name) = ANY(sqlc.arg(names)::text[])
+ORDER BY st.feed_id;
+
+-- name: ClaimTags :execrows
+-- Hands the open API's tags to the first user, who has just subscribed to
+-- every feed.
+UPDATE tags
+SET user_id = sqlc.arg(user_id)::uuid
+WHERE user_id IS NULL;
+
+-- name: ClaimSubscriptionTags :execrows
+UPDATE subscription_tags
+SET user_id = sqlc.arg(user_id)::uuid
+WHERE user_id IS NULL;
@@ -0,0 +1,72 @@
+-- name: CountUsers :one
+SELECT COUNT(*)::bigint
+FROM users;
+
+-- name: CreateUser :one
+INSERT INTO users (username, password_hash, is_admin)
+VALUES (sqlc.arg(username), sqlc.arg(password_hash), sqlc.arg(is_admin))
+ON CONFLICT DO NOTHING
+RETURNING id, username, password_hash, is_admin, created_at;
+
+-- name: GetUserByUsername :one
+SELECT id, username, password_hash, is_admin, created_at
+FROM users
+WHERE lower(username) = lower(sqlc.arg(username));
+
+-- name: CreateSession :exec
+INSERT INTO sessions (token_hash, user_id, expires_at)
+VALUES (sqlc.arg(token_hash), sqlc.arg(user_id), sqlc.arg(expires_at));
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM sessions
+WHERE expires_at <= now();
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = sqlc.arg(token_hash);
+
+-- name: GetSessionUser :one
+SELECT u.id, u.username, u.password_hash, u.is_admin, u.created_at
+FROM sessions s
+JOIN users u ON u.id = s.user_id
+WHERE s.token_hash = sqlc.arg(token_hash)
+  AND s.expires_at > now();
+
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (user_id, name, token_hash, scopes)
+VALUES (sqlc.arg(user_id), sqlc.arg(name), sqlc.arg(token_hash), sqlc.arg(scopes))
+RETURNING id, user_id, name, token_hash, scopes, created_at, last_used_at;
+
+-- name: ListAPIKeys :many
+SELECT id, user_id, name, token_hash, scopes, created_at, last_used_at
+FROM api_keys
+WHERE user_id = sqlc.arg(user_id)
+ORDER BY created_at ASC;
+
+-- name: DeleteAPIKey :execrows
+DELETE FROM api_keys
+WHERE id = sqlc.arg(id)
+  AND user_id = sqlc.arg(user_id);
+
+-- name: UseAPIKey :one
+UPDATE api_keys k
+SET last_used_at = now()
+FROM users u
+WHERE k.token_hash = sqlc.arg(token_hash)
+  AND u.id = k.user_id
+RETURNING k.id, k.user_id, k.name, k.scopes, k.created_at, k.last_used_at, u.username, u.is_admin;
+
+-- name: SetFeverKey :exec
+INSERT INTO fever_keys (user_id, key_hash)
+VALUES (sqlc.arg(user_id), sqlc.arg(key_hash))
+ON CONFLICT (user_id) DO UPDATE SET key_hash = EXCLUDED.key_hash, created_at = now();
+
+-- name: DeleteFeverKey :execrows
+DELETE FROM fever_keys
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: GetFeverKeyUser :one
+SELECT u.id, u.username, u.password_hash, u.is_admin, u.created_at
+FROM fever_keys k
+JOIN users u ON u.id = k.user_id
+WHERE k.key_hash = sqlc.arg(key_hash);
@@ -0,0 +1,110 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (user_id, url, secret, feed_ids, keywords)
+VALUES (sqlc.narg(user_id), sqlc.arg(url), sqlc.arg(secret), sqlc.arg(feed_ids), sqlc.arg(keywords))
+RETURNING id, user_id, url, secret, feed_ids, keywords, active, created_at;
+
+-- name: GetWebhook :one
+SELECT id, user_id, url, secret, feed_ids, keywords, active, created_at
+FROM webhooks
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid;
+
+-- name: ListWebhooks :many
+SELECT id, user_id, url, secret, feed_ids, keywords, active, created_at
+FROM webhooks
+WHERE user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+ORDER BY created_at ASC;
+
+-- name: UpdateWebhook :one
+-- Null arguments leave their column unchanged.
+UPDATE webhooks
+SET url = COALESCE(sqlc.narg(url), url),
+    secret = COALESCE(sqlc.narg(secret), secret),
+    feed_ids = COALESCE(sqlc.narg(feed_ids)::uuid[], feed_ids),
+    keywords = COALESCE(sqlc.narg(keywords)::text[], keywords),
+    active = COALESCE(sqlc.narg(active), active)
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+RETURNING id, user_id, url, secret, feed_ids, keywords, active, created_at;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid;
+
+-- name: ListActiveWebhooks :many
+SELECT id, keywords
+FROM webhooks
+WHERE active
+ORDER BY created_at ASC;
+
+-- name: EnqueueWebhookDeliveries :execrows
+-- Candidates are kept only when the owner subscribes to their feed and
+-- they pass the webhook's feed filter.
+INSERT INTO webhook_deliveries (webhook_id, item_id, event, payload)
+SELECT w.id, c.item_id, c.event, c.payload
+FROM webhooks w
+CROSS JOIN unnest(
+    sqlc.arg(item_ids)::uuid[],
+    sqlc.arg(events)::text[],
+    sqlc.arg(payloads)::text[]
+) AS c(item_id, event, payload)
+JOIN items i ON i.id = c.item_id
+WHERE w.id = sqlc.arg(webhook_id)
+  AND w.active
+  AND (
+      w.user_id IS NULL
+      OR i.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = w.user_id)
+  )
+  AND (cardinality(w.feed_ids) = 0 OR i.feed_id = ANY(w.feed_ids));
+
+-- name: ClaimWebhookDeliveries :many
+-- Claimed deliveries are pushed back to lease_until, so one a sender
+-- crashed on is retried once the lease runs out and concurrent senders
+-- skip it meanwhile.
+UPDATE webhook_deliveries d
+SET next_attempt_at = sqlc.arg(lease_until)
+FROM webhooks w
+WHERE w.id = d.webhook_id
+  AND d.id IN (
+      SELECT due.id
+      FROM webhook_deliveries due
+      JOIN webhooks hook ON hook.id = due.webhook_id
+      WHERE due.status = 'pending'
+        AND due.next_attempt_at <= sqlc.arg(due_before)
+        AND hook.active
+      ORDER BY due.next_attempt_at ASC
+      LIMIT sqlc.arg(result_limit)::int
+      FOR UPDATE OF due SKIP LOCKED
+  )
+RETURNING d.id, d.webhook_id, d.event, d.payload, d.attempts, w.url, w.secret;
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    last_attempt_at = sqlc.arg(attempted_at),
+    response_status = sqlc.narg(response_status),
+    last_error = sqlc.narg(last_error)
+WHERE id = sqlc.arg(id);
+
+-- name: ListWebhookDeliveries :many
+SELECT id,
+       webhook_id,
+       item_id,
+       event,
+       payload,
+       status,
+       attempts,
+       next_attempt_at,
+       last_attempt_at,
+       response_status,
+       last_error,
+       created_at
+FROM webhook_deliveries
+WHERE webhook_id = sqlc.arg(webhook_id)
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(result_limit)::int
+OFFSET sqlc.arg(result_offset)::int;
@@ -10,6 +10,7 @@ require (
 	github.com/meilisearch/meilisearch-go v0.30.0
 	github.com/mmcdole/gofeed v1.2.1
 	github.com/prometheus/client_golang v1.17.0
+	golang.org/x/crypto v0.17.0
 	golang.org/x/net v0.19.0
 )
 
@@ -40,7 +41,6 @@ require (
 	github.com/prometheus/procfs v0.11.1 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasttemplate v1.2.2 // indirect
-	golang.org/x/crypto v0.17.0 // indirect
 	golang.org/x/sync v0.3.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
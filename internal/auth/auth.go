@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Scopes grant access to groups of endpoints. Sessions carry every scope
+// their user holds; API keys carry the subset they were created with.
+const (
+	// ScopeRead allows GET requests.
+	ScopeRead = "read"
+	// ScopeWrite allows requests that change data.
+	ScopeWrite = "write"
+	// ScopeAdmin allows user management, /metrics and /config. Only admin
+	// users hold it.
+	ScopeAdmin = "admin"
+)
+
+// Token prefixes tell session tokens and API keys apart without a lookup.
+const (
+	SessionPrefix = "cs_"
+	KeyPrefix     = "ck_"
+)
+
+// MinPasswordLength is the shortest password accepted for new accounts.
+const MinPasswordLength = 8
+
+// ErrPasswordTooShort is returned by HashPassword for passwords shorter than
+// MinPasswordLength.
+var ErrPasswordTooShort = errors.New("password too short")
+
+// HashPassword returns the bcrypt hash of password.
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches a hash produced by
+// HashPassword.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewToken returns a random token starting with prefix together with the
+// hash to store for it. Only the hash is kept; the token is shown once.
+func NewToken(prefix string) (string, []byte, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := prefix + base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the stored form of a session token or API key.
+func HashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// FeverKey returns the API key Fever clients derive from a username and
+// password: the hex MD5 of "username:password". It is only as strong as
+// MD5, so the password should not be reused elsewhere.
+func FeverKey(username, password string) string {
+	sum := md5.Sum([]byte(username + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// UserScopes returns the scopes a user's sessions carry.
+func UserScopes(admin bool) []string {
+	if admin {
+		return []string{ScopeRead, ScopeWrite, ScopeAdmin}
+	}
+	return []string{ScopeRead, ScopeWrite}
+}
+
+// ValidScope reports whether scope is one of the known scopes.
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeRead, ScopeWrite, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// HasScope reports whether scopes includes scope.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal is the user a request is made on behalf of.
+type Principal struct {
+	UserID   string
+	Username string
+	Admin    bool
+	Scopes   []string
+	// APIKeyID is set when the request authenticated with an API key
+	// rather than a session.
+	APIKeyID string
+	// SessionHash identifies the session a request authenticated with so
+	// it can be ended.
+	SessionHash []byte
+}
+
+// Has reports whether the principal holds scope.
+func (p Principal) Has(scope string) bool {
+	return HasScope(p.Scopes, scope)
+}
+
+// TokenKind returns SessionPrefix or KeyPrefix for tokens issued by
+// NewToken, or "" for anything else.
+func TokenKind(token string) string {
+	switch {
+	case strings.HasPrefix(token, SessionPrefix):
+		return SessionPrefix
+	case strings.HasPrefix(token, KeyPrefix):
+		return KeyPrefix
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPasswords(t *testing.T) {
+	if _, err := HashPassword("short"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Fatalf("expected ErrPasswordTooShort, got %v", err)
+	}
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if !CheckPassword(hash, "correct horse") {
+		t.Fatalf("expected the password to match its hash")
+	}
+	if CheckPassword(hash, "battery staple") {
+		t.Fatalf("expected a different password not to match")
+	}
+}
+
+func TestTokens(t *testing.T) {
+	token, hash, err := NewToken(KeyPrefix)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	if TokenKind(token) != KeyPrefix {
+		t.Fatalf("expected an API key token, got %q", token)
+	}
+	if !bytes.Equal(HashToken(token), hash) {
+		t.Fatalf("expected HashToken to reproduce the stored hash")
+	}
+	other, _, err := NewToken(SessionPrefix)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	if other == token || TokenKind(other) != SessionPrefix {
+		t.Fatalf("expected a distinct session token, got %q", other)
+	}
+	if TokenKind("Bearer xyz") != "" {
+		t.Fatalf("expected unknown tokens to have no kind")
+	}
+}
+
+func TestFeverKey(t *testing.T) {
+	// md5("reader:secret")
+	if got := FeverKey("reader", "secret"); got != "d86e2552797d076a6178f7b038ad6b69" {
+		t.Fatalf("unexpected Fever key %q", got)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	p := Principal{Scopes: UserScopes(false)}
+	if !p.Has(ScopeRead) || !p.Has(ScopeWrite) || p.Has(ScopeAdmin) {
+		t.Fatalf("unexpected scopes for a regular user: %v", p.Scopes)
+	}
+	if !HasScope(UserScopes(true), ScopeAdmin) {
+		t.Fatalf("expected admins to hold the admin scope")
+	}
+	if ValidScope("delete") {
+		t.Fatalf("expected unknown scopes to be rejected")
+	}
+}
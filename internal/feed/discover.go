@@ -0,0 +1,193 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	xhtml "golang.org/x/net/html"
+)
+
+const (
+	// maxDiscoveryBody bounds how much Discover reads. A feed is parsed in
+	// full, and podcast feeds with long histories run to tens of megabytes.
+	maxDiscoveryBody = 64 << 20
+	// maxLinkScanBody bounds how much of an HTML page is scanned for feed
+	// links; they live in the document head, so large pages need not be
+	// scanned in full.
+	maxLinkScanBody = 2 << 20
+)
+
+// ErrNoFeed is returned by Discover when the URL is neither a feed nor a page
+// advertising one.
+var ErrNoFeed = errors.New("no feed found")
+
+// Candidate is a feed advertised by an HTML page through
+// <link rel="alternate">.
+type Candidate struct {
+	URL   string
+	Title string
+	Type  string
+}
+
+// Discovery describes what a URL offered when probed for a feed. Feed and
+// Hints are set when the URL serves a feed itself; otherwise Candidates lists
+// the feeds the page links to.
+type Discovery struct {
+	// URL is the address that answered, after following redirects.
+	URL        string
+	Feed       *gofeed.Feed
+	Hints      RefreshHints
+	Candidates []Candidate
+}
+
+var discoveryTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// Discover fetches rawURL and reports whether it is a feed or an HTML page
+// linking to feeds. It shares the client and host scheduler used by Fetch.
+func (f *Fetcher) Discover(ctx context.Context, rawURL string) (Discovery, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Discovery{}, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, text/html;q=0.8, */*;q=0.5")
+
+	if f.scheduler != nil {
+		release, err := f.scheduler.Acquire(ctx, HostKey(rawURL))
+		if err != nil {
+			var blocked *BlockedError
+			if errors.As(err, &blocked) {
+				return Discovery{}, fmt.Errorf("%w: %w", ErrRetryLater, err)
+			}
+			return Discovery{}, err
+		}
+		defer release()
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if isTransientFetchError(err) {
+			return Discovery{}, fmt.Errorf("%w: %w", ErrTransientFetch, err)
+		}
+		return Discovery{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case isTransientStatus(resp.StatusCode):
+		return Discovery{}, fmt.Errorf("%w: http status %d", ErrTransientFetch, resp.StatusCode)
+	case isRateLimited(resp.StatusCode):
+		return Discovery{}, fmt.Errorf("%w: http status %d", ErrRetryLater, resp.StatusCode)
+	case resp.StatusCode == http.StatusGone:
+		return Discovery{}, fmt.Errorf("%w: http status %d", ErrGone, resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		return Discovery{}, fmt.Errorf("%w: http status %d", ErrNotFound, resp.StatusCode)
+	default:
+		return Discovery{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryBody))
+	if err != nil {
+		if isTransientFetchError(err) {
+			return Discovery{}, fmt.Errorf("%w: %w", ErrTransientFetch, err)
+		}
+		return Discovery{}, err
+	}
+
+	res := Discovery{URL: resp.Request.URL.String()}
+	parsed, parseErr := f.parser.Parse(bytes.NewReader(body))
+	if parseErr == nil {
+		res.Feed = parsed
+		res.Hints = documentHints(resp.Header, parsed, body)
+		return res, nil
+	}
+
+	if len(body) > maxLinkScanBody {
+		body = body[:maxLinkScanBody]
+	}
+	res.Candidates = feedLinks(body, resp.Request.URL)
+	if len(res.Candidates) == 0 {
+		return res, fmt.Errorf("%w: %v", ErrNoFeed, parseErr)
+	}
+	return res, nil
+}
+
+// feedLinks returns the feeds advertised by an HTML document, resolved
+// against base (or the document's own <base href>), in document order and
+// without duplicates.
+func feedLinks(body []byte, base *url.URL) []Candidate {
+	var (
+		candidates []Candidate
+		seen       = map[string]bool{}
+		z          = xhtml.NewTokenizer(bytes.NewReader(body))
+	)
+	for {
+		tt := z.Next()
+		switch tt {
+		case xhtml.ErrorToken:
+			return candidates
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+		default:
+			continue
+		}
+
+		tok := z.Token()
+		attrs := make(map[string]string, len(tok.Attr))
+		for _, a := range tok.Attr {
+			attrs[strings.ToLower(a.Key)] = strings.TrimSpace(a.Val)
+		}
+
+		switch tok.Data {
+		case "base":
+			if href, err := base.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+				base = href
+			}
+		case "link":
+			if !hasToken(attrs["rel"], "alternate") {
+				continue
+			}
+			mediaType, _, _ := strings.Cut(strings.ToLower(attrs["type"]), ";")
+			mediaType = strings.TrimSpace(mediaType)
+			if !discoveryTypes[mediaType] || attrs["href"] == "" {
+				continue
+			}
+			target, err := base.Parse(attrs["href"])
+			if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+				continue
+			}
+			target.Fragment = ""
+			if seen[target.String()] {
+				continue
+			}
+			seen[target.String()] = true
+			candidates = append(candidates, Candidate{
+				URL:   target.String(),
+				Title: attrs["title"],
+				Type:  mediaType,
+			})
+		case "body":
+			// Feed links belong in the head; stop before scanning the page.
+			return candidates
+		}
+	}
+}
+
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(list) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetcherDiscoverFindsAlternateLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blog":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<!doctype html><html><head>
+<title>Blog</title>
+<link rel="stylesheet" href="/style.css">
+<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed.xml">
+<link rel="Alternate" type="application/atom+xml" title="Atom" href="atom.xml#top">
+<link rel="alternate" type="application/feed+json" href="/feed.json">
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<link rel="alternate" type="text/html" hreflang="fr" href="/fr/">
+</head><body><link rel="alternate" type="application/rss+xml" href="/ignored.xml"></body></html>`))
+		case "/empty":
+			_, _ = w.Write([]byte(`<html><head><title>Nothing</title></head><body></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	fetcher := NewFetcher(loopback)
+	res, err := fetcher.Discover(context.Background(), srv.URL+"/blog")
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if res.Feed != nil {
+		t.Fatalf("expected html page, got feed")
+	}
+	want := []Candidate{
+		{URL: srv.URL + "/feed.xml", Title: "Posts", Type: "application/rss+xml"},
+		{URL: srv.URL + "/atom.xml", Title: "Atom", Type: "application/atom+xml"},
+		{URL: srv.URL + "/feed.json", Type: "application/feed+json"},
+	}
+	if len(res.Candidates) != len(want) {
+		t.Fatalf("expected %d candidates, got %+v", len(want), res.Candidates)
+	}
+	for i := range want {
+		if res.Candidates[i] != want[i] {
+			t.Fatalf("candidate %d: expected %+v, got %+v", i, want[i], res.Candidates[i])
+		}
+	}
+
+	if _, err := fetcher.Discover(context.Background(), srv.URL+"/empty"); !errors.Is(err, ErrNoFeed) {
+		t.Fatalf("expected ErrNoFeed, got %v", err)
+	}
+	if _, err := fetcher.Discover(context.Background(), srv.URL+"/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFetcherDiscoverRecognizesFeeds(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/rss", http.StatusMovedPermanently)
+			return
+		}
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Direct</title></channel></rss>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	res, err := NewFetcher(loopback).Discover(context.Background(), srv.URL+"/old")
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if res.Feed == nil || res.Feed.Title != "Direct" {
+		t.Fatalf("expected parsed feed, got %+v", res)
+	}
+	if res.URL != srv.URL+"/rss" {
+		t.Fatalf("expected final url %q, got %q", srv.URL+"/rss", res.URL)
+	}
+	if len(res.Candidates) != 0 {
+		t.Fatalf("expected no candidates for a feed, got %+v", res.Candidates)
+	}
+}
+
+func TestFetcherDiscoverParsesLargeFeeds(t *testing.T) {
+	item := `<item><title>Episode</title><description>` + strings.Repeat("x", 4096) + `</description></item>`
+	body := `<?xml version="1.0"?><rss version="2.0"><channel><title>Podcast</title>` +
+		strings.Repeat(item, (3<<20)/len(item)) + `</channel></rss>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	res, err := NewFetcher(loopback).Discover(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if res.Feed == nil || res.Feed.Title != "Podcast" {
+		t.Fatalf("expected a feed larger than the link scan limit to parse, got %+v", res.Candidates)
+	}
+}
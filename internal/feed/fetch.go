@@ -1,6 +1,7 @@
 package feed
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	"github.com/mmcdole/gofeed"
+
+	"courier/internal/netguard"
 )
 
 type Result struct {
@@ -20,17 +23,35 @@ type Result struct {
 	ETag         string
 	LastModified string
 	RetryAfter   time.Duration
+	Hints        RefreshHints
+	// PermanentURL is set when the feed was reached through permanent
+	// redirects (301/308) and holds the URL they point to.
+	PermanentURL string
 }
 
 type Fetcher struct {
-	client *http.Client
-	parser *gofeed.Parser
+	client    *http.Client
+	parser    *gofeed.Parser
+	scheduler *HostScheduler
+}
+
+// NewFetcher returns a Fetcher without per-host politeness. Like every
+// Fetcher it only connects to public addresses and the networks in allow,
+// redirects included.
+func NewFetcher(allow netguard.Allowlist) *Fetcher {
+	return NewFetcherWithScheduler(nil, allow)
 }
 
-func NewFetcher() *Fetcher {
+// NewFetcherWithScheduler returns a Fetcher that routes every request through
+// scheduler. A nil scheduler disables per-host politeness.
+func NewFetcherWithScheduler(scheduler *HostScheduler, allow netguard.Allowlist) *Fetcher {
 	return &Fetcher{
-		client: &http.Client{Timeout: 20 * time.Second},
-		parser: gofeed.NewParser(),
+		client: &http.Client{
+			Timeout:   20 * time.Second,
+			Transport: allow.Transport(),
+		},
+		parser:    gofeed.NewParser(),
+		scheduler: scheduler,
 	}
 }
 
@@ -39,6 +60,19 @@ func (f *Fetcher) Fetch(ctx context.Context, url, etag, lastModified string) (Re
 	if err != nil {
 		return Result{}, err
 	}
+
+	host := HostKey(url)
+	if f.scheduler != nil {
+		release, err := f.scheduler.Acquire(ctx, host)
+		if err != nil {
+			var blocked *BlockedError
+			if errors.As(err, &blocked) {
+				return Result{RetryAfter: time.Until(blocked.Until)}, fmt.Errorf("%w: %w", ErrRetryLater, err)
+			}
+			return Result{}, err
+		}
+		defer release()
+	}
 	if etag != "" {
 		req.Header.Set("If-None-Match", etag)
 	}
@@ -56,10 +90,14 @@ func (f *Fetcher) Fetch(ctx context.Context, url, etag, lastModified string) (Re
 	defer resp.Body.Close()
 
 	res := Result{Status: resp.StatusCode}
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotModified {
+		res.PermanentURL = permanentRedirect(resp)
+	}
 	currentETag := resp.Header.Get("ETag")
 	currentLastModified := resp.Header.Get("Last-Modified")
 
 	if resp.StatusCode == http.StatusNotModified {
+		res.Hints = parseHTTPHints(resp.Header)
 		if currentETag != "" {
 			res.ETag = currentETag
 		} else {
@@ -81,19 +119,42 @@ func (f *Fetcher) Fetch(ctx context.Context, url, etag, lastModified string) (Re
 
 		if isRateLimited(resp.StatusCode) {
 			res.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			if f.scheduler != nil {
+				penalty := res.RetryAfter
+				if penalty <= 0 {
+					penalty = defaultHostPenalty
+				}
+				f.scheduler.Block(host, time.Now().Add(penalty))
+			}
 			return res, fmt.Errorf("%w: http status %d", ErrRetryLater, resp.StatusCode)
 		}
 
+		switch resp.StatusCode {
+		case http.StatusGone:
+			return res, fmt.Errorf("%w: http status %d", ErrGone, resp.StatusCode)
+		case http.StatusNotFound:
+			return res, fmt.Errorf("%w: http status %d", ErrNotFound, resp.StatusCode)
+		}
+
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
 		return res, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
 	}
 
-	feed, err := f.parser.Parse(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if isTransientFetchError(err) {
+			return res, fmt.Errorf("%w: %w", ErrTransientFetch, err)
+		}
+		return res, err
+	}
+
+	feed, err := f.parser.Parse(bytes.NewReader(body))
 	if err != nil {
 		return res, err
 	}
 
 	res.Feed = feed
+	res.Hints = documentHints(resp.Header, feed, body)
 	res.ETag = currentETag
 	res.LastModified = currentLastModified
 	if res.ETag == "" {
@@ -108,8 +169,36 @@ func (f *Fetcher) Fetch(ctx context.Context, url, etag, lastModified string) (Re
 var (
 	ErrRetryLater     = errors.New("retry later")
 	ErrTransientFetch = errors.New("transient fetch")
+	ErrGone           = errors.New("feed gone")
+	ErrNotFound       = errors.New("feed not found")
 )
 
+// permanentRedirect returns the URL reached by the leading run of permanent
+// redirects that produced resp, or "" when the first hop was not permanent.
+// A temporary hop ends the run: the publisher only committed to the URLs
+// before it.
+func permanentRedirect(resp *http.Response) string {
+	var hops []*http.Response
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		hops = append(hops, r)
+	}
+
+	target := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		switch hops[i].StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		default:
+			return target
+		}
+		if i == 0 {
+			target = resp.Request.URL.String()
+		} else {
+			target = hops[i-1].Request.URL.String()
+		}
+	}
+	return target
+}
+
 var transientSyscallErrors = []error{
 	syscall.ECONNRESET,
 	syscall.ECONNREFUSED,
@@ -2,12 +2,20 @@ package feed
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
 	"time"
+
+	"courier/internal/netguard"
 )
 
+// loopback lets tests reach httptest servers through the fetcher's address
+// guard.
+var loopback = netguard.Allowlist{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
 func TestFetcherPreservesHeadersOnNotModified(t *testing.T) {
 	var count int
 	const (
@@ -27,7 +35,7 @@ func TestFetcherPreservesHeadersOnNotModified(t *testing.T) {
 	}))
 	t.Cleanup(srv.Close)
 
-	fetcher := NewFetcher()
+	fetcher := NewFetcher(loopback)
 	ctx := context.Background()
 
 	res, err := fetcher.Fetch(ctx, srv.URL, "", "")
@@ -73,3 +81,92 @@ func TestParseRetryAfter(t *testing.T) {
 		t.Fatalf("expected 0 for invalid header, got %s", d)
 	}
 }
+
+func TestFetcherReportsPermanentRedirects(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Test</title></channel></rss>`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/current", http.StatusPermanentRedirect)
+	})
+	mux.HandleFunc("/temp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/current", http.StatusFound)
+	})
+	mux.HandleFunc("/moved-then-temp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/temp", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/current", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(doc))
+	})
+	mux.HandleFunc("/moved-to-missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/missing", http.StatusMovedPermanently)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{path: "/old", want: srv.URL + "/current"},
+		{path: "/temp", want: ""},
+		{path: "/moved-then-temp", want: srv.URL + "/temp"},
+		{path: "/current", want: ""},
+	}
+	for _, tc := range cases {
+		res, err := NewFetcher(loopback).Fetch(context.Background(), srv.URL+tc.path, "", "")
+		if err != nil {
+			t.Fatalf("%s: fetch: %v", tc.path, err)
+		}
+		if res.PermanentURL != tc.want {
+			t.Fatalf("%s: PermanentURL = %q, want %q", tc.path, res.PermanentURL, tc.want)
+		}
+	}
+
+	res, err := NewFetcher(loopback).Fetch(context.Background(), srv.URL+"/moved-to-missing", "", "")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if res.PermanentURL != "" {
+		t.Fatalf("expected no permanent URL for a failed fetch, got %q", res.PermanentURL)
+	}
+}
+
+func TestFetcherClassifiesGoneAndNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	res, err := NewFetcher(loopback).Fetch(context.Background(), srv.URL+"/gone", "", "")
+	if !errors.Is(err, ErrGone) || res.Status != http.StatusGone {
+		t.Fatalf("expected ErrGone with status 410, got %d %v", res.Status, err)
+	}
+
+	res, err = NewFetcher(loopback).Fetch(context.Background(), srv.URL+"/missing", "", "")
+	if !errors.Is(err, ErrNotFound) || res.Status != http.StatusNotFound {
+		t.Fatalf("expected ErrNotFound with status 404, got %d %v", res.Status, err)
+	}
+}
+
+func TestFetcherRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>Internal</title></channel></rss>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := NewFetcher(nil).Fetch(context.Background(), srv.URL, "", "")
+	if !errors.Is(err, netguard.ErrBlocked) {
+		t.Fatalf("expected loopback fetch to be blocked, got %v", err)
+	}
+	if errors.Is(err, ErrTransientFetch) {
+		t.Fatalf("expected a blocked address not to be retried as transient")
+	}
+}
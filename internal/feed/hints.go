@@ -0,0 +1,176 @@
+package feed
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+// RefreshHints collects the refresh guidance a publisher declared for a feed,
+// either in the document (RSS ttl/skipHours/skipDays, the syndication module)
+// or in HTTP caching headers.
+type RefreshHints struct {
+	TTL          time.Duration
+	UpdatePeriod time.Duration
+	MaxAge       time.Duration
+	Expires      time.Time
+	// SkipHours has bit h set when the feed asks not to be fetched during
+	// hour h (0-23, GMT).
+	SkipHours uint32
+	// SkipDays has bit d set when the feed asks not to be fetched on
+	// time.Weekday d (GMT).
+	SkipDays uint8
+}
+
+// DeclaredInterval returns the interval the feed document asks for, ignoring
+// HTTP caching headers.
+func (h RefreshHints) DeclaredInterval() time.Duration {
+	if h.UpdatePeriod > h.TTL {
+		return h.UpdatePeriod
+	}
+	return h.TTL
+}
+
+// Interval returns the longest wait requested by any hint. Cache-Control
+// max-age takes precedence over Expires, as in HTTP caching.
+func (h RefreshHints) Interval(now time.Time) time.Duration {
+	interval := h.DeclaredInterval()
+	cache := h.MaxAge
+	if cache <= 0 && !h.Expires.IsZero() {
+		cache = h.Expires.Sub(now)
+	}
+	if cache > interval {
+		interval = cache
+	}
+	return interval
+}
+
+// Allowed returns the earliest time at or after t that is outside the feed's
+// skipHours and skipDays. A feed that skips every hour or day is ignored.
+func (h RefreshHints) Allowed(t time.Time) time.Time {
+	if h.SkipHours == 0 && h.SkipDays == 0 {
+		return t
+	}
+	if h.SkipHours&(1<<24-1) == 1<<24-1 || h.SkipDays&(1<<7-1) == 1<<7-1 {
+		return t
+	}
+
+	candidate := t.UTC()
+	for i := 0; i < 8*24; i++ {
+		if h.SkipDays&(1<<uint(candidate.Weekday())) == 0 && h.SkipHours&(1<<uint(candidate.Hour())) == 0 {
+			return candidate
+		}
+		candidate = candidate.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
+
+// documentHints combines the HTTP caching headers with the hints declared in
+// a parsed feed. The universal feed drops RSS channel elements such as ttl
+// and skipHours, so RSS documents are parsed again from body.
+func documentHints(header http.Header, parsed *gofeed.Feed, body []byte) RefreshHints {
+	var channel *rss.Feed
+	if parsed.FeedType == "rss" {
+		channel, _ = (&rss.Parser{}).Parse(bytes.NewReader(body))
+	}
+
+	hints := parseHTTPHints(header)
+	parseFeedHints(parsed, channel, &hints)
+	return hints
+}
+
+func parseHTTPHints(header http.Header) RefreshHints {
+	var hints RefreshHints
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return RefreshHints{}
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds > 0 {
+				hints.MaxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	if v := header.Get("Expires"); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			hints.Expires = t
+		}
+	}
+	return hints
+}
+
+func parseFeedHints(parsed *gofeed.Feed, channel *rss.Feed, hints *RefreshHints) {
+	if channel != nil {
+		if minutes, err := strconv.Atoi(strings.TrimSpace(channel.TTL)); err == nil && minutes > 0 {
+			hints.TTL = time.Duration(minutes) * time.Minute
+		}
+		for _, v := range channel.SkipHours {
+			hour, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil || hour < 0 || hour > 24 {
+				continue
+			}
+			hints.SkipHours |= 1 << uint(hour%24)
+		}
+		for _, v := range channel.SkipDays {
+			if day, ok := weekdays[strings.ToLower(strings.TrimSpace(v))]; ok {
+				hints.SkipDays |= 1 << uint(day)
+			}
+		}
+	}
+
+	if parsed != nil {
+		hints.UpdatePeriod = syndicationInterval(parsed)
+	}
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// syndicationInterval reads sy:updatePeriod and sy:updateFrequency, which
+// together mean "updateFrequency times per updatePeriod".
+func syndicationInterval(parsed *gofeed.Feed) time.Duration {
+	sy, ok := parsed.Extensions["sy"]
+	if !ok {
+		return 0
+	}
+
+	period := updatePeriods["daily"]
+	if values := sy["updatePeriod"]; len(values) > 0 {
+		p, ok := updatePeriods[strings.ToLower(strings.TrimSpace(values[0].Value))]
+		if !ok {
+			return 0
+		}
+		period = p
+	} else if len(sy["updateFrequency"]) == 0 {
+		return 0
+	}
+
+	frequency := 1
+	if values := sy["updateFrequency"]; len(values) > 0 {
+		if n, err := strconv.Atoi(strings.TrimSpace(values[0].Value)); err == nil && n > 0 {
+			frequency = n
+		}
+	}
+	return period / time.Duration(frequency)
+}
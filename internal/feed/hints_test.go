@@ -0,0 +1,99 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetcherParsesRefreshHints(t *testing.T) {
+	const body = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+<channel>
+<title>Test</title>
+<ttl>90</ttl>
+<skipHours><hour>0</hour><hour>1</hour><hour>24</hour></skipHours>
+<skipDays><day>Saturday</day><day>Sunday</day></skipDays>
+<sy:updatePeriod>daily</sy:updatePeriod>
+<sy:updateFrequency>4</sy:updateFrequency>
+</channel>
+</rss>`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	res, err := NewFetcher(loopback).Fetch(context.Background(), srv.URL, "", "")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	hints := res.Hints
+	if hints.TTL != 90*time.Minute {
+		t.Fatalf("TTL = %s, want 90m", hints.TTL)
+	}
+	if hints.UpdatePeriod != 6*time.Hour {
+		t.Fatalf("UpdatePeriod = %s, want 6h", hints.UpdatePeriod)
+	}
+	if hints.MaxAge != 10*time.Minute {
+		t.Fatalf("MaxAge = %s, want 10m", hints.MaxAge)
+	}
+	if want := uint32(1<<0 | 1<<1); hints.SkipHours != want {
+		t.Fatalf("SkipHours = %b, want %b", hints.SkipHours, want)
+	}
+	if want := uint8(1<<time.Saturday | 1<<time.Sunday); hints.SkipDays != want {
+		t.Fatalf("SkipDays = %b, want %b", hints.SkipDays, want)
+	}
+	if got := hints.DeclaredInterval(); got != 6*time.Hour {
+		t.Fatalf("DeclaredInterval = %s, want 6h", got)
+	}
+}
+
+func TestParseHTTPHints(t *testing.T) {
+	now := time.Now()
+
+	header := http.Header{}
+	header.Set("Expires", now.Add(time.Hour).UTC().Format(http.TimeFormat))
+	hints := parseHTTPHints(header)
+	if d := hints.Interval(now); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("expected Expires to yield about 1h, got %s", d)
+	}
+
+	header.Set("Cache-Control", "max-age=120")
+	hints = parseHTTPHints(header)
+	if d := hints.Interval(now); d != 2*time.Minute {
+		t.Fatalf("expected max-age to override Expires, got %s", d)
+	}
+
+	header.Set("Cache-Control", "no-cache")
+	if hints := parseHTTPHints(header); hints.Interval(now) != 0 {
+		t.Fatalf("expected no-cache to disable hints, got %+v", hints)
+	}
+}
+
+func TestRefreshHintsAllowed(t *testing.T) {
+	// Friday 2024-05-03 22:30 UTC.
+	start := time.Date(2024, 5, 3, 22, 30, 0, 0, time.UTC)
+
+	hints := RefreshHints{SkipHours: 1<<22 | 1<<23}
+	if got, want := hints.Allowed(start), time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("Allowed skipping hours = %s, want %s", got, want)
+	}
+
+	hints.SkipDays = 1<<time.Saturday | 1<<time.Sunday
+	if got, want := hints.Allowed(start), time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("Allowed skipping weekend = %s, want %s", got, want)
+	}
+
+	if got := (RefreshHints{}).Allowed(start); !got.Equal(start) {
+		t.Fatalf("Allowed without hints = %s, want %s", got, start)
+	}
+
+	if got := (RefreshHints{SkipDays: 0x7f}).Allowed(start); !got.Equal(start) {
+		t.Fatalf("expected skipping every day to be ignored, got %s", got)
+	}
+}
@@ -0,0 +1,160 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultHostPenalty is how long a host is blocked after a 429 that did not
+// carry a usable Retry-After header.
+const defaultHostPenalty = 30 * time.Second
+
+// HostScheduler spaces out requests to the same host. It enforces a minimum
+// interval between request starts, caps the number of in-flight requests per
+// host, and lets a rate limit observed on one feed block every feed served by
+// that host.
+type HostScheduler struct {
+	minInterval time.Duration
+	maxInFlight int
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	inFlight     int
+	nextStart    time.Time
+	blockedUntil time.Time
+	wake         chan struct{}
+}
+
+// BlockedError reports that a host is rate limiting us and no request was
+// sent.
+type BlockedError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("host %s blocked until %s", e.Host, e.Until.UTC().Format(time.RFC3339))
+}
+
+func NewHostScheduler(minInterval time.Duration, maxInFlight int) *HostScheduler {
+	if maxInFlight <= 0 {
+		maxInFlight = 1
+	}
+	return &HostScheduler{
+		minInterval: minInterval,
+		maxInFlight: maxInFlight,
+		hosts:       make(map[string]*hostState),
+	}
+}
+
+// Acquire waits until a request to host may start and returns a release
+// function that must be called once the request completes. It returns a
+// *BlockedError without waiting when the host is blocked.
+func (s *HostScheduler) Acquire(ctx context.Context, host string) (func(), error) {
+	for {
+		s.mu.Lock()
+		st := s.state(host)
+		now := time.Now()
+
+		if now.Before(st.blockedUntil) {
+			until := st.blockedUntil
+			s.mu.Unlock()
+			return nil, &BlockedError{Host: host, Until: until}
+		}
+
+		if st.inFlight < s.maxInFlight && !now.Before(st.nextStart) {
+			st.inFlight++
+			st.nextStart = now.Add(s.minInterval)
+			s.mu.Unlock()
+			var once sync.Once
+			return func() { once.Do(func() { s.release(host) }) }, nil
+		}
+
+		// At capacity we wait for a release; otherwise only the spacing
+		// interval is pending.
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if st.inFlight < s.maxInFlight {
+			timer = time.NewTimer(st.nextStart.Sub(now))
+			timerC = timer.C
+		}
+		wake := st.wake
+		s.mu.Unlock()
+
+		var err error
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-timerC:
+		case <-wake:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Block prevents requests to host until the given time. An existing block
+// that ends later is kept.
+func (s *HostScheduler) Block(host string, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.state(host)
+	if until.After(st.blockedUntil) {
+		st.blockedUntil = until
+	}
+}
+
+// Blocked returns how long host remains blocked, or zero.
+func (s *HostScheduler) Blocked(host string, now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.hosts[host]
+	if !ok || !now.Before(st.blockedUntil) {
+		return 0
+	}
+	return st.blockedUntil.Sub(now)
+}
+
+func (s *HostScheduler) release(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.state(host)
+	if st.inFlight > 0 {
+		st.inFlight--
+	}
+	close(st.wake)
+	st.wake = make(chan struct{})
+}
+
+func (s *HostScheduler) state(host string) *hostState {
+	st, ok := s.hosts[host]
+	if !ok {
+		st = &hostState{wake: make(chan struct{})}
+		s.hosts[host] = st
+	}
+	return st
+}
+
+// HostKey returns the scheduling key for rawURL: its lowercased host,
+// including any explicit port.
+func HostKey(rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return strings.ToLower(parsed.Host)
+}
@@ -0,0 +1,141 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostSchedulerSpacesRequests(t *testing.T) {
+	const interval = 30 * time.Millisecond
+	s := NewHostScheduler(interval, 4)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := s.Acquire(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("acquire %d: %v", i, err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Fatalf("expected at least %s between three requests, took %s", 2*interval, elapsed)
+	}
+
+	other := time.Now()
+	release, err := s.Acquire(ctx, "other.example.com")
+	if err != nil {
+		t.Fatalf("acquire other host: %v", err)
+	}
+	release()
+	if waited := time.Since(other); waited > interval {
+		t.Fatalf("expected other host to be independent, waited %s", waited)
+	}
+}
+
+func TestHostSchedulerLimitsInFlight(t *testing.T) {
+	s := NewHostScheduler(0, 2)
+	ctx := context.Background()
+
+	var (
+		wg      sync.WaitGroup
+		current int32
+		peak    int32
+	)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.Acquire(ctx, "example.com")
+			if err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			n := atomic.AddInt32(&current, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&current, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Fatalf("expected at most 2 in-flight requests, saw %d", peak)
+	}
+}
+
+func TestHostSchedulerBlockRejectsWithoutWaiting(t *testing.T) {
+	s := NewHostScheduler(0, 1)
+	until := time.Now().Add(time.Minute)
+	s.Block("example.com", until)
+
+	_, err := s.Acquire(context.Background(), "example.com")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("expected BlockedError, got %v", err)
+	}
+	if !blocked.Until.Equal(until) {
+		t.Fatalf("blocked until %s, want %s", blocked.Until, until)
+	}
+	if d := s.Blocked("example.com", time.Now()); d <= 0 {
+		t.Fatalf("expected remaining block, got %s", d)
+	}
+}
+
+func TestFetcherRateLimitBlocksWholeHost(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(srv.Close)
+
+	fetcher := NewFetcherWithScheduler(NewHostScheduler(0, 2), loopback)
+	ctx := context.Background()
+
+	res, err := fetcher.Fetch(ctx, srv.URL+"/a.xml", "", "")
+	if !errors.Is(err, ErrRetryLater) {
+		t.Fatalf("expected ErrRetryLater, got %v", err)
+	}
+	if res.RetryAfter != 120*time.Second {
+		t.Fatalf("expected retry after 120s, got %s", res.RetryAfter)
+	}
+
+	res, err = fetcher.Fetch(ctx, srv.URL+"/b.xml", "", "")
+	if !errors.Is(err, ErrRetryLater) {
+		t.Fatalf("expected ErrRetryLater for sibling feed, got %v", err)
+	}
+	if res.RetryAfter < 110*time.Second {
+		t.Fatalf("expected sibling feed to inherit host retry, got %s", res.RetryAfter)
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("expected one request to reach the host, got %d", got)
+	}
+}
+
+func TestHostKey(t *testing.T) {
+	cases := map[string]string{
+		"https://Example.com/feed.xml":   "example.com",
+		"http://example.com:8080/rss":    "example.com:8080",
+		" https://sub.example.com/atom ": "sub.example.com",
+		"not a url":                      "",
+	}
+	for in, want := range cases {
+		if got := HostKey(in); got != want {
+			t.Fatalf("HostKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package feed
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+
+	"courier/internal/item/urlcanon"
+)
+
+// Relocation returns the URL a parsed feed says it now lives at, or "" when
+// it names no other location. An itunes:new-feed-url is an explicit
+// migration and takes precedence over the atom:link rel="self" declaration.
+// Relative URLs are resolved against fetchedURL, and a feed fetched over
+// https is never moved to plain http.
+func Relocation(parsed *gofeed.Feed, fetchedURL string) string {
+	if parsed == nil {
+		return ""
+	}
+
+	base, err := url.Parse(fetchedURL)
+	if err != nil {
+		return ""
+	}
+
+	var candidates []string
+	if parsed.ITunesExt != nil {
+		candidates = append(candidates, parsed.ITunesExt.NewFeedURL)
+	}
+	candidates = append(candidates, parsed.FeedLink)
+
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" {
+			continue
+		}
+		ref, err := url.Parse(candidate)
+		if err != nil {
+			continue
+		}
+		ref = base.ResolveReference(ref)
+		if (ref.Scheme != "http" && ref.Scheme != "https") || ref.Host == "" {
+			continue
+		}
+		if ref.Scheme == "http" && base.Scheme == "https" {
+			ref.Scheme = "https"
+		}
+		if sameLocation(ref, base) {
+			return ""
+		}
+		return ref.String()
+	}
+	return ""
+}
+
+// sameLocation compares two feed URLs in canonical form, ignoring the
+// scheme, so a feed that names its http or www. alias as its self link is
+// not relocated.
+func sameLocation(a, b *url.URL) bool {
+	return canonicalLocation(a) == canonicalLocation(b)
+}
+
+func canonicalLocation(u *url.URL) string {
+	withoutScheme := *u
+	withoutScheme.Scheme = "https"
+	return urlcanon.Normalize(withoutScheme.String())
+}
+
+// SameFeed reports whether two parsed documents are the same feed. Documents
+// with items must share at least one item GUID or link; empty documents must
+// agree on title and site link.
+func SameFeed(a, b *gofeed.Feed) bool {
+	if a == nil || b == nil {
+		return false
+	}
+
+	if len(a.Items) > 0 && len(b.Items) > 0 {
+		seen := make(map[string]struct{}, 2*len(a.Items))
+		for _, it := range a.Items {
+			if it == nil {
+				continue
+			}
+			if it.GUID != "" {
+				seen["guid:"+it.GUID] = struct{}{}
+			}
+			if it.Link != "" {
+				seen["link:"+it.Link] = struct{}{}
+			}
+		}
+		for _, it := range b.Items {
+			if it == nil {
+				continue
+			}
+			if _, ok := seen["guid:"+it.GUID]; ok && it.GUID != "" {
+				return true
+			}
+			if _, ok := seen["link:"+it.Link]; ok && it.Link != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	title := strings.TrimSpace(a.Title)
+	return title != "" && title == strings.TrimSpace(b.Title) && a.Link == b.Link
+}
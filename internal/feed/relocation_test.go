@@ -0,0 +1,60 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestRelocation(t *testing.T) {
+	const current = "https://example.com/feed.xml"
+
+	cases := []struct {
+		name   string
+		parsed *gofeed.Feed
+		want   string
+	}{
+		{name: "no hints", parsed: &gofeed.Feed{}, want: ""},
+		{name: "self matches", parsed: &gofeed.Feed{FeedLink: "https://EXAMPLE.com/feed.xml/"}, want: ""},
+		{name: "self over http", parsed: &gofeed.Feed{FeedLink: "http://example.com/feed.xml"}, want: ""},
+		{name: "self on www", parsed: &gofeed.Feed{FeedLink: "https://www.example.com/feed.xml"}, want: ""},
+		{name: "moved over http", parsed: &gofeed.Feed{FeedLink: "http://feeds.example.org/main"}, want: "https://feeds.example.org/main"},
+		{name: "self moved", parsed: &gofeed.Feed{FeedLink: "https://feeds.example.org/main"}, want: "https://feeds.example.org/main"},
+		{name: "relative self", parsed: &gofeed.Feed{FeedLink: "/rss"}, want: "https://example.com/rss"},
+		{
+			name: "itunes wins over self",
+			parsed: &gofeed.Feed{
+				FeedLink:  "https://example.com/feed.xml",
+				ITunesExt: &ext.ITunesFeedExtension{NewFeedURL: "https://podcasts.example.net/show"},
+			},
+			want: "https://podcasts.example.net/show",
+		},
+		{name: "unsupported scheme", parsed: &gofeed.Feed{FeedLink: "ftp://example.com/feed"}, want: ""},
+	}
+
+	for _, tc := range cases {
+		if got := Relocation(tc.parsed, current); got != tc.want {
+			t.Fatalf("%s: Relocation = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSameFeed(t *testing.T) {
+	a := &gofeed.Feed{Title: "Blog", Items: []*gofeed.Item{{GUID: "1"}, {Link: "https://example.com/b"}}}
+	if !SameFeed(a, &gofeed.Feed{Items: []*gofeed.Item{{GUID: "1"}}}) {
+		t.Fatalf("expected shared GUID to match")
+	}
+	if !SameFeed(a, &gofeed.Feed{Items: []*gofeed.Item{{Link: "https://example.com/b"}}}) {
+		t.Fatalf("expected shared link to match")
+	}
+	if SameFeed(a, &gofeed.Feed{Title: "Blog", Items: []*gofeed.Item{{GUID: "2"}}}) {
+		t.Fatalf("expected disjoint items not to match")
+	}
+	if !SameFeed(&gofeed.Feed{Title: "Blog", Link: "https://example.com"}, &gofeed.Feed{Title: "Blog", Link: "https://example.com"}) {
+		t.Fatalf("expected empty feeds with the same title and link to match")
+	}
+	if SameFeed(&gofeed.Feed{}, &gofeed.Feed{}) {
+		t.Fatalf("expected untitled empty feeds not to match")
+	}
+}
@@ -0,0 +1,86 @@
+package feed
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Summary describes a parsed feed for a subscriber deciding whether to add
+// it.
+type Summary struct {
+	Title       string
+	Description string
+	FeedType    string
+	ItemCount   int
+	// NewestPublished and NewestUpdated are the latest item dates, or zero
+	// when no item carries one.
+	NewestPublished time.Time
+	NewestUpdated   time.Time
+	// Warnings lists problems that do not stop the feed from being
+	// crawled but will degrade what it produces.
+	Warnings []string
+}
+
+// Summarize inspects parsed and reports its metadata, newest item dates and
+// any quality warnings. Dates later than now are reported as warnings rather
+// than as the newest dates.
+func Summarize(parsed *gofeed.Feed, now time.Time) Summary {
+	if parsed == nil {
+		return Summary{}
+	}
+
+	s := Summary{
+		Title:       strings.TrimSpace(parsed.Title),
+		Description: strings.TrimSpace(parsed.Description),
+		FeedType:    parsed.FeedType,
+		ItemCount:   len(parsed.Items),
+	}
+	if s.Title == "" {
+		s.Warnings = append(s.Warnings, "feed has no title")
+	}
+	if s.ItemCount == 0 {
+		s.Warnings = append(s.Warnings, "feed has no items")
+		return s
+	}
+
+	var noLink, noDate, future int
+	for _, it := range parsed.Items {
+		if it == nil {
+			continue
+		}
+		if strings.TrimSpace(it.Link) == "" && len(it.Links) == 0 {
+			noLink++
+		}
+		if it.PublishedParsed == nil && it.UpdatedParsed == nil {
+			noDate++
+		}
+		if it.PublishedParsed != nil {
+			if it.PublishedParsed.After(now) {
+				future++
+			} else if it.PublishedParsed.After(s.NewestPublished) {
+				s.NewestPublished = it.PublishedParsed.UTC()
+			}
+		}
+		if it.UpdatedParsed != nil && !it.UpdatedParsed.After(now) && it.UpdatedParsed.After(s.NewestUpdated) {
+			s.NewestUpdated = it.UpdatedParsed.UTC()
+		}
+	}
+
+	if noLink > 0 {
+		s.Warnings = append(s.Warnings, countWarning(noLink, s.ItemCount, "have no link"))
+	}
+	if noDate > 0 {
+		s.Warnings = append(s.Warnings, countWarning(noDate, s.ItemCount, "have no publication date"))
+	}
+	if future > 0 {
+		s.Warnings = append(s.Warnings, countWarning(future, s.ItemCount, "are dated in the future"))
+	}
+	return s
+}
+
+func countWarning(n, total int, problem string) string {
+	return fmt.Sprintf("%d of %d items %s", n, total, problem)
+}
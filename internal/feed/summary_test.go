@@ -0,0 +1,60 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestSummarize(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	parsed := &gofeed.Feed{
+		Title:       " Example ",
+		Description: "Posts about things",
+		FeedType:    "rss",
+		Items: []*gofeed.Item{
+			{Link: "https://example.com/a", PublishedParsed: at(-2 * time.Hour), UpdatedParsed: at(-time.Hour)},
+			{Link: "https://example.com/b", PublishedParsed: at(-time.Hour)},
+			{Link: "https://example.com/c", PublishedParsed: at(48 * time.Hour)},
+			{Title: "no link or date"},
+		},
+	}
+
+	s := Summarize(parsed, now)
+	if s.Title != "Example" || s.Description != "Posts about things" || s.FeedType != "rss" {
+		t.Fatalf("unexpected metadata: %+v", s)
+	}
+	if s.ItemCount != 4 {
+		t.Fatalf("expected 4 items, got %d", s.ItemCount)
+	}
+	if !s.NewestPublished.Equal(*at(-time.Hour)) {
+		t.Fatalf("expected newest published %s, got %s", *at(-time.Hour), s.NewestPublished)
+	}
+	if !s.NewestUpdated.Equal(*at(-time.Hour)) {
+		t.Fatalf("expected newest updated %s, got %s", *at(-time.Hour), s.NewestUpdated)
+	}
+	want := []string{
+		"1 of 4 items have no link",
+		"1 of 4 items have no publication date",
+		"1 of 4 items are dated in the future",
+	}
+	if len(s.Warnings) != len(want) {
+		t.Fatalf("expected warnings %q, got %q", want, s.Warnings)
+	}
+	for i := range want {
+		if s.Warnings[i] != want[i] {
+			t.Fatalf("warning %d: expected %q, got %q", i, want[i], s.Warnings[i])
+		}
+	}
+
+	empty := Summarize(&gofeed.Feed{}, now)
+	if len(empty.Warnings) != 2 || empty.Warnings[0] != "feed has no title" || empty.Warnings[1] != "feed has no items" {
+		t.Fatalf("unexpected warnings for empty feed: %q", empty.Warnings)
+	}
+}
@@ -0,0 +1,486 @@
+package httpx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/auth"
+	"courier/internal/store"
+)
+
+// authStore holds accounts, sessions and API keys.
+type authStore interface {
+	CreateUser(context.Context, store.CreateUserParams) (store.User, error)
+	GetUserByUsername(context.Context, string) (store.User, error)
+	CreateSession(context.Context, string, []byte, time.Time) error
+	DeleteSession(context.Context, []byte) error
+	GetSessionUser(context.Context, []byte) (store.User, error)
+	CreateAPIKey(context.Context, store.CreateAPIKeyParams) (store.APIKey, error)
+	ListAPIKeys(context.Context, string) ([]store.APIKey, error)
+	DeleteAPIKey(context.Context, string, string) error
+	UseAPIKey(context.Context, []byte) (store.APIKey, error)
+	SetFeverKey(context.Context, string, []byte) error
+	DeleteFeverKey(context.Context, string) error
+	GetFeverKeyUser(context.Context, []byte) (store.User, error)
+}
+
+const (
+	sessionCookie     = "courier_session"
+	principalKey      = "principal"
+	maxUsernameLength = 64
+	maxKeyNameLength  = 100
+)
+
+// publicRoutes are reachable without credentials, keyed by method and route
+// path.
+var publicRoutes = map[string]bool{
+	http.MethodGet + " /healthz":               true,
+	http.MethodPost + " /auth/login":           true,
+	http.MethodPost + " /accounts/ClientLogin": true,
+	http.MethodPost + " /fever/":               true,
+}
+
+// authenticate rejects requests without a valid session or API key and
+// checks that the credentials carry the scope the route needs. The
+// principal is stored on the context for handlers.
+func authenticate(cfg Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if publicRoutes[c.Request().Method+" "+c.Path()] {
+				return next(c)
+			}
+
+			token := requestToken(c)
+			if token == "" {
+				return unauthorized(c, "authentication required")
+			}
+
+			ctx := c.Request().Context()
+			hash := auth.HashToken(token)
+			var p auth.Principal
+			switch auth.TokenKind(token) {
+			case auth.SessionPrefix:
+				user, err := cfg.Auth.GetSessionUser(ctx, hash)
+				if errors.Is(err, sql.ErrNoRows) {
+					return unauthorized(c, "invalid or expired credentials")
+				}
+				if err != nil {
+					return err
+				}
+				p = auth.Principal{
+					UserID:      user.ID,
+					Username:    user.Username,
+					Admin:       user.Admin,
+					Scopes:      auth.UserScopes(user.Admin),
+					SessionHash: hash,
+				}
+			case auth.KeyPrefix:
+				key, err := cfg.Auth.UseAPIKey(ctx, hash)
+				if errors.Is(err, sql.ErrNoRows) {
+					return unauthorized(c, "invalid or expired credentials")
+				}
+				if err != nil {
+					return err
+				}
+				// Keys never outrank their owner, so demoting a user
+				// also narrows their keys.
+				var scopes []string
+				for _, scope := range key.Scopes {
+					if auth.HasScope(auth.UserScopes(key.Admin), scope) {
+						scopes = append(scopes, scope)
+					}
+				}
+				p = auth.Principal{
+					UserID:   key.UserID,
+					Username: key.Username,
+					Admin:    key.Admin,
+					Scopes:   scopes,
+					APIKeyID: key.ID,
+				}
+			default:
+				return unauthorized(c, "invalid or expired credentials")
+			}
+
+			if scope := requiredScope(c); !p.Has(scope) {
+				return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("missing %s scope", scope))
+			}
+			c.Set(principalKey, p)
+			return next(c)
+		}
+	}
+}
+
+// requiredScope maps a request to the scope it needs: admin for operator
+// and account endpoints, read for other reads and write for everything
+// else.
+func requiredScope(c echo.Context) string {
+	path := c.Path()
+	switch {
+	case path == "/metrics" || path == "/config" || strings.HasPrefix(path, "/users"):
+		return auth.ScopeAdmin
+	case path == readerPrefix+"/stream/items/contents":
+		// Reader clients post item IDs to fetch them.
+		return auth.ScopeRead
+	case c.Request().Method == http.MethodGet || c.Request().Method == http.MethodHead:
+		return auth.ScopeRead
+	default:
+		return auth.ScopeWrite
+	}
+}
+
+// requestToken reads a bearer token from the Authorization header, falling
+// back to the session cookie set by POST /auth/login. Google Reader clients
+// send the token as "GoogleLogin auth=<token>".
+func requestToken(c echo.Context) string {
+	if header := c.Request().Header.Get(echo.HeaderAuthorization); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok {
+			return ""
+		}
+		switch {
+		case strings.EqualFold(scheme, "Bearer"):
+			return strings.TrimSpace(token)
+		case strings.EqualFold(scheme, "GoogleLogin"):
+			token, ok = strings.CutPrefix(strings.TrimSpace(token), "auth=")
+			if !ok {
+				return ""
+			}
+			return token
+		default:
+			return ""
+		}
+	}
+	if cookie, err := c.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+func unauthorized(c echo.Context, message string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="courier"`)
+	return echo.NewHTTPError(http.StatusUnauthorized, message)
+}
+
+func principal(c echo.Context) auth.Principal {
+	p, _ := c.Get(principalKey).(auth.Principal)
+	return p
+}
+
+// currentUserID returns the ID of the signed-in user, or "" when the API is
+// open.
+func currentUserID(c echo.Context) string {
+	return principal(c).UserID
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// checkMissingUserPassword spends as long on unknown usernames as on wrong
+// passwords so logins do not reveal which accounts exist.
+func checkMissingUserPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = auth.HashPassword("courier-missing-user")
+	})
+	auth.CheckPassword(dummyHash, password)
+}
+
+var errBadCredentials = errors.New("invalid username or password")
+
+// signIn checks a username and password and opens a session for the user.
+// It returns errBadCredentials when either is wrong.
+func signIn(ctx context.Context, cfg Config, username, password string) (user store.User, token string, expiresAt time.Time, err error) {
+	user, err = cfg.Auth.GetUserByUsername(ctx, strings.TrimSpace(username))
+	if errors.Is(err, sql.ErrNoRows) {
+		checkMissingUserPassword(password)
+		return store.User{}, "", time.Time{}, errBadCredentials
+	}
+	if err != nil {
+		return store.User{}, "", time.Time{}, err
+	}
+	if !auth.CheckPassword(user.PasswordHash, password) {
+		return store.User{}, "", time.Time{}, errBadCredentials
+	}
+
+	token, hash, err := auth.NewToken(auth.SessionPrefix)
+	if err != nil {
+		return store.User{}, "", time.Time{}, err
+	}
+	ttl := cfg.SessionTTL
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	expiresAt = time.Now().UTC().Add(ttl)
+	if err := cfg.Auth.CreateSession(ctx, user.ID, hash, expiresAt); err != nil {
+		return store.User{}, "", time.Time{}, err
+	}
+	return user, token, expiresAt, nil
+}
+
+func registerAuthRoutes(e *echo.Echo, cfg Config) {
+	type loginReq struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	e.POST("/auth/login", func(c echo.Context) error {
+		var req loginReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		user, token, expiresAt, err := signIn(c.Request().Context(), cfg, req.Username, req.Password)
+		if errors.Is(err, errBadCredentials) {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		c.SetCookie(&http.Cookie{
+			Name:     sessionCookie,
+			Value:    token,
+			Path:     "/",
+			Expires:  expiresAt,
+			HttpOnly: true,
+			Secure:   c.IsTLS(),
+			SameSite: http.SameSiteLaxMode,
+		})
+		return c.JSON(http.StatusOK, sessionView{
+			Token:     token,
+			ExpiresAt: expiresAt,
+			User:      mapUser(user),
+		})
+	})
+
+	e.POST("/auth/logout", func(c echo.Context) error {
+		if p := principal(c); p.SessionHash != nil {
+			if err := cfg.Auth.DeleteSession(c.Request().Context(), p.SessionHash); err != nil {
+				return err
+			}
+		}
+		c.SetCookie(&http.Cookie{
+			Name:     sessionCookie,
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   c.IsTLS(),
+			SameSite: http.SameSiteLaxMode,
+		})
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.GET("/auth/me", func(c echo.Context) error {
+		p := principal(c)
+		view := principalView{
+			ID:       p.UserID,
+			Username: p.Username,
+			Admin:    p.Admin,
+			Scopes:   p.Scopes,
+		}
+		if p.APIKeyID != "" {
+			view.APIKeyID = &p.APIKeyID
+		}
+		return c.JSON(http.StatusOK, view)
+	})
+
+	e.GET("/auth/keys", func(c echo.Context) error {
+		keys, err := cfg.Auth.ListAPIKeys(c.Request().Context(), principal(c).UserID)
+		if err != nil {
+			return err
+		}
+		views := make([]apiKeyView, 0, len(keys))
+		for _, k := range keys {
+			views = append(views, mapAPIKey(k))
+		}
+		return c.JSON(http.StatusOK, views)
+	})
+
+	type createKeyReq struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+
+	e.POST("/auth/keys", func(c echo.Context) error {
+		var req createKeyReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > maxKeyNameLength {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("name must be between 1 and %d bytes", maxKeyNameLength))
+		}
+		p := principal(c)
+		scopes := []string{auth.ScopeRead}
+		if len(req.Scopes) > 0 {
+			scopes = scopes[:0]
+			for _, scope := range req.Scopes {
+				if !auth.ValidScope(scope) {
+					return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown scope %q", scope))
+				}
+				if !p.Has(scope) {
+					return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("cannot grant %s scope", scope))
+				}
+				if !auth.HasScope(scopes, scope) {
+					scopes = append(scopes, scope)
+				}
+			}
+		}
+
+		token, hash, err := auth.NewToken(auth.KeyPrefix)
+		if err != nil {
+			return err
+		}
+		key, err := cfg.Auth.CreateAPIKey(c.Request().Context(), store.CreateAPIKeyParams{
+			UserID:    p.UserID,
+			Name:      name,
+			TokenHash: hash,
+			Scopes:    scopes,
+		})
+		if err != nil {
+			return err
+		}
+		view := mapAPIKey(key)
+		view.Token = token
+		return c.JSON(http.StatusCreated, view)
+	})
+
+	e.DELETE("/auth/keys/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid key id")
+		}
+		if err := cfg.Auth.DeleteAPIKey(c.Request().Context(), principal(c).UserID, id); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	type feverReq struct {
+		Password string `json:"password"`
+	}
+
+	// Fever clients sign in with a password of their own, kept apart from
+	// the account password because Fever keys are plain MD5.
+	e.PUT("/auth/fever", func(c echo.Context) error {
+		var req feverReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		if len(req.Password) < auth.MinPasswordLength {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("password must be at least %d characters", auth.MinPasswordLength))
+		}
+		ctx := c.Request().Context()
+		p := principal(c)
+		user, err := cfg.Auth.GetUserByUsername(ctx, p.Username)
+		if err != nil {
+			return err
+		}
+		if auth.CheckPassword(user.PasswordHash, req.Password) {
+			return echo.NewHTTPError(http.StatusBadRequest, "the Fever password must differ from the account password")
+		}
+		key := auth.FeverKey(user.Username, req.Password)
+		if err := cfg.Auth.SetFeverKey(ctx, user.ID, auth.HashToken(key)); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, map[string]string{"username": user.Username})
+	})
+
+	e.DELETE("/auth/fever", func(c echo.Context) error {
+		if err := cfg.Auth.DeleteFeverKey(c.Request().Context(), principal(c).UserID); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	type createUserReq struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Admin    bool   `json:"admin"`
+	}
+
+	e.POST("/users", func(c echo.Context) error {
+		var req createUserReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		user, err := CreateUser(c.Request().Context(), cfg.Auth, req.Username, req.Password, req.Admin)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, mapUser(user))
+	})
+}
+
+// CreateUser validates a new account's credentials and stores it with a
+// hashed password.
+func CreateUser(ctx context.Context, users authStore, username, password string, admin bool) (store.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || len(username) > maxUsernameLength || strings.ContainsAny(username, " \t\r\n") {
+		return store.User{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("username must be 1 to %d bytes without spaces", maxUsernameLength))
+	}
+	hash, err := auth.HashPassword(password)
+	if errors.Is(err, auth.ErrPasswordTooShort) {
+		return store.User{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("password must be at least %d characters", auth.MinPasswordLength))
+	}
+	if err != nil {
+		return store.User{}, err
+	}
+	return users.CreateUser(ctx, store.CreateUserParams{
+		Username:     username,
+		PasswordHash: hash,
+		Admin:        admin,
+	})
+}
+
+type userView struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Admin     bool      `json:"admin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func mapUser(u store.User) userView {
+	return userView{ID: u.ID, Username: u.Username, Admin: u.Admin, CreatedAt: u.CreatedAt}
+}
+
+type sessionView struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      userView  `json:"user"`
+}
+
+type principalView struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Admin    bool     `json:"admin"`
+	Scopes   []string `json:"scopes"`
+	APIKeyID *string  `json:"api_key_id,omitempty"`
+}
+
+type apiKeyView struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// Token is only returned when the key is created.
+	Token string `json:"token,omitempty"`
+}
+
+func mapAPIKey(k store.APIKey) apiKeyView {
+	view := apiKeyView{ID: k.ID, Name: k.Name, Scopes: k.Scopes, CreatedAt: k.CreatedAt}
+	if k.LastUsedAt.Valid {
+		t := k.LastUsedAt.Time.UTC()
+		view.LastUsedAt = &t
+	}
+	return view
+}
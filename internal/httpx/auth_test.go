@@ -0,0 +1,345 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/auth"
+	"courier/internal/store"
+)
+
+type stubAuth struct {
+	mu        sync.Mutex
+	users     []store.User
+	sessions  map[string]string
+	keys      []stubKey
+	feverKeys map[string]string
+}
+
+type stubKey struct {
+	key  store.APIKey
+	hash []byte
+}
+
+func (s *stubAuth) CreateUser(ctx context.Context, params store.CreateUserParams) (store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if strings.EqualFold(u.Username, params.Username) {
+			return store.User{}, store.ErrUserExists
+		}
+	}
+	user := store.User{
+		ID:           "00000000-0000-4000-8000-00000000000" + string(rune('1'+len(s.users))),
+		Username:     params.Username,
+		PasswordHash: params.PasswordHash,
+		Admin:        params.Admin,
+	}
+	s.users = append(s.users, user)
+	return user, nil
+}
+
+func (s *stubAuth) GetUserByUsername(ctx context.Context, username string) (store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if strings.EqualFold(u.Username, username) {
+			return u, nil
+		}
+	}
+	return store.User{}, sql.ErrNoRows
+}
+
+func (s *stubAuth) CreateSession(ctx context.Context, userID string, hash []byte, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = map[string]string{}
+	}
+	s.sessions[string(hash)] = userID
+	return nil
+}
+
+func (s *stubAuth) DeleteSession(ctx context.Context, hash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, string(hash))
+	return nil
+}
+
+func (s *stubAuth) GetSessionUser(ctx context.Context, hash []byte) (store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.sessions[string(hash)]
+	if !ok {
+		return store.User{}, sql.ErrNoRows
+	}
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return store.User{}, sql.ErrNoRows
+}
+
+func (s *stubAuth) CreateAPIKey(ctx context.Context, params store.CreateAPIKeyParams) (store.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := store.APIKey{
+		ID:     "10000000-0000-4000-8000-00000000000" + string(rune('1'+len(s.keys))),
+		UserID: params.UserID,
+		Name:   params.Name,
+		Scopes: params.Scopes,
+	}
+	s.keys = append(s.keys, stubKey{key: key, hash: params.TokenHash})
+	return key, nil
+}
+
+func (s *stubAuth) ListAPIKeys(ctx context.Context, userID string) ([]store.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []store.APIKey
+	for _, k := range s.keys {
+		if k.key.UserID == userID {
+			keys = append(keys, k.key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *stubAuth) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, k := range s.keys {
+		if k.key.ID == id && k.key.UserID == userID {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (s *stubAuth) UseAPIKey(ctx context.Context, hash []byte) (store.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if bytes.Equal(k.hash, hash) {
+			key := k.key
+			for _, u := range s.users {
+				if u.ID == key.UserID {
+					key.Username = u.Username
+					key.Admin = u.Admin
+				}
+			}
+			return key, nil
+		}
+	}
+	return store.APIKey{}, sql.ErrNoRows
+}
+
+func (s *stubAuth) SetFeverKey(ctx context.Context, userID string, hash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.feverKeys == nil {
+		s.feverKeys = map[string]string{}
+	}
+	for h, id := range s.feverKeys {
+		if id == userID {
+			delete(s.feverKeys, h)
+		}
+	}
+	s.feverKeys[string(hash)] = userID
+	return nil
+}
+
+func (s *stubAuth) DeleteFeverKey(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for h, id := range s.feverKeys {
+		if id == userID {
+			delete(s.feverKeys, h)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (s *stubAuth) GetFeverKeyUser(ctx context.Context, hash []byte) (store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.feverKeys[string(hash)]
+	if !ok {
+		return store.User{}, sql.ErrNoRows
+	}
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return store.User{}, sql.ErrNoRows
+}
+
+func TestAuthRequiresCredentials(t *testing.T) {
+	t.Parallel()
+
+	users := &stubAuth{}
+	if _, err := CreateUser(context.Background(), users, "reader", "correct horse", false); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	srv := NewServer(Config{
+		Store:   &stubStore{},
+		DB:      nil,
+		Service: "test",
+		Auth:    users,
+	})
+	RegisterConfigRoute(srv, RuntimeConfig{Expose: true})
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/feeds", "", "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d without credentials, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec.Header().Get(echo.HeaderWWWAuthenticate) == "" {
+		t.Fatalf("expected a WWW-Authenticate challenge")
+	}
+	if rec := do(http.MethodGet, "/feeds", "cs_unknown", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d for an unknown session, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/auth/login", "", `{"username":"reader","password":"wrong password"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d for a wrong password, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/auth/login", "", `{"username":"nobody","password":"correct horse"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d for an unknown user, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	rec = do(http.MethodPost, "/auth/login", "", `{"username":"Reader","password":"correct horse"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var session sessionView
+	if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(session.Token, auth.SessionPrefix) || session.User.Username != "reader" {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly {
+		t.Fatalf("expected an HttpOnly session cookie, got %+v", cookies)
+	}
+
+	if rec := do(http.MethodGet, "/feeds", session.Token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d with a session, got %d", http.StatusOK, rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/feeds", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d with the session cookie, got %d", http.StatusOK, rec.Code)
+	}
+	if rec := do(http.MethodGet, "/config", session.Token, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for /config without admin, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/users", session.Token, `{"username":"other","password":"correct horse"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for creating users without admin, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	if rec := do(http.MethodPost, "/auth/keys", session.Token, `{"name":"cron","scopes":["admin"]}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d when granting a scope the user lacks, got %d", http.StatusForbidden, rec.Code)
+	}
+	rec = do(http.MethodPost, "/auth/keys", session.Token, `{"name":"cron"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rec.Code)
+	}
+	var key apiKeyView
+	if err := json.Unmarshal(rec.Body.Bytes(), &key); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(key.Token, auth.KeyPrefix) || len(key.Scopes) != 1 || key.Scopes[0] != auth.ScopeRead {
+		t.Fatalf("expected a read-only key with its token, got %+v", key)
+	}
+
+	if rec := do(http.MethodGet, "/items", key.Token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d for a read with a read key, got %d", http.StatusOK, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/tags", key.Token, `{"name":"go"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for a write with a read key, got %d", http.StatusForbidden, rec.Code)
+	}
+	rec = do(http.MethodGet, "/auth/me", key.Token, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"api_key_id":"`+key.ID+`"`) {
+		t.Fatalf("unexpected /auth/me response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := do(http.MethodPost, "/auth/logout", session.Token, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := do(http.MethodGet, "/feeds", session.Token, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d after logout, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestAuthAdminManagesUsers(t *testing.T) {
+	t.Parallel()
+
+	users := &stubAuth{}
+	if _, err := CreateUser(context.Background(), users, "admin", "correct horse", true); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	srv := NewServer(Config{Store: &stubStore{}, Service: "test", Auth: users})
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/auth/login", "", `{"username":"admin","password":"correct horse"}`)
+	var session sessionView
+	if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if rec := do(http.MethodPost, "/users", session.Token, `{"username":"jane","password":"short"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a short password, got %d", http.StatusBadRequest, rec.Code)
+	}
+	rec = do(http.MethodPost, "/users", session.Token, `{"username":"jane","password":"long enough"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/users", session.Token, `{"username":"JANE","password":"long enough"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d for a taken username, got %d", http.StatusConflict, rec.Code)
+	}
+	for _, u := range users.users {
+		if u.Username == "jane" && (u.PasswordHash == "long enough" || !auth.CheckPassword(u.PasswordHash, "long enough")) {
+			t.Fatalf("expected the password to be stored hashed")
+		}
+	}
+}
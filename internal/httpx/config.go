@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+
+	"courier/internal/auth"
+	"courier/internal/netguard"
 )
 
 const (
@@ -21,9 +24,16 @@ const (
 	defaultDBPingTimeout     = 10 * time.Second
 	defaultFetcherInterval   = 2 * time.Minute
 	defaultFetcherBatchSize  = 250
+	defaultFetcherWorkers    = 8
+	defaultCrawlMaxInterval  = 6 * time.Hour
+	defaultHostInterval      = time.Second
+	defaultHostConcurrency   = 2
+	defaultNotFoundLimit     = 5
+	defaultWebhookInterval   = 15 * time.Second
 	defaultBackoffMin        = 30 * time.Second
 	defaultBackoffMax        = 10 * time.Minute
 	defaultBackoffFactor     = 2.0
+	defaultSessionTTL        = 30 * 24 * time.Hour
 )
 
 type RuntimeConfig struct {
@@ -32,6 +42,7 @@ type RuntimeConfig struct {
 	HTTP     HTTPConfig
 	Search   SearchConfig
 	Fetcher  FetcherConfig
+	Auth     AuthConfig
 	Expose   bool
 }
 
@@ -54,9 +65,29 @@ type SearchConfig struct {
 }
 
 type FetcherConfig struct {
-	Interval  time.Duration
-	BatchSize int
-	Backoff   BackoffConfig
+	Interval         time.Duration
+	BatchSize        int
+	Concurrency      int
+	HostInterval     time.Duration
+	HostConcurrency  int
+	CrawlMinInterval time.Duration
+	CrawlMaxInterval time.Duration
+	NotFoundLimit    int
+	WebhookInterval  time.Duration
+	// AllowedNetworks are private networks feeds may still be fetched
+	// from and webhooks delivered to; every other non-public address is
+	// refused.
+	AllowedNetworks netguard.Allowlist
+	Backoff         BackoffConfig
+}
+
+type AuthConfig struct {
+	SessionTTL time.Duration
+	// AdminUser and AdminPassword create the first account when the
+	// database has no users yet. Without AdminPassword a random password
+	// is generated and logged once.
+	AdminUser     string
+	AdminPassword string
 }
 
 type BackoffConfig struct {
@@ -80,14 +111,23 @@ func LoadRuntimeConfig(service string) (RuntimeConfig, error) {
 			ShutdownTimeout: defaultShutdownTimeout,
 		},
 		Fetcher: FetcherConfig{
-			Interval:  defaultFetcherInterval,
-			BatchSize: defaultFetcherBatchSize,
+			Interval:         defaultFetcherInterval,
+			BatchSize:        defaultFetcherBatchSize,
+			Concurrency:      defaultFetcherWorkers,
+			HostInterval:     defaultHostInterval,
+			HostConcurrency:  defaultHostConcurrency,
+			CrawlMaxInterval: defaultCrawlMaxInterval,
+			NotFoundLimit:    defaultNotFoundLimit,
+			WebhookInterval:  defaultWebhookInterval,
 			Backoff: BackoffConfig{
 				Min:    defaultBackoffMin,
 				Max:    defaultBackoffMax,
 				Factor: defaultBackoffFactor,
 			},
 		},
+		Auth: AuthConfig{
+			SessionTTL: defaultSessionTTL,
+		},
 	}
 
 	if v := envString("COURIER_SERVICE_NAME"); v != "" {
@@ -170,6 +210,72 @@ func LoadRuntimeConfig(service string) (RuntimeConfig, error) {
 	}
 	cfg.Fetcher.BatchSize = batchSize
 
+	concurrency, err := intFromEnv("COURIER_FETCH_CONCURRENCY", cfg.Fetcher.Concurrency)
+	if err != nil {
+		return cfg, err
+	}
+	if concurrency <= 0 {
+		return cfg, fmt.Errorf("COURIER_FETCH_CONCURRENCY must be a positive integer")
+	}
+	cfg.Fetcher.Concurrency = concurrency
+
+	hostInterval, err := durationFromEnv("COURIER_HOST_INTERVAL", cfg.Fetcher.HostInterval)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Fetcher.HostInterval = hostInterval
+
+	hostConcurrency, err := intFromEnv("COURIER_HOST_CONCURRENCY", cfg.Fetcher.HostConcurrency)
+	if err != nil {
+		return cfg, err
+	}
+	if hostConcurrency <= 0 {
+		return cfg, fmt.Errorf("COURIER_HOST_CONCURRENCY must be a positive integer")
+	}
+	cfg.Fetcher.HostConcurrency = hostConcurrency
+
+	crawlMin, err := durationFromEnv("COURIER_CRAWL_MIN_INTERVAL", cfg.Fetcher.Interval)
+	if err != nil {
+		return cfg, err
+	}
+	if crawlMin <= 0 {
+		return cfg, fmt.Errorf("COURIER_CRAWL_MIN_INTERVAL must be greater than zero")
+	}
+	cfg.Fetcher.CrawlMinInterval = crawlMin
+
+	crawlMax, err := durationFromEnv("COURIER_CRAWL_MAX_INTERVAL", cfg.Fetcher.CrawlMaxInterval)
+	if err != nil {
+		return cfg, err
+	}
+	if crawlMax < cfg.Fetcher.CrawlMinInterval {
+		return cfg, fmt.Errorf("COURIER_CRAWL_MAX_INTERVAL must be greater than or equal to COURIER_CRAWL_MIN_INTERVAL")
+	}
+	cfg.Fetcher.CrawlMaxInterval = crawlMax
+
+	notFoundLimit, err := intFromEnv("COURIER_NOT_FOUND_LIMIT", cfg.Fetcher.NotFoundLimit)
+	if err != nil {
+		return cfg, err
+	}
+	if notFoundLimit <= 0 {
+		return cfg, fmt.Errorf("COURIER_NOT_FOUND_LIMIT must be a positive integer")
+	}
+	cfg.Fetcher.NotFoundLimit = notFoundLimit
+
+	webhookInterval, err := durationFromEnv("COURIER_WEBHOOK_INTERVAL", cfg.Fetcher.WebhookInterval)
+	if err != nil {
+		return cfg, err
+	}
+	if webhookInterval <= 0 {
+		return cfg, fmt.Errorf("COURIER_WEBHOOK_INTERVAL must be greater than zero")
+	}
+	cfg.Fetcher.WebhookInterval = webhookInterval
+
+	allowedNetworks, err := netguard.ParseAllowlist(envString("COURIER_ALLOWED_NETWORKS"))
+	if err != nil {
+		return cfg, fmt.Errorf("invalid COURIER_ALLOWED_NETWORKS: %w", err)
+	}
+	cfg.Fetcher.AllowedNetworks = allowedNetworks
+
 	backoffMin, err := durationFromEnv("COURIER_BACKOFF_MIN", cfg.Fetcher.Backoff.Min)
 	if err != nil {
 		return cfg, err
@@ -200,6 +306,21 @@ func LoadRuntimeConfig(service string) (RuntimeConfig, error) {
 	}
 	cfg.Fetcher.Backoff.Factor = backoffFactor
 
+	sessionTTL, err := durationFromEnv("COURIER_SESSION_TTL", cfg.Auth.SessionTTL)
+	if err != nil {
+		return cfg, err
+	}
+	if sessionTTL <= 0 {
+		return cfg, fmt.Errorf("COURIER_SESSION_TTL must be greater than zero")
+	}
+	cfg.Auth.SessionTTL = sessionTTL
+
+	cfg.Auth.AdminUser = envString("COURIER_ADMIN_USER")
+	cfg.Auth.AdminPassword = os.Getenv("COURIER_ADMIN_PASSWORD")
+	if cfg.Auth.AdminPassword != "" && len(cfg.Auth.AdminPassword) < auth.MinPasswordLength {
+		return cfg, fmt.Errorf("COURIER_ADMIN_PASSWORD must be at least %d characters", auth.MinPasswordLength)
+	}
+
 	if v := envString("COURIER_EXPOSE_CONFIG"); v != "" {
 		expose, err := strconv.ParseBool(v)
 		if err != nil {
@@ -217,6 +338,7 @@ type RuntimeConfigSnapshot struct {
 	Database DatabaseSnapshot `json:"database"`
 	Search   SearchSnapshot   `json:"search"`
 	Fetcher  FetcherSnapshot  `json:"fetcher"`
+	Auth     AuthSnapshot     `json:"auth"`
 }
 
 type HTTPSnapshot struct {
@@ -238,9 +360,22 @@ type SearchSnapshot struct {
 }
 
 type FetcherSnapshot struct {
-	Interval  string          `json:"interval"`
-	BatchSize int             `json:"batch_size"`
-	Backoff   BackoffSnapshot `json:"backoff"`
+	Interval         string          `json:"interval"`
+	BatchSize        int             `json:"batch_size"`
+	Concurrency      int             `json:"concurrency"`
+	HostInterval     string          `json:"host_interval"`
+	HostConcurrency  int             `json:"host_concurrency"`
+	CrawlMinInterval string          `json:"crawl_min_interval"`
+	CrawlMaxInterval string          `json:"crawl_max_interval"`
+	NotFoundLimit    int             `json:"not_found_limit"`
+	WebhookInterval  string          `json:"webhook_interval"`
+	AllowedNetworks  []string        `json:"allowed_networks"`
+	Backoff          BackoffSnapshot `json:"backoff"`
+}
+
+type AuthSnapshot struct {
+	SessionTTL string `json:"session_ttl"`
+	AdminUser  string `json:"admin_user,omitempty"`
 }
 
 type BackoffSnapshot struct {
@@ -268,14 +403,26 @@ func (cfg RuntimeConfig) Snapshot() RuntimeConfigSnapshot {
 			URL: cfg.Search.URL,
 		},
 		Fetcher: FetcherSnapshot{
-			Interval:  cfg.Fetcher.Interval.String(),
-			BatchSize: cfg.Fetcher.BatchSize,
+			Interval:         cfg.Fetcher.Interval.String(),
+			BatchSize:        cfg.Fetcher.BatchSize,
+			Concurrency:      cfg.Fetcher.Concurrency,
+			HostInterval:     cfg.Fetcher.HostInterval.String(),
+			HostConcurrency:  cfg.Fetcher.HostConcurrency,
+			CrawlMinInterval: cfg.Fetcher.CrawlMinInterval.String(),
+			CrawlMaxInterval: cfg.Fetcher.CrawlMaxInterval.String(),
+			NotFoundLimit:    cfg.Fetcher.NotFoundLimit,
+			WebhookInterval:  cfg.Fetcher.WebhookInterval.String(),
+			AllowedNetworks:  cfg.Fetcher.AllowedNetworks.Strings(),
 			Backoff: BackoffSnapshot{
 				Min:    cfg.Fetcher.Backoff.Min.String(),
 				Max:    cfg.Fetcher.Backoff.Max.String(),
 				Factor: cfg.Fetcher.Backoff.Factor,
 			},
 		},
+		Auth: AuthSnapshot{
+			SessionTTL: cfg.Auth.SessionTTL.String(),
+			AdminUser:  cfg.Auth.AdminUser,
+		},
 	}
 }
 
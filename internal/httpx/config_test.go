@@ -2,6 +2,7 @@ package httpx
 
 import (
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -42,3 +43,22 @@ func TestRuntimeConfigSnapshotSanitizesDSN(t *testing.T) {
 		t.Fatalf("expected non-sensitive query parameter to remain, got %q", got)
 	}
 }
+
+func TestLoadRuntimeConfigRejectsShortAdminPassword(t *testing.T) {
+	t.Setenv("COURIER_DSN", "postgres://localhost/courier")
+	t.Setenv("MEILI_URL", "http://localhost:7700")
+	t.Setenv("COURIER_ADMIN_USER", "admin")
+	t.Setenv("COURIER_ADMIN_PASSWORD", "admin")
+	if _, err := LoadRuntimeConfig("test"); err == nil || !strings.Contains(err.Error(), "COURIER_ADMIN_PASSWORD") {
+		t.Fatalf("expected a short admin password to be rejected, got %v", err)
+	}
+
+	t.Setenv("COURIER_ADMIN_PASSWORD", "")
+	cfg, err := LoadRuntimeConfig("test")
+	if err != nil {
+		t.Fatalf("expected a missing admin password to be generated later, got %v", err)
+	}
+	if cfg.Auth.AdminUser != "admin" || cfg.Auth.AdminPassword != "" {
+		t.Fatalf("unexpected auth config: %+v", cfg.Auth)
+	}
+}
@@ -53,6 +53,12 @@ func HTTPErrorHandler(service string) echo.HTTPErrorHandler {
 		case errors.Is(originalErr, store.ErrFeedExists):
 			status = http.StatusConflict
 			message = store.ErrFeedExists.Error()
+		case errors.Is(originalErr, store.ErrTagExists):
+			status = http.StatusConflict
+			message = store.ErrTagExists.Error()
+		case errors.Is(originalErr, store.ErrUserExists):
+			status = http.StatusConflict
+			message = store.ErrUserExists.Error()
 		case errors.Is(originalErr, sql.ErrNoRows):
 			status = http.StatusNotFound
 			message = "resource not found"
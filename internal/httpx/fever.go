@@ -0,0 +1,345 @@
+package httpx
+
+import (
+	"database/sql"
+	"errors"
+	"hash/crc32"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/auth"
+	"courier/internal/store"
+)
+
+// The Fever API serves every call from POST /fever/?api, picking the data
+// to return from the query parameters present. Feeds are numbered by
+// subscription, groups by a checksum of the category name and items by
+// their sequence number.
+const (
+	feverAPIVersion = 3
+	feverPageSize   = 50
+
+	// Courier stores no favicons, so every feed points at one blank icon.
+	feverFaviconID   = 1
+	feverFaviconData = "image/gif;base64,R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7"
+)
+
+func registerFeverRoutes(e *echo.Echo, cfg Config) {
+	e.POST("/fever/", func(c echo.Context) error {
+		resp := map[string]any{"api_version": feverAPIVersion, "auth": 0}
+		key := strings.ToLower(strings.TrimSpace(c.FormValue("api_key")))
+		if key == "" {
+			return c.JSON(http.StatusOK, resp)
+		}
+		ctx := c.Request().Context()
+		user, err := cfg.Auth.GetFeverKeyUser(ctx, auth.HashToken(key))
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusOK, resp)
+		}
+		if err != nil {
+			return err
+		}
+		resp["auth"] = 1
+
+		feeds, err := userFeeds(ctx, cfg, user.ID)
+		if err != nil {
+			return err
+		}
+		var refreshed int64
+		for _, f := range feeds {
+			if f.LastCrawled.Valid && f.LastCrawled.Time.Unix() > refreshed {
+				refreshed = f.LastCrawled.Time.Unix()
+			}
+		}
+		resp["last_refreshed_on_time"] = refreshed
+
+		params := formValues(c)
+		has := func(name string) bool {
+			_, ok := params[name]
+			return ok
+		}
+
+		if has("mark") {
+			if err := feverMark(c, cfg, user.ID, feeds); err != nil {
+				return err
+			}
+		}
+		if has("groups") || has("feeds") {
+			resp["feeds_groups"] = feverFeedsGroups(feeds)
+		}
+		if has("groups") {
+			groups := []feverGroupView{}
+			for _, category := range feedCategories(feeds) {
+				groups = append(groups, feverGroupView{ID: feverGroupID(category), Title: category})
+			}
+			resp["groups"] = groups
+		}
+		if has("feeds") {
+			views := make([]feverFeedView, 0, len(feeds))
+			for _, f := range feeds {
+				view := feverFeedView{
+					ID:        f.Seq,
+					FaviconID: feverFaviconID,
+					Title:     f.Title,
+					URL:       f.URL,
+					SiteURL:   siteURL(f.URL),
+				}
+				if f.LastCrawled.Valid {
+					view.LastUpdatedOnTime = f.LastCrawled.Time.Unix()
+				}
+				views = append(views, view)
+			}
+			resp["feeds"] = views
+		}
+		if has("favicons") {
+			resp["favicons"] = []feverFaviconView{{ID: feverFaviconID, Data: feverFaviconData}}
+		}
+		if has("items") {
+			arg := store.ListItemsBySeqParams{UserID: user.ID, Limit: feverPageSize}
+			switch {
+			case c.FormValue("with_ids") != "":
+				seqs, err := parseFeverIDs(c.FormValue("with_ids"))
+				if err != nil {
+					return err
+				}
+				if len(seqs) > feverPageSize {
+					seqs = seqs[:feverPageSize]
+				}
+				arg.Seqs = seqs
+			case c.FormValue("max_id") != "":
+				maxID, err := strconv.ParseInt(c.FormValue("max_id"), 10, 64)
+				if err != nil || maxID < 0 {
+					return echo.NewHTTPError(http.StatusBadRequest, "max_id must be an item id")
+				}
+				// max_id=0 asks for the newest items.
+				arg.MaxSeq = maxID
+				if maxID == 0 {
+					arg.MaxSeq = math.MaxInt64
+				}
+			default:
+				// Without a bound Fever starts from the oldest item.
+				since, err := strconv.ParseInt(c.FormValue("since_id"), 10, 64)
+				if err == nil && since > 0 {
+					arg.SinceSeq = since
+				}
+			}
+			items, err := cfg.Store.ListItemsBySeq(ctx, arg)
+			if err != nil {
+				return err
+			}
+			total, err := cfg.Store.CountUserItems(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			resp["items"] = mapFeverItems(items, feeds)
+			resp["total_items"] = total
+		}
+		if has("links") {
+			resp["links"] = []any{}
+		}
+		if has("unread_item_ids") || c.FormValue("mark") == "item" {
+			seqs, err := cfg.Store.ListUnreadItemSeqs(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			resp["unread_item_ids"] = joinFeverIDs(seqs)
+		}
+		if has("saved_item_ids") || c.FormValue("mark") == "item" {
+			seqs, err := cfg.Store.ListStarredItemSeqs(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			resp["saved_item_ids"] = joinFeverIDs(seqs)
+		}
+		return c.JSON(http.StatusOK, resp)
+	})
+}
+
+// feverMark applies a mark=item|feed|group request. Items can be marked
+// read, unread, saved or unsaved; feeds and groups only read, up to the
+// before timestamp. Group 0 stands for every feed.
+func feverMark(c echo.Context, cfg Config, userID string, feeds []store.Feed) error {
+	ctx := c.Request().Context()
+	id, err := strconv.ParseInt(c.FormValue("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "id must be a number")
+	}
+	as := c.FormValue("as")
+
+	switch c.FormValue("mark") {
+	case "item":
+		update := store.UpdateItemStateParams{UserID: userID}
+		yes, no := true, false
+		switch as {
+		case "read":
+			update.Read = &yes
+		case "unread":
+			update.Read = &no
+		case "saved":
+			update.Starred = &yes
+		case "unsaved":
+			update.Starred = &no
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "as must be read, unread, saved or unsaved")
+		}
+		items, err := cfg.Store.ListItemsBySeq(ctx, store.ListItemsBySeqParams{UserID: userID, Seqs: []int64{id}, Limit: 1})
+		if err != nil {
+			return err
+		}
+		for _, it := range items {
+			update.ID = it.ID
+			if _, err := cfg.Store.UpdateItemState(ctx, update); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "feed", "group":
+		if as != "read" {
+			return echo.NewHTTPError(http.StatusBadRequest, "feeds and groups can only be marked read")
+		}
+		before := time.Now().UTC()
+		if raw := c.FormValue("before"); raw != "" {
+			sec, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || sec < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "before must be a unix timestamp")
+			}
+			before = time.Unix(sec, 0).UTC()
+		}
+		group := c.FormValue("mark") == "group"
+		for _, f := range feeds {
+			switch {
+			case !group && f.Seq != id:
+				continue
+			case group && id != 0 && (!f.Category.Valid || feverGroupID(f.Category.String) != id):
+				continue
+			}
+			if _, err := cfg.Store.MarkFeedRead(ctx, userID, f.ID, before); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "mark must be item, feed or group")
+	}
+}
+
+// feverGroupID numbers a category. Fever wants integers and categories are
+// plain names, so the ID is a checksum that stays put while the name does.
+func feverGroupID(category string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte(category)) & 0x7fffffff)
+}
+
+func feverFeedsGroups(feeds []store.Feed) []feverFeedsGroupView {
+	members := map[string][]int64{}
+	for _, f := range feeds {
+		if f.Category.Valid {
+			members[f.Category.String] = append(members[f.Category.String], f.Seq)
+		}
+	}
+	views := []feverFeedsGroupView{}
+	for _, category := range feedCategories(feeds) {
+		views = append(views, feverFeedsGroupView{GroupID: feverGroupID(category), FeedIDs: joinFeverIDs(members[category])})
+	}
+	return views
+}
+
+func parseFeverIDs(raw string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "with_ids must be comma-separated item ids")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func joinFeverIDs(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+type feverGroupView struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroupView struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeedView struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	SiteURL           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverFaviconView struct {
+	ID   int64  `json:"id"`
+	Data string `json:"data"`
+}
+
+type feverItemView struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	URL           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+func mapFeverItems(items []store.Item, feeds []store.Feed) []feverItemView {
+	seqs := make(map[string]int64, len(feeds))
+	for _, f := range feeds {
+		seqs[f.ID] = f.Seq
+	}
+	views := make([]feverItemView, 0, len(items))
+	for _, it := range items {
+		created := it.RetrievedAt
+		if it.PublishedAt.Valid {
+			created = it.PublishedAt.Time
+		}
+		html := it.ContentHTML
+		if html == "" {
+			html = it.ContentText
+		}
+		view := feverItemView{
+			ID:            it.Seq,
+			FeedID:        seqs[it.FeedID],
+			Title:         it.Title,
+			Author:        it.Author.String,
+			HTML:          html,
+			URL:           it.URL,
+			CreatedOnTime: created.Unix(),
+		}
+		if it.Read {
+			view.IsRead = 1
+		}
+		if it.Starred {
+			view.IsSaved = 1
+		}
+		views = append(views, view)
+	}
+	return views
+}
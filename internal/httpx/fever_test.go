@@ -0,0 +1,172 @@
+package httpx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/auth"
+	"courier/internal/store"
+)
+
+func TestFeverAPI(t *testing.T) {
+	t.Parallel()
+
+	users := &stubAuth{}
+	user, err := CreateUser(context.Background(), users, "reader", "correct horse", false)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	const feedID = "11111111-1111-1111-1111-111111111111"
+	item := store.Item{
+		ID:          "22222222-2222-2222-2222-222222222222",
+		FeedID:      feedID,
+		URL:         "https://go.dev/blog/post",
+		Title:       "Post",
+		ContentHTML: "<p>Hello</p>",
+		PublishedAt: sql.NullTime{Time: time.Unix(1714564800, 0), Valid: true},
+		Read:        true,
+		Seq:         42,
+	}
+
+	var (
+		mu      sync.Mutex
+		queries []store.ListItemsBySeqParams
+		updates []store.UpdateItemStateParams
+		marked  []string
+	)
+	stub := &stubStore{
+		listFeedsFunc: func(ctx context.Context, userID string, active bool) ([]store.Feed, error) {
+			if !active {
+				return nil, nil
+			}
+			return []store.Feed{{
+				ID:       feedID,
+				URL:      "https://go.dev/blog/feed.atom",
+				Title:    "Go Blog",
+				Active:   true,
+				Category: sql.NullString{String: "Tech", Valid: true},
+				Seq:      7,
+			}}, nil
+		},
+		itemsBySeqFunc: func(ctx context.Context, params store.ListItemsBySeqParams) ([]store.Item, error) {
+			mu.Lock()
+			queries = append(queries, params)
+			mu.Unlock()
+			return []store.Item{item}, nil
+		},
+		itemStateFunc: func(ctx context.Context, params store.UpdateItemStateParams) (store.Item, error) {
+			mu.Lock()
+			updates = append(updates, params)
+			mu.Unlock()
+			return item, nil
+		},
+		markFeedReadFunc: func(ctx context.Context, userID, id string, before time.Time) (int64, error) {
+			mu.Lock()
+			marked = append(marked, id)
+			mu.Unlock()
+			return 1, nil
+		},
+		starredSeqsFunc: func(ctx context.Context, userID string) ([]int64, error) {
+			return []int64{42}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test", Auth: users})
+
+	_, token, _, err := signIn(context.Background(), Config{Auth: users}, "reader", "correct horse")
+	if err != nil {
+		t.Fatalf("signIn: %v", err)
+	}
+	setPassword := func(password string) int {
+		req := httptest.NewRequest(http.MethodPut, "/auth/fever", strings.NewReader(`{"password":"`+password+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := setPassword("correct horse"); code != http.StatusBadRequest {
+		t.Fatalf("expected status %d when reusing the account password, got %d", http.StatusBadRequest, code)
+	}
+	if code := setPassword("fever password"); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+
+	fever := func(query string, form url.Values) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/fever/?api&"+query, strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := fever("groups", url.Values{"api_key": {auth.FeverKey("reader", "correct horse")}}); resp["auth"] != float64(0) {
+		t.Fatalf("expected auth 0 for the account password, got %v", resp)
+	}
+	key := url.Values{"api_key": {auth.FeverKey("reader", "fever password")}}
+
+	resp := fever("groups&feeds", key)
+	if resp["auth"] != float64(1) || resp["api_version"] != float64(3) {
+		t.Fatalf("expected an authenticated response, got %v", resp)
+	}
+	groups := resp["groups"].([]any)
+	group := groups[0].(map[string]any)
+	if len(groups) != 1 || group["title"] != "Tech" {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+	feeds := resp["feeds"].([]any)
+	if len(feeds) != 1 || feeds[0].(map[string]any)["id"] != float64(7) {
+		t.Fatalf("unexpected feeds: %v", feeds)
+	}
+	feedsGroups := resp["feeds_groups"].([]any)
+	if len(feedsGroups) != 1 || feedsGroups[0].(map[string]any)["feed_ids"] != "7" || feedsGroups[0].(map[string]any)["group_id"] != group["id"] {
+		t.Fatalf("unexpected feeds_groups: %v", feedsGroups)
+	}
+
+	resp = fever("items&since_id=41", key)
+	items := resp["items"].([]any)
+	got := items[0].(map[string]any)
+	if got["id"] != float64(42) || got["feed_id"] != float64(7) || got["is_read"] != float64(1) || got["created_on_time"] != float64(1714564800) {
+		t.Fatalf("unexpected item: %v", got)
+	}
+	if q := queries[len(queries)-1]; q.UserID != user.ID || q.SinceSeq != 41 || q.MaxSeq != 0 || q.Limit != 50 {
+		t.Fatalf("unexpected item query: %+v", q)
+	}
+	fever("items&with_ids=1,2,3", key)
+	if q := queries[len(queries)-1]; len(q.Seqs) != 3 || q.Seqs[2] != 3 {
+		t.Fatalf("unexpected item query: %+v", q)
+	}
+
+	mark := url.Values{"api_key": key["api_key"], "mark": {"item"}, "as": {"saved"}, "id": {"42"}}
+	resp = fever("", mark)
+	if len(updates) != 1 || updates[0].ID != item.ID || updates[0].UserID != user.ID || !*updates[0].Starred || updates[0].Read != nil {
+		t.Fatalf("unexpected state updates: %+v", updates)
+	}
+	if resp["saved_item_ids"] != "42" {
+		t.Fatalf("expected the saved item ids after marking, got %v", resp["saved_item_ids"])
+	}
+
+	mark = url.Values{"api_key": key["api_key"], "mark": {"group"}, "as": {"read"}, "id": {"0"}, "before": {"1714564800"}}
+	fever("", mark)
+	if len(marked) != 1 || marked[0] != feedID {
+		t.Fatalf("expected every feed to be marked read, got %v", marked)
+	}
+}
@@ -0,0 +1,629 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/store"
+)
+
+// The Google Reader API subset spoken by mobile clients such as Reeder,
+// FeedMe and NetNewsWire. Feeds are "feed/<id>" streams, categories are
+// labels and items are numbered by their sequence number.
+const (
+	readerPrefix = "/reader/api/0"
+
+	readerReadingList = "user/-/state/com.google/reading-list"
+	readerRead        = "user/-/state/com.google/read"
+	readerStarred     = "user/-/state/com.google/starred"
+	readerKeptUnread  = "user/-/state/com.google/kept-unread"
+	readerLabelPrefix = "user/-/label/"
+	readerFeedPrefix  = "feed/"
+	readerItemPrefix  = "tag:google.com,2005:reader/item/"
+
+	maxReaderIDs      = 1000
+	maxReaderContents = 250
+)
+
+func registerReaderRoutes(e *echo.Echo, cfg Config) {
+	e.POST("/accounts/ClientLogin", func(c echo.Context) error {
+		_, token, _, err := signIn(c.Request().Context(), cfg, c.FormValue("Email"), c.FormValue("Passwd"))
+		if errors.Is(err, errBadCredentials) {
+			return c.String(http.StatusUnauthorized, "Error=BadAuthentication\n")
+		}
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, fmt.Sprintf("SID=%s\nLSID=%s\nAuth=%s\n", token, token, token))
+	})
+
+	g := e.Group(readerPrefix, requireAuthorizationHeader)
+
+	// Reader clients echo this token back as T on writes. Credentials must
+	// come in the Authorization header, which a cross-site form cannot
+	// set, so the token itself is not checked.
+	g.GET("/token", func(c echo.Context) error {
+		return c.String(http.StatusOK, "courier")
+	})
+
+	g.GET("/user-info", func(c echo.Context) error {
+		p := principal(c)
+		return c.JSON(http.StatusOK, map[string]string{
+			"userId":        p.UserID,
+			"userName":      p.Username,
+			"userProfileId": p.UserID,
+			"userEmail":     "",
+		})
+	})
+
+	g.GET("/subscription/list", func(c echo.Context) error {
+		feeds, err := userFeeds(c.Request().Context(), cfg, currentUserID(c))
+		if err != nil {
+			return err
+		}
+		subs := make([]readerSubscriptionView, 0, len(feeds))
+		for _, f := range feeds {
+			sub := readerSubscriptionView{
+				ID:         readerFeedPrefix + f.ID,
+				Title:      f.Title,
+				URL:        f.URL,
+				HTMLURL:    siteURL(f.URL),
+				Categories: []readerCategoryView{},
+			}
+			if f.Category.Valid {
+				sub.Categories = append(sub.Categories, readerCategoryView{ID: readerLabelPrefix + f.Category.String, Label: f.Category.String})
+			}
+			subs = append(subs, sub)
+		}
+		return c.JSON(http.StatusOK, map[string]any{"subscriptions": subs})
+	})
+
+	g.GET("/tag/list", func(c echo.Context) error {
+		feeds, err := userFeeds(c.Request().Context(), cfg, currentUserID(c))
+		if err != nil {
+			return err
+		}
+		tags := []readerTagView{{ID: readerStarred}}
+		for _, category := range feedCategories(feeds) {
+			tags = append(tags, readerTagView{ID: readerLabelPrefix + category, Type: "folder"})
+		}
+		return c.JSON(http.StatusOK, map[string]any{"tags": tags})
+	})
+
+	g.GET("/unread-count", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := currentUserID(c)
+		feeds, err := userFeeds(ctx, cfg, user)
+		if err != nil {
+			return err
+		}
+		counts, err := cfg.Store.ListUnreadCounts(ctx, user)
+		if err != nil {
+			return err
+		}
+		var total int64
+		labels := map[string]int64{}
+		views := []readerUnreadView{}
+		for _, f := range feeds {
+			n := counts[f.ID]
+			if n == 0 {
+				continue
+			}
+			total += n
+			if f.Category.Valid {
+				labels[f.Category.String] += n
+			}
+			views = append(views, readerUnreadView{ID: readerFeedPrefix + f.ID, Count: n})
+		}
+		for _, category := range feedCategories(feeds) {
+			if n := labels[category]; n > 0 {
+				views = append(views, readerUnreadView{ID: readerLabelPrefix + category, Count: n})
+			}
+		}
+		views = append(views, readerUnreadView{ID: readerReadingList, Count: total})
+		return c.JSON(http.StatusOK, map[string]any{"max": total, "unreadcounts": views})
+	})
+
+	g.GET("/stream/items/ids", func(c echo.Context) error {
+		params, err := readerStreamParams(c, c.FormValue("s"), maxReaderIDs)
+		if err != nil {
+			return err
+		}
+		result, err := cfg.Store.FilterItems(c.Request().Context(), params)
+		if errors.Is(err, store.ErrInvalidCursor) {
+			return echo.NewHTTPError(http.StatusBadRequest, "continuation does not match the stream order")
+		}
+		if err != nil {
+			return err
+		}
+		refs := make([]readerItemRefView, 0, len(result.Items))
+		for _, it := range result.Items {
+			refs = append(refs, readerItemRefView{
+				ID:              strconv.FormatInt(it.Seq, 10),
+				DirectStreamIDs: []string{},
+				TimestampUsec:   strconv.FormatInt(it.RetrievedAt.UnixMicro(), 10),
+			})
+		}
+		payload := map[string]any{"itemRefs": refs}
+		if result.NextCursor != nil {
+			payload["continuation"] = result.NextCursor.Encode()
+		}
+		return c.JSON(http.StatusOK, payload)
+	})
+
+	streamItemsContents := func(c echo.Context) error {
+		raw := formValues(c)["i"]
+		if len(raw) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "i is required")
+		}
+		if len(raw) > maxReaderContents {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d items per request", maxReaderContents))
+		}
+		seqs, err := parseReaderItemIDs(raw)
+		if err != nil {
+			return err
+		}
+		ctx := c.Request().Context()
+		user := currentUserID(c)
+		result, err := cfg.Store.FilterItems(ctx, store.FilterItemsParams{
+			UserID:        user,
+			Seqs:          seqs,
+			SortField:     store.ItemSortFieldRetrievedAt,
+			SortDirection: store.SortDirectionDesc,
+			Limit:         int32(len(seqs)),
+		})
+		if err != nil {
+			return err
+		}
+		feeds, err := userFeeds(ctx, cfg, user)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, readerStreamView{
+			Direction: "ltr",
+			ID:        readerReadingList,
+			Updated:   time.Now().Unix(),
+			Items:     mapReaderItems(result.Items, feeds),
+		})
+	}
+	g.GET("/stream/items/contents", streamItemsContents)
+	g.POST("/stream/items/contents", streamItemsContents)
+
+	g.GET("/stream/contents/*", func(c echo.Context) error {
+		stream, err := url.PathUnescape(c.Param("*"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid stream id")
+		}
+		if stream == "" {
+			stream = c.FormValue("s")
+		}
+		params, err := readerStreamParams(c, stream, maxReaderContents)
+		if err != nil {
+			return err
+		}
+		ctx := c.Request().Context()
+		result, err := cfg.Store.FilterItems(ctx, params)
+		if errors.Is(err, store.ErrInvalidCursor) {
+			return echo.NewHTTPError(http.StatusBadRequest, "continuation does not match the stream order")
+		}
+		if err != nil {
+			return err
+		}
+		feeds, err := userFeeds(ctx, cfg, params.UserID)
+		if err != nil {
+			return err
+		}
+		view := readerStreamView{
+			Direction: "ltr",
+			ID:        stream,
+			Updated:   time.Now().Unix(),
+			Items:     mapReaderItems(result.Items, feeds),
+		}
+		if result.NextCursor != nil {
+			view.Continuation = result.NextCursor.Encode()
+		}
+		return c.JSON(http.StatusOK, view)
+	})
+
+	g.POST("/edit-tag", func(c echo.Context) error {
+		form := formValues(c)
+		var update store.UpdateItemStateParams
+		for _, change := range []struct {
+			tags  []string
+			value bool
+		}{
+			{form["a"], true},
+			{form["r"], false},
+		} {
+			for _, tag := range change.tags {
+				v := change.value
+				switch normalizeReaderStream(tag) {
+				case readerRead:
+					update.Read = &v
+				case readerKeptUnread:
+					unread := !v
+					update.Read = &unread
+				case readerStarred:
+					update.Starred = &v
+				}
+			}
+		}
+		if update.Read == nil && update.Starred == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "a or r must name the read or starred state")
+		}
+		if len(form["i"]) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "i is required")
+		}
+		if len(form["i"]) > maxReaderIDs {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d items per request", maxReaderIDs))
+		}
+		seqs, err := parseReaderItemIDs(form["i"])
+		if err != nil {
+			return err
+		}
+
+		ctx := c.Request().Context()
+		update.UserID = currentUserID(c)
+		result, err := cfg.Store.FilterItems(ctx, store.FilterItemsParams{
+			UserID:        update.UserID,
+			Seqs:          seqs,
+			SortField:     store.ItemSortFieldRetrievedAt,
+			SortDirection: store.SortDirectionDesc,
+			Limit:         int32(len(seqs)),
+		})
+		if err != nil {
+			return err
+		}
+		for _, it := range result.Items {
+			update.ID = it.ID
+			if _, err := cfg.Store.UpdateItemState(ctx, update); err != nil {
+				return err
+			}
+		}
+		return c.String(http.StatusOK, "OK")
+	})
+
+	g.POST("/mark-all-as-read", func(c echo.Context) error {
+		stream := normalizeReaderStream(c.FormValue("s"))
+		before := time.Now().UTC()
+		if raw := c.FormValue("ts"); raw != "" {
+			usec, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || usec < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "ts must be a timestamp in microseconds")
+			}
+			before = time.UnixMicro(usec).UTC()
+		}
+
+		ctx := c.Request().Context()
+		user := currentUserID(c)
+		var feedIDs []string
+		switch {
+		case strings.HasPrefix(stream, readerFeedPrefix):
+			id := strings.TrimPrefix(stream, readerFeedPrefix)
+			if _, err := cfg.Store.GetUserFeed(ctx, user, id); err != nil {
+				return err
+			}
+			feedIDs = []string{id}
+		case stream == readerReadingList || strings.HasPrefix(stream, readerLabelPrefix):
+			feeds, err := userFeeds(ctx, cfg, user)
+			if err != nil {
+				return err
+			}
+			category := strings.TrimPrefix(stream, readerLabelPrefix)
+			for _, f := range feeds {
+				if stream == readerReadingList || f.Category.String == category {
+					feedIDs = append(feedIDs, f.ID)
+				}
+			}
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "s must be a feed, a label or the reading list")
+		}
+		for _, id := range feedIDs {
+			if _, err := cfg.Store.MarkFeedRead(ctx, user, id, before); err != nil {
+				return err
+			}
+		}
+		return c.String(http.StatusOK, "OK")
+	})
+}
+
+// requireAuthorizationHeader turns away Reader API calls authenticated by
+// the session cookie alone. Reader writes are plain form posts, so cookies
+// would let any site change a signed-in user's read state.
+func requireAuthorizationHeader(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Header.Get(echo.HeaderAuthorization) == "" {
+			return unauthorized(c, "authentication required")
+		}
+		return next(c)
+	}
+}
+
+// formValues returns the query and form parameters of a request,
+// keeping repeated keys such as i.
+func formValues(c echo.Context) url.Values {
+	values, err := c.FormParams()
+	if err != nil {
+		return c.QueryParams()
+	}
+	return values
+}
+
+// userFeeds lists every feed the user subscribes to, inactive ones
+// included, since their items can still be read.
+func userFeeds(ctx context.Context, cfg Config, userID string) ([]store.Feed, error) {
+	active, err := cfg.Store.ListUserFeeds(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	inactive, err := cfg.Store.ListUserFeeds(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	return append(active, inactive...), nil
+}
+
+func feedCategories(feeds []store.Feed) []string {
+	seen := map[string]bool{}
+	var categories []string
+	for _, f := range feeds {
+		if f.Category.Valid && !seen[f.Category.String] {
+			seen[f.Category.String] = true
+			categories = append(categories, f.Category.String)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// normalizeReaderStream rewrites "user/<id>/..." stream IDs to the
+// "user/-/..." form some clients avoid.
+func normalizeReaderStream(stream string) string {
+	rest, ok := strings.CutPrefix(stream, "user/")
+	if !ok {
+		return stream
+	}
+	if _, tail, ok := strings.Cut(rest, "/"); ok {
+		return "user/-/" + tail
+	}
+	return stream
+}
+
+// siteURL guesses a feed's site from its URL, since only the feed URL is
+// stored.
+func siteURL(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil || u.Host == "" {
+		return feedURL
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
+
+// readerStreamParams maps a stream ID and the Reader paging parameters (n,
+// r, ot, nt, xt, it and c) to an item filter. Streams are ordered by
+// retrieval time, newest first unless r=o.
+func readerStreamParams(c echo.Context, stream string, maxItems int) (store.FilterItemsParams, error) {
+	params := store.FilterItemsParams{
+		UserID:        currentUserID(c),
+		SortField:     store.ItemSortFieldRetrievedAt,
+		SortDirection: store.SortDirectionDesc,
+	}
+	yes, no := true, false
+	stream = normalizeReaderStream(stream)
+	switch {
+	case stream == "" || stream == readerReadingList:
+	case stream == readerStarred:
+		params.Starred = &yes
+	case stream == readerRead:
+		params.Unread = &no
+	case strings.HasPrefix(stream, readerLabelPrefix):
+		params.Category = strings.TrimPrefix(stream, readerLabelPrefix)
+	case strings.HasPrefix(stream, readerFeedPrefix):
+		params.FeedIDs = []string{strings.TrimPrefix(stream, readerFeedPrefix)}
+		if _, err := uuid.Parse(params.FeedIDs[0]); err != nil {
+			return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, "unknown stream")
+		}
+	default:
+		return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, "unknown stream")
+	}
+
+	switch normalizeReaderStream(c.FormValue("xt")) {
+	case "":
+	case readerRead:
+		params.Unread = &yes
+	case readerStarred:
+		params.Starred = &no
+	default:
+		return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, "xt must be the read or starred state")
+	}
+	switch normalizeReaderStream(c.FormValue("it")) {
+	case "":
+	case readerRead:
+		params.Unread = &no
+	case readerStarred:
+		params.Starred = &yes
+	default:
+		return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, "it must be the read or starred state")
+	}
+
+	n := parseInt(c.FormValue("n"), 20)
+	if n <= 0 {
+		return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, "n must be positive")
+	}
+	params.Limit = int32(min(n, maxItems))
+	if c.FormValue("r") == "o" {
+		params.SortDirection = store.SortDirectionAsc
+	}
+	for _, bound := range []struct {
+		param string
+		dst   *time.Time
+	}{
+		{"ot", &params.RetrievedAfter},
+		{"nt", &params.RetrievedBefore},
+	} {
+		raw := c.FormValue(bound.param)
+		if raw == "" {
+			continue
+		}
+		sec, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || sec < 0 {
+			return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be a unix timestamp", bound.param))
+		}
+		*bound.dst = time.Unix(sec, 0).UTC()
+	}
+	if raw := c.FormValue("c"); raw != "" {
+		cursor, err := store.DecodeItemCursor(raw)
+		if err != nil {
+			return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, "invalid continuation")
+		}
+		params.After = &cursor
+	}
+	return params, nil
+}
+
+// parseReaderItemIDs accepts item IDs in the long form
+// "tag:google.com,2005:reader/item/<16 hex digits>" or as decimal numbers.
+func parseReaderItemIDs(raw []string) ([]int64, error) {
+	seqs := make([]int64, 0, len(raw))
+	for _, id := range raw {
+		var (
+			n   uint64
+			err error
+		)
+		if hex, ok := strings.CutPrefix(id, readerItemPrefix); ok {
+			n, err = strconv.ParseUint(hex, 16, 64)
+		} else {
+			var signed int64
+			signed, err = strconv.ParseInt(id, 10, 64)
+			n = uint64(signed)
+		}
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid item id %q", id))
+		}
+		seqs = append(seqs, int64(n))
+	}
+	return seqs, nil
+}
+
+type readerSubscriptionView struct {
+	ID         string               `json:"id"`
+	Title      string               `json:"title"`
+	Categories []readerCategoryView `json:"categories"`
+	URL        string               `json:"url"`
+	HTMLURL    string               `json:"htmlUrl"`
+}
+
+type readerCategoryView struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type readerTagView struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+type readerUnreadView struct {
+	ID    string `json:"id"`
+	Count int64  `json:"count"`
+}
+
+type readerItemRefView struct {
+	ID              string   `json:"id"`
+	DirectStreamIDs []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+type readerStreamView struct {
+	Direction    string           `json:"direction"`
+	ID           string           `json:"id"`
+	Updated      int64            `json:"updated"`
+	Items        []readerItemView `json:"items"`
+	Continuation string           `json:"continuation,omitempty"`
+}
+
+type readerLinkView struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type readerItemView struct {
+	ID            string           `json:"id"`
+	CrawlTimeMsec string           `json:"crawlTimeMsec"`
+	TimestampUsec string           `json:"timestampUsec"`
+	Published     int64            `json:"published"`
+	Updated       int64            `json:"updated"`
+	Title         string           `json:"title"`
+	Author        string           `json:"author,omitempty"`
+	Canonical     []readerLinkView `json:"canonical"`
+	Alternate     []readerLinkView `json:"alternate"`
+	Categories    []string         `json:"categories"`
+	Origin        readerOriginView `json:"origin"`
+	Summary       readerTextView   `json:"summary"`
+}
+
+type readerOriginView struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HTMLURL  string `json:"htmlUrl,omitempty"`
+}
+
+type readerTextView struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+func mapReaderItems(items []store.Item, feeds []store.Feed) []readerItemView {
+	categories := make(map[string]string, len(feeds))
+	for _, f := range feeds {
+		if f.Category.Valid {
+			categories[f.ID] = f.Category.String
+		}
+	}
+	views := make([]readerItemView, 0, len(items))
+	for _, it := range items {
+		published := it.RetrievedAt
+		if it.PublishedAt.Valid {
+			published = it.PublishedAt.Time
+		}
+		tags := []string{readerReadingList}
+		if category, ok := categories[it.FeedID]; ok {
+			tags = append(tags, readerLabelPrefix+category)
+		}
+		if it.Read {
+			tags = append(tags, readerRead)
+		}
+		if it.Starred {
+			tags = append(tags, readerStarred)
+		}
+		content := it.ContentHTML
+		if content == "" {
+			content = it.ContentText
+		}
+		views = append(views, readerItemView{
+			ID:            fmt.Sprintf("%s%016x", readerItemPrefix, uint64(it.Seq)),
+			CrawlTimeMsec: strconv.FormatInt(it.RetrievedAt.UnixMilli(), 10),
+			TimestampUsec: strconv.FormatInt(it.RetrievedAt.UnixMicro(), 10),
+			Published:     published.Unix(),
+			Updated:       published.Unix(),
+			Title:         it.Title,
+			Author:        it.Author.String,
+			Canonical:     []readerLinkView{{Href: it.URL}},
+			Alternate:     []readerLinkView{{Href: it.URL, Type: "text/html"}},
+			Categories:    tags,
+			Origin:        readerOriginView{StreamID: readerFeedPrefix + it.FeedID, Title: it.FeedTitle},
+			Summary:       readerTextView{Direction: "ltr", Content: content},
+		})
+	}
+	return views
+}
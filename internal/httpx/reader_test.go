@@ -0,0 +1,191 @@
+package httpx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/store"
+)
+
+func TestReaderAPI(t *testing.T) {
+	t.Parallel()
+
+	users := &stubAuth{}
+	user, err := CreateUser(context.Background(), users, "reader", "correct horse", false)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	const feedID = "11111111-1111-1111-1111-111111111111"
+	retrieved := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	item := store.Item{
+		ID:          "22222222-2222-2222-2222-222222222222",
+		FeedID:      feedID,
+		FeedTitle:   "Go Blog",
+		URL:         "https://go.dev/blog/post",
+		Title:       "Post",
+		ContentHTML: "<p>Hello</p>",
+		RetrievedAt: retrieved,
+		Starred:     true,
+		Seq:         42,
+	}
+
+	var (
+		mu      sync.Mutex
+		filters []store.FilterItemsParams
+		updates []store.UpdateItemStateParams
+		marked  []string
+	)
+	stub := &stubStore{
+		listFeedsFunc: func(ctx context.Context, userID string, active bool) ([]store.Feed, error) {
+			if !active {
+				return nil, nil
+			}
+			return []store.Feed{{
+				ID:       feedID,
+				URL:      "https://go.dev/blog/feed.atom",
+				Title:    "Go Blog",
+				Active:   true,
+				Category: sql.NullString{String: "Tech", Valid: true},
+			}}, nil
+		},
+		filterItemsFunc: func(ctx context.Context, params store.FilterItemsParams) (store.FilterItemsResult, error) {
+			mu.Lock()
+			filters = append(filters, params)
+			mu.Unlock()
+			return store.FilterItemsResult{Items: []store.Item{item}}, nil
+		},
+		itemStateFunc: func(ctx context.Context, params store.UpdateItemStateParams) (store.Item, error) {
+			mu.Lock()
+			updates = append(updates, params)
+			mu.Unlock()
+			return item, nil
+		},
+		markFeedReadFunc: func(ctx context.Context, userID, id string, before time.Time) (int64, error) {
+			mu.Lock()
+			marked = append(marked, id)
+			mu.Unlock()
+			return 1, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test", Auth: users})
+
+	do := func(method, path, token string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		if form != nil {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		}
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "GoogleLogin auth="+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/accounts/ClientLogin", "", url.Values{"Email": {"reader"}, "Passwd": {"wrong"}}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d for a wrong password, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	rec := do(http.MethodPost, "/accounts/ClientLogin", "", url.Values{"Email": {"reader"}, "Passwd": {"correct horse"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var token string
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if v, ok := strings.CutPrefix(line, "Auth="); ok {
+			token = v
+		}
+	}
+	if token == "" {
+		t.Fatalf("expected an Auth token, got %q", rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/reader/api/0/subscription/list", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d with only a session cookie, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	rec = do(http.MethodGet, "/reader/api/0/subscription/list?output=json", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var subs struct {
+		Subscriptions []readerSubscriptionView `json:"subscriptions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &subs); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(subs.Subscriptions) != 1 || subs.Subscriptions[0].ID != "feed/"+feedID || subs.Subscriptions[0].Categories[0].ID != "user/-/label/Tech" {
+		t.Fatalf("unexpected subscriptions: %+v", subs.Subscriptions)
+	}
+
+	rec = do(http.MethodGet, "/reader/api/0/stream/items/ids?s=user/-/state/com.google/reading-list&xt=user/-/state/com.google/read&n=5", token, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":"42"`) {
+		t.Fatalf("unexpected item ids response %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := filters[len(filters)-1]; got.UserID != user.ID || got.Unread == nil || !*got.Unread || got.Limit != 5 || got.SortField != store.ItemSortFieldRetrievedAt {
+		t.Fatalf("unexpected filter: %+v", got)
+	}
+
+	rec = do(http.MethodGet, "/reader/api/0/stream/contents/"+url.PathEscape("user/-/label/Tech")+"?r=o", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var stream readerStreamView
+	if err := json.Unmarshal(rec.Body.Bytes(), &stream); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got := filters[len(filters)-1]; got.Category != "Tech" || got.SortDirection != store.SortDirectionAsc {
+		t.Fatalf("unexpected filter: %+v", got)
+	}
+	if len(stream.Items) != 1 {
+		t.Fatalf("expected one item, got %+v", stream.Items)
+	}
+	got := stream.Items[0]
+	if got.ID != "tag:google.com,2005:reader/item/000000000000002a" || got.TimestampUsec != "1714564800000000" || got.Origin.StreamID != "feed/"+feedID {
+		t.Fatalf("unexpected item: %+v", got)
+	}
+	if strings.Join(got.Categories, ",") != "user/-/state/com.google/reading-list,user/-/label/Tech,user/-/state/com.google/starred" {
+		t.Fatalf("unexpected categories: %v", got.Categories)
+	}
+
+	rec = do(http.MethodPost, "/reader/api/0/stream/items/contents", token, url.Values{"i": {"42", "tag:google.com,2005:reader/item/000000000000002b"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := filters[len(filters)-1]; len(got.Seqs) != 2 || got.Seqs[0] != 42 || got.Seqs[1] != 43 {
+		t.Fatalf("unexpected item filter: %+v", got)
+	}
+	if rec := do(http.MethodPost, "/reader/api/0/stream/items/contents", token, url.Values{"i": {"not-an-id"}}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a bad item id, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = do(http.MethodPost, "/reader/api/0/edit-tag", token, url.Values{"i": {"42"}, "a": {"user/-/state/com.google/read"}, "r": {"user/-/state/com.google/starred"}})
+	if rec.Code != http.StatusOK || rec.Body.String() != "OK" {
+		t.Fatalf("unexpected edit-tag response %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(updates) != 1 || updates[0].ID != item.ID || updates[0].UserID != user.ID || !*updates[0].Read || *updates[0].Starred {
+		t.Fatalf("unexpected state updates: %+v", updates)
+	}
+
+	rec = do(http.MethodPost, "/reader/api/0/mark-all-as-read", token, url.Values{"s": {"user/-/label/Tech"}, "ts": {"1714564800000000"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if len(marked) != 1 || marked[0] != feedID {
+		t.Fatalf("expected the labelled feed to be marked read, got %v", marked)
+	}
+}
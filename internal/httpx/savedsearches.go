@@ -0,0 +1,246 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/store"
+)
+
+const maxSavedSearchNameLength = 100
+
+type savedSearchReq struct {
+	Name    *string  `json:"name"`
+	Query   *string  `json:"query"`
+	FeedIDs []string `json:"feed_ids"`
+	Tags    []string `json:"tags"`
+}
+
+// registerSavedSearchRoutes serves saved searches. The fetcher checks every
+// item it indexes against them; matches found since a search was last
+// marked viewed count as new.
+func registerSavedSearchRoutes(e *echo.Echo, cfg Config) {
+	e.GET("/saved-searches", func(c echo.Context) error {
+		searches, err := cfg.Store.ListSavedSearches(c.Request().Context(), currentUserID(c))
+		if err != nil {
+			return err
+		}
+		views := make([]savedSearchView, 0, len(searches))
+		for _, s := range searches {
+			views = append(views, mapSavedSearch(s))
+		}
+		return c.JSON(http.StatusOK, views)
+	})
+
+	e.POST("/saved-searches", func(c echo.Context) error {
+		var req savedSearchReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		if req.Name == nil || req.Query == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "name and query required")
+		}
+		if err := validateSavedSearch(&req); err != nil {
+			return err
+		}
+		saved, err := cfg.Store.CreateSavedSearch(c.Request().Context(), store.CreateSavedSearchParams{
+			UserID:  currentUserID(c),
+			Name:    *req.Name,
+			Query:   *req.Query,
+			FeedIDs: req.FeedIDs,
+			Tags:    req.Tags,
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, mapSavedSearch(saved))
+	})
+
+	e.GET("/saved-searches/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid saved search id")
+		}
+		saved, err := cfg.Store.GetSavedSearch(c.Request().Context(), currentUserID(c), id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapSavedSearch(saved))
+	})
+
+	e.PATCH("/saved-searches/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid saved search id")
+		}
+		var req savedSearchReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		if req.Name == nil && req.Query == nil && req.FeedIDs == nil && req.Tags == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "no changes requested")
+		}
+		if err := validateSavedSearch(&req); err != nil {
+			return err
+		}
+		saved, err := cfg.Store.UpdateSavedSearch(c.Request().Context(), store.UpdateSavedSearchParams{
+			UserID:  currentUserID(c),
+			ID:      id,
+			Name:    req.Name,
+			Query:   req.Query,
+			FeedIDs: req.FeedIDs,
+			Tags:    req.Tags,
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapSavedSearch(saved))
+	})
+
+	e.DELETE("/saved-searches/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid saved search id")
+		}
+		if err := cfg.Store.DeleteSavedSearch(c.Request().Context(), currentUserID(c), id); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.GET("/saved-searches/:id/matches", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid saved search id")
+		}
+		limit := parseInt(c.QueryParam("limit"), 50)
+		if limit < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be non-negative")
+		}
+		if limit > maxItemsLimit {
+			limit = maxItemsLimit
+		}
+		offset := parseInt(c.QueryParam("offset"), 0)
+		if offset < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "offset must be non-negative")
+		}
+		onlyNew := false
+		if raw := c.QueryParam("new"); raw != "" {
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "new must be true or false")
+			}
+			onlyNew = v
+		}
+
+		matches, err := cfg.Store.ListSavedSearchMatches(c.Request().Context(), store.ListSavedSearchMatchesParams{
+			UserID: currentUserID(c),
+			ID:     id,
+			New:    onlyNew,
+			Limit:  int32(limit),
+			Offset: int32(offset),
+		})
+		if err != nil {
+			return err
+		}
+		views := make([]savedSearchMatchView, 0, len(matches))
+		for _, m := range matches {
+			views = append(views, savedSearchMatchView{itemView: mapItem(m.Item), MatchedAt: m.MatchedAt, New: m.New})
+		}
+		return c.JSON(http.StatusOK, views)
+	})
+
+	e.POST("/saved-searches/:id/viewed", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid saved search id")
+		}
+		if err := cfg.Store.MarkSavedSearchViewed(c.Request().Context(), currentUserID(c), id); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+}
+
+// validateSavedSearch trims and checks the fields present in req.
+func validateSavedSearch(req *savedSearchReq) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "name required")
+		}
+		if utf8.RuneCountInString(name) > maxSavedSearchNameLength {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("name must be at most %d characters", maxSavedSearchNameLength))
+		}
+		req.Name = &name
+	}
+	if req.Query != nil {
+		query := strings.TrimSpace(*req.Query)
+		if query == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "query required")
+		}
+		if len(query) > maxTextFilterLength {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("query must be at most %d bytes", maxTextFilterLength))
+		}
+		req.Query = &query
+	}
+	for _, id := range req.FeedIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid feed_ids")
+		}
+	}
+	for i, tag := range req.Tags {
+		name, err := validateTagName(tag)
+		if err != nil {
+			return err
+		}
+		req.Tags[i] = name
+	}
+	return nil
+}
+
+type savedSearchView struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Query        string    `json:"query"`
+	FeedIDs      []string  `json:"feed_ids"`
+	Tags         []string  `json:"tags"`
+	MatchCount   int64     `json:"match_count"`
+	NewCount     int64     `json:"new_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastViewedAt time.Time `json:"last_viewed_at"`
+}
+
+func mapSavedSearch(s store.SavedSearch) savedSearchView {
+	view := savedSearchView{
+		ID:           s.ID,
+		Name:         s.Name,
+		Query:        s.Query,
+		FeedIDs:      s.FeedIDs,
+		Tags:         s.Tags,
+		MatchCount:   s.MatchCount,
+		NewCount:     s.NewCount,
+		CreatedAt:    s.CreatedAt,
+		LastViewedAt: s.LastViewedAt,
+	}
+	if view.FeedIDs == nil {
+		view.FeedIDs = []string{}
+	}
+	if view.Tags == nil {
+		view.Tags = []string{}
+	}
+	return view
+}
+
+type savedSearchMatchView struct {
+	itemView
+	MatchedAt time.Time `json:"matched_at"`
+	New       bool      `json:"new"`
+}
@@ -0,0 +1,129 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/store"
+)
+
+func TestSavedSearchHandlers(t *testing.T) {
+	t.Parallel()
+
+	const (
+		id     = "33333333-3333-3333-3333-333333333333"
+		feedID = "11111111-1111-1111-1111-111111111111"
+	)
+	var (
+		mu      sync.Mutex
+		created []store.CreateSavedSearchParams
+		updates []store.UpdateSavedSearchParams
+		lists   []store.ListSavedSearchMatchesParams
+		viewed  []string
+	)
+	saved := store.SavedSearch{ID: id, Name: "Go", Query: "generics", FeedIDs: []string{feedID}, Tags: []string{"go"}, MatchCount: 3, NewCount: 2}
+	stub := &stubStore{
+		createSavedSearchFunc: func(ctx context.Context, arg store.CreateSavedSearchParams) (store.SavedSearch, error) {
+			mu.Lock()
+			created = append(created, arg)
+			mu.Unlock()
+			return saved, nil
+		},
+		listSavedSearchesFunc: func(ctx context.Context, userID string) ([]store.SavedSearch, error) {
+			return []store.SavedSearch{saved}, nil
+		},
+		updateSavedSearchFunc: func(ctx context.Context, arg store.UpdateSavedSearchParams) (store.SavedSearch, error) {
+			mu.Lock()
+			updates = append(updates, arg)
+			mu.Unlock()
+			return saved, nil
+		},
+		viewSavedSearchFunc: func(ctx context.Context, userID, searchID string) error {
+			mu.Lock()
+			viewed = append(viewed, searchID)
+			mu.Unlock()
+			return nil
+		},
+		savedMatchesFunc: func(ctx context.Context, arg store.ListSavedSearchMatchesParams) ([]store.SavedSearchMatch, error) {
+			mu.Lock()
+			lists = append(lists, arg)
+			mu.Unlock()
+			return []store.SavedSearchMatch{{
+				Item:      store.Item{ID: "22222222-2222-2222-2222-222222222222", FeedID: feedID, Title: "Generics"},
+				MatchedAt: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+				New:       true,
+			}}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, body := range []string{
+		`{"name":"Go"}`,
+		`{"name":" ","query":"generics"}`,
+		`{"name":"Go","query":"generics","feed_ids":["nope"]}`,
+		`{"name":"Go","query":"generics","tags":[""]}`,
+	} {
+		if rec := do(http.MethodPost, "/saved-searches", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("POST %s: expected status %d, got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+	rec := do(http.MethodPost, "/saved-searches", `{"name":" Go ","query":" generics ","feed_ids":["`+feedID+`"],"tags":[" go "]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if len(created) != 1 || created[0].Name != "Go" || created[0].Query != "generics" || created[0].Tags[0] != "go" || created[0].FeedIDs[0] != feedID {
+		t.Fatalf("unexpected create params: %+v", created)
+	}
+
+	rec = do(http.MethodGet, "/saved-searches", "")
+	var views []savedSearchView
+	if err := json.Unmarshal(rec.Body.Bytes(), &views); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(views) != 1 || views[0].NewCount != 2 || views[0].MatchCount != 3 {
+		t.Fatalf("unexpected saved searches: %+v", views)
+	}
+
+	if rec := do(http.MethodPatch, "/saved-searches/"+id, `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an empty patch, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do(http.MethodPatch, "/saved-searches/"+id, `{"tags":[]}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := updates[0]; got.Name != nil || got.Query != nil || got.FeedIDs != nil || got.Tags == nil || len(got.Tags) != 0 {
+		t.Fatalf("expected only the tags to be cleared, got %+v", got)
+	}
+
+	rec = do(http.MethodGet, "/saved-searches/"+id+"/matches?new=true&limit=500", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"new":true`) || !strings.Contains(rec.Body.String(), `"matched_at":"2024-05-02T08:00:00Z"`) {
+		t.Fatalf("unexpected matches response %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := lists[0]; got.ID != id || !got.New || got.Limit != maxItemsLimit {
+		t.Fatalf("unexpected match params: %+v", got)
+	}
+
+	if rec := do(http.MethodPost, "/saved-searches/"+id+"/viewed", ""); rec.Code != http.StatusNoContent || len(viewed) != 1 {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/saved-searches/"+id, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for a missing saved search, got %d", http.StatusNotFound, rec.Code)
+	}
+}
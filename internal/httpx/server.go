@@ -1,12 +1,18 @@
 package httpx
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -14,27 +20,91 @@ import (
 
 	"github.com/google/uuid"
 
+	"courier/internal/feed"
 	"courier/internal/logx"
+	"courier/internal/netguard"
+	"courier/internal/opml"
 	"courier/internal/search"
 	"courier/internal/store"
 )
 
+// storeAPI methods that take a user ID scope their results to that user's
+// subscriptions; an empty ID, used when the API is open, covers every feed.
 type storeAPI interface {
-	ListFeeds(context.Context, bool) ([]store.Feed, error)
-	InsertFeed(context.Context, string) (store.Feed, error)
+	ListUserFeeds(context.Context, string, bool) ([]store.Feed, error)
+	GetUserFeed(context.Context, string, string) (store.Feed, error)
+	ListSubscribedFeedIDs(context.Context, string) ([]string, error)
+	ListFeedFetchLog(context.Context, string, int32) ([]store.FeedFetch, error)
+	InsertFeed(context.Context, store.InsertFeedParams) (store.Feed, error)
+	UpdateFeed(context.Context, store.UpdateFeedParams) (store.Feed, error)
+	Unsubscribe(context.Context, string, string) (bool, error)
+	DeleteFeed(context.Context, string) error
 	FilterItems(context.Context, store.FilterItemsParams) (store.FilterItemsResult, error)
+	GetItem(context.Context, string, string) (store.Item, error)
+	GetItemNeighbors(context.Context, string, string) (store.ItemNeighbors, error)
+	UpdateItemState(context.Context, store.UpdateItemStateParams) (store.Item, error)
+	MarkFeedRead(context.Context, string, string, time.Time) (int64, error)
+	ListUnreadCounts(context.Context, string) (map[string]int64, error)
+	ListTags(context.Context, string) ([]store.Tag, error)
+	CreateTag(context.Context, string, string) (store.Tag, error)
+	RenameTag(context.Context, string, string, string) (store.Tag, error)
+	DeleteTag(context.Context, string, string) error
+	ListFeedTags(context.Context, string, string) ([]store.Tag, error)
+	SetFeedTags(context.Context, string, string, []string) ([]store.Tag, error)
+	ListTaggedFeedIDs(context.Context, string, []string) ([]string, error)
+	ListItemsBySeq(context.Context, store.ListItemsBySeqParams) ([]store.Item, error)
+	CountUserItems(context.Context, string) (int64, error)
+	ListUnreadItemSeqs(context.Context, string) ([]int64, error)
+	ListStarredItemSeqs(context.Context, string) ([]int64, error)
+	CreateSavedSearch(context.Context, store.CreateSavedSearchParams) (store.SavedSearch, error)
+	GetSavedSearch(context.Context, string, string) (store.SavedSearch, error)
+	ListSavedSearches(context.Context, string) ([]store.SavedSearch, error)
+	UpdateSavedSearch(context.Context, store.UpdateSavedSearchParams) (store.SavedSearch, error)
+	DeleteSavedSearch(context.Context, string, string) error
+	MarkSavedSearchViewed(context.Context, string, string) error
+	ListSavedSearchMatches(context.Context, store.ListSavedSearchMatchesParams) ([]store.SavedSearchMatch, error)
+	CreateWebhook(context.Context, store.CreateWebhookParams) (store.Webhook, error)
+	GetWebhook(context.Context, string, string) (store.Webhook, error)
+	ListWebhooks(context.Context, string) ([]store.Webhook, error)
+	UpdateWebhook(context.Context, store.UpdateWebhookParams) (store.Webhook, error)
+	DeleteWebhook(context.Context, string, string) error
+	ListWebhookDeliveries(context.Context, store.ListWebhookDeliveriesParams) ([]store.WebhookDelivery, error)
+}
+
+type searchAPI interface {
+	Health(context.Context) error
+	Search(context.Context, string, int, int, search.SearchFilters) (search.SearchResponse, error)
+	DeleteFeedDocuments(context.Context, string) error
+}
+
+// feedDiscoverer probes a URL submitted to POST /feeds for a feed, either
+// the URL itself or the feeds an HTML page links to.
+type feedDiscoverer interface {
+	Discover(context.Context, string) (feed.Discovery, error)
 }
 
 type Config struct {
 	Store   storeAPI
-	Search  *search.Client
+	Search  searchAPI
 	DB      *sql.DB
 	Service string
 	Metrics *Metrics
+	// Discoverer resolves submitted URLs to feeds. When nil, POST /feeds
+	// stores URLs as given.
+	Discoverer feedDiscoverer
+	// Auth enables accounts and requires credentials on every route but
+	// /healthz and POST /auth/login. When nil, the API is open.
+	Auth       authStore
+	SessionTTL time.Duration
+	// AllowedNetworks lists private networks webhooks may be delivered
+	// to; webhook URLs naming any other non-public address are refused.
+	AllowedNetworks netguard.Allowlist
 }
 
 const maxItemsLimit = 200
 
+const feedHealthHistory = 20
+
 func NewServer(cfg Config) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
@@ -48,6 +118,15 @@ func NewServer(cfg Config) *echo.Echo {
 		handler := promhttp.HandlerFor(cfg.Metrics.Gatherer(), promhttp.HandlerOpts{})
 		e.GET("/metrics", echo.WrapHandler(handler))
 	}
+	if cfg.Auth != nil {
+		e.Use(authenticate(cfg))
+		registerAuthRoutes(e, cfg)
+		registerReaderRoutes(e, cfg)
+		registerFeverRoutes(e, cfg)
+	}
+	registerSyndicationRoutes(e, cfg)
+	registerSavedSearchRoutes(e, cfg)
+	registerWebhookRoutes(e, cfg)
 
 	e.GET("/healthz", func(c echo.Context) error {
 		ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Second)
@@ -65,22 +144,214 @@ func NewServer(cfg Config) *echo.Echo {
 	})
 
 	e.GET("/feeds", func(c echo.Context) error {
+		status := strings.ToLower(strings.TrimSpace(c.QueryParam("status")))
+		switch status {
+		case "", feedStatusFailing, feedStatusHealthy, feedStatusInactive:
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+		}
+
 		ctx := c.Request().Context()
-		feeds, err := cfg.Store.ListFeeds(ctx, true)
+		user := currentUserID(c)
+		feeds, err := cfg.Store.ListUserFeeds(ctx, user, status != feedStatusInactive)
+		if err != nil {
+			return err
+		}
+		unread, err := cfg.Store.ListUnreadCounts(ctx, user)
 		if err != nil {
 			return err
 		}
 		views := make([]feedView, 0, len(feeds))
 		for _, f := range feeds {
-			views = append(views, mapFeed(f))
+			if status != "" && feedStatus(f) != status {
+				continue
+			}
+			view := mapFeed(f)
+			count := unread[f.ID]
+			view.UnreadCount = &count
+			views = append(views, view)
 		}
 		return c.JSON(http.StatusOK, views)
 	})
 
+	e.GET("/feeds/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid feed id")
+		}
+
+		f, err := cfg.Store.GetUserFeed(c.Request().Context(), currentUserID(c), id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapFeed(f))
+	})
+
+	type updateFeedReq struct {
+		Title    *string `json:"title"`
+		Active   *bool   `json:"active"`
+		URL      *string `json:"url"`
+		Category *string `json:"category"`
+	}
+
+	e.PATCH("/feeds/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid feed id")
+		}
+		var req updateFeedReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		if req.Title == nil && req.Active == nil && req.URL == nil && req.Category == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "no changes requested")
+		}
+		ctx := c.Request().Context()
+
+		params := store.UpdateFeedParams{UserID: currentUserID(c), ID: id, Active: req.Active}
+		if req.Title != nil {
+			title := strings.TrimSpace(*req.Title)
+			params.Title = &title
+		}
+		if req.Category != nil {
+			category := normalizeCategory(*req.Category)
+			params.Category = &category
+		}
+		if req.URL != nil {
+			if err := validateFeedURL(*req.URL); err != nil {
+				return err
+			}
+			feedURL := *req.URL
+			if cfg.Discoverer != nil {
+				resolved, found, err := resolveFeed(ctx, cfg.Discoverer, feedURL)
+				if err != nil {
+					return discoveryError(err)
+				}
+				if found.Feed == nil {
+					return c.JSON(http.StatusMultipleChoices, map[string]any{
+						"candidates": mapCandidates(found.Candidates),
+					})
+				}
+				feedURL = resolved
+			}
+			params.URL = &feedURL
+		}
+
+		f, err := cfg.Store.UpdateFeed(ctx, params)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapFeed(f))
+	})
+
+	e.DELETE("/feeds/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid feed id")
+		}
+		ctx := c.Request().Context()
+		user := currentUserID(c)
+
+		// Other subscribers keep the feed and its items; the last one
+		// deletes them along with the subscription.
+		deleted := true
+		if user != "" {
+			var err error
+			deleted, err = cfg.Store.Unsubscribe(ctx, user, id)
+			if err != nil {
+				return err
+			}
+		} else if err := cfg.Store.DeleteFeed(ctx, id); err != nil {
+			return err
+		}
+		// The fetcher clears the documents of deleted feeds as well, so a
+		// failure here only delays their removal.
+		if deleted {
+			if err := cfg.Search.DeleteFeedDocuments(ctx, id); err != nil {
+				logx.Error(cfg.Service, "delete feed documents", err, map[string]any{"feed_id": id})
+			}
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	type markReadReq struct {
+		Before *time.Time `json:"before"`
+	}
+
+	e.POST("/feeds/:id/mark-read", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid feed id")
+		}
+		var req markReadReq
+		if c.Request().ContentLength != 0 {
+			if err := c.Bind(&req); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+			}
+		}
+		before := time.Now().UTC()
+		if req.Before != nil {
+			before = req.Before.UTC()
+		}
+		ctx := c.Request().Context()
+		user := currentUserID(c)
+
+		if _, err := cfg.Store.GetUserFeed(ctx, user, id); err != nil {
+			return err
+		}
+		marked, err := cfg.Store.MarkFeedRead(ctx, user, id, before)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, map[string]int64{"marked": marked})
+	})
+
+	e.GET("/feeds/:id/health", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid feed id")
+		}
+
+		ctx := c.Request().Context()
+		f, err := cfg.Store.GetUserFeed(ctx, currentUserID(c), id)
+		if err != nil {
+			return err
+		}
+		fetches, err := cfg.Store.ListFeedFetchLog(ctx, id, feedHealthHistory)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapFeedHealth(f, fetches))
+	})
+
 	type createFeedReq struct {
 		URL string `json:"url"`
 	}
 
+	e.POST("/feeds/preview", func(c echo.Context) error {
+		var req createFeedReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		if err := validateFeedURL(req.URL); err != nil {
+			return err
+		}
+		if cfg.Discoverer == nil {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "feed discovery unavailable")
+		}
+
+		feedURL, found, err := resolveFeed(c.Request().Context(), cfg.Discoverer, req.URL)
+		if err != nil {
+			return discoveryError(err)
+		}
+		if found.Feed == nil {
+			return c.JSON(http.StatusMultipleChoices, map[string]any{
+				"candidates": mapCandidates(found.Candidates),
+			})
+		}
+		return c.JSON(http.StatusOK, mapFeedPreview(feedURL, feed.Summarize(found.Feed, time.Now())))
+	})
+
 	e.POST("/feeds", func(c echo.Context) error {
 		var req createFeedReq
 		if err := c.Bind(&req); err != nil {
@@ -90,11 +361,223 @@ func NewServer(cfg Config) *echo.Echo {
 			return echo.NewHTTPError(http.StatusBadRequest, "url required")
 		}
 		ctx := c.Request().Context()
-		feed, err := cfg.Store.InsertFeed(ctx, req.URL)
+
+		params := store.InsertFeedParams{URL: req.URL}
+		if cfg.Discoverer != nil {
+			if err := validateFeedURL(req.URL); err != nil {
+				return err
+			}
+			feedURL, found, err := resolveFeed(ctx, cfg.Discoverer, req.URL)
+			if err != nil {
+				return discoveryError(err)
+			}
+			if found.Feed == nil {
+				return c.JSON(http.StatusMultipleChoices, map[string]any{
+					"candidates": mapCandidates(found.Candidates),
+				})
+			}
+			params = store.InsertFeedParams{
+				URL:             feedURL,
+				Title:           strings.TrimSpace(found.Feed.Title),
+				RefreshInterval: found.Hints.DeclaredInterval(),
+				SkipHours:       found.Hints.SkipHours,
+				SkipDays:        found.Hints.SkipDays,
+				ValidatedAt:     time.Now().UTC(),
+				Status:          http.StatusOK,
+			}
+		}
+
+		params.UserID = currentUserID(c)
+		f, err := cfg.Store.InsertFeed(ctx, params)
 		if err != nil {
 			return err
 		}
-		return c.JSON(http.StatusCreated, mapFeed(feed))
+		return c.JSON(http.StatusCreated, mapFeed(f))
+	})
+
+	e.GET("/tags", func(c echo.Context) error {
+		tags, err := cfg.Store.ListTags(c.Request().Context(), currentUserID(c))
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapTagCounts(tags))
+	})
+
+	type tagReq struct {
+		Name string `json:"name"`
+	}
+
+	e.POST("/tags", func(c echo.Context) error {
+		var req tagReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		name, err := validateTagName(req.Name)
+		if err != nil {
+			return err
+		}
+		tag, err := cfg.Store.CreateTag(c.Request().Context(), currentUserID(c), name)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, mapTag(tag))
+	})
+
+	e.PATCH("/tags/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid tag id")
+		}
+		var req tagReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		name, err := validateTagName(req.Name)
+		if err != nil {
+			return err
+		}
+		tag, err := cfg.Store.RenameTag(c.Request().Context(), currentUserID(c), id, name)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapTag(tag))
+	})
+
+	e.DELETE("/tags/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid tag id")
+		}
+		if err := cfg.Store.DeleteTag(c.Request().Context(), currentUserID(c), id); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.GET("/feeds/:id/tags", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid feed id")
+		}
+		ctx := c.Request().Context()
+
+		user := currentUserID(c)
+		if _, err := cfg.Store.GetUserFeed(ctx, user, id); err != nil {
+			return err
+		}
+		tags, err := cfg.Store.ListFeedTags(ctx, user, id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapTags(tags))
+	})
+
+	type feedTagsReq struct {
+		Tags []string `json:"tags"`
+	}
+
+	e.PUT("/feeds/:id/tags", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid feed id")
+		}
+		var req feedTagsReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		names := make([]string, 0, len(req.Tags))
+		seen := make(map[string]bool, len(req.Tags))
+		for _, raw := range req.Tags {
+			name, err := validateTagName(raw)
+			if err != nil {
+				return err
+			}
+			if key := strings.ToLower(name); !seen[key] {
+				seen[key] = true
+				names = append(names, name)
+			}
+		}
+
+		tags, err := cfg.Store.SetFeedTags(c.Request().Context(), currentUserID(c), id, names)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapTags(tags))
+	})
+
+	e.GET("/opml", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		var subs []opml.Subscription
+		for _, active := range []bool{true, false} {
+			feeds, err := cfg.Store.ListUserFeeds(ctx, currentUserID(c), active)
+			if err != nil {
+				return err
+			}
+			for _, f := range feeds {
+				title := f.Title
+				if title == "" {
+					title = f.URL
+				}
+				subs = append(subs, opml.Subscription{Title: title, XMLURL: f.URL, Category: f.Category.String})
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := opml.Write(&buf, "Courier subscriptions", subs); err != nil {
+			return err
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="courier.opml"`)
+		return c.Blob(http.StatusOK, "text/x-opml; charset=utf-8", buf.Bytes())
+	})
+
+	e.POST("/opml", func(c echo.Context) error {
+		doc, err := opmlUpload(c)
+		if err != nil {
+			return err
+		}
+
+		subs, err := opml.Parse(bytes.NewReader(doc))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+		}
+
+		ctx := c.Request().Context()
+		report := opmlImportView{Results: make([]opmlResultView, 0, len(subs))}
+		for _, sub := range subs {
+			res := opmlResultView{URL: sub.XMLURL, Title: sub.Title, Category: normalizeCategory(sub.Category)}
+			if !isFeedURL(sub.XMLURL) {
+				res.Status = opmlStatusInvalid
+				res.Error = errInvalidFeedURL
+				report.Invalid++
+				report.Results = append(report.Results, res)
+				continue
+			}
+
+			f, err := cfg.Store.InsertFeed(ctx, store.InsertFeedParams{
+				UserID:      currentUserID(c),
+				URL:         sub.XMLURL,
+				Title:       sub.Title,
+				TitleLocked: sub.Title != "",
+				Category:    res.Category,
+			})
+			switch {
+			case errors.Is(err, store.ErrFeedExists):
+				res.Status = opmlStatusDuplicate
+				report.Duplicates++
+			case err != nil:
+				// One bad row should not undo the feeds already imported.
+				logx.Error(cfg.Service, "opml import feed", err, map[string]any{"feed": sub.XMLURL})
+				res.Status = opmlStatusFailed
+				res.Error = "could not subscribe"
+				report.Failed++
+			default:
+				res.Status = opmlStatusCreated
+				res.FeedID = f.ID
+				report.Created++
+			}
+			report.Results = append(report.Results, res)
+		}
+		return c.JSON(http.StatusOK, report)
 	})
 
 	e.GET("/items", func(c echo.Context) error {
@@ -110,53 +593,45 @@ func NewServer(cfg Config) *echo.Echo {
 		if offset < 0 {
 			return echo.NewHTTPError(http.StatusBadRequest, "offset must be non-negative")
 		}
-		feedIDs := c.QueryParams()["feed_id"]
-		for _, id := range feedIDs {
-			if _, err := uuid.Parse(id); err != nil {
-				return echo.NewHTTPError(http.StatusBadRequest, "invalid feed_id")
+		params, err := itemFilters(c)
+		if err != nil {
+			return err
+		}
+
+		var after *store.ItemCursor
+		if raw := c.QueryParam("cursor"); raw != "" {
+			if c.QueryParam("offset") != "" {
+				return echo.NewHTTPError(http.StatusBadRequest, "cursor and offset cannot be combined")
+			}
+			cursor, err := store.DecodeItemCursor(raw)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
 			}
+			after = &cursor
 		}
 		sortParam := c.QueryParam("sort")
+		if sortParam == "" && after != nil {
+			sortParam = string(after.SortField) + ":" + string(after.SortDirection)
+		}
 		if sortParam == "" {
 			sortParam = "published_at:desc"
 		}
 
-		parts := strings.SplitN(sortParam, ":", 2)
-		if len(parts) != 2 {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid sort parameter")
-		}
-
-		field := strings.ToLower(strings.TrimSpace(parts[0]))
-		direction := strings.ToLower(strings.TrimSpace(parts[1]))
-
-		var sortField store.ItemSortField
-		switch field {
-		case string(store.ItemSortFieldPublishedAt):
-			sortField = store.ItemSortFieldPublishedAt
-		case string(store.ItemSortFieldRetrievedAt):
-			sortField = store.ItemSortFieldRetrievedAt
-		default:
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid sort field")
-		}
-
-		var sortDirection store.SortDirection
-		switch direction {
-		case string(store.SortDirectionAsc):
-			sortDirection = store.SortDirectionAsc
-		case string(store.SortDirectionDesc):
-			sortDirection = store.SortDirectionDesc
-		default:
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid sort direction")
+		sortField, sortDirection, err := parseItemSort(sortParam)
+		if err != nil {
+			return err
 		}
 
 		ctx := c.Request().Context()
-		result, err := cfg.Store.FilterItems(ctx, store.FilterItemsParams{
-			FeedIDs:       feedIDs,
-			SortField:     sortField,
-			SortDirection: sortDirection,
-			Limit:         int32(limit),
-			Offset:        int32(offset),
-		})
+		params.SortField = sortField
+		params.SortDirection = sortDirection
+		params.Limit = int32(limit)
+		params.Offset = int32(offset)
+		params.After = after
+		result, err := cfg.Store.FilterItems(ctx, params)
+		if errors.Is(err, store.ErrInvalidCursor) {
+			return echo.NewHTTPError(http.StatusBadRequest, "cursor does not match sort")
+		}
 		if err != nil {
 			return err
 		}
@@ -164,11 +639,70 @@ func NewServer(cfg Config) *echo.Echo {
 		for _, it := range result.Items {
 			views = append(views, mapItem(it))
 		}
-		c.Response().Header().Set("X-Total-Count", strconv.FormatInt(result.Total, 10))
-		return c.JSON(http.StatusOK, map[string]any{
-			"items": views,
-			"total": result.Total,
+		var nextCursor *string
+		if result.NextCursor != nil {
+			encoded := result.NextCursor.Encode()
+			nextCursor = &encoded
+		}
+		payload := map[string]any{
+			"items":       views,
+			"next_cursor": nextCursor,
+		}
+		// Cursor pages are not counted; the total comes with the first page.
+		if after == nil {
+			c.Response().Header().Set("X-Total-Count", strconv.FormatInt(result.Total, 10))
+			payload["total"] = result.Total
+		}
+		return c.JSON(http.StatusOK, payload)
+	})
+
+	e.GET("/items/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid item id")
+		}
+		ctx := c.Request().Context()
+		user := currentUserID(c)
+
+		it, err := cfg.Store.GetItem(ctx, user, id)
+		if err != nil {
+			return err
+		}
+		neighbors, err := cfg.Store.GetItemNeighbors(ctx, user, id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapItemDetail(it, neighbors))
+	})
+
+	type updateItemReq struct {
+		Read    *bool `json:"read"`
+		Starred *bool `json:"starred"`
+	}
+
+	e.PATCH("/items/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid item id")
+		}
+		var req updateItemReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		if req.Read == nil && req.Starred == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "no changes requested")
+		}
+
+		it, err := cfg.Store.UpdateItemState(c.Request().Context(), store.UpdateItemStateParams{
+			UserID:  currentUserID(c),
+			ID:      id,
+			Read:    req.Read,
+			Starred: req.Starred,
 		})
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapItem(it))
 	})
 
 	e.GET("/search", func(c echo.Context) error {
@@ -176,8 +710,44 @@ func NewServer(cfg Config) *echo.Echo {
 		limit := parseInt(c.QueryParam("limit"), 20)
 		offset := parseInt(c.QueryParam("offset"), 0)
 		feedID := c.QueryParam("feed_id")
+		if feedID != "" {
+			if _, err := uuid.Parse(feedID); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid feed_id")
+			}
+		}
 		ctx := c.Request().Context()
-		res, err := cfg.Search.Search(ctx, query, limit, offset, search.SearchFilters{FeedID: feedID})
+		filters := search.SearchFilters{FeedID: feedID}
+		// A nil list leaves the feeds open; an empty one matches nothing.
+		var feedIDs []string
+		user := currentUserID(c)
+		if tags := tagParams(c); len(tags) > 0 {
+			ids, err := cfg.Store.ListTaggedFeedIDs(ctx, user, tags)
+			if err != nil {
+				return err
+			}
+			feedIDs = append([]string{}, ids...)
+		}
+		if user != "" {
+			subscribed, err := cfg.Store.ListSubscribedFeedIDs(ctx, user)
+			if err != nil {
+				return err
+			}
+			if feedIDs == nil {
+				feedIDs = append([]string{}, subscribed...)
+			} else {
+				feedIDs = intersectIDs(feedIDs, subscribed)
+			}
+		}
+		if feedIDs != nil && len(feedIDs) == 0 {
+			return c.JSON(http.StatusOK, search.SearchResponse{
+				Query:  query,
+				Limit:  limit,
+				Offset: offset,
+				Hits:   []search.Document{},
+			})
+		}
+		filters.FeedIDs = feedIDs
+		res, err := cfg.Search.Search(ctx, query, limit, offset, filters)
 		if err != nil {
 			return err
 		}
@@ -187,6 +757,269 @@ func NewServer(cfg Config) *echo.Echo {
 	return e
 }
 
+const errInvalidFeedURL = "url must be an absolute http(s) url"
+
+func validateFeedURL(raw string) error {
+	if raw == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "url required")
+	}
+	if !isFeedURL(raw) {
+		return echo.NewHTTPError(http.StatusBadRequest, errInvalidFeedURL)
+	}
+	return nil
+}
+
+func isFeedURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// resolveFeed probes rawURL for a feed, following a page's only advertised
+// feed. It returns the URL to subscribe to and its discovery; the discovery
+// has no Feed when a page advertises several feeds to choose from.
+func resolveFeed(ctx context.Context, d feedDiscoverer, rawURL string) (string, feed.Discovery, error) {
+	found, err := d.Discover(ctx, rawURL)
+	if err != nil || found.Feed != nil || len(found.Candidates) != 1 {
+		return rawURL, found, err
+	}
+
+	candidate := found.Candidates[0].URL
+	found, err = d.Discover(ctx, candidate)
+	if err == nil && found.Feed == nil {
+		err = fmt.Errorf("%w: %s does not serve a feed", feed.ErrNoFeed, candidate)
+	}
+	return candidate, found, err
+}
+
+func discoveryError(err error) error {
+	if errors.Is(err, feed.ErrNoFeed) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error()).SetInternal(err)
+	}
+	return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("could not fetch url: %v", err)).SetInternal(err)
+}
+
+// maxOPMLSize bounds imported OPML documents; a few thousand subscriptions
+// fit comfortably.
+const maxOPMLSize = 5 << 20
+
+// opmlUpload returns the OPML document posted either as the "file" field of
+// a multipart form or as the raw request body. Documents over maxOPMLSize
+// are rejected with 413 rather than truncated.
+func opmlUpload(c echo.Context) ([]byte, error) {
+	req := c.Request()
+	// Multipart framing gets some room on top of the document itself.
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxOPMLSize+64<<10)
+
+	var body io.Reader = req.Body
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		header, err := c.FormFile("file")
+		if err != nil {
+			if isTooLarge(err) {
+				return nil, opmlTooLarge(err)
+			}
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "file required").SetInternal(err)
+		}
+		if header.Size > maxOPMLSize {
+			return nil, opmlTooLarge(nil)
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		body = file
+	}
+
+	doc, err := io.ReadAll(io.LimitReader(body, maxOPMLSize+1))
+	if err != nil {
+		if isTooLarge(err) {
+			return nil, opmlTooLarge(err)
+		}
+		return nil, err
+	}
+	if len(doc) > maxOPMLSize {
+		return nil, opmlTooLarge(nil)
+	}
+	return doc, nil
+}
+
+func opmlTooLarge(err error) error {
+	return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("opml documents are limited to %d MiB", maxOPMLSize>>20)).SetInternal(err)
+}
+
+func isTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// normalizeCategory trims each folder of a "/"-separated category and drops
+// empty ones.
+func normalizeCategory(category string) string {
+	var parts []string
+	for _, part := range strings.Split(category, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+const maxTagNameLength = 64
+
+func validateTagName(raw string) (string, error) {
+	name := strings.TrimSpace(raw)
+	if name == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "tag name required")
+	}
+	if utf8.RuneCountInString(name) > maxTagNameLength {
+		return "", echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("tag name must be at most %d characters", maxTagNameLength))
+	}
+	return name, nil
+}
+
+// tagParams returns the non-empty tag query parameters; items from feeds
+// carrying any of them match.
+func tagParams(c echo.Context) []string {
+	var tags []string
+	for _, tag := range c.QueryParams()["tag"] {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// intersectIDs returns the IDs of a that are also in b, in the order of a.
+func intersectIDs(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, id := range b {
+		in[id] = true
+	}
+	out := []string{}
+	for _, id := range a {
+		if in[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+const maxTextFilterLength = 200
+
+// itemFilters reads the item filters shared by GET /items and the
+// syndication feeds: feed_id, tag, author, title, the published and
+// retrieved ranges, unread, starred and host.
+func itemFilters(c echo.Context) (store.FilterItemsParams, error) {
+	feedIDs := c.QueryParams()["feed_id"]
+	for _, id := range feedIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, "invalid feed_id")
+		}
+	}
+	params := store.FilterItemsParams{
+		UserID:  currentUserID(c),
+		FeedIDs: feedIDs,
+		Tags:    tagParams(c),
+		Author:  strings.TrimSpace(c.QueryParam("author")),
+		Title:   strings.TrimSpace(c.QueryParam("title")),
+	}
+	if err := parseRange(c, "published", &params.PublishedAfter, &params.PublishedBefore); err != nil {
+		return store.FilterItemsParams{}, err
+	}
+	if err := parseRange(c, "retrieved", &params.RetrievedAfter, &params.RetrievedBefore); err != nil {
+		return store.FilterItemsParams{}, err
+	}
+	if len(params.Author) > maxTextFilterLength || len(params.Title) > maxTextFilterLength {
+		return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("author and title must be at most %d bytes", maxTextFilterLength))
+	}
+	for _, flag := range []struct {
+		param string
+		dst   **bool
+	}{
+		{"unread", &params.Unread},
+		{"starred", &params.Starred},
+	} {
+		raw := c.QueryParam(flag.param)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be true or false", flag.param))
+		}
+		*flag.dst = &v
+	}
+	if host := strings.ToLower(strings.TrimSpace(c.QueryParam("host"))); host != "" {
+		if strings.ContainsAny(host, "/:?#@ ") {
+			return store.FilterItemsParams{}, echo.NewHTTPError(http.StatusBadRequest, "host must be a bare host name")
+		}
+		params.Host = host
+	}
+	return params, nil
+}
+
+// parseItemSort reads a sort parameter of the form field:direction.
+func parseItemSort(raw string) (store.ItemSortField, store.SortDirection, error) {
+	parts := strings.SplitN(raw, ":", 2)
+	if len(parts) != 2 {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid sort parameter")
+	}
+
+	field := strings.ToLower(strings.TrimSpace(parts[0]))
+	direction := strings.ToLower(strings.TrimSpace(parts[1]))
+
+	var sortField store.ItemSortField
+	switch field {
+	case string(store.ItemSortFieldPublishedAt):
+		sortField = store.ItemSortFieldPublishedAt
+	case string(store.ItemSortFieldRetrievedAt):
+		sortField = store.ItemSortFieldRetrievedAt
+	default:
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid sort field")
+	}
+
+	var sortDirection store.SortDirection
+	switch direction {
+	case string(store.SortDirectionAsc):
+		sortDirection = store.SortDirectionAsc
+	case string(store.SortDirectionDesc):
+		sortDirection = store.SortDirectionDesc
+	default:
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid sort direction")
+	}
+	return sortField, sortDirection, nil
+}
+
+// parseRange reads the <name>_after and <name>_before query parameters into
+// after and before. Values are RFC 3339 timestamps or dates, which stand for
+// midnight UTC.
+func parseRange(c echo.Context, name string, after, before *time.Time) error {
+	for _, bound := range []struct {
+		param string
+		dst   *time.Time
+	}{
+		{name + "_after", after},
+		{name + "_before", before},
+	} {
+		raw := strings.TrimSpace(c.QueryParam(bound.param))
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			t, err = time.Parse(time.DateOnly, raw)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s: expected an RFC 3339 timestamp or a YYYY-MM-DD date", bound.param))
+		}
+		*bound.dst = t.UTC()
+	}
+	if !after.IsZero() && !before.IsZero() && !after.Before(*before) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s_after must be before %s_before", name, name))
+	}
+	return nil
+}
+
 func parseInt(v string, def int) int {
 	if v == "" {
 		return def
@@ -226,19 +1059,142 @@ func requestLogger(service string) echo.MiddlewareFunc {
 }
 
 type feedView struct {
-	ID           string     `json:"id"`
-	URL          string     `json:"url"`
-	Title        string     `json:"title"`
-	ETag         *string    `json:"etag,omitempty"`
-	LastModified *string    `json:"last_modified,omitempty"`
-	LastCrawled  *time.Time `json:"last_crawled,omitempty"`
+	ID           string       `json:"id"`
+	URL          string       `json:"url"`
+	Title        string       `json:"title"`
+	Active       bool         `json:"active"`
+	Category     *string      `json:"category,omitempty"`
+	ETag         *string      `json:"etag,omitempty"`
+	LastModified *string      `json:"last_modified,omitempty"`
+	LastCrawled  *time.Time   `json:"last_crawled,omitempty"`
+	NextCrawlAt  *time.Time   `json:"next_crawl_at,omitempty"`
+	Backoff      *backoffView `json:"backoff,omitempty"`
+	// UnreadCount is only filled in by GET /feeds.
+	UnreadCount *int64 `json:"unread_count,omitempty"`
+
+	Status              string     `json:"status"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	LastStatus          *int32     `json:"last_status,omitempty"`
+	LastError           *string    `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	DeactivatedAt       *time.Time `json:"deactivated_at,omitempty"`
+	DeactivatedReason   *string    `json:"deactivated_reason,omitempty"`
+}
+
+const (
+	opmlStatusCreated   = "created"
+	opmlStatusDuplicate = "duplicate"
+	opmlStatusInvalid   = "invalid"
+	opmlStatusFailed    = "failed"
+)
+
+type opmlImportView struct {
+	Created    int              `json:"created"`
+	Duplicates int              `json:"duplicates"`
+	Invalid    int              `json:"invalid"`
+	Failed     int              `json:"failed"`
+	Results    []opmlResultView `json:"results"`
+}
+
+type opmlResultView struct {
+	URL      string `json:"url"`
+	Title    string `json:"title,omitempty"`
+	Category string `json:"category,omitempty"`
+	Status   string `json:"status"`
+	FeedID   string `json:"feed_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type candidateView struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type"`
+}
+
+func mapCandidates(candidates []feed.Candidate) []candidateView {
+	views := make([]candidateView, 0, len(candidates))
+	for _, c := range candidates {
+		views = append(views, candidateView{URL: c.URL, Title: c.Title, Type: c.Type})
+	}
+	return views
+}
+
+type feedPreviewView struct {
+	URL               string     `json:"url"`
+	Title             string     `json:"title"`
+	Description       string     `json:"description,omitempty"`
+	FeedType          string     `json:"feed_type"`
+	ItemCount         int        `json:"item_count"`
+	NewestPublishedAt *time.Time `json:"newest_published_at,omitempty"`
+	NewestUpdatedAt   *time.Time `json:"newest_updated_at,omitempty"`
+	Warnings          []string   `json:"warnings"`
+}
+
+func mapFeedPreview(feedURL string, s feed.Summary) feedPreviewView {
+	view := feedPreviewView{
+		URL:         feedURL,
+		Title:       s.Title,
+		Description: s.Description,
+		FeedType:    s.FeedType,
+		ItemCount:   s.ItemCount,
+		Warnings:    s.Warnings,
+	}
+	if view.Warnings == nil {
+		view.Warnings = []string{}
+	}
+	if !s.NewestPublished.IsZero() {
+		t := s.NewestPublished
+		view.NewestPublishedAt = &t
+	}
+	if !s.NewestUpdated.IsZero() {
+		t := s.NewestUpdated
+		view.NewestUpdatedAt = &t
+	}
+	return view
+}
+
+type backoffView struct {
+	Until               time.Time `json:"until"`
+	Active              bool      `json:"active"`
+	DurationSeconds     int64     `json:"duration_seconds"`
+	Reason              string    `json:"reason,omitempty"`
+	ConsecutiveFailures int32     `json:"consecutive_failures"`
+}
+
+const (
+	feedStatusHealthy  = "healthy"
+	feedStatusFailing  = "failing"
+	feedStatusUnknown  = "unknown"
+	feedStatusInactive = "inactive"
+)
+
+// feedStatus summarizes a feed's health: failing after any consecutive
+// failure, unknown until the first recorded fetch.
+func feedStatus(f store.Feed) string {
+	switch {
+	case !f.Active:
+		return feedStatusInactive
+	case f.ConsecutiveFailures > 0:
+		return feedStatusFailing
+	case f.LastSuccessAt.Valid:
+		return feedStatusHealthy
+	default:
+		return feedStatusUnknown
+	}
 }
 
 func mapFeed(f store.Feed) feedView {
 	view := feedView{
-		ID:    f.ID,
-		URL:   f.URL,
-		Title: f.Title,
+		ID:                  f.ID,
+		URL:                 f.URL,
+		Title:               f.Title,
+		Active:              f.Active,
+		Status:              feedStatus(f),
+		ConsecutiveFailures: f.ConsecutiveFailures,
+	}
+	if f.Category.Valid {
+		view.Category = &f.Category.String
 	}
 	if f.ETag.Valid {
 		view.ETag = &f.ETag.String
@@ -250,9 +1206,130 @@ func mapFeed(f store.Feed) feedView {
 		t := f.LastCrawled.Time.UTC()
 		view.LastCrawled = &t
 	}
+	if f.NextCrawlAt.Valid {
+		t := f.NextCrawlAt.Time.UTC()
+		view.NextCrawlAt = &t
+	}
+	if f.BackoffUntil.Valid {
+		view.Backoff = &backoffView{
+			Until:               f.BackoffUntil.Time.UTC(),
+			Active:              time.Now().Before(f.BackoffUntil.Time),
+			DurationSeconds:     int64(f.BackoffDuration / time.Second),
+			Reason:              f.BackoffReason.String,
+			ConsecutiveFailures: f.BackoffFailures,
+		}
+	}
+	if f.LastStatus.Valid {
+		view.LastStatus = &f.LastStatus.Int32
+	}
+	if f.LastError.Valid {
+		view.LastError = &f.LastError.String
+	}
+	if f.LastErrorAt.Valid {
+		t := f.LastErrorAt.Time.UTC()
+		view.LastErrorAt = &t
+	}
+	if f.LastSuccessAt.Valid {
+		t := f.LastSuccessAt.Time.UTC()
+		view.LastSuccessAt = &t
+	}
+	if f.DeactivatedAt.Valid {
+		t := f.DeactivatedAt.Time.UTC()
+		view.DeactivatedAt = &t
+	}
+	if f.DeactivatedReason.Valid {
+		view.DeactivatedReason = &f.DeactivatedReason.String
+	}
+	return view
+}
+
+type feedHealthView struct {
+	FeedID              string          `json:"feed_id"`
+	URL                 string          `json:"url"`
+	Status              string          `json:"status"`
+	ConsecutiveFailures int32           `json:"consecutive_failures"`
+	LastStatus          *int32          `json:"last_status,omitempty"`
+	LastError           *string         `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time      `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time      `json:"last_success_at,omitempty"`
+	DeactivatedAt       *time.Time      `json:"deactivated_at,omitempty"`
+	DeactivatedReason   *string         `json:"deactivated_reason,omitempty"`
+	Backoff             *backoffView    `json:"backoff,omitempty"`
+	Recent              []feedFetchView `json:"recent"`
+}
+
+type feedFetchView struct {
+	FetchedAt  time.Time `json:"fetched_at"`
+	Status     *int32    `json:"status,omitempty"`
+	Items      int32     `json:"items"`
+	Error      *string   `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+func mapFeedHealth(f store.Feed, fetches []store.FeedFetch) feedHealthView {
+	feed := mapFeed(f)
+	view := feedHealthView{
+		FeedID:              feed.ID,
+		URL:                 feed.URL,
+		Status:              feed.Status,
+		ConsecutiveFailures: feed.ConsecutiveFailures,
+		LastStatus:          feed.LastStatus,
+		LastError:           feed.LastError,
+		LastErrorAt:         feed.LastErrorAt,
+		LastSuccessAt:       feed.LastSuccessAt,
+		DeactivatedAt:       feed.DeactivatedAt,
+		DeactivatedReason:   feed.DeactivatedReason,
+		Backoff:             feed.Backoff,
+		Recent:              make([]feedFetchView, 0, len(fetches)),
+	}
+	for _, fetch := range fetches {
+		entry := feedFetchView{
+			FetchedAt:  fetch.FetchedAt.UTC(),
+			Items:      fetch.Items,
+			DurationMS: fetch.Duration.Milliseconds(),
+		}
+		if fetch.Status.Valid {
+			status := fetch.Status.Int32
+			entry.Status = &status
+		}
+		if fetch.Error.Valid {
+			msg := fetch.Error.String
+			entry.Error = &msg
+		}
+		view.Recent = append(view.Recent, entry)
+	}
 	return view
 }
 
+type tagView struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	FeedCount *int64    `json:"feed_count,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func mapTag(t store.Tag) tagView {
+	return tagView{ID: t.ID, Name: t.Name, CreatedAt: t.CreatedAt}
+}
+
+func mapTags(tags []store.Tag) []tagView {
+	views := make([]tagView, 0, len(tags))
+	for _, t := range tags {
+		views = append(views, mapTag(t))
+	}
+	return views
+}
+
+// mapTagCounts maps tags listed by ListTags, which carry feed counts.
+func mapTagCounts(tags []store.Tag) []tagView {
+	views := mapTags(tags)
+	for i := range views {
+		count := tags[i].FeedCount
+		views[i].FeedCount = &count
+	}
+	return views
+}
+
 type itemView struct {
 	ID          string     `json:"id"`
 	FeedID      string     `json:"feed_id"`
@@ -265,6 +1342,36 @@ type itemView struct {
 	ContentText string     `json:"content_text"`
 	PublishedAt *time.Time `json:"published_at,omitempty"`
 	RetrievedAt time.Time  `json:"retrieved_at"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+}
+
+// itemDetailView is an item with the IDs of the items listed around it,
+// newest first, across all feeds and within its own feed.
+type itemDetailView struct {
+	itemView
+	Neighbors struct {
+		Timeline neighborsView `json:"timeline"`
+		Feed     neighborsView `json:"feed"`
+	} `json:"neighbors"`
+}
+
+type neighborsView struct {
+	Previous *string `json:"previous"`
+	Next     *string `json:"next"`
+}
+
+func mapItemDetail(it store.Item, n store.ItemNeighbors) itemDetailView {
+	optional := func(id string) *string {
+		if id == "" {
+			return nil
+		}
+		return &id
+	}
+	view := itemDetailView{itemView: mapItem(it)}
+	view.Neighbors.Timeline = neighborsView{Previous: optional(n.Previous), Next: optional(n.Next)}
+	view.Neighbors.Feed = neighborsView{Previous: optional(n.FeedPrevious), Next: optional(n.FeedNext)}
+	return view
 }
 
 func mapItem(it store.Item) itemView {
@@ -277,6 +1384,8 @@ func mapItem(it store.Item) itemView {
 		ContentHTML: it.ContentHTML,
 		ContentText: it.ContentText,
 		RetrievedAt: it.RetrievedAt,
+		Read:        it.Read,
+		Starred:     it.Starred,
 	}
 	if it.GUID.Valid {
 		view.GUID = &it.GUID.String
@@ -1,27 +1,131 @@
 package httpx
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/labstack/echo/v4"
+	"github.com/mmcdole/gofeed"
+
+	"courier/internal/feed"
+	"courier/internal/opml"
+	"courier/internal/search"
 	"courier/internal/store"
 )
 
 type stubStore struct {
 	filterItemsFunc func(context.Context, store.FilterItemsParams) (store.FilterItemsResult, error)
+	listFeedsFunc   func(context.Context, string, bool) ([]store.Feed, error)
+	getFeedFunc     func(context.Context, string, string) (store.Feed, error)
+	fetchLogFunc    func(context.Context, string, int32) ([]store.FeedFetch, error)
+	insertFeedFunc  func(context.Context, store.InsertFeedParams) (store.Feed, error)
+	updateFeedFunc  func(context.Context, store.UpdateFeedParams) (store.Feed, error)
+	deleteFeedFunc  func(context.Context, string) error
+	unsubscribeFunc func(context.Context, string, string) (bool, error)
+	subscribedFunc  func(context.Context, string) ([]string, error)
+
+	listTagsFunc     func(context.Context, string) ([]store.Tag, error)
+	createTagFunc    func(context.Context, string, string) (store.Tag, error)
+	renameTagFunc    func(context.Context, string, string, string) (store.Tag, error)
+	deleteTagFunc    func(context.Context, string, string) error
+	listFeedTagsFunc func(context.Context, string, string) ([]store.Tag, error)
+	setFeedTagsFunc  func(context.Context, string, string, []string) ([]store.Tag, error)
+	taggedFeedsFunc  func(context.Context, string, []string) ([]string, error)
+
+	getItemFunc      func(context.Context, string, string) (store.Item, error)
+	itemNeighborFunc func(context.Context, string, string) (store.ItemNeighbors, error)
+	itemStateFunc    func(context.Context, store.UpdateItemStateParams) (store.Item, error)
+	markFeedReadFunc func(context.Context, string, string, time.Time) (int64, error)
+	unreadFunc       func(context.Context, string) (map[string]int64, error)
+
+	itemsBySeqFunc  func(context.Context, store.ListItemsBySeqParams) ([]store.Item, error)
+	unreadSeqsFunc  func(context.Context, string) ([]int64, error)
+	starredSeqsFunc func(context.Context, string) ([]int64, error)
+
+	createSavedSearchFunc func(context.Context, store.CreateSavedSearchParams) (store.SavedSearch, error)
+	getSavedSearchFunc    func(context.Context, string, string) (store.SavedSearch, error)
+	listSavedSearchesFunc func(context.Context, string) ([]store.SavedSearch, error)
+	updateSavedSearchFunc func(context.Context, store.UpdateSavedSearchParams) (store.SavedSearch, error)
+	deleteSavedSearchFunc func(context.Context, string, string) error
+	viewSavedSearchFunc   func(context.Context, string, string) error
+	savedMatchesFunc      func(context.Context, store.ListSavedSearchMatchesParams) ([]store.SavedSearchMatch, error)
+
+	createWebhookFunc func(context.Context, store.CreateWebhookParams) (store.Webhook, error)
+	getWebhookFunc    func(context.Context, string, string) (store.Webhook, error)
+	listWebhooksFunc  func(context.Context, string) ([]store.Webhook, error)
+	updateWebhookFunc func(context.Context, store.UpdateWebhookParams) (store.Webhook, error)
+	deleteWebhookFunc func(context.Context, string, string) error
+	deliveriesFunc    func(context.Context, store.ListWebhookDeliveriesParams) ([]store.WebhookDelivery, error)
+}
+
+func (s *stubStore) GetUserFeed(ctx context.Context, userID, id string) (store.Feed, error) {
+	if s.getFeedFunc != nil {
+		return s.getFeedFunc(ctx, userID, id)
+	}
+	return store.Feed{}, sql.ErrNoRows
+}
+
+func (s *stubStore) ListSubscribedFeedIDs(ctx context.Context, userID string) ([]string, error) {
+	if s.subscribedFunc != nil {
+		return s.subscribedFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) Unsubscribe(ctx context.Context, userID, id string) (bool, error) {
+	if s.unsubscribeFunc != nil {
+		return s.unsubscribeFunc(ctx, userID, id)
+	}
+	return false, sql.ErrNoRows
 }
 
-func (s *stubStore) ListFeeds(context.Context, bool) ([]store.Feed, error) {
+func (s *stubStore) ListFeedFetchLog(ctx context.Context, id string, limit int32) ([]store.FeedFetch, error) {
+	if s.fetchLogFunc != nil {
+		return s.fetchLogFunc(ctx, id, limit)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) ListUserFeeds(ctx context.Context, userID string, active bool) ([]store.Feed, error) {
+	if s.listFeedsFunc != nil {
+		return s.listFeedsFunc(ctx, userID, active)
+	}
 	return nil, nil
 }
 
-func (s *stubStore) InsertFeed(context.Context, string) (store.Feed, error) {
+func (s *stubStore) InsertFeed(ctx context.Context, params store.InsertFeedParams) (store.Feed, error) {
+	if s.insertFeedFunc != nil {
+		return s.insertFeedFunc(ctx, params)
+	}
 	return store.Feed{}, nil
 }
 
+func (s *stubStore) UpdateFeed(ctx context.Context, params store.UpdateFeedParams) (store.Feed, error) {
+	if s.updateFeedFunc != nil {
+		return s.updateFeedFunc(ctx, params)
+	}
+	return store.Feed{}, sql.ErrNoRows
+}
+
+func (s *stubStore) DeleteFeed(ctx context.Context, id string) error {
+	if s.deleteFeedFunc != nil {
+		return s.deleteFeedFunc(ctx, id)
+	}
+	return sql.ErrNoRows
+}
+
 func (s *stubStore) FilterItems(ctx context.Context, params store.FilterItemsParams) (store.FilterItemsResult, error) {
 	if s.filterItemsFunc != nil {
 		return s.filterItemsFunc(ctx, params)
@@ -29,6 +133,206 @@ func (s *stubStore) FilterItems(ctx context.Context, params store.FilterItemsPar
 	return store.FilterItemsResult{}, nil
 }
 
+func (s *stubStore) ListTags(ctx context.Context, userID string) ([]store.Tag, error) {
+	if s.listTagsFunc != nil {
+		return s.listTagsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) CreateTag(ctx context.Context, userID, name string) (store.Tag, error) {
+	if s.createTagFunc != nil {
+		return s.createTagFunc(ctx, userID, name)
+	}
+	return store.Tag{}, nil
+}
+
+func (s *stubStore) RenameTag(ctx context.Context, userID, id, name string) (store.Tag, error) {
+	if s.renameTagFunc != nil {
+		return s.renameTagFunc(ctx, userID, id, name)
+	}
+	return store.Tag{}, sql.ErrNoRows
+}
+
+func (s *stubStore) DeleteTag(ctx context.Context, userID, id string) error {
+	if s.deleteTagFunc != nil {
+		return s.deleteTagFunc(ctx, userID, id)
+	}
+	return sql.ErrNoRows
+}
+
+func (s *stubStore) ListFeedTags(ctx context.Context, userID, feedID string) ([]store.Tag, error) {
+	if s.listFeedTagsFunc != nil {
+		return s.listFeedTagsFunc(ctx, userID, feedID)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) SetFeedTags(ctx context.Context, userID, feedID string, names []string) ([]store.Tag, error) {
+	if s.setFeedTagsFunc != nil {
+		return s.setFeedTagsFunc(ctx, userID, feedID, names)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubStore) ListTaggedFeedIDs(ctx context.Context, userID string, names []string) ([]string, error) {
+	if s.taggedFeedsFunc != nil {
+		return s.taggedFeedsFunc(ctx, userID, names)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) GetItem(ctx context.Context, userID, id string) (store.Item, error) {
+	if s.getItemFunc != nil {
+		return s.getItemFunc(ctx, userID, id)
+	}
+	return store.Item{}, sql.ErrNoRows
+}
+
+func (s *stubStore) GetItemNeighbors(ctx context.Context, userID, id string) (store.ItemNeighbors, error) {
+	if s.itemNeighborFunc != nil {
+		return s.itemNeighborFunc(ctx, userID, id)
+	}
+	return store.ItemNeighbors{}, sql.ErrNoRows
+}
+
+func (s *stubStore) UpdateItemState(ctx context.Context, params store.UpdateItemStateParams) (store.Item, error) {
+	if s.itemStateFunc != nil {
+		return s.itemStateFunc(ctx, params)
+	}
+	return store.Item{}, sql.ErrNoRows
+}
+
+func (s *stubStore) MarkFeedRead(ctx context.Context, userID, feedID string, before time.Time) (int64, error) {
+	if s.markFeedReadFunc != nil {
+		return s.markFeedReadFunc(ctx, userID, feedID, before)
+	}
+	return 0, nil
+}
+
+func (s *stubStore) ListUnreadCounts(ctx context.Context, userID string) (map[string]int64, error) {
+	if s.unreadFunc != nil {
+		return s.unreadFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) ListItemsBySeq(ctx context.Context, params store.ListItemsBySeqParams) ([]store.Item, error) {
+	if s.itemsBySeqFunc != nil {
+		return s.itemsBySeqFunc(ctx, params)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) CountUserItems(ctx context.Context, userID string) (int64, error) {
+	return 0, nil
+}
+
+func (s *stubStore) ListUnreadItemSeqs(ctx context.Context, userID string) ([]int64, error) {
+	if s.unreadSeqsFunc != nil {
+		return s.unreadSeqsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) ListStarredItemSeqs(ctx context.Context, userID string) ([]int64, error) {
+	if s.starredSeqsFunc != nil {
+		return s.starredSeqsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) CreateSavedSearch(ctx context.Context, arg store.CreateSavedSearchParams) (store.SavedSearch, error) {
+	if s.createSavedSearchFunc != nil {
+		return s.createSavedSearchFunc(ctx, arg)
+	}
+	return store.SavedSearch{}, nil
+}
+
+func (s *stubStore) GetSavedSearch(ctx context.Context, userID, id string) (store.SavedSearch, error) {
+	if s.getSavedSearchFunc != nil {
+		return s.getSavedSearchFunc(ctx, userID, id)
+	}
+	return store.SavedSearch{}, sql.ErrNoRows
+}
+
+func (s *stubStore) ListSavedSearches(ctx context.Context, userID string) ([]store.SavedSearch, error) {
+	if s.listSavedSearchesFunc != nil {
+		return s.listSavedSearchesFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) UpdateSavedSearch(ctx context.Context, arg store.UpdateSavedSearchParams) (store.SavedSearch, error) {
+	if s.updateSavedSearchFunc != nil {
+		return s.updateSavedSearchFunc(ctx, arg)
+	}
+	return store.SavedSearch{}, sql.ErrNoRows
+}
+
+func (s *stubStore) DeleteSavedSearch(ctx context.Context, userID, id string) error {
+	if s.deleteSavedSearchFunc != nil {
+		return s.deleteSavedSearchFunc(ctx, userID, id)
+	}
+	return sql.ErrNoRows
+}
+
+func (s *stubStore) MarkSavedSearchViewed(ctx context.Context, userID, id string) error {
+	if s.viewSavedSearchFunc != nil {
+		return s.viewSavedSearchFunc(ctx, userID, id)
+	}
+	return sql.ErrNoRows
+}
+
+func (s *stubStore) ListSavedSearchMatches(ctx context.Context, arg store.ListSavedSearchMatchesParams) ([]store.SavedSearchMatch, error) {
+	if s.savedMatchesFunc != nil {
+		return s.savedMatchesFunc(ctx, arg)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubStore) CreateWebhook(ctx context.Context, arg store.CreateWebhookParams) (store.Webhook, error) {
+	if s.createWebhookFunc != nil {
+		return s.createWebhookFunc(ctx, arg)
+	}
+	return store.Webhook{}, nil
+}
+
+func (s *stubStore) GetWebhook(ctx context.Context, userID, id string) (store.Webhook, error) {
+	if s.getWebhookFunc != nil {
+		return s.getWebhookFunc(ctx, userID, id)
+	}
+	return store.Webhook{}, sql.ErrNoRows
+}
+
+func (s *stubStore) ListWebhooks(ctx context.Context, userID string) ([]store.Webhook, error) {
+	if s.listWebhooksFunc != nil {
+		return s.listWebhooksFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (s *stubStore) UpdateWebhook(ctx context.Context, arg store.UpdateWebhookParams) (store.Webhook, error) {
+	if s.updateWebhookFunc != nil {
+		return s.updateWebhookFunc(ctx, arg)
+	}
+	return store.Webhook{}, sql.ErrNoRows
+}
+
+func (s *stubStore) DeleteWebhook(ctx context.Context, userID, id string) error {
+	if s.deleteWebhookFunc != nil {
+		return s.deleteWebhookFunc(ctx, userID, id)
+	}
+	return sql.ErrNoRows
+}
+
+func (s *stubStore) ListWebhookDeliveries(ctx context.Context, arg store.ListWebhookDeliveriesParams) ([]store.WebhookDelivery, error) {
+	if s.deliveriesFunc != nil {
+		return s.deliveriesFunc(ctx, arg)
+	}
+	return nil, sql.ErrNoRows
+}
+
 func TestItemsHandlerValidPagination(t *testing.T) {
 	t.Parallel()
 
@@ -124,3 +428,1328 @@ func TestItemsHandlerNegativeOffset(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
 	}
 }
+
+func TestItemsHandlerCursorPagination(t *testing.T) {
+	t.Parallel()
+
+	cursor := store.ItemCursor{
+		SortField:     store.ItemSortFieldRetrievedAt,
+		SortDirection: store.SortDirectionAsc,
+		RetrievedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		ID:            "0b9f4b0e-7a4e-4c8e-9a43-0f1f2b3c4d5e",
+	}
+	var (
+		mu    sync.Mutex
+		calls []store.FilterItemsParams
+	)
+	stub := &stubStore{
+		filterItemsFunc: func(ctx context.Context, params store.FilterItemsParams) (store.FilterItemsResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, params)
+			if params.After == nil {
+				return store.FilterItemsResult{Items: []store.Item{{ID: "1"}}, Total: 2, NextCursor: &cursor}, nil
+			}
+			return store.FilterItemsResult{Items: []store.Item{{ID: "2"}}}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	var page struct {
+		Items      []itemView `json:"items"`
+		Total      *int64     `json:"total"`
+		NextCursor *string    `json:"next_cursor"`
+	}
+	rec := get("/items?limit=1&sort=retrieved_at:asc")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if page.Total == nil || *page.Total != 2 || page.NextCursor == nil {
+		t.Fatalf("expected a total and next cursor on the first page, got %s", rec.Body.String())
+	}
+
+	next := *page.NextCursor
+	page.Total, page.NextCursor = nil, nil
+	rec = get("/items?limit=1&cursor=" + next)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if page.Total != nil || page.NextCursor != nil {
+		t.Fatalf("expected the last cursor page without a total, got %s", rec.Body.String())
+	}
+	if rec.Header().Get("X-Total-Count") != "" {
+		t.Fatalf("expected no X-Total-Count header on cursor pages")
+	}
+	after := calls[1].After
+	if after == nil || after.ID != cursor.ID || !after.RetrievedAt.Equal(cursor.RetrievedAt) {
+		t.Fatalf("expected the decoded cursor passed to FilterItems, got %+v", calls[1])
+	}
+	if calls[1].SortField != store.ItemSortFieldRetrievedAt || calls[1].SortDirection != store.SortDirectionAsc {
+		t.Fatalf("expected the sort to default to the cursor's, got %+v", calls[1])
+	}
+
+	if rec := get("/items?cursor=" + next + "&offset=10"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for cursor with offset, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := get("/items?cursor=not-a-cursor"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a malformed cursor, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected invalid requests to be rejected before FilterItems, got %d calls", len(calls))
+	}
+}
+
+func TestItemsHandlerFilters(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		calls []store.FilterItemsParams
+	)
+	stub := &stubStore{
+		filterItemsFunc: func(ctx context.Context, params store.FilterItemsParams) (store.FilterItemsResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, params)
+			return store.FilterItemsResult{}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/items?published_after=2024-05-06&published_before=2024-05-13T00:00:00%2B02:00&author=%20Jane%20Doe&title=rust&host=WWW.Example.com")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	got := calls[0]
+	if !got.PublishedAfter.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected published_after: %s", got.PublishedAfter)
+	}
+	if !got.PublishedBefore.Equal(time.Date(2024, 5, 12, 22, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected published_before: %s", got.PublishedBefore)
+	}
+	if !got.RetrievedAfter.IsZero() || !got.RetrievedBefore.IsZero() {
+		t.Fatalf("expected no retrieved range, got %+v", got)
+	}
+	if got.Author != "Jane Doe" || got.Title != "rust" || got.Host != "www.example.com" {
+		t.Fatalf("unexpected text filters: %+v", got)
+	}
+
+	for _, path := range []string{
+		"/items?published_after=last-week",
+		"/items?retrieved_after=2024-05-13&retrieved_before=2024-05-06",
+		"/items?host=https://example.com/",
+		"/items?title=" + strings.Repeat("x", maxTextFilterLength+1),
+	} {
+		if rec := get(path); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for %s, got %d", http.StatusBadRequest, path, rec.Code)
+		}
+	}
+	if len(calls) != 1 {
+		t.Fatalf("expected invalid filters to be rejected before FilterItems, got %d calls", len(calls))
+	}
+}
+
+func TestItemHandlerIncludesNeighbors(t *testing.T) {
+	t.Parallel()
+
+	const (
+		id    = "0b9f4b0e-7a4e-4c8e-9a43-0f1f2b3c4d5e"
+		newer = "6c1d7e4a-3b2f-4f5e-8d9c-1a2b3c4d5e6f"
+	)
+	stub := &stubStore{
+		getItemFunc: func(ctx context.Context, _, got string) (store.Item, error) {
+			if got != id {
+				return store.Item{}, sql.ErrNoRows
+			}
+			return store.Item{ID: id, FeedID: "feed-1", Title: "Hello", ContentHTML: "<p>Hi</p>"}, nil
+		},
+		itemNeighborFunc: func(ctx context.Context, _, got string) (store.ItemNeighbors, error) {
+			return store.ItemNeighbors{Previous: newer, FeedNext: newer}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/"+id, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var got struct {
+		itemView
+		Neighbors map[string]map[string]*string `json:"neighbors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.ID != id || got.ContentHTML != "<p>Hi</p>" {
+		t.Fatalf("unexpected item: %s", rec.Body.String())
+	}
+	timeline, feed := got.Neighbors["timeline"], got.Neighbors["feed"]
+	if timeline["previous"] == nil || *timeline["previous"] != newer || timeline["next"] != nil {
+		t.Fatalf("unexpected timeline neighbors: %s", rec.Body.String())
+	}
+	if feed["previous"] != nil || feed["next"] == nil || *feed["next"] != newer {
+		t.Fatalf("unexpected feed neighbors: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/"+newer, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	var envelope errorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode error: %v", err)
+	}
+	if envelope.Error.Message != "resource not found" {
+		t.Fatalf("expected the uniform not found envelope, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/not-a-uuid", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestItemStateHandlers(t *testing.T) {
+	t.Parallel()
+
+	const (
+		itemID = "0b9f4b0e-7a4e-4c8e-9a43-0f1f2b3c4d5e"
+		feedID = "6c1d7e4a-3b2f-4f5e-8d9c-1a2b3c4d5e6f"
+	)
+	var (
+		mu      sync.Mutex
+		updates []store.UpdateItemStateParams
+		marks   []time.Time
+		filters []store.FilterItemsParams
+	)
+	stub := &stubStore{
+		itemStateFunc: func(ctx context.Context, params store.UpdateItemStateParams) (store.Item, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			updates = append(updates, params)
+			return store.Item{ID: params.ID, Read: *params.Read}, nil
+		},
+		getFeedFunc: func(ctx context.Context, _, got string) (store.Feed, error) {
+			if got != feedID {
+				return store.Feed{}, sql.ErrNoRows
+			}
+			return store.Feed{ID: feedID, Active: true}, nil
+		},
+		listFeedsFunc: func(ctx context.Context, _ string, active bool) ([]store.Feed, error) {
+			return []store.Feed{{ID: feedID, Active: true}, {ID: "feed-read", Active: true}}, nil
+		},
+		markFeedReadFunc: func(ctx context.Context, _, got string, before time.Time) (int64, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			marks = append(marks, before)
+			return 3, nil
+		},
+		unreadFunc: func(ctx context.Context, _ string) (map[string]int64, error) {
+			return map[string]int64{feedID: 7}, nil
+		},
+		filterItemsFunc: func(ctx context.Context, params store.FilterItemsParams) (store.FilterItemsResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			filters = append(filters, params)
+			return store.FilterItemsResult{}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPatch, "/items/"+itemID, `{"read":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var item itemView
+	if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !item.Read || len(updates) != 1 || updates[0].Starred != nil {
+		t.Fatalf("expected only the read state updated, got %+v and %+v", item, updates)
+	}
+	if rec := do(http.MethodPatch, "/items/"+itemID, `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an empty patch, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = do(http.MethodPost, "/feeds/"+feedID+"/mark-read", `{"before":"2024-05-10T12:00:00Z"}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"marked":3`) {
+		t.Fatalf("unexpected mark-read response %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/feeds/"+feedID+"/mark-read", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d without a body, got %d", http.StatusOK, rec.Code)
+	}
+	if len(marks) != 2 || !marks[0].Equal(time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)) || marks[1].IsZero() {
+		t.Fatalf("unexpected mark-read cutoffs: %v", marks)
+	}
+	if rec := do(http.MethodPost, "/feeds/"+itemID+"/mark-read", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for an unknown feed, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec = do(http.MethodGet, "/feeds", "")
+	var feeds []feedView
+	if err := json.Unmarshal(rec.Body.Bytes(), &feeds); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(feeds) != 2 || *feeds[0].UnreadCount != 7 || *feeds[1].UnreadCount != 0 {
+		t.Fatalf("unexpected unread counts: %s", rec.Body.String())
+	}
+
+	if rec := do(http.MethodGet, "/items?unread=true&starred=false", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if len(filters) != 1 || filters[0].Unread == nil || !*filters[0].Unread || filters[0].Starred == nil || *filters[0].Starred {
+		t.Fatalf("unexpected state filters: %+v", filters)
+	}
+	if rec := do(http.MethodGet, "/items?unread=maybe", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid flag, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestFeedsHandlerIncludesBackoff(t *testing.T) {
+	t.Parallel()
+
+	until := time.Now().Add(10 * time.Minute).UTC().Truncate(time.Second)
+	stub := &stubStore{
+		listFeedsFunc: func(context.Context, string, bool) ([]store.Feed, error) {
+			return []store.Feed{
+				{ID: "1", URL: "http://example.com/a"},
+				{
+					ID:              "2",
+					URL:             "http://example.com/b",
+					BackoffUntil:    sql.NullTime{Valid: true, Time: until},
+					BackoffDuration: 10 * time.Minute,
+					BackoffReason:   sql.NullString{Valid: true, String: "rate_limit"},
+					BackoffFailures: 3,
+				},
+			}, nil
+		},
+	}
+
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	req := httptest.NewRequest(http.MethodGet, "/feeds", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var payload []feedView
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 2 {
+		t.Fatalf("expected 2 feeds, got %d", len(payload))
+	}
+	if payload[0].Backoff != nil {
+		t.Fatalf("expected no backoff for healthy feed, got %+v", payload[0].Backoff)
+	}
+	backoff := payload[1].Backoff
+	if backoff == nil {
+		t.Fatalf("expected backoff for feed 2")
+	}
+	if !backoff.Active || !backoff.Until.Equal(until) || backoff.DurationSeconds != 600 || backoff.Reason != "rate_limit" || backoff.ConsecutiveFailures != 3 {
+		t.Fatalf("unexpected backoff: %+v", backoff)
+	}
+}
+
+func TestFeedsHandlerFiltersFailing(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubStore{
+		listFeedsFunc: func(context.Context, string, bool) ([]store.Feed, error) {
+			return []store.Feed{
+				{ID: "1", Active: true, LastSuccessAt: sql.NullTime{Valid: true, Time: time.Now()}},
+				{ID: "2", Active: true, ConsecutiveFailures: 3, LastError: sql.NullString{Valid: true, String: "unexpected status 500"}},
+				{ID: "3", Active: true},
+			}, nil
+		},
+	}
+
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	req := httptest.NewRequest(http.MethodGet, "/feeds?status=failing", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var payload []feedView
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0].ID != "2" {
+		t.Fatalf("expected only feed 2, got %+v", payload)
+	}
+	if payload[0].Status != "failing" || payload[0].LastError == nil {
+		t.Fatalf("unexpected failing feed view: %+v", payload[0])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/feeds?status=broken", nil)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for invalid status, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestFeedHealthHandler(t *testing.T) {
+	t.Parallel()
+
+	const feedID = "5b0e1e38-4a8b-4d43-9d52-6f7c3f1e2a10"
+	fetchedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	stub := &stubStore{
+		getFeedFunc: func(ctx context.Context, _, id string) (store.Feed, error) {
+			if id != feedID {
+				return store.Feed{}, sql.ErrNoRows
+			}
+			return store.Feed{
+				ID:                  feedID,
+				URL:                 "http://example.com/feed",
+				Active:              true,
+				ConsecutiveFailures: 2,
+				LastStatus:          sql.NullInt32{Valid: true, Int32: 502},
+				LastError:           sql.NullString{Valid: true, String: "transient fetch error"},
+				LastErrorAt:         sql.NullTime{Valid: true, Time: fetchedAt},
+			}, nil
+		},
+		fetchLogFunc: func(ctx context.Context, id string, limit int32) ([]store.FeedFetch, error) {
+			if limit != feedHealthHistory {
+				t.Fatalf("expected limit %d, got %d", feedHealthHistory, limit)
+			}
+			return []store.FeedFetch{{
+				FeedID:    feedID,
+				FetchedAt: fetchedAt,
+				Status:    sql.NullInt32{Valid: true, Int32: 502},
+				Error:     sql.NullString{Valid: true, String: "transient fetch error"},
+				Duration:  1500 * time.Millisecond,
+			}}, nil
+		},
+	}
+
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	req := httptest.NewRequest(http.MethodGet, "/feeds/"+feedID+"/health", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var payload feedHealthView
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if payload.Status != "failing" || payload.ConsecutiveFailures != 2 || payload.LastStatus == nil || *payload.LastStatus != 502 {
+		t.Fatalf("unexpected health payload: %+v", payload)
+	}
+	if len(payload.Recent) != 1 || payload.Recent[0].DurationMS != 1500 || payload.Recent[0].Error == nil {
+		t.Fatalf("unexpected recent fetches: %+v", payload.Recent)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/feeds/4b0e1e38-4a8b-4d43-9d52-6f7c3f1e2a10/health", nil)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for unknown feed, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/feeds/not-a-uuid/health", nil)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for invalid id, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestFeedsHandlerListsInactive(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubStore{
+		listFeedsFunc: func(ctx context.Context, _ string, active bool) ([]store.Feed, error) {
+			if active {
+				t.Fatalf("expected inactive feeds to be requested")
+			}
+			return []store.Feed{{
+				ID:                "1",
+				DeactivatedAt:     sql.NullTime{Valid: true, Time: time.Now()},
+				DeactivatedReason: sql.NullString{Valid: true, String: "gone: http status 410"},
+			}}, nil
+		},
+	}
+
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	req := httptest.NewRequest(http.MethodGet, "/feeds?status=inactive", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var payload []feedView
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0].Status != "inactive" || payload[0].DeactivatedReason == nil {
+		t.Fatalf("unexpected inactive feeds: %+v", payload)
+	}
+}
+
+type stubDiscoverer map[string]feed.Discovery
+
+func (s stubDiscoverer) Discover(ctx context.Context, url string) (feed.Discovery, error) {
+	res, ok := s[url]
+	if !ok {
+		return feed.Discovery{}, feed.ErrNoFeed
+	}
+	return res, nil
+}
+
+func TestCreateFeedDiscoversFeeds(t *testing.T) {
+	t.Parallel()
+
+	discoverer := stubDiscoverer{
+		"https://example.com/feed.xml": {URL: "https://example.com/feed.xml", Feed: &gofeed.Feed{Title: "Feed"}},
+		"https://single.example.com/": {
+			URL:        "https://single.example.com/",
+			Candidates: []feed.Candidate{{URL: "https://single.example.com/rss", Type: "application/rss+xml"}},
+		},
+		"https://single.example.com/rss": {URL: "https://single.example.com/rss", Feed: &gofeed.Feed{Title: "Single"}},
+		"https://multi.example.com/": {
+			URL: "https://multi.example.com/",
+			Candidates: []feed.Candidate{
+				{URL: "https://multi.example.com/rss", Title: "RSS", Type: "application/rss+xml"},
+				{URL: "https://multi.example.com/atom", Title: "Atom", Type: "application/atom+xml"},
+			},
+		},
+	}
+
+	var (
+		mu       sync.Mutex
+		inserted []string
+	)
+	stub := &stubStore{
+		insertFeedFunc: func(ctx context.Context, params store.InsertFeedParams) (store.Feed, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			inserted = append(inserted, params.URL)
+			return store.Feed{ID: "1", URL: params.URL, Title: params.Title}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test", Discoverer: discoverer})
+
+	post := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/feeds", strings.NewReader(`{"url":"`+url+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("https://example.com/feed.xml"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d for a feed url, got %d", http.StatusCreated, rec.Code)
+	}
+
+	rec := post("https://single.example.com/")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d for a single candidate, got %d", http.StatusCreated, rec.Code)
+	}
+	var created feedView
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.URL != "https://single.example.com/rss" || created.Title != "Single" {
+		t.Fatalf("expected candidate to be inserted, got %+v", created)
+	}
+
+	rec = post("https://multi.example.com/")
+	if rec.Code != http.StatusMultipleChoices {
+		t.Fatalf("expected status %d for several candidates, got %d", http.StatusMultipleChoices, rec.Code)
+	}
+	var choices struct {
+		Candidates []candidateView `json:"candidates"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &choices); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(choices.Candidates) != 2 || choices.Candidates[1].URL != "https://multi.example.com/atom" {
+		t.Fatalf("unexpected candidates: %+v", choices.Candidates)
+	}
+
+	if rec := post("https://nothing.example.com/"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d without a feed, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+	if rec := post("ftp://example.com/feed"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a non-http url, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"https://example.com/feed.xml", "https://single.example.com/rss"}
+	if len(inserted) != len(want) || inserted[0] != want[0] || inserted[1] != want[1] {
+		t.Fatalf("expected inserts %v, got %v", want, inserted)
+	}
+}
+
+func TestPreviewFeed(t *testing.T) {
+	t.Parallel()
+
+	published := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	discoverer := stubDiscoverer{
+		"https://example.com/feed.xml": {
+			URL: "https://example.com/feed.xml",
+			Feed: &gofeed.Feed{
+				Title:       "Example",
+				Description: "Posts",
+				FeedType:    "rss",
+				Items: []*gofeed.Item{
+					{Link: "https://example.com/a", PublishedParsed: &published},
+					{Title: "undated"},
+				},
+			},
+		},
+	}
+	stub := &stubStore{
+		insertFeedFunc: func(ctx context.Context, params store.InsertFeedParams) (store.Feed, error) {
+			t.Fatalf("preview must not insert feeds")
+			return store.Feed{}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test", Discoverer: discoverer})
+
+	req := httptest.NewRequest(http.MethodPost, "/feeds/preview", strings.NewReader(`{"url":"https://example.com/feed.xml"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var preview feedPreviewView
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if preview.Title != "Example" || preview.Description != "Posts" || preview.ItemCount != 2 {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if preview.NewestPublishedAt == nil || !preview.NewestPublishedAt.Equal(published) {
+		t.Fatalf("expected newest published %s, got %v", published, preview.NewestPublishedAt)
+	}
+	if len(preview.Warnings) != 2 {
+		t.Fatalf("expected warnings for the undated, unlinked item, got %q", preview.Warnings)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/feeds/preview", strings.NewReader(`{"url":"https://example.com/broken"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d for an unparseable url, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+type stubSearch struct {
+	mu           sync.Mutex
+	deletedFeeds []string
+	filters      []search.SearchFilters
+	hits         []search.Document
+}
+
+func (s *stubSearch) Health(context.Context) error {
+	return nil
+}
+
+func (s *stubSearch) Search(ctx context.Context, query string, limit, offset int, filters search.SearchFilters) (search.SearchResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filters = append(s.filters, filters)
+	return search.SearchResponse{Query: query, Limit: limit, Offset: offset, Hits: s.hits}, nil
+}
+
+func (s *stubSearch) DeleteFeedDocuments(ctx context.Context, feedID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deletedFeeds = append(s.deletedFeeds, feedID)
+	return nil
+}
+
+func TestFeedHandlersManageFeed(t *testing.T) {
+	t.Parallel()
+
+	const id = "0b9f4b0e-7a4e-4c8e-9a43-0f1f2b3c4d5e"
+	var (
+		mu      sync.Mutex
+		updates []store.UpdateFeedParams
+		deleted []string
+	)
+	stub := &stubStore{
+		getFeedFunc: func(ctx context.Context, _, got string) (store.Feed, error) {
+			if got != id {
+				return store.Feed{}, sql.ErrNoRows
+			}
+			return store.Feed{ID: id, URL: "https://example.com/feed", Title: "Example", Active: true}, nil
+		},
+		updateFeedFunc: func(ctx context.Context, params store.UpdateFeedParams) (store.Feed, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			updates = append(updates, params)
+			f := store.Feed{ID: params.ID, URL: "https://example.com/feed", Title: "Example", Active: true}
+			if params.Title != nil {
+				f.Title = *params.Title
+			}
+			if params.Active != nil {
+				f.Active = *params.Active
+			}
+			return f, nil
+		},
+		deleteFeedFunc: func(ctx context.Context, got string) error {
+			if got != id {
+				return sql.ErrNoRows
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			deleted = append(deleted, got)
+			return nil
+		},
+	}
+	index := &stubSearch{}
+	srv := NewServer(Config{Store: stub, Search: index, Service: "test"})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/feeds/"+id, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var got feedView
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.ID != id || got.Title != "Example" || !got.Active {
+		t.Fatalf("unexpected feed: %+v", got)
+	}
+
+	rec = do(http.MethodPatch, "/feeds/"+id, `{"title":"  Renamed ","active":false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.Title != "Renamed" || got.Active {
+		t.Fatalf("unexpected updated feed: %+v", got)
+	}
+	if rec := do(http.MethodPatch, "/feeds/"+id, `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an empty patch, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do(http.MethodPatch, "/feeds/"+id, `{"url":"mailto:someone@example.com"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid url, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	if rec := do(http.MethodDelete, "/feeds/"+id, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/feeds/1c6a3f0e-0000-4000-8000-000000000000", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for a missing feed, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := do(http.MethodGet, "/feeds/not-a-uuid", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid id, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(updates) != 1 || updates[0].Title == nil || *updates[0].Title != "Renamed" || updates[0].URL != nil {
+		t.Fatalf("unexpected updates: %+v", updates)
+	}
+	if len(deleted) != 1 || deleted[0] != id {
+		t.Fatalf("expected feed %s deleted, got %v", id, deleted)
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if len(index.deletedFeeds) != 1 || index.deletedFeeds[0] != id {
+		t.Fatalf("expected documents of feed %s deleted, got %v", id, index.deletedFeeds)
+	}
+}
+
+func TestOPMLImportAndExport(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		inserted []store.InsertFeedParams
+	)
+	stub := &stubStore{
+		insertFeedFunc: func(ctx context.Context, params store.InsertFeedParams) (store.Feed, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, prev := range inserted {
+				if prev.URL == params.URL {
+					return store.Feed{}, store.ErrFeedExists
+				}
+			}
+			inserted = append(inserted, params)
+			return store.Feed{ID: "feed-" + strconv.Itoa(len(inserted)), URL: params.URL}, nil
+		},
+		listFeedsFunc: func(ctx context.Context, _ string, active bool) ([]store.Feed, error) {
+			if !active {
+				return []store.Feed{{ID: "3", URL: "https://paused.example.com/rss"}}, nil
+			}
+			return []store.Feed{
+				{ID: "1", URL: "https://blog.rust-lang.org/feed.xml", Title: "Rust Blog"},
+				{ID: "2", URL: "https://go.dev/blog/feed.atom", Title: "The Go Blog", Category: sql.NullString{Valid: true, String: "Tech/Go"}},
+			}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	const doc = `<?xml version="1.0"?>
+<opml version="2.0"><body>
+  <outline text="Tech">
+    <outline text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/>
+    <outline text="Go Blog again" xmlUrl="https://go.dev/blog/feed.atom"/>
+  </outline>
+  <outline text="Broken" xmlUrl="feed.xml"/>
+</body></opml>`
+
+	req := httptest.NewRequest(http.MethodPost, "/opml", strings.NewReader(doc))
+	req.Header.Set(echo.HeaderContentType, "text/x-opml")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var report opmlImportView
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if report.Created != 1 || report.Duplicates != 1 || report.Invalid != 1 || len(report.Results) != 3 {
+		t.Fatalf("unexpected import report: %+v", report)
+	}
+	statuses := []string{report.Results[0].Status, report.Results[1].Status, report.Results[2].Status}
+	if statuses[0] != "created" || statuses[1] != "duplicate" || statuses[2] != "invalid" {
+		t.Fatalf("unexpected result statuses: %v", statuses)
+	}
+	mu.Lock()
+	first := inserted[0]
+	mu.Unlock()
+	if first.Title != "Go Blog" || !first.TitleLocked || first.Category != "Tech" {
+		t.Fatalf("expected outline title and folder to be kept, got %+v", first)
+	}
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, err := mw.CreateFormFile("file", "subscriptions.opml")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	_, _ = part.Write([]byte(`<opml version="2.0"><body><outline text="Rust" xmlUrl="https://blog.rust-lang.org/feed.xml"/></body></opml>`))
+	_ = mw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/opml", &form)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d for a multipart upload, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if report.Created != 1 {
+		t.Fatalf("unexpected multipart import report: %+v", report)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/opml", strings.NewReader("not opml"))
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid document, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/opml", nil)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	exported, err := opml.Parse(rec.Body)
+	if err != nil {
+		t.Fatalf("exported document does not parse: %v", err)
+	}
+	if len(exported) != 3 {
+		t.Fatalf("expected 3 exported subscriptions, got %+v", exported)
+	}
+	if exported[0].XMLURL != "https://go.dev/blog/feed.atom" || exported[0].Category != "Tech/Go" {
+		t.Fatalf("expected the Go blog inside its folder first, got %+v", exported[0])
+	}
+	if exported[2].Title != "https://paused.example.com/rss" {
+		t.Fatalf("expected untitled feeds to be exported under their url, got %+v", exported[2])
+	}
+}
+
+func TestOPMLImportRejectsOversizedDocuments(t *testing.T) {
+	t.Parallel()
+
+	srv := NewServer(Config{Store: &stubStore{}, Service: "test"})
+	oversized := `<opml version="2.0"><body>` + strings.Repeat(" ", maxOPMLSize) + `</body></opml>`
+
+	req := httptest.NewRequest(http.MethodPost, "/opml", strings.NewReader(oversized))
+	req.Header.Set(echo.HeaderContentType, "text/x-opml")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status %d for a raw upload, got %d: %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	}
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, err := mw.CreateFormFile("file", "subscriptions.opml")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	_, _ = part.Write([]byte(oversized))
+	_ = mw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/opml", &form)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status %d for a multipart upload, got %d: %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	}
+}
+
+func TestOPMLImportReportsStoreFailures(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubStore{
+		insertFeedFunc: func(ctx context.Context, params store.InsertFeedParams) (store.Feed, error) {
+			if params.URL == "https://broken.example.com/rss" {
+				return store.Feed{}, errors.New("connection reset")
+			}
+			return store.Feed{ID: "feed-1", URL: params.URL}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	const doc = `<opml version="2.0"><body>
+  <outline text="Broken" xmlUrl="https://broken.example.com/rss"/>
+  <outline text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/>
+</body></opml>`
+	req := httptest.NewRequest(http.MethodPost, "/opml", strings.NewReader(doc))
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var report opmlImportView
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if report.Failed != 1 || report.Created != 1 || len(report.Results) != 2 {
+		t.Fatalf("unexpected import report: %+v", report)
+	}
+	if report.Results[0].Status != "failed" || report.Results[0].Error == "" {
+		t.Fatalf("expected the failing feed to be reported, got %+v", report.Results[0])
+	}
+	if report.Results[1].Status != "created" {
+		t.Fatalf("expected the import to continue past the failure, got %+v", report.Results[1])
+	}
+}
+
+func TestTagHandlers(t *testing.T) {
+	t.Parallel()
+
+	const (
+		feedID = "0b9f4b0e-7a4e-4c8e-9a43-0f1f2b3c4d5e"
+		tagID  = "6c1d7e4a-3b2f-4f5e-8d9c-1a2b3c4d5e6f"
+	)
+	var (
+		mu      sync.Mutex
+		set     []string
+		filters []store.FilterItemsParams
+	)
+	stub := &stubStore{
+		createTagFunc: func(ctx context.Context, _, name string) (store.Tag, error) {
+			if strings.EqualFold(name, "security") {
+				return store.Tag{}, store.ErrTagExists
+			}
+			return store.Tag{ID: tagID, Name: name}, nil
+		},
+		listTagsFunc: func(ctx context.Context, _ string) ([]store.Tag, error) {
+			return []store.Tag{{ID: tagID, Name: "Go", FeedCount: 2}}, nil
+		},
+		getFeedFunc: func(ctx context.Context, _, got string) (store.Feed, error) {
+			if got != feedID {
+				return store.Feed{}, sql.ErrNoRows
+			}
+			return store.Feed{ID: feedID}, nil
+		},
+		setFeedTagsFunc: func(ctx context.Context, _, got string, names []string) ([]store.Tag, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			set = names
+			tags := make([]store.Tag, 0, len(names))
+			for _, name := range names {
+				tags = append(tags, store.Tag{ID: tagID, Name: name})
+			}
+			return tags, nil
+		},
+		taggedFeedsFunc: func(ctx context.Context, _ string, names []string) ([]string, error) {
+			if len(names) == 1 && names[0] == "go" {
+				return []string{feedID}, nil
+			}
+			return nil, nil
+		},
+		filterItemsFunc: func(ctx context.Context, params store.FilterItemsParams) (store.FilterItemsResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			filters = append(filters, params)
+			return store.FilterItemsResult{}, nil
+		},
+	}
+	index := &stubSearch{}
+	srv := NewServer(Config{Store: stub, Search: index, Service: "test"})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/tags", `{"name":" Go "}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rec.Code)
+	}
+	var created tagView
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Name != "Go" || created.FeedCount != nil {
+		t.Fatalf("unexpected tag: %+v", created)
+	}
+	if rec := do(http.MethodPost, "/tags", `{"name":"Security"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d for a duplicate tag, got %d", http.StatusConflict, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/tags", `{"name":"  "}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an empty name, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = do(http.MethodGet, "/tags", "")
+	var listed []tagView
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(listed) != 1 || listed[0].FeedCount == nil || *listed[0].FeedCount != 2 {
+		t.Fatalf("unexpected tags: %s", rec.Body.String())
+	}
+
+	rec = do(http.MethodPut, "/feeds/"+feedID+"/tags", `{"tags":["Go","go"," News "]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if len(set) != 2 || set[0] != "Go" || set[1] != "News" {
+		t.Fatalf("expected deduplicated tag names, got %q", set)
+	}
+
+	if rec := do(http.MethodGet, "/items?tag=go&tag=news", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if len(filters) != 1 || len(filters[0].Tags) != 2 || filters[0].Tags[1] != "news" {
+		t.Fatalf("expected tags passed to FilterItems, got %+v", filters)
+	}
+
+	if rec := do(http.MethodGet, "/search?q=x&tag=go", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if len(index.filters) != 1 || len(index.filters[0].FeedIDs) != 1 || index.filters[0].FeedIDs[0] != feedID {
+		t.Fatalf("expected search restricted to tagged feeds, got %+v", index.filters)
+	}
+	rec = do(http.MethodGet, "/search?q=x&tag=unused", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if len(index.filters) != 1 {
+		t.Fatalf("expected no search for a tag without feeds, got %+v", index.filters)
+	}
+}
+
+func TestSubscriptionsScopeToUser(t *testing.T) {
+	t.Parallel()
+
+	const (
+		feedA = "0b9f4b0e-7a4e-4c8e-9a43-0f1f2b3c4d5a"
+		feedB = "0b9f4b0e-7a4e-4c8e-9a43-0f1f2b3c4d5b"
+		feedC = "0b9f4b0e-7a4e-4c8e-9a43-0f1f2b3c4d5c"
+	)
+	users := &stubAuth{}
+	user, err := CreateUser(context.Background(), users, "jane", "correct horse", false)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	var (
+		mu       sync.Mutex
+		seen     []string
+		feedGone = []bool{false, true}
+	)
+	record := func(userID string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, userID)
+	}
+	stub := &stubStore{
+		listFeedsFunc: func(ctx context.Context, userID string, active bool) ([]store.Feed, error) {
+			record(userID)
+			return []store.Feed{{ID: feedA, Title: "Renamed by jane", Active: true}}, nil
+		},
+		unreadFunc: func(ctx context.Context, userID string) (map[string]int64, error) {
+			record(userID)
+			return nil, nil
+		},
+		getFeedFunc: func(ctx context.Context, userID, id string) (store.Feed, error) {
+			record(userID)
+			return store.Feed{ID: id}, nil
+		},
+		unsubscribeFunc: func(ctx context.Context, userID, id string) (bool, error) {
+			record(userID)
+			mu.Lock()
+			defer mu.Unlock()
+			gone := feedGone[0]
+			feedGone = feedGone[1:]
+			return gone, nil
+		},
+		subscribedFunc: func(ctx context.Context, userID string) ([]string, error) {
+			record(userID)
+			return []string{feedA, feedB}, nil
+		},
+		taggedFeedsFunc: func(ctx context.Context, userID string, names []string) ([]string, error) {
+			record(userID)
+			return []string{feedB, feedC}, nil
+		},
+		filterItemsFunc: func(ctx context.Context, params store.FilterItemsParams) (store.FilterItemsResult, error) {
+			record(params.UserID)
+			return store.FilterItemsResult{}, nil
+		},
+	}
+	searcher := &stubSearch{}
+	srv := NewServer(Config{Store: stub, Search: searcher, Service: "test", Auth: users})
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"jane","password":"correct horse"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	var session sessionView
+	if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	for _, path := range []string{"/feeds", "/items", "/search?q=rust&tag=go"} {
+		if rec := do(http.MethodGet, path, session.Token); rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d: %s", path, http.StatusOK, rec.Code, rec.Body.String())
+		}
+	}
+	if len(searcher.filters) != 1 || len(searcher.filters[0].FeedIDs) != 1 || searcher.filters[0].FeedIDs[0] != feedB {
+		t.Fatalf("expected search limited to subscribed tagged feeds, got %+v", searcher.filters)
+	}
+	// A feed_id crafted to rewrite the filter must not reach the index.
+	injected := "/search?q=rust&feed_id=" + url.QueryEscape(`x" OR feed_id EXISTS OR feed_id = "y`)
+	if rec := do(http.MethodGet, injected, session.Token); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an injected feed_id, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if len(searcher.filters) != 1 {
+		t.Fatalf("expected the injected search not to run, got %+v", searcher.filters)
+	}
+
+	// Another user still subscribes, so the feed and its documents stay.
+	if rec := do(http.MethodDelete, "/feeds/"+feedA, session.Token); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if len(searcher.deletedFeeds) != 0 {
+		t.Fatalf("expected a shared feed's documents to be kept, deleted documents of %v", searcher.deletedFeeds)
+	}
+	// The last subscriber removes the feed.
+	if rec := do(http.MethodDelete, "/feeds/"+feedA, session.Token); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if len(searcher.deletedFeeds) != 1 {
+		t.Fatalf("expected the last unsubscribe to delete the feed's documents, deleted documents of %v", searcher.deletedFeeds)
+	}
+
+	for _, got := range seen {
+		if got != user.ID {
+			t.Fatalf("expected every store call to act for %s, got %v", user.ID, seen)
+		}
+	}
+}
+
+func TestTagsScopeToUser(t *testing.T) {
+	t.Parallel()
+
+	const feedID = "0b9f4b0e-7a4e-4c8e-9a43-0f1f2b3c4d5a"
+	users := &stubAuth{}
+	jane, err := CreateUser(context.Background(), users, "jane", "correct horse", false)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := CreateUser(context.Background(), users, "joe", "battery staple", false); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	// The stub keeps each user's tags apart the way the store does, so the
+	// test fails if a handler drops the caller's ID.
+	var (
+		mu     sync.Mutex
+		tags   = map[string][]store.Tag{}
+		tagged = map[string][]string{}
+	)
+	stub := &stubStore{
+		createTagFunc: func(ctx context.Context, userID, name string) (store.Tag, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, tag := range tags[userID] {
+				if strings.EqualFold(tag.Name, name) {
+					return store.Tag{}, store.ErrTagExists
+				}
+			}
+			tag := store.Tag{ID: userID + "-" + strconv.Itoa(len(tags[userID])), Name: name}
+			tags[userID] = append(tags[userID], tag)
+			return tag, nil
+		},
+		listTagsFunc: func(ctx context.Context, userID string) ([]store.Tag, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return tags[userID], nil
+		},
+		setFeedTagsFunc: func(ctx context.Context, userID, id string, names []string) ([]store.Tag, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			tagged[userID] = append(tagged[userID], id)
+			return nil, nil
+		},
+		taggedFeedsFunc: func(ctx context.Context, userID string, names []string) ([]string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return tagged[userID], nil
+		},
+		subscribedFunc: func(ctx context.Context, userID string) ([]string, error) {
+			return []string{feedID}, nil
+		},
+	}
+	searcher := &stubSearch{}
+	srv := NewServer(Config{Store: stub, Search: searcher, Service: "test", Auth: users})
+
+	login := func(username, password string) string {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"`+username+`","password":"`+password+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		var session sessionView
+		if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return session.Token
+	}
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	janeToken := login("jane", "correct horse")
+	joeToken := login("joe", "battery staple")
+
+	if rec := do(http.MethodPost, "/tags", `{"name":"Go"}`, janeToken); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/tags", `{"name":"go"}`, joeToken); rec.Code != http.StatusCreated {
+		t.Fatalf("expected joe to create his own go tag, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/feeds/"+feedID+"/tags", `{"tags":["Go"]}`, janeToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/tags", "", joeToken)
+	var listed []tagView
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(listed) != 1 || listed[0].Name != "go" {
+		t.Fatalf("expected joe to see only his own tag, got %s", rec.Body.String())
+	}
+
+	if rec := do(http.MethodGet, "/search?q=x&tag=go", "", joeToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if len(searcher.filters) != 0 {
+		t.Fatalf("expected jane's tags not to match joe's search, got %+v", searcher.filters)
+	}
+	if rec := do(http.MethodGet, "/search?q=x&tag=go", "", janeToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if len(searcher.filters) != 1 || len(searcher.filters[0].FeedIDs) != 1 || searcher.filters[0].FeedIDs[0] != feedID {
+		t.Fatalf("expected jane's search restricted to her tagged feed, got %+v", searcher.filters)
+	}
+	if got := tagged[jane.ID]; len(got) != 1 {
+		t.Fatalf("expected jane's feed tags stored under her ID, got %v", tagged)
+	}
+}
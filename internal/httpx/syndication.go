@@ -0,0 +1,196 @@
+package httpx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/search"
+	"courier/internal/store"
+	"courier/internal/syndication"
+)
+
+const defaultSyndicationLimit = 50
+
+// registerSyndicationRoutes serves item listings as Atom, RSS and JSON Feed
+// documents. They take the filters of GET /items plus q, which narrows the
+// listing to search hits.
+func registerSyndicationRoutes(e *echo.Echo, cfg Config) {
+	for _, format := range []struct {
+		path        string
+		contentType string
+		write       func(io.Writer, syndication.Feed) error
+	}{
+		{"/feeds.atom", "application/atom+xml; charset=utf-8", syndication.WriteAtom},
+		{"/feeds.rss", "application/rss+xml; charset=utf-8", syndication.WriteRSS},
+		{"/feeds.json", "application/feed+json; charset=utf-8", syndication.WriteJSON},
+	} {
+		e.GET(format.path, func(c echo.Context) error {
+			feed, err := syndicationFeed(c, cfg)
+			if err != nil {
+				return err
+			}
+			var body bytes.Buffer
+			if err := format.write(&body, feed); err != nil {
+				return err
+			}
+
+			sum := sha256.Sum256(body.Bytes())
+			etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+			header := c.Response().Header()
+			header.Set("ETag", etag)
+			if !feed.Updated.IsZero() {
+				header.Set(echo.HeaderLastModified, feed.Updated.UTC().Format(http.TimeFormat))
+			}
+			if notModified(c.Request(), etag, feed.Updated) {
+				return c.NoContent(http.StatusNotModified)
+			}
+			return c.Blob(http.StatusOK, format.contentType, body.Bytes())
+		})
+	}
+}
+
+// syndicationFeed loads the items a feed request asks for. Items are sorted
+// as GET /items sorts them, newest published first by default.
+func syndicationFeed(c echo.Context, cfg Config) (syndication.Feed, error) {
+	limit := parseInt(c.QueryParam("limit"), defaultSyndicationLimit)
+	if limit < 0 {
+		return syndication.Feed{}, echo.NewHTTPError(http.StatusBadRequest, "limit must be non-negative")
+	}
+	if limit > maxItemsLimit {
+		limit = maxItemsLimit
+	}
+	params, err := itemFilters(c)
+	if err != nil {
+		return syndication.Feed{}, err
+	}
+	sortParam := c.QueryParam("sort")
+	if sortParam == "" {
+		sortParam = "published_at:desc"
+	}
+	params.SortField, params.SortDirection, err = parseItemSort(sortParam)
+	if err != nil {
+		return syndication.Feed{}, err
+	}
+	params.Limit = int32(limit)
+
+	req := c.Request()
+	feed := syndication.Feed{
+		Title:   "Courier",
+		FeedURL: c.Scheme() + "://" + req.Host + req.URL.RequestURI(),
+	}
+	query := strings.TrimSpace(c.QueryParam("q"))
+	if query != "" {
+		feed.Title = "Courier search: " + query
+		ids, err := searchItemIDs(c, cfg, query, params)
+		if err != nil {
+			return syndication.Feed{}, err
+		}
+		if len(ids) == 0 {
+			return feed, nil
+		}
+		params.IDs = ids
+	}
+
+	result, err := cfg.Store.FilterItems(req.Context(), params)
+	if err != nil {
+		return syndication.Feed{}, err
+	}
+	feed.Entries = make([]syndication.Entry, 0, len(result.Items))
+	for _, it := range result.Items {
+		entry := syndication.Entry{
+			ID:          it.ID,
+			Title:       it.Title,
+			URL:         it.URL,
+			Author:      it.Author.String,
+			ContentHTML: it.ContentHTML,
+			ContentText: it.ContentText,
+			Updated:     it.RetrievedAt,
+		}
+		if it.PublishedAt.Valid {
+			entry.Published = it.PublishedAt.Time
+		}
+		if it.RetrievedAt.After(feed.Updated) {
+			feed.Updated = it.RetrievedAt
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed, nil
+}
+
+// searchItemIDs returns the IDs of the items matching query, scoped like
+// GET /search to the requested feeds and tags and the user's subscriptions.
+// The remaining filters are applied to the hits by FilterItems.
+func searchItemIDs(c echo.Context, cfg Config, query string, params store.FilterItemsParams) ([]string, error) {
+	ctx := c.Request().Context()
+	// A nil list leaves the feeds open; an empty one matches nothing.
+	var feedIDs []string
+	if len(params.FeedIDs) > 0 {
+		feedIDs = append([]string{}, params.FeedIDs...)
+	}
+	if len(params.Tags) > 0 {
+		tagged, err := cfg.Store.ListTaggedFeedIDs(ctx, params.UserID, params.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if feedIDs == nil {
+			feedIDs = append([]string{}, tagged...)
+		} else {
+			feedIDs = intersectIDs(feedIDs, tagged)
+		}
+	}
+	if params.UserID != "" {
+		subscribed, err := cfg.Store.ListSubscribedFeedIDs(ctx, params.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if feedIDs == nil {
+			feedIDs = append([]string{}, subscribed...)
+		} else {
+			feedIDs = intersectIDs(feedIDs, subscribed)
+		}
+	}
+	if feedIDs != nil && len(feedIDs) == 0 {
+		return nil, nil
+	}
+
+	// Fetch as many hits as a page can hold, since the other filters may
+	// drop some of them.
+	res, err := cfg.Search.Search(ctx, query, maxItemsLimit, 0, search.SearchFilters{FeedIDs: feedIDs})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids, nil
+}
+
+// notModified reports whether the client's cached copy is current. As in
+// net/http, If-None-Match takes precedence over If-Modified-Since.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get(echo.HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
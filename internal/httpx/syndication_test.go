@@ -0,0 +1,115 @@
+package httpx
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/search"
+	"courier/internal/store"
+)
+
+func TestSyndicationFeeds(t *testing.T) {
+	t.Parallel()
+
+	const feedID = "11111111-1111-1111-1111-111111111111"
+	retrieved := time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC)
+	item := store.Item{
+		ID:          "22222222-2222-2222-2222-222222222222",
+		FeedID:      feedID,
+		URL:         "https://go.dev/blog/generics",
+		Title:       "Generics",
+		Author:      sql.NullString{String: "Jane Doe", Valid: true},
+		ContentHTML: "<p>Type parameters</p>",
+		PublishedAt: sql.NullTime{Time: retrieved.Add(-time.Hour), Valid: true},
+		RetrievedAt: retrieved,
+	}
+
+	var (
+		mu      sync.Mutex
+		filters []store.FilterItemsParams
+	)
+	stub := &stubStore{
+		filterItemsFunc: func(ctx context.Context, params store.FilterItemsParams) (store.FilterItemsResult, error) {
+			mu.Lock()
+			filters = append(filters, params)
+			mu.Unlock()
+			return store.FilterItemsResult{Items: []store.Item{item}}, nil
+		},
+	}
+	searcher := &stubSearch{hits: []search.Document{{ID: item.ID, FeedID: feedID}}}
+	srv := NewServer(Config{Store: stub, Search: searcher, Service: "test"})
+
+	do := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for path, contentType := range map[string]string{
+		"/feeds.atom": "application/atom+xml",
+		"/feeds.rss":  "application/rss+xml",
+		"/feeds.json": "application/feed+json",
+	} {
+		rec := do(path+"?tag=go&unread=true", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d: %s", path, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, contentType) {
+			t.Fatalf("GET %s: expected content type %s, got %s", path, contentType, got)
+		}
+		if !strings.Contains(rec.Body.String(), "https://go.dev/blog/generics") {
+			t.Fatalf("GET %s: expected the item in the feed, got %s", path, rec.Body.String())
+		}
+		if got := rec.Header().Get(echo.HeaderLastModified); got != "Thu, 02 May 2024 08:00:00 GMT" {
+			t.Fatalf("GET %s: unexpected Last-Modified %q", path, got)
+		}
+	}
+	if got := filters[len(filters)-1]; len(got.Tags) != 1 || got.Unread == nil || !*got.Unread || got.Limit != 50 || got.SortField != store.ItemSortFieldPublishedAt {
+		t.Fatalf("unexpected filter: %+v", got)
+	}
+
+	rec := do("/feeds.atom", nil)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected an ETag")
+	}
+	if rec := do("/feeds.atom", http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("expected status %d for a matching ETag, got %d", http.StatusNotModified, rec.Code)
+	}
+	if rec := do("/feeds.atom", http.Header{"If-None-Match": {`"stale"`}}); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d for a stale ETag, got %d", http.StatusOK, rec.Code)
+	}
+	if rec := do("/feeds.atom", http.Header{"If-Modified-Since": {"Thu, 02 May 2024 08:00:00 GMT"}}); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected status %d when unmodified, got %d", http.StatusNotModified, rec.Code)
+	}
+	if rec := do("/feeds.atom", http.Header{"If-Modified-Since": {"Thu, 02 May 2024 07:00:00 GMT"}}); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d when modified, got %d", http.StatusOK, rec.Code)
+	}
+
+	rec = do("/feeds.json?q=generics&feed_id="+feedID, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Courier search: generics") {
+		t.Fatalf("unexpected search feed %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := searcher.filters[len(searcher.filters)-1]; len(got.FeedIDs) != 1 || got.FeedIDs[0] != feedID {
+		t.Fatalf("expected the search limited to the requested feed, got %+v", got)
+	}
+	if got := filters[len(filters)-1]; len(got.IDs) != 1 || got.IDs[0] != item.ID {
+		t.Fatalf("expected the hits passed to FilterItems, got %+v", got)
+	}
+
+	if rec := do("/feeds.rss?feed_id=nope", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a bad feed_id, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,324 @@
+package httpx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/netguard"
+	"courier/internal/store"
+)
+
+const (
+	minWebhookSecretLength = 16
+	maxWebhookSecretLength = 256
+	maxWebhookKeywords     = 50
+)
+
+type webhookReq struct {
+	URL      *string  `json:"url"`
+	Secret   *string  `json:"secret"`
+	FeedIDs  []string `json:"feed_ids"`
+	Keywords []string `json:"keywords"`
+	Active   *bool    `json:"active"`
+}
+
+// registerWebhookRoutes serves webhook subscriptions and their delivery
+// log. The fetcher queues and sends the deliveries; see the README for the
+// payload and signature.
+func registerWebhookRoutes(e *echo.Echo, cfg Config) {
+	e.GET("/webhooks", func(c echo.Context) error {
+		hooks, err := cfg.Store.ListWebhooks(c.Request().Context(), currentUserID(c))
+		if err != nil {
+			return err
+		}
+		views := make([]webhookView, 0, len(hooks))
+		for _, h := range hooks {
+			views = append(views, mapWebhook(h))
+		}
+		return c.JSON(http.StatusOK, views)
+	})
+
+	// The secret is only returned here, when the webhook is created.
+	e.POST("/webhooks", func(c echo.Context) error {
+		var req webhookReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		if req.URL == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "url required")
+		}
+		if req.Active != nil && !*req.Active {
+			return echo.NewHTTPError(http.StatusBadRequest, "webhooks are created active; PATCH active to pause one")
+		}
+		if err := validateWebhook(&req, cfg.AllowedNetworks); err != nil {
+			return err
+		}
+		secret := ""
+		if req.Secret != nil {
+			secret = *req.Secret
+		} else {
+			generated, err := newWebhookSecret()
+			if err != nil {
+				return err
+			}
+			secret = generated
+		}
+		hook, err := cfg.Store.CreateWebhook(c.Request().Context(), store.CreateWebhookParams{
+			UserID:   currentUserID(c),
+			URL:      *req.URL,
+			Secret:   secret,
+			FeedIDs:  req.FeedIDs,
+			Keywords: req.Keywords,
+		})
+		if err != nil {
+			return err
+		}
+		view := mapWebhook(hook)
+		view.Secret = hook.Secret
+		return c.JSON(http.StatusCreated, view)
+	})
+
+	e.GET("/webhooks/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook id")
+		}
+		hook, err := cfg.Store.GetWebhook(c.Request().Context(), currentUserID(c), id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapWebhook(hook))
+	})
+
+	e.PATCH("/webhooks/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook id")
+		}
+		var req webhookReq
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid payload")
+		}
+		if req.URL == nil && req.Secret == nil && req.FeedIDs == nil && req.Keywords == nil && req.Active == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "no changes requested")
+		}
+		if err := validateWebhook(&req, cfg.AllowedNetworks); err != nil {
+			return err
+		}
+		hook, err := cfg.Store.UpdateWebhook(c.Request().Context(), store.UpdateWebhookParams{
+			UserID:   currentUserID(c),
+			ID:       id,
+			URL:      req.URL,
+			Secret:   req.Secret,
+			FeedIDs:  req.FeedIDs,
+			Keywords: req.Keywords,
+			Active:   req.Active,
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, mapWebhook(hook))
+	})
+
+	e.DELETE("/webhooks/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook id")
+		}
+		if err := cfg.Store.DeleteWebhook(c.Request().Context(), currentUserID(c), id); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.GET("/webhooks/:id/deliveries", func(c echo.Context) error {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook id")
+		}
+		limit := parseInt(c.QueryParam("limit"), 50)
+		if limit < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be non-negative")
+		}
+		if limit > maxItemsLimit {
+			limit = maxItemsLimit
+		}
+		offset := parseInt(c.QueryParam("offset"), 0)
+		if offset < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "offset must be non-negative")
+		}
+		status := c.QueryParam("status")
+		switch status {
+		case "", store.WebhookPending, store.WebhookDelivered, store.WebhookFailed:
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "status must be pending, delivered or failed")
+		}
+
+		deliveries, err := cfg.Store.ListWebhookDeliveries(c.Request().Context(), store.ListWebhookDeliveriesParams{
+			UserID: currentUserID(c),
+			ID:     id,
+			Status: status,
+			Limit:  int32(limit),
+			Offset: int32(offset),
+		})
+		if err != nil {
+			return err
+		}
+		views := make([]webhookDeliveryView, 0, len(deliveries))
+		for _, d := range deliveries {
+			views = append(views, mapWebhookDelivery(d))
+		}
+		return c.JSON(http.StatusOK, views)
+	})
+}
+
+// validateWebhook trims and checks the fields present in req. URLs naming a
+// private address outside allow are refused here; hostnames are checked
+// again by the fetcher each time it connects.
+func validateWebhook(req *webhookReq, allow netguard.Allowlist) error {
+	if req.URL != nil {
+		u := strings.TrimSpace(*req.URL)
+		if u == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "url required")
+		}
+		if !isFeedURL(u) {
+			return echo.NewHTTPError(http.StatusBadRequest, errInvalidFeedURL)
+		}
+		if !permittedWebhookHost(u, allow) {
+			return echo.NewHTTPError(http.StatusBadRequest, "url must point at a public address")
+		}
+		req.URL = &u
+	}
+	if req.Secret != nil {
+		if n := len(*req.Secret); n < minWebhookSecretLength || n > maxWebhookSecretLength {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("secret must be %d to %d bytes", minWebhookSecretLength, maxWebhookSecretLength))
+		}
+	}
+	for _, id := range req.FeedIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid feed_ids")
+		}
+	}
+	if len(req.Keywords) > maxWebhookKeywords {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d keywords allowed", maxWebhookKeywords))
+	}
+	for i, keyword := range req.Keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "keywords must not be empty")
+		}
+		if len(keyword) > maxTextFilterLength {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("keywords must be at most %d bytes", maxTextFilterLength))
+		}
+		req.Keywords[i] = keyword
+	}
+	return nil
+}
+
+// permittedWebhookHost reports whether raw's host may receive deliveries:
+// any hostname but localhost, or an address allow permits.
+func permittedWebhookHost(raw string, allow netguard.Allowlist) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		host = "127.0.0.1"
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return true
+	}
+	return allow.Permits(addr)
+}
+
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+type webhookView struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	FeedIDs   []string  `json:"feed_ids"`
+	Keywords  []string  `json:"keywords"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func mapWebhook(h store.Webhook) webhookView {
+	view := webhookView{
+		ID:        h.ID,
+		URL:       h.URL,
+		FeedIDs:   h.FeedIDs,
+		Keywords:  h.Keywords,
+		Active:    h.Active,
+		CreatedAt: h.CreatedAt,
+	}
+	if view.FeedIDs == nil {
+		view.FeedIDs = []string{}
+	}
+	if view.Keywords == nil {
+		view.Keywords = []string{}
+	}
+	return view
+}
+
+type webhookDeliveryView struct {
+	ID             string          `json:"id"`
+	ItemID         *string         `json:"item_id,omitempty"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	ResponseStatus *int32          `json:"response_status,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func mapWebhookDelivery(d store.WebhookDelivery) webhookDeliveryView {
+	view := webhookDeliveryView{
+		ID:        d.ID,
+		Event:     d.Event,
+		Status:    d.Status,
+		Attempts:  d.Attempts,
+		CreatedAt: d.CreatedAt,
+		Payload:   json.RawMessage(d.Payload),
+	}
+	if d.ItemID != "" {
+		view.ItemID = &d.ItemID
+	}
+	if d.ResponseStatus.Valid {
+		view.ResponseStatus = &d.ResponseStatus.Int32
+	}
+	if d.LastError.Valid {
+		view.LastError = &d.LastError.String
+	}
+	if d.LastAttemptAt.Valid {
+		t := d.LastAttemptAt.Time.UTC()
+		view.LastAttemptAt = &t
+	}
+	// Only pending deliveries have another attempt coming.
+	if d.Status == store.WebhookPending {
+		t := d.NextAttemptAt.UTC()
+		view.NextAttemptAt = &t
+	}
+	return view
+}
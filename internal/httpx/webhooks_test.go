@@ -0,0 +1,175 @@
+package httpx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"courier/internal/netguard"
+	"courier/internal/store"
+)
+
+func TestWebhookHandlers(t *testing.T) {
+	t.Parallel()
+
+	const (
+		id     = "44444444-4444-4444-4444-444444444444"
+		feedID = "11111111-1111-1111-1111-111111111111"
+	)
+	var (
+		mu      sync.Mutex
+		created []store.CreateWebhookParams
+		updates []store.UpdateWebhookParams
+		lists   []store.ListWebhookDeliveriesParams
+	)
+	hook := store.Webhook{ID: id, URL: "https://hooks.example.com/courier", Secret: "whsec_test", Keywords: []string{"go"}, Active: true}
+	stub := &stubStore{
+		createWebhookFunc: func(ctx context.Context, arg store.CreateWebhookParams) (store.Webhook, error) {
+			mu.Lock()
+			created = append(created, arg)
+			mu.Unlock()
+			h := hook
+			h.Secret = arg.Secret
+			return h, nil
+		},
+		listWebhooksFunc: func(ctx context.Context, userID string) ([]store.Webhook, error) {
+			return []store.Webhook{hook}, nil
+		},
+		updateWebhookFunc: func(ctx context.Context, arg store.UpdateWebhookParams) (store.Webhook, error) {
+			mu.Lock()
+			updates = append(updates, arg)
+			mu.Unlock()
+			return hook, nil
+		},
+		deliveriesFunc: func(ctx context.Context, arg store.ListWebhookDeliveriesParams) ([]store.WebhookDelivery, error) {
+			mu.Lock()
+			lists = append(lists, arg)
+			mu.Unlock()
+			return []store.WebhookDelivery{{
+				ID:             "55555555-5555-5555-5555-555555555555",
+				WebhookID:      id,
+				ItemID:         "22222222-2222-2222-2222-222222222222",
+				Event:          "item.created",
+				Payload:        `{"event":"item.created"}`,
+				Status:         store.WebhookPending,
+				Attempts:       2,
+				NextAttemptAt:  time.Date(2024, 5, 2, 8, 1, 0, 0, time.UTC),
+				LastAttemptAt:  sql.NullTime{Time: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC), Valid: true},
+				ResponseStatus: sql.NullInt32{Int32: http.StatusBadGateway, Valid: true},
+				LastError:      sql.NullString{String: "unexpected status 502", Valid: true},
+			}}, nil
+		},
+	}
+	srv := NewServer(Config{Store: stub, Service: "test"})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, body := range []string{
+		`{}`,
+		`{"url":"ftp://example.com/hook"}`,
+		`{"url":"https://example.com/hook","secret":"short"}`,
+		`{"url":"https://example.com/hook","feed_ids":["nope"]}`,
+		`{"url":"https://example.com/hook","keywords":[" "]}`,
+		`{"url":"https://example.com/hook","active":false}`,
+		`{"url":"http://localhost:9000/hook"}`,
+		`{"url":"http://169.254.169.254/latest/meta-data"}`,
+		`{"url":"http://[::1]/hook"}`,
+		`{"url":"http://10.0.0.5/hook"}`,
+	} {
+		if rec := do(http.MethodPost, "/webhooks", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("POST %s: expected status %d, got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+	rec := do(http.MethodPost, "/webhooks", `{"url":" https://hooks.example.com/courier ","feed_ids":["`+feedID+`"],"keywords":[" go "]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if len(created) != 1 || created[0].URL != hook.URL || created[0].Keywords[0] != "go" || created[0].FeedIDs[0] != feedID || !strings.HasPrefix(created[0].Secret, "whsec_") {
+		t.Fatalf("unexpected create params: %+v", created)
+	}
+	var view webhookView
+	if err := json.Unmarshal(rec.Body.Bytes(), &view); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if view.Secret != created[0].Secret {
+		t.Fatalf("expected the generated secret in the create response, got %q", view.Secret)
+	}
+
+	rec = do(http.MethodGet, "/webhooks", "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "whsec_") {
+		t.Fatalf("expected webhooks without secrets, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := do(http.MethodPatch, "/webhooks/"+id, `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an empty patch, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do(http.MethodPatch, "/webhooks/"+id, `{"active":false,"keywords":[]}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := updates[0]; got.Active == nil || *got.Active || got.URL != nil || got.FeedIDs != nil || got.Keywords == nil || len(got.Keywords) != 0 {
+		t.Fatalf("expected the webhook paused and its keywords cleared, got %+v", got)
+	}
+
+	if rec := do(http.MethodGet, "/webhooks/"+id+"/deliveries?status=sent", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an unknown status, got %d", http.StatusBadRequest, rec.Code)
+	}
+	rec = do(http.MethodGet, "/webhooks/"+id+"/deliveries?status=pending&limit=500", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	for _, want := range []string{`"payload":{"event":"item.created"}`, `"response_status":502`, `"next_attempt_at":"2024-05-02T08:01:00Z"`, `"last_error":"unexpected status 502"`} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("expected %s in deliveries response: %s", want, rec.Body.String())
+		}
+	}
+	if got := lists[0]; got.ID != id || got.Status != store.WebhookPending || got.Limit != maxItemsLimit {
+		t.Fatalf("unexpected delivery params: %+v", got)
+	}
+
+	if rec := do(http.MethodDelete, "/webhooks/"+id, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for a missing webhook, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestWebhookAllowedNetworks(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubStore{
+		createWebhookFunc: func(ctx context.Context, arg store.CreateWebhookParams) (store.Webhook, error) {
+			return store.Webhook{ID: "44444444-4444-4444-4444-444444444444", URL: arg.URL, Secret: arg.Secret, Active: true}, nil
+		},
+	}
+	allow := netguard.Allowlist{netip.MustParsePrefix("10.1.0.0/16")}
+	srv := NewServer(Config{Store: stub, Service: "test", AllowedNetworks: allow})
+
+	for url, want := range map[string]int{
+		"http://10.1.2.3/hook":           http.StatusCreated,
+		"http://10.2.0.1/hook":           http.StatusBadRequest,
+		"https://hooks.example.com/hook": http.StatusCreated,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"`+url+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("POST %s: expected status %d, got %d", url, want, rec.Code)
+		}
+	}
+}
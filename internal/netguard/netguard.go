@@ -0,0 +1,131 @@
+// Package netguard keeps requests made to user-supplied URLs off the
+// private network. The check runs when a connection is dialed, after DNS
+// resolution, so a hostname that resolves (or is rebound) to an internal
+// address is refused just like a literal one.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlocked is returned when a connection to a non-public address is
+// refused.
+var ErrBlocked = errors.New("netguard: address is not publicly routable")
+
+// reserved lists ranges that are neither private nor loopback according to
+// the standard library but still never reach the public internet.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// Allowlist holds networks that may be dialed even though they are not
+// public, for deployments that subscribe to internal feeds or deliver
+// webhooks to internal services.
+type Allowlist []netip.Prefix
+
+// ParseAllowlist parses a comma-separated list of CIDR prefixes and bare
+// addresses. An empty string allows nothing.
+func ParseAllowlist(raw string) (Allowlist, error) {
+	var allow Allowlist
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if strings.Contains(field, "/") {
+			prefix, err := netip.ParsePrefix(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", field, err)
+			}
+			allow = append(allow, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", field, err)
+		}
+		addr = addr.Unmap()
+		allow = append(allow, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return allow, nil
+}
+
+// Strings returns the allowlist in CIDR notation.
+func (a Allowlist) Strings() []string {
+	out := make([]string, 0, len(a))
+	for _, prefix := range a {
+		out = append(out, prefix.String())
+	}
+	return out
+}
+
+// Permits reports whether addr may be dialed: it is publicly routable or
+// falls inside the allowlist.
+func (a Allowlist) Permits(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range a {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return IsPublic(addr)
+}
+
+// Control is a net.Dialer Control function that refuses connections to
+// addresses the allowlist does not permit.
+func (a Allowlist) Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !a.Permits(addr) {
+		return fmt.Errorf("%w: %s", ErrBlocked, addr)
+	}
+	return nil
+}
+
+// Transport returns an http.Transport like http.DefaultTransport whose
+// connections are checked by Control. Proxies from the environment are not
+// used, since the guard would only see the proxy's address.
+func (a Allowlist) Transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   a.Control,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// IsPublic reports whether addr is a publicly routable unicast address:
+// not private, loopback, link-local, multicast, unspecified or reserved.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
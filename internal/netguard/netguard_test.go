@@ -0,0 +1,77 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestPermits(t *testing.T) {
+	allow, err := ParseAllowlist(" 10.1.0.0/16, 192.168.1.5 ")
+	if err != nil {
+		t.Fatalf("parse allowlist: %v", err)
+	}
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1::1", want: true},
+		{addr: "127.0.0.1", want: false},
+		{addr: "::1", want: false},
+		{addr: "10.0.0.1", want: false},
+		{addr: "172.16.4.2", want: false},
+		{addr: "169.254.169.254", want: false},
+		{addr: "fe80::1", want: false},
+		{addr: "fd00::1", want: false},
+		{addr: "0.0.0.0", want: false},
+		{addr: "::", want: false},
+		{addr: "100.64.0.1", want: false},
+		{addr: "::ffff:127.0.0.1", want: false},
+		{addr: "10.1.2.3", want: true},
+		{addr: "192.168.1.5", want: true},
+		{addr: "192.168.1.6", want: false},
+	}
+	for _, tc := range tests {
+		if got := allow.Permits(netip.MustParseAddr(tc.addr)); got != tc.want {
+			t.Fatalf("Permits(%s) = %v, want %v", tc.addr, got, tc.want)
+		}
+	}
+}
+
+func TestParseAllowlistRejectsGarbage(t *testing.T) {
+	if _, err := ParseAllowlist("10.0.0.0/8,intranet"); err == nil {
+		t.Fatalf("expected an invalid entry to be rejected")
+	}
+	allow, err := ParseAllowlist("")
+	if err != nil || len(allow) != 0 {
+		t.Fatalf("expected an empty allowlist, got %v (%v)", allow, err)
+	}
+}
+
+func TestTransportRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+
+	client := &http.Client{Transport: Allowlist(nil).Transport()}
+	if _, err := client.Do(req); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("expected loopback to be blocked, got %v", err)
+	}
+
+	allowed := Allowlist{netip.MustParsePrefix("127.0.0.0/8")}
+	client = &http.Client{Transport: allowed.Transport()}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("expected allowlisted loopback to connect, got %v", err)
+	}
+	resp.Body.Close()
+}
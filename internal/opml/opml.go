@@ -0,0 +1,193 @@
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Subscription is a feed listed in an OPML document.
+type Subscription struct {
+	Title   string
+	XMLURL  string
+	HTMLURL string
+	// Category is the path of folders the feed is filed under, joined with
+	// "/", or "" for top-level feeds.
+	Category string
+}
+
+// ErrInvalidDocument is returned by Parse when the input is not an OPML
+// document.
+var ErrInvalidDocument = errors.New("invalid opml document")
+
+type document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    head     `xml:"head"`
+	Body    body     `xml:"body"`
+}
+
+type head struct {
+	Title string `xml:"title,omitempty"`
+}
+
+type body struct {
+	Outlines []outline `xml:"outline"`
+}
+
+type outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []outline `xml:"outline"`
+}
+
+// UnmarshalXML reads outline attributes case-insensitively; exporters
+// disagree on xmlUrl versus xmlurl.
+func (o *outline) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		value := strings.TrimSpace(attr.Value)
+		switch strings.ToLower(attr.Name.Local) {
+		case "text":
+			o.Text = value
+		case "title":
+			o.Title = value
+		case "type":
+			o.Type = value
+		case "xmlurl":
+			o.XMLURL = value
+		case "htmlurl":
+			o.HTMLURL = value
+		}
+	}
+
+	var children struct {
+		Outlines []outline `xml:"outline"`
+	}
+	if err := d.DecodeElement(&children, &start); err != nil {
+		return err
+	}
+	o.Outlines = children.Outlines
+	return nil
+}
+
+// Parse reads the subscriptions in an OPML document in document order.
+// Outlines without an xmlUrl are folders; their titles make up the
+// Category of the feeds nested inside them.
+func Parse(r io.Reader) ([]Subscription, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+
+	var doc document
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+
+	var subs []Subscription
+	var walk func(outlines []outline, path []string)
+	walk = func(outlines []outline, path []string) {
+		for _, o := range outlines {
+			name := o.Title
+			if name == "" {
+				name = o.Text
+			}
+			if o.XMLURL == "" {
+				if name == "" {
+					walk(o.Outlines, path)
+					continue
+				}
+				walk(o.Outlines, append(path[:len(path):len(path)], name))
+				continue
+			}
+			subs = append(subs, Subscription{
+				Title:    name,
+				XMLURL:   o.XMLURL,
+				HTMLURL:  o.HTMLURL,
+				Category: strings.Join(path, "/"),
+			})
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+	return subs, nil
+}
+
+// Write encodes subs as an OPML 2.0 document titled title. Feeds are nested
+// in folders following their Category; folders are sorted by name and
+// listed before the feeds that sit next to them.
+func Write(w io.Writer, title string, subs []Subscription) error {
+	root := &folder{}
+	for _, sub := range subs {
+		f := root
+		if sub.Category != "" {
+			for _, name := range strings.Split(sub.Category, "/") {
+				f = f.child(name)
+			}
+		}
+		f.feeds = append(f.feeds, outline{
+			Text:    sub.Title,
+			Title:   sub.Title,
+			Type:    "rss",
+			XMLURL:  sub.XMLURL,
+			HTMLURL: sub.HTMLURL,
+		})
+	}
+
+	doc := document{
+		Version: "2.0",
+		Head:    head{Title: title},
+		Body:    body{Outlines: root.outlines()},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type folder struct {
+	children map[string]*folder
+	feeds    []outline
+}
+
+func (f *folder) child(name string) *folder {
+	if f.children == nil {
+		f.children = map[string]*folder{}
+	}
+	c, ok := f.children[name]
+	if !ok {
+		c = &folder{}
+		f.children[name] = c
+	}
+	return c
+}
+
+func (f *folder) outlines() []outline {
+	names := make([]string, 0, len(f.children))
+	for name := range f.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]outline, 0, len(names)+len(f.feeds))
+	for _, name := range names {
+		out = append(out, outline{
+			Text:     name,
+			Title:    name,
+			Outlines: f.children[name].outlines(),
+		})
+	}
+	return append(out, f.feeds...)
+}
@@ -0,0 +1,78 @@
+package opml
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const sample = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+  <head><title>Exported subscriptions</title></head>
+  <body>
+    <outline text="Rust Blog" title="Rust Blog" type="rss" xmlUrl="https://blog.rust-lang.org/feed.xml" htmlUrl="https://blog.rust-lang.org/"/>
+    <outline text="Tech">
+      <outline text="Go" title="Go">
+        <outline text="The Go Blog" xmlurl="https://go.dev/blog/feed.atom"/>
+      </outline>
+      <outline title="LWN" xmlUrl="https://lwn.net/headlines/rss"/>
+    </outline>
+    <outline text="Empty folder"/>
+  </body>
+</opml>`
+
+func TestParse(t *testing.T) {
+	subs, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Subscription{
+		{Title: "Rust Blog", XMLURL: "https://blog.rust-lang.org/feed.xml", HTMLURL: "https://blog.rust-lang.org/"},
+		{Title: "The Go Blog", XMLURL: "https://go.dev/blog/feed.atom", Category: "Tech/Go"},
+		{Title: "LWN", XMLURL: "https://lwn.net/headlines/rss", Category: "Tech"},
+	}
+	if len(subs) != len(want) {
+		t.Fatalf("expected %d subscriptions, got %+v", len(want), subs)
+	}
+	for i := range want {
+		if subs[i] != want[i] {
+			t.Fatalf("subscription %d: expected %+v, got %+v", i, want[i], subs[i])
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("not xml")); !errors.Is(err, ErrInvalidDocument) {
+		t.Fatalf("expected ErrInvalidDocument, got %v", err)
+	}
+}
+
+func TestWriteRoundTrips(t *testing.T) {
+	subs := []Subscription{
+		{Title: "LWN", XMLURL: "https://lwn.net/headlines/rss", Category: "Tech"},
+		{Title: "Rust Blog", XMLURL: "https://blog.rust-lang.org/feed.xml"},
+		{Title: "The Go Blog", XMLURL: "https://go.dev/blog/feed.atom", Category: "Tech/Go"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "Courier subscriptions", subs); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !strings.Contains(buf.String(), `<opml version="2.0">`) {
+		t.Fatalf("expected an OPML 2.0 document, got:\n%s", buf.String())
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// Folders come before the feeds next to them.
+	want := []Subscription{subs[2], subs[0], subs[1]}
+	if len(parsed) != len(want) {
+		t.Fatalf("expected %d subscriptions, got %+v", len(want), parsed)
+	}
+	for i := range want {
+		if parsed[i] != want[i] {
+			t.Fatalf("subscription %d: expected %+v, got %+v", i, want[i], parsed[i])
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"courier/internal/logx"
@@ -92,6 +94,26 @@ type SearchResponse struct {
 
 type SearchFilters struct {
 	FeedID string
+	// FeedIDs restricts hits to any of the listed feeds. It combines with
+	// FeedID when both are set.
+	FeedIDs []string
+}
+
+// expression renders the filters as a Meilisearch filter, or "" when none
+// are set. Every condition is parenthesized so one cannot widen another.
+func (f SearchFilters) expression() string {
+	var conditions []string
+	if f.FeedID != "" {
+		conditions = append(conditions, fmt.Sprintf("(feed_id = %s)", strconv.Quote(f.FeedID)))
+	}
+	if len(f.FeedIDs) > 0 {
+		quoted := make([]string, 0, len(f.FeedIDs))
+		for _, id := range f.FeedIDs {
+			quoted = append(quoted, strconv.Quote(id))
+		}
+		conditions = append(conditions, fmt.Sprintf("(feed_id IN [%s])", strings.Join(quoted, ", ")))
+	}
+	return strings.Join(conditions, " AND ")
 }
 
 func (c *Client) Search(ctx context.Context, query string, limit, offset int, filters SearchFilters) (resp SearchResponse, err error) {
@@ -105,8 +127,8 @@ func (c *Client) Search(ctx context.Context, query string, limit, offset int, fi
 		Offset: int64(offset),
 		Limit:  int64(limit),
 	}
-	if filters.FeedID != "" {
-		req.Filter = fmt.Sprintf("feed_id = \"%s\"", filters.FeedID)
+	if filter := filters.expression(); filter != "" {
+		req.Filter = filter
 	}
 
 	var searchRes *meilisearch.SearchResponse
@@ -181,6 +203,32 @@ func (c *Client) UpsertBatch(ctx context.Context, docs []Document) (err error) {
 	return err
 }
 
+func (c *Client) DeleteDocuments(ctx context.Context, ids []string) (err error) {
+	if c.metrics != nil {
+		defer func(start time.Time) {
+			c.metrics.ObserveSearch("DeleteDocuments", err, time.Since(start))
+		}(time.Now())
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err = c.client.Index(c.index).DeleteDocumentsWithContext(ctx, ids)
+	return err
+}
+
+// DeleteFeedDocuments removes every document indexed for feedID.
+func (c *Client) DeleteFeedDocuments(ctx context.Context, feedID string) (err error) {
+	if c.metrics != nil {
+		defer func(start time.Time) {
+			c.metrics.ObserveSearch("DeleteFeedDocuments", err, time.Since(start))
+		}(time.Now())
+	}
+
+	_, err = c.client.Index(c.index).DeleteDocumentsByFilterWithContext(ctx, fmt.Sprintf("feed_id = \"%s\"", feedID))
+	return err
+}
+
 func (c *Client) IndexName() string {
 	return c.index
 }
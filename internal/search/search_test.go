@@ -0,0 +1,31 @@
+package search
+
+import "testing"
+
+func TestSearchFiltersExpression(t *testing.T) {
+	cases := []struct {
+		name    string
+		filters SearchFilters
+		want    string
+	}{
+		{name: "none", filters: SearchFilters{}, want: ""},
+		{name: "feed", filters: SearchFilters{FeedID: "a"}, want: `(feed_id = "a")`},
+		{
+			name:    "feed within scope",
+			filters: SearchFilters{FeedID: "a", FeedIDs: []string{"a", "b"}},
+			want:    `(feed_id = "a") AND (feed_id IN ["a", "b"])`,
+		},
+		{
+			name:    "injected feed",
+			filters: SearchFilters{FeedID: `x" OR feed_id EXISTS OR feed_id = "y`, FeedIDs: []string{"a"}},
+			want:    `(feed_id = "x\" OR feed_id EXISTS OR feed_id = \"y") AND (feed_id IN ["a"])`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filters.expression(); got != tc.want {
+				t.Fatalf("expression() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
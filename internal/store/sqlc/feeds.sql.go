@@ -12,15 +12,240 @@ import (
 	"github.com/google/uuid"
 )
 
+const activateFeed = `-- name: ActivateFeed :exec
+UPDATE feeds
+SET active = TRUE,
+    deactivated_at = NULL,
+    deactivated_reason = NULL,
+    consecutive_not_found = 0,
+    next_crawl_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) ActivateFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, activateFeed, id)
+	return err
+}
+
+const clearFeedBackoff = `-- name: ClearFeedBackoff :exec
+UPDATE feeds
+SET backoff_until = NULL,
+    backoff_seconds = 0,
+    backoff_reason = NULL,
+    backoff_failures = 0
+WHERE id = $1
+`
+
+func (q *Queries) ClearFeedBackoff(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, clearFeedBackoff, id)
+	return err
+}
+
+const deactivateFeed = `-- name: DeactivateFeed :exec
+UPDATE feeds
+SET active = FALSE,
+    deactivated_at = $1,
+    deactivated_reason = $2
+WHERE id = $3
+`
+
+type DeactivateFeedParams struct {
+	DeactivatedAt sql.NullTime
+	Reason        sql.NullString
+	ID            uuid.UUID
+}
+
+func (q *Queries) DeactivateFeed(ctx context.Context, arg DeactivateFeedParams) error {
+	_, err := q.db.ExecContext(ctx, deactivateFeed, arg.DeactivatedAt, arg.Reason, arg.ID)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeed = `-- name: GetFeed :one
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeed(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeed, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Title,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastCrawled,
+		&i.Active,
+		&i.NextCrawlAt,
+		&i.RefreshIntervalSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.BackoffUntil,
+		&i.BackoffSeconds,
+		&i.BackoffReason,
+		&i.BackoffFailures,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.LastStatus,
+		&i.ConsecutiveNotFound,
+		&i.DeactivatedAt,
+		&i.DeactivatedReason,
+		&i.RejectedRelocationUrl,
+		&i.CanonicalUrl,
+		&i.TitleLocked,
+		&i.Category,
+	)
+	return i, err
+}
+
+const getFeedByCanonicalURL = `-- name: GetFeedByCanonicalURL :one
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE canonical_url = $1
+`
+
+func (q *Queries) GetFeedByCanonicalURL(ctx context.Context, canonicalUrl sql.NullString) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByCanonicalURL, canonicalUrl)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Title,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastCrawled,
+		&i.Active,
+		&i.NextCrawlAt,
+		&i.RefreshIntervalSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.BackoffUntil,
+		&i.BackoffSeconds,
+		&i.BackoffReason,
+		&i.BackoffFailures,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.LastStatus,
+		&i.ConsecutiveNotFound,
+		&i.DeactivatedAt,
+		&i.DeactivatedReason,
+		&i.RejectedRelocationUrl,
+		&i.CanonicalUrl,
+		&i.TitleLocked,
+		&i.Category,
+	)
+	return i, err
+}
+
+const getFeedByURL = `-- name: GetFeedByURL :one
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE url = $1 OR canonical_url = $2::text
+ORDER BY url = $1 DESC
+LIMIT 1
+`
+
+type GetFeedByURLParams struct {
+	Url          string
+	CanonicalUrl string
+}
+
+func (q *Queries) GetFeedByURL(ctx context.Context, arg GetFeedByURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURL, arg.Url, arg.CanonicalUrl)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Title,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastCrawled,
+		&i.Active,
+		&i.NextCrawlAt,
+		&i.RefreshIntervalSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.BackoffUntil,
+		&i.BackoffSeconds,
+		&i.BackoffReason,
+		&i.BackoffFailures,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.LastStatus,
+		&i.ConsecutiveNotFound,
+		&i.DeactivatedAt,
+		&i.DeactivatedReason,
+		&i.RejectedRelocationUrl,
+		&i.CanonicalUrl,
+		&i.TitleLocked,
+		&i.Category,
+	)
+	return i, err
+}
+
 const insertFeed = `-- name: InsertFeed :one
-INSERT INTO feeds (url)
-VALUES ($1)
-ON CONFLICT (url) DO NOTHING
-RETURNING id, url, title, etag, last_modified, last_crawled, active
+INSERT INTO feeds (url, canonical_url, title, title_locked, category, refresh_interval_seconds, skip_hours, skip_days, last_success_at, last_status)
+SELECT $1::text,
+       $2::text,
+       $3::text,
+       $4::boolean,
+       $5::text,
+       $6::int,
+       $7::int,
+       $8::smallint,
+       $9::timestamptz,
+       $10::int
+WHERE NOT EXISTS (SELECT 1 FROM feed_url_history h WHERE h.url = $1::text)
+ON CONFLICT DO NOTHING
+RETURNING id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
 `
 
-func (q *Queries) InsertFeed(ctx context.Context, url string) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, insertFeed, url)
+type InsertFeedParams struct {
+	Url                    string
+	CanonicalUrl           string
+	Title                  string
+	TitleLocked            bool
+	Category               sql.NullString
+	RefreshIntervalSeconds int32
+	SkipHours              int32
+	SkipDays               int16
+	LastSuccessAt          sql.NullTime
+	LastStatus             sql.NullInt32
+}
+
+func (q *Queries) InsertFeed(ctx context.Context, arg InsertFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, insertFeed,
+		arg.Url,
+		arg.CanonicalUrl,
+		arg.Title,
+		arg.TitleLocked,
+		arg.Category,
+		arg.RefreshIntervalSeconds,
+		arg.SkipHours,
+		arg.SkipDays,
+		arg.LastSuccessAt,
+		arg.LastStatus,
+	)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -30,12 +255,156 @@ func (q *Queries) InsertFeed(ctx context.Context, url string) (Feed, error) {
 		&i.LastModified,
 		&i.LastCrawled,
 		&i.Active,
+		&i.NextCrawlAt,
+		&i.RefreshIntervalSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.BackoffUntil,
+		&i.BackoffSeconds,
+		&i.BackoffReason,
+		&i.BackoffFailures,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.LastStatus,
+		&i.ConsecutiveNotFound,
+		&i.DeactivatedAt,
+		&i.DeactivatedReason,
+		&i.RejectedRelocationUrl,
+		&i.CanonicalUrl,
+		&i.TitleLocked,
+		&i.Category,
 	)
 	return i, err
 }
 
+const listBackedOffFeeds = `-- name: ListBackedOffFeeds :many
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE active
+  AND backoff_until > $1
+ORDER BY backoff_until ASC
+`
+
+func (q *Queries) ListBackedOffFeeds(ctx context.Context, now sql.NullTime) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, listBackedOffFeeds, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Feed{}
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Title,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastCrawled,
+			&i.Active,
+			&i.NextCrawlAt,
+			&i.RefreshIntervalSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.BackoffUntil,
+			&i.BackoffSeconds,
+			&i.BackoffReason,
+			&i.BackoffFailures,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.LastStatus,
+			&i.ConsecutiveNotFound,
+			&i.DeactivatedAt,
+			&i.DeactivatedReason,
+			&i.RejectedRelocationUrl,
+			&i.CanonicalUrl,
+			&i.TitleLocked,
+			&i.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueFeeds = `-- name: ListDueFeeds :many
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE active
+  AND (next_crawl_at IS NULL OR next_crawl_at <= $1)
+  AND (
+      EXISTS (SELECT 1 FROM subscriptions s WHERE s.feed_id = feeds.id AND s.active)
+      OR NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.feed_id = feeds.id)
+  )
+ORDER BY next_crawl_at ASC NULLS FIRST, title ASC, url ASC
+`
+
+// Feeds every subscriber has paused are skipped; feeds without subscribers
+// belong to the open API and are still crawled.
+func (q *Queries) ListDueFeeds(ctx context.Context, now sql.NullTime) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, listDueFeeds, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Feed{}
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Title,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastCrawled,
+			&i.Active,
+			&i.NextCrawlAt,
+			&i.RefreshIntervalSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.BackoffUntil,
+			&i.BackoffSeconds,
+			&i.BackoffReason,
+			&i.BackoffFailures,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.LastStatus,
+			&i.ConsecutiveNotFound,
+			&i.DeactivatedAt,
+			&i.DeactivatedReason,
+			&i.RejectedRelocationUrl,
+			&i.CanonicalUrl,
+			&i.TitleLocked,
+			&i.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listFeeds = `-- name: ListFeeds :many
-SELECT id, url, title, etag, last_modified, last_crawled, active
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
 FROM feeds
 WHERE active = $1
 ORDER BY title ASC, url ASC
@@ -58,6 +427,84 @@ func (q *Queries) ListFeeds(ctx context.Context, active bool) ([]Feed, error) {
 			&i.LastModified,
 			&i.LastCrawled,
 			&i.Active,
+			&i.NextCrawlAt,
+			&i.RefreshIntervalSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.BackoffUntil,
+			&i.BackoffSeconds,
+			&i.BackoffReason,
+			&i.BackoffFailures,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.LastStatus,
+			&i.ConsecutiveNotFound,
+			&i.DeactivatedAt,
+			&i.DeactivatedReason,
+			&i.RejectedRelocationUrl,
+			&i.CanonicalUrl,
+			&i.TitleLocked,
+			&i.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeedsWithoutCanonicalURL = `-- name: ListFeedsWithoutCanonicalURL :many
+SELECT id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+FROM feeds
+WHERE canonical_url IS NULL
+ORDER BY active DESC, last_success_at DESC NULLS LAST, id ASC
+`
+
+func (q *Queries) ListFeedsWithoutCanonicalURL(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedsWithoutCanonicalURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Feed{}
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Title,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastCrawled,
+			&i.Active,
+			&i.NextCrawlAt,
+			&i.RefreshIntervalSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.BackoffUntil,
+			&i.BackoffSeconds,
+			&i.BackoffReason,
+			&i.BackoffFailures,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.LastStatus,
+			&i.ConsecutiveNotFound,
+			&i.DeactivatedAt,
+			&i.DeactivatedReason,
+			&i.RejectedRelocationUrl,
+			&i.CanonicalUrl,
+			&i.TitleLocked,
+			&i.Category,
 		); err != nil {
 			return nil, err
 		}
@@ -72,22 +519,167 @@ func (q *Queries) ListFeeds(ctx context.Context, active bool) ([]Feed, error) {
 	return items, nil
 }
 
+const recordFeedFailure = `-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    consecutive_not_found = CASE WHEN $1::int = 404 THEN consecutive_not_found + 1 ELSE 0 END,
+    last_error = $2,
+    last_error_at = $3,
+    last_status = $1
+WHERE id = $4
+`
+
+type RecordFeedFailureParams struct {
+	LastStatus sql.NullInt32
+	LastError  sql.NullString
+	FetchedAt  sql.NullTime
+	ID         uuid.UUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFailure,
+		arg.LastStatus,
+		arg.LastError,
+		arg.FetchedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    consecutive_not_found = 0,
+    last_success_at = $1,
+    last_status = $2
+WHERE id = $3
+`
+
+type RecordFeedSuccessParams struct {
+	FetchedAt  sql.NullTime
+	LastStatus sql.NullInt32
+	ID         uuid.UUID
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.FetchedAt, arg.LastStatus, arg.ID)
+	return err
+}
+
+const rejectFeedRelocation = `-- name: RejectFeedRelocation :exec
+UPDATE feeds
+SET rejected_relocation_url = $1
+WHERE id = $2
+`
+
+type RejectFeedRelocationParams struct {
+	Url sql.NullString
+	ID  uuid.UUID
+}
+
+func (q *Queries) RejectFeedRelocation(ctx context.Context, arg RejectFeedRelocationParams) error {
+	_, err := q.db.ExecContext(ctx, rejectFeedRelocation, arg.Url, arg.ID)
+	return err
+}
+
+const scheduleFeedCrawl = `-- name: ScheduleFeedCrawl :exec
+UPDATE feeds
+SET next_crawl_at = $1
+WHERE id = $2
+`
+
+type ScheduleFeedCrawlParams struct {
+	NextCrawlAt sql.NullTime
+	ID          uuid.UUID
+}
+
+func (q *Queries) ScheduleFeedCrawl(ctx context.Context, arg ScheduleFeedCrawlParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleFeedCrawl, arg.NextCrawlAt, arg.ID)
+	return err
+}
+
+const setFeedCanonicalURL = `-- name: SetFeedCanonicalURL :exec
+UPDATE feeds
+SET canonical_url = $1
+WHERE id = $2
+`
+
+type SetFeedCanonicalURLParams struct {
+	CanonicalUrl sql.NullString
+	ID           uuid.UUID
+}
+
+func (q *Queries) SetFeedCanonicalURL(ctx context.Context, arg SetFeedCanonicalURLParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCanonicalURL, arg.CanonicalUrl, arg.ID)
+	return err
+}
+
+const updateFeedBackoff = `-- name: UpdateFeedBackoff :exec
+UPDATE feeds
+SET backoff_until = $1,
+    backoff_seconds = $2,
+    backoff_reason = $3,
+    backoff_failures = $4
+WHERE id = $5
+`
+
+type UpdateFeedBackoffParams struct {
+	BackoffUntil    sql.NullTime
+	BackoffSeconds  int32
+	BackoffReason   sql.NullString
+	BackoffFailures int32
+	ID              uuid.UUID
+}
+
+func (q *Queries) UpdateFeedBackoff(ctx context.Context, arg UpdateFeedBackoffParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedBackoff,
+		arg.BackoffUntil,
+		arg.BackoffSeconds,
+		arg.BackoffReason,
+		arg.BackoffFailures,
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedCategory = `-- name: UpdateFeedCategory :exec
+UPDATE feeds
+SET category = $1
+WHERE id = $2
+`
+
+type UpdateFeedCategoryParams struct {
+	Category sql.NullString
+	ID       uuid.UUID
+}
+
+func (q *Queries) UpdateFeedCategory(ctx context.Context, arg UpdateFeedCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCategory, arg.Category, arg.ID)
+	return err
+}
+
 const updateFeedCrawlState = `-- name: UpdateFeedCrawlState :one
 UPDATE feeds
 SET etag = COALESCE($1, feeds.etag),
     last_modified = COALESCE($2, feeds.last_modified),
     last_crawled = COALESCE($3, last_crawled),
-    title = COALESCE(NULLIF($4::text, ''), title)
-WHERE id = $5
-RETURNING id, url, title, etag, last_modified, last_crawled, active
+    title = CASE WHEN title_locked THEN title ELSE COALESCE(NULLIF($4::text, ''), title) END,
+    refresh_interval_seconds = $5,
+    skip_hours = $6,
+    skip_days = $7
+WHERE id = $8
+RETURNING id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
 `
 
 type UpdateFeedCrawlStateParams struct {
-	Etag         sql.NullString
-	LastModified sql.NullString
-	LastCrawled  sql.NullTime
-	NewTitle     string
-	ID           uuid.UUID
+	Etag                   sql.NullString
+	LastModified           sql.NullString
+	LastCrawled            sql.NullTime
+	NewTitle               string
+	RefreshIntervalSeconds int32
+	SkipHours              int32
+	SkipDays               int16
+	ID                     uuid.UUID
 }
 
 func (q *Queries) UpdateFeedCrawlState(ctx context.Context, arg UpdateFeedCrawlStateParams) (Feed, error) {
@@ -96,6 +688,9 @@ func (q *Queries) UpdateFeedCrawlState(ctx context.Context, arg UpdateFeedCrawlS
 		arg.LastModified,
 		arg.LastCrawled,
 		arg.NewTitle,
+		arg.RefreshIntervalSeconds,
+		arg.SkipHours,
+		arg.SkipDays,
 		arg.ID,
 	)
 	var i Feed
@@ -107,6 +702,98 @@ func (q *Queries) UpdateFeedCrawlState(ctx context.Context, arg UpdateFeedCrawlS
 		&i.LastModified,
 		&i.LastCrawled,
 		&i.Active,
+		&i.NextCrawlAt,
+		&i.RefreshIntervalSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.BackoffUntil,
+		&i.BackoffSeconds,
+		&i.BackoffReason,
+		&i.BackoffFailures,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.LastStatus,
+		&i.ConsecutiveNotFound,
+		&i.DeactivatedAt,
+		&i.DeactivatedReason,
+		&i.RejectedRelocationUrl,
+		&i.CanonicalUrl,
+		&i.TitleLocked,
+		&i.Category,
+	)
+	return i, err
+}
+
+const updateFeedTitle = `-- name: UpdateFeedTitle :exec
+UPDATE feeds
+SET title = COALESCE(NULLIF($1::text, ''), title),
+    title_locked = $1::text <> ''
+WHERE id = $2
+`
+
+type UpdateFeedTitleParams struct {
+	Title string
+	ID    uuid.UUID
+}
+
+func (q *Queries) UpdateFeedTitle(ctx context.Context, arg UpdateFeedTitleParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedTitle, arg.Title, arg.ID)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :one
+UPDATE feeds
+SET url = $1,
+    canonical_url = $2::text
+WHERE id = $3
+  AND NOT EXISTS (
+    SELECT 1 FROM feeds other
+    WHERE (other.url = $1 OR other.canonical_url = $2::text)
+      AND other.id <> $3
+  )
+  AND NOT EXISTS (SELECT 1 FROM feed_url_history h WHERE h.url = $1 AND h.feed_id <> $3)
+RETURNING id, url, title, etag, last_modified, last_crawled, active, next_crawl_at, refresh_interval_seconds, skip_hours, skip_days, backoff_until, backoff_seconds, backoff_reason, backoff_failures, consecutive_failures, last_error, last_error_at, last_success_at, last_status, consecutive_not_found, deactivated_at, deactivated_reason, rejected_relocation_url, canonical_url, title_locked, category
+`
+
+type UpdateFeedURLParams struct {
+	Url          string
+	CanonicalUrl string
+	ID           uuid.UUID
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedURL, arg.Url, arg.CanonicalUrl, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Title,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastCrawled,
+		&i.Active,
+		&i.NextCrawlAt,
+		&i.RefreshIntervalSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.BackoffUntil,
+		&i.BackoffSeconds,
+		&i.BackoffReason,
+		&i.BackoffFailures,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.LastStatus,
+		&i.ConsecutiveNotFound,
+		&i.DeactivatedAt,
+		&i.DeactivatedReason,
+		&i.RejectedRelocationUrl,
+		&i.CanonicalUrl,
+		&i.TitleLocked,
+		&i.Category,
 	)
 	return i, err
 }
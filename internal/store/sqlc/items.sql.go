@@ -14,6 +14,282 @@ import (
 	"github.com/lib/pq"
 )
 
+const countUserItems = `-- name: CountUserItems :one
+SELECT COUNT(*)::bigint
+FROM items i
+JOIN subscriptions sub ON sub.feed_id = i.feed_id
+WHERE sub.user_id = $1
+`
+
+func (q *Queries) CountUserItems(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserItems, userID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const deleteDuplicateFeedItems = `-- name: DeleteDuplicateFeedItems :many
+DELETE FROM items d
+WHERE d.feed_id = $1
+  AND EXISTS (
+      SELECT 1
+      FROM items k
+      WHERE k.feed_id = $2
+        AND COALESCE(k.guid, k.url) = COALESCE(d.guid, d.url)
+  )
+RETURNING d.id
+`
+
+type DeleteDuplicateFeedItemsParams struct {
+	DropFeedID uuid.UUID
+	KeepFeedID uuid.UUID
+}
+
+func (q *Queries) DeleteDuplicateFeedItems(ctx context.Context, arg DeleteDuplicateFeedItemsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, deleteDuplicateFeedItems, arg.DropFeedID, arg.KeepFeedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getItem = `-- name: GetItem :one
+SELECT i.id,
+       i.feed_id,
+       COALESCE(sub.title, f.title) AS feed_title,
+       i.guid,
+       i.url,
+       i.title,
+       i.author,
+       i.content_html,
+       i.content_text,
+       i.published_at,
+       i.retrieved_at,
+       COALESCE(s.read, false) AS read,
+       COALESCE(s.starred, false) AS starred
+FROM items i
+JOIN feeds f ON f.id = i.feed_id
+LEFT JOIN subscriptions sub ON sub.feed_id = i.feed_id AND sub.user_id = $1::uuid
+LEFT JOIN item_states s ON s.item_id = i.id AND s.user_id IS NOT DISTINCT FROM $1::uuid
+WHERE i.id = $2
+  AND ($1::uuid IS NULL OR sub.id IS NOT NULL)
+`
+
+type GetItemRow struct {
+	ID          uuid.UUID
+	FeedID      uuid.UUID
+	FeedTitle   string
+	Guid        sql.NullString
+	Url         string
+	Title       string
+	Author      sql.NullString
+	ContentHtml string
+	ContentText string
+	PublishedAt sql.NullTime
+	RetrievedAt time.Time
+	Read        bool
+	Starred     bool
+}
+
+type GetItemParams struct {
+	UserID uuid.NullUUID
+	ID     uuid.UUID
+}
+
+func (q *Queries) GetItem(ctx context.Context, arg GetItemParams) (GetItemRow, error) {
+	row := q.db.QueryRowContext(ctx, getItem, arg.UserID, arg.ID)
+	var i GetItemRow
+	err := row.Scan(
+		&i.ID,
+		&i.FeedID,
+		&i.FeedTitle,
+		&i.Guid,
+		&i.Url,
+		&i.Title,
+		&i.Author,
+		&i.ContentHtml,
+		&i.ContentText,
+		&i.PublishedAt,
+		&i.RetrievedAt,
+		&i.Read,
+		&i.Starred,
+	)
+	return i, err
+}
+
+const getItemNeighbors = `-- name: GetItemNeighbors :one
+WITH cur AS (
+    SELECT id, feed_id, published_at, retrieved_at
+    FROM items
+    WHERE id = $1
+)
+SELECT (
+           SELECT b.id
+           FROM (
+               (SELECT n.id, 0 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND (n.published_at, n.retrieved_at, n.id) > (cur.published_at, cur.retrieved_at, cur.id)
+                  AND ($2::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = $2::uuid))
+                ORDER BY n.published_at, n.retrieved_at, n.id
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 1 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NULL AND (n.retrieved_at, n.id) > (cur.retrieved_at, cur.id)
+                  AND ($2::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = $2::uuid))
+                ORDER BY n.retrieved_at, n.id
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 2 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NOT NULL
+                  AND ($2::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = $2::uuid))
+                ORDER BY n.published_at, n.retrieved_at, n.id
+                LIMIT 1)
+           ) b
+           ORDER BY b.branch
+           LIMIT 1
+       )::uuid AS previous_id,
+       (
+           SELECT b.id
+           FROM (
+               (SELECT n.id, 0 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND (n.published_at, n.retrieved_at, n.id) < (cur.published_at, cur.retrieved_at, cur.id)
+                  AND ($2::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = $2::uuid))
+                ORDER BY n.published_at DESC, n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 1 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND n.published_at IS NULL
+                  AND ($2::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = $2::uuid))
+                ORDER BY n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 2 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NULL AND (n.retrieved_at, n.id) < (cur.retrieved_at, cur.id)
+                  AND ($2::uuid IS NULL OR n.feed_id IN (SELECT feed_id FROM subscriptions WHERE user_id = $2::uuid))
+                ORDER BY n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+           ) b
+           ORDER BY b.branch
+           LIMIT 1
+       )::uuid AS next_id,
+       (
+           SELECT b.id
+           FROM (
+               (SELECT n.id, 0 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND (n.published_at, n.retrieved_at, n.id) > (cur.published_at, cur.retrieved_at, cur.id)
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.published_at, n.retrieved_at, n.id
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 1 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NULL AND (n.retrieved_at, n.id) > (cur.retrieved_at, cur.id)
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.retrieved_at, n.id
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 2 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NOT NULL
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.published_at, n.retrieved_at, n.id
+                LIMIT 1)
+           ) b
+           ORDER BY b.branch
+           LIMIT 1
+       )::uuid AS feed_previous_id,
+       (
+           SELECT b.id
+           FROM (
+               (SELECT n.id, 0 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND (n.published_at, n.retrieved_at, n.id) < (cur.published_at, cur.retrieved_at, cur.id)
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.published_at DESC, n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 1 AS branch
+                FROM items n
+                WHERE cur.published_at IS NOT NULL
+                  AND n.published_at IS NULL
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+               UNION ALL
+               (SELECT n.id, 2 AS branch
+                FROM items n
+                WHERE cur.published_at IS NULL
+                  AND n.published_at IS NULL AND (n.retrieved_at, n.id) < (cur.retrieved_at, cur.id)
+                  AND n.feed_id = cur.feed_id
+                ORDER BY n.retrieved_at DESC, n.id DESC
+                LIMIT 1)
+           ) b
+           ORDER BY b.branch
+           LIMIT 1
+       )::uuid AS feed_next_id
+FROM cur
+`
+
+type GetItemNeighborsParams struct {
+	ID     uuid.UUID
+	UserID uuid.NullUUID
+}
+
+type GetItemNeighborsRow struct {
+	PreviousID     uuid.NullUUID
+	NextID         uuid.NullUUID
+	FeedPreviousID uuid.NullUUID
+	FeedNextID     uuid.NullUUID
+}
+
+// Neighbors follow the default GET /items order: newest first by
+// published_at (undated items last), then retrieved_at and id. Each
+// neighbor is the first row of up to three ranges over the timeline
+// indexes, one for dated and one for undated items, rather than a single
+// disjunction that no index can serve.
+func (q *Queries) GetItemNeighbors(ctx context.Context, arg GetItemNeighborsParams) (GetItemNeighborsRow, error) {
+	row := q.db.QueryRowContext(ctx, getItemNeighbors, arg.ID, arg.UserID)
+	var i GetItemNeighborsRow
+	err := row.Scan(
+		&i.PreviousID,
+		&i.NextID,
+		&i.FeedPreviousID,
+		&i.FeedNextID,
+	)
+	return i, err
+}
+
 const listByFeed = `-- name: ListByFeed :many
 SELECT i.id,
        i.feed_id,
@@ -88,6 +364,140 @@ func (q *Queries) ListByFeed(ctx context.Context, arg ListByFeedParams) ([]ListB
 	return items, nil
 }
 
+const listFeedPublishTimes = `-- name: ListFeedPublishTimes :many
+SELECT i.published_at
+FROM items i
+WHERE i.feed_id = $1
+  AND i.published_at IS NOT NULL
+ORDER BY i.published_at DESC
+LIMIT $2::int
+`
+
+type ListFeedPublishTimesParams struct {
+	FeedID      uuid.UUID
+	ResultLimit int32
+}
+
+func (q *Queries) ListFeedPublishTimes(ctx context.Context, arg ListFeedPublishTimesParams) ([]sql.NullTime, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedPublishTimes, arg.FeedID, arg.ResultLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []sql.NullTime{}
+	for rows.Next() {
+		var published_at sql.NullTime
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listItemsBySeq = `-- name: ListItemsBySeq :many
+SELECT i.id,
+       i.feed_id,
+       COALESCE(sub.title, f.title) AS feed_title,
+       i.guid,
+       i.url,
+       i.title,
+       i.author,
+       i.content_html,
+       i.content_text,
+       i.published_at,
+       i.retrieved_at,
+       i.seq,
+       COALESCE(s.read, false) AS read,
+       COALESCE(s.starred, false) AS starred
+FROM items i
+JOIN feeds f ON f.id = i.feed_id
+JOIN subscriptions sub ON sub.feed_id = i.feed_id AND sub.user_id = $1
+LEFT JOIN item_states s ON s.item_id = i.id AND s.user_id = $1
+WHERE ($2::bigint IS NULL OR i.seq > $2::bigint)
+  AND ($3::bigint IS NULL OR i.seq < $3::bigint)
+  AND ($4::bigint[] IS NULL OR i.seq = ANY($4::bigint[]))
+ORDER BY CASE WHEN $3::bigint IS NULL THEN i.seq END ASC,
+         i.seq DESC
+LIMIT $5::int
+`
+
+type ListItemsBySeqParams struct {
+	UserID      uuid.UUID
+	SinceSeq    sql.NullInt64
+	MaxSeq      sql.NullInt64
+	Seqs        []int64
+	ResultLimit int32
+}
+
+type ListItemsBySeqRow struct {
+	ID          uuid.UUID
+	FeedID      uuid.UUID
+	FeedTitle   string
+	Guid        sql.NullString
+	Url         string
+	Title       string
+	Author      sql.NullString
+	ContentHtml string
+	ContentText string
+	PublishedAt sql.NullTime
+	RetrievedAt time.Time
+	Seq         int64
+	Read        bool
+	Starred     bool
+}
+
+// Items come oldest first, or newest first below max_seq.
+func (q *Queries) ListItemsBySeq(ctx context.Context, arg ListItemsBySeqParams) ([]ListItemsBySeqRow, error) {
+	rows, err := q.db.QueryContext(ctx, listItemsBySeq,
+		arg.UserID,
+		arg.SinceSeq,
+		arg.MaxSeq,
+		pq.Array(arg.Seqs),
+		arg.ResultLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListItemsBySeqRow{}
+	for rows.Next() {
+		var i ListItemsBySeqRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.FeedTitle,
+			&i.Guid,
+			&i.Url,
+			&i.Title,
+			&i.Author,
+			&i.ContentHtml,
+			&i.ContentText,
+			&i.PublishedAt,
+			&i.RetrievedAt,
+			&i.Seq,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listRecent = `-- name: ListRecent :many
 SELECT i.id,
        i.feed_id,
@@ -244,6 +654,79 @@ func (q *Queries) ListRecentFiltered(ctx context.Context, arg ListRecentFiltered
 	return items, nil
 }
 
+const moveFeedItems = `-- name: MoveFeedItems :many
+UPDATE items i
+SET feed_id = f.id
+FROM feeds f
+WHERE i.feed_id = $1
+  AND f.id = $2
+RETURNING i.id,
+          i.feed_id,
+          f.title AS feed_title,
+          i.guid,
+          i.url,
+          i.title,
+          i.author,
+          i.content_html,
+          i.content_text,
+          i.published_at,
+          i.retrieved_at
+`
+
+type MoveFeedItemsParams struct {
+	DropFeedID uuid.UUID
+	KeepFeedID uuid.UUID
+}
+
+type MoveFeedItemsRow struct {
+	ID          uuid.UUID
+	FeedID      uuid.UUID
+	FeedTitle   string
+	Guid        sql.NullString
+	Url         string
+	Title       string
+	Author      sql.NullString
+	ContentHtml string
+	ContentText string
+	PublishedAt sql.NullTime
+	RetrievedAt time.Time
+}
+
+func (q *Queries) MoveFeedItems(ctx context.Context, arg MoveFeedItemsParams) ([]MoveFeedItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, moveFeedItems, arg.DropFeedID, arg.KeepFeedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MoveFeedItemsRow{}
+	for rows.Next() {
+		var i MoveFeedItemsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.FeedTitle,
+			&i.Guid,
+			&i.Url,
+			&i.Title,
+			&i.Author,
+			&i.ContentHtml,
+			&i.ContentText,
+			&i.PublishedAt,
+			&i.RetrievedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const upsertItem = `-- name: UpsertItem :one
 WITH existing AS (
     SELECT i.id, i.content_hash
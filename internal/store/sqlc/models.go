@@ -12,13 +12,49 @@ import (
 )
 
 type Feed struct {
-	ID           uuid.UUID
-	Url          string
-	Title        string
-	Etag         sql.NullString
-	LastModified sql.NullString
-	LastCrawled  sql.NullTime
-	Active       bool
+	ID                     uuid.UUID
+	Url                    string
+	Title                  string
+	Etag                   sql.NullString
+	LastModified           sql.NullString
+	LastCrawled            sql.NullTime
+	Active                 bool
+	NextCrawlAt            sql.NullTime
+	RefreshIntervalSeconds int32
+	SkipHours              int32
+	SkipDays               int16
+	BackoffUntil           sql.NullTime
+	BackoffSeconds         int32
+	BackoffReason          sql.NullString
+	BackoffFailures        int32
+	ConsecutiveFailures    int32
+	LastError              sql.NullString
+	LastErrorAt            sql.NullTime
+	LastSuccessAt          sql.NullTime
+	LastStatus             sql.NullInt32
+	ConsecutiveNotFound    int32
+	DeactivatedAt          sql.NullTime
+	DeactivatedReason      sql.NullString
+	RejectedRelocationUrl  sql.NullString
+	CanonicalUrl           sql.NullString
+	TitleLocked            bool
+	Category               sql.NullString
+}
+
+type FeedUrlHistory struct {
+	Url        string
+	FeedID     uuid.UUID
+	ReplacedAt time.Time
+}
+
+type FeedFetchLog struct {
+	ID         int64
+	FeedID     uuid.UUID
+	FetchedAt  time.Time
+	Status     sql.NullInt32
+	Items      int32
+	Error      sql.NullString
+	DurationMs int32
 }
 
 type Item struct {
@@ -33,4 +69,116 @@ type Item struct {
 	PublishedAt sql.NullTime
 	RetrievedAt time.Time
 	ContentHash []byte
+	Seq         int64
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
+	UserID    uuid.NullUUID
+}
+
+type ItemState struct {
+	ItemID  uuid.UUID
+	Read    bool
+	Starred bool
+	ReadAt  sql.NullTime
+	UserID  uuid.NullUUID
+}
+
+type User struct {
+	ID           uuid.UUID
+	Username     string
+	PasswordHash string
+	IsAdmin      bool
+	CreatedAt    time.Time
+}
+
+type Session struct {
+	TokenHash []byte
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type ApiKey struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  []byte
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+}
+
+type Subscription struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	Title     sql.NullString
+	Category  sql.NullString
+	CreatedAt time.Time
+	Seq       int64
+	Active    bool
+}
+
+type FeverKey struct {
+	UserID    uuid.UUID
+	KeyHash   []byte
+	CreatedAt time.Time
+}
+
+type SavedSearch struct {
+	ID           uuid.UUID
+	UserID       uuid.NullUUID
+	Name         string
+	Query        string
+	FeedIds      []uuid.UUID
+	Tags         []string
+	CreatedAt    time.Time
+	LastViewedAt time.Time
+}
+
+type SavedSearchMatch struct {
+	SavedSearchID uuid.UUID
+	ItemID        uuid.UUID
+	MatchedAt     time.Time
+}
+
+type Webhook struct {
+	ID        uuid.UUID
+	UserID    uuid.NullUUID
+	Url       string
+	Secret    string
+	FeedIds   []uuid.UUID
+	Keywords  []string
+	Active    bool
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	WebhookID      uuid.UUID
+	ItemID         uuid.NullUUID
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	ResponseStatus sql.NullInt32
+	LastError      sql.NullString
+	CreatedAt      time.Time
+}
+
+type FeedDeletion struct {
+	FeedID    uuid.UUID
+	DeletedAt time.Time
+}
+
+type SubscriptionTag struct {
+	UserID uuid.NullUUID
+	FeedID uuid.UUID
+	TagID  uuid.UUID
 }
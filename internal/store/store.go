@@ -3,18 +3,27 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
+	"courier/internal/item/urlcanon"
 	"courier/internal/store/sqlc"
 )
 
 var (
 	ErrFeedExists = errors.New("feed already exists")
+	ErrTagExists  = errors.New("tag already exists")
+	ErrUserExists = errors.New("user already exists")
+	// ErrInvalidCursor is returned for item cursors that do not decode or
+	// were issued for a different sort order.
+	ErrInvalidCursor = errors.New("invalid cursor")
 )
 
 type Store struct {
@@ -50,249 +59,379 @@ const (
 )
 
 type FilterItemsParams struct {
-	FeedIDs       []string
+	// UserID limits items to the user's subscriptions and reports the
+	// user's read state. When empty, every feed is listed with the shared
+	// read state of the open API.
+	UserID  string
+	FeedIDs []string
+	// Tags restricts items to feeds the user put any of the named tags on.
+	// Names match case-insensitively.
+	Tags []string
+	// Category restricts items to feeds filed under it, using the user's
+	// own folder names when UserID is set.
+	Category string
+	// Seqs restricts items to the listed sequence numbers.
+	Seqs []int64
+	// IDs restricts items to the listed item IDs.
+	IDs []string
+	// Date ranges include their lower bound and exclude their upper bound;
+	// zero times leave the range open. Items without a publication date
+	// never match a published range.
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
+	RetrievedAfter  time.Time
+	RetrievedBefore time.Time
+	// Author matches the item's author exactly, ignoring case.
+	Author string
+	// Title matches items whose title contains it, ignoring case.
+	Title string
+	// Host matches the host of the item's URL, ignoring case and a leading
+	// "www.".
+	Host string
+	// Unread and Starred, when set, keep only items in that state.
+	Unread        *bool
+	Starred       *bool
 	SortField     ItemSortField
 	SortDirection SortDirection
 	Limit         int32
 	Offset        int32
+	// After continues a listing after the item a cursor points at. Offset
+	// is ignored and Total is not computed when it is set.
+	After *ItemCursor
 }
 
 type FilterItemsResult struct {
 	Items []Item
 	Total int64
+	// NextCursor points at the last item returned, or is nil when there
+	// are no further items.
+	NextCursor *ItemCursor
+}
+
+// ItemCursor is a position in an item listing, keyed on the sort order and
+// the (published_at, retrieved_at, id) of the last item seen. Unlike
+// offsets, cursors do not shift when new items are inserted.
+type ItemCursor struct {
+	SortField     ItemSortField
+	SortDirection SortDirection
+	PublishedAt   sql.NullTime
+	RetrievedAt   time.Time
+	ID            string
+}
+
+type itemCursorJSON struct {
+	Sort        string     `json:"s"`
+	PublishedAt *time.Time `json:"p,omitempty"`
+	RetrievedAt time.Time  `json:"r"`
+	ID          string     `json:"i"`
+}
+
+// Encode returns the cursor as an opaque URL-safe string.
+func (c ItemCursor) Encode() string {
+	payload := itemCursorJSON{
+		Sort:        string(c.SortField) + ":" + string(c.SortDirection),
+		RetrievedAt: c.RetrievedAt,
+		ID:          c.ID,
+	}
+	if c.PublishedAt.Valid {
+		payload.PublishedAt = &c.PublishedAt.Time
+	}
+	raw, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeItemCursor parses a cursor produced by ItemCursor.Encode.
+func DecodeItemCursor(encoded string) (ItemCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ItemCursor{}, ErrInvalidCursor
+	}
+	var payload itemCursorJSON
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return ItemCursor{}, ErrInvalidCursor
+	}
+	field, direction, ok := strings.Cut(payload.Sort, ":")
+	if !ok || payload.RetrievedAt.IsZero() {
+		return ItemCursor{}, ErrInvalidCursor
+	}
+	if _, err := uuid.Parse(payload.ID); err != nil {
+		return ItemCursor{}, ErrInvalidCursor
+	}
+	c := ItemCursor{
+		SortField:     ItemSortField(field),
+		SortDirection: SortDirection(direction),
+		RetrievedAt:   payload.RetrievedAt,
+		ID:            payload.ID,
+	}
+	if payload.PublishedAt != nil {
+		c.PublishedAt = sql.NullTime{Valid: true, Time: *payload.PublishedAt}
+	}
+	return c, nil
 }
 
 type Feed struct {
-	ID           string         `json:"id"`
-	URL          string         `json:"url"`
-	Title        string         `json:"title"`
-	ETag         sql.NullString `json:"etag"`
-	LastModified sql.NullString `json:"last_modified"`
-	LastCrawled  sql.NullTime   `json:"last_crawled"`
-	Active       bool           `json:"active"`
+	ID                  string         `json:"id"`
+	URL                 string         `json:"url"`
+	Title               string         `json:"title"`
+	ETag                sql.NullString `json:"etag"`
+	LastModified        sql.NullString `json:"last_modified"`
+	LastCrawled         sql.NullTime   `json:"last_crawled"`
+	Active              bool           `json:"active"`
+	NextCrawlAt         sql.NullTime   `json:"next_crawl_at"`
+	RefreshInterval     time.Duration  `json:"refresh_interval"`
+	SkipHours           uint32         `json:"skip_hours"`
+	SkipDays            uint8          `json:"skip_days"`
+	BackoffUntil        sql.NullTime   `json:"backoff_until"`
+	BackoffDuration     time.Duration  `json:"backoff_duration"`
+	BackoffReason       sql.NullString `json:"backoff_reason"`
+	BackoffFailures     int32          `json:"backoff_failures"`
+	ConsecutiveFailures int32          `json:"consecutive_failures"`
+	LastError           sql.NullString `json:"last_error"`
+	LastErrorAt         sql.NullTime   `json:"last_error_at"`
+	LastSuccessAt       sql.NullTime   `json:"last_success_at"`
+	LastStatus          sql.NullInt32  `json:"last_status"`
+	ConsecutiveNotFound int32          `json:"consecutive_not_found"`
+	DeactivatedAt       sql.NullTime   `json:"deactivated_at"`
+	DeactivatedReason   sql.NullString `json:"deactivated_reason"`
+	RejectedRelocation  sql.NullString `json:"rejected_relocation"`
+	CanonicalURL        sql.NullString `json:"canonical_url"`
+	TitleLocked         bool           `json:"title_locked"`
+	Category            sql.NullString `json:"category"`
+	// Seq numbers the user's subscription for clients that cannot take
+	// UUIDs. Only ListUserFeeds and GetUserFeed load it.
+	Seq int64 `json:"-"`
+}
+
+// InsertFeedParams describes a new subscription. URL is stored as given and
+// used for fetching, while its canonical form rejects duplicates. The
+// remaining fields pre-populate the feed from a validation fetch; HTTP
+// validators are not stored so the first crawl still ingests the feed's items.
+type InsertFeedParams struct {
+	// UserID subscribes the user to the feed, reusing the feed another user
+	// already added. Title, when locked, and Category then belong to the
+	// subscription. When empty, the feed is shared by the open API.
+	UserID string
+	URL    string
+	Title  string
+	// TitleLocked keeps crawls from replacing Title with the feed's own
+	// name.
+	TitleLocked bool
+	// Category files the feed in a folder; "/" separates nested folders.
+	Category        string
+	RefreshInterval time.Duration
+	SkipHours       uint32
+	SkipDays        uint8
+	// ValidatedAt is when the feed was last fetched successfully, or zero
+	// when it was never fetched.
+	ValidatedAt time.Time
+	Status      int32
 }
 
-func (s *Store) InsertFeed(ctx context.Context, url string) (feed Feed, err error) {
+// InsertFeed adds a feed. It returns ErrFeedExists when the URL belongs to
+// an existing feed, or with a UserID, when the user is already subscribed
+// to it.
+func (s *Store) InsertFeed(ctx context.Context, arg InsertFeedParams) (feed Feed, err error) {
 	if s.metrics != nil {
 		defer func(start time.Time) {
 			s.metrics.ObserveDB("InsertFeed", err, time.Since(start))
 		}(time.Now())
 	}
 
-	row, err := s.queries.InsertFeed(ctx, url)
+	var userID uuid.NullUUID
+	userID, err = parseUserID(arg.UserID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			err = ErrFeedExists
+		return Feed{}, err
+	}
+
+	params := sqlc.InsertFeedParams{
+		Url:                    arg.URL,
+		CanonicalUrl:           urlcanon.Normalize(arg.URL),
+		Title:                  arg.Title,
+		TitleLocked:            arg.TitleLocked,
+		Category:               sql.NullString{Valid: arg.Category != "", String: arg.Category},
+		RefreshIntervalSeconds: int32(arg.RefreshInterval / time.Second),
+		SkipHours:              int32(arg.SkipHours),
+		SkipDays:               int16(arg.SkipDays),
+	}
+	if !arg.ValidatedAt.IsZero() {
+		params.LastSuccessAt = sql.NullTime{Valid: true, Time: arg.ValidatedAt}
+		params.LastStatus = sql.NullInt32{Valid: arg.Status != 0, Int32: arg.Status}
+	}
+	if !userID.Valid {
+		var row sqlc.Feed
+		row, err = s.queries.InsertFeed(ctx, params)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrFeedExists
+			}
+			return Feed{}, err
 		}
+		feed = mapFeed(row)
+		return feed, nil
+	}
+
+	// The user's name and folder live on the subscription; the shared row
+	// keeps the name the feed declares.
+	params.TitleLocked = false
+	params.Category = sql.NullString{}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
 		return Feed{}, err
 	}
-	feed = mapFeed(row)
-	return feed, nil
-}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
 
-func (s *Store) ListFeeds(ctx context.Context, active bool) (feeds []Feed, err error) {
-	if s.metrics != nil {
-		defer func(start time.Time) {
-			s.metrics.ObserveDB("ListFeeds", err, time.Since(start))
-		}(time.Now())
+	var feedID uuid.UUID
+	var row sqlc.Feed
+	row, err = q.InsertFeed(ctx, params)
+	switch {
+	case err == nil:
+		feedID = row.ID
+	case errors.Is(err, sql.ErrNoRows):
+		feedID, err = q.FindFeedID(ctx, sqlc.FindFeedIDParams{Url: params.Url, CanonicalUrl: params.CanonicalUrl})
+		if err != nil {
+			return Feed{}, err
+		}
+	default:
+		return Feed{}, err
 	}
 
-	rows, err := s.queries.ListFeeds(ctx, active)
+	var created int64
+	created, err = q.CreateSubscription(ctx, sqlc.CreateSubscriptionParams{
+		UserID:   userID.UUID,
+		FeedID:   feedID,
+		Title:    sql.NullString{Valid: arg.TitleLocked && arg.Title != "", String: arg.Title},
+		Category: sql.NullString{Valid: arg.Category != "", String: arg.Category},
+	})
 	if err != nil {
-		return nil, err
+		return Feed{}, err
 	}
-	feeds = make([]Feed, 0, len(rows))
-	for _, f := range rows {
-		feeds = append(feeds, mapFeed(f))
+	if created == 0 {
+		err = ErrFeedExists
+		return Feed{}, err
 	}
-	return feeds, nil
-}
 
-type UpdateFeedCrawlStateParams struct {
-	ID           string
-	ETag         sql.NullString
-	LastModified sql.NullString
-	LastCrawled  sql.NullTime
-	Title        string
+	var sub sqlc.GetUserFeedRow
+	sub, err = q.GetUserFeed(ctx, sqlc.GetUserFeedParams{UserID: userID.UUID, FeedID: feedID})
+	if err != nil {
+		return Feed{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return Feed{}, err
+	}
+	feed = mapUserFeed(sub.Feed, sub.SubscriptionTitle, sub.SubscriptionCategory, sub.SubscriptionActive)
+	return feed, nil
 }
 
-func (s *Store) UpdateFeedCrawlState(ctx context.Context, arg UpdateFeedCrawlStateParams) (feed Feed, err error) {
+func (s *Store) GetFeed(ctx context.Context, id string) (feed Feed, err error) {
 	if s.metrics != nil {
 		defer func(start time.Time) {
-			s.metrics.ObserveDB("UpdateFeedCrawlState", err, time.Since(start))
+			s.metrics.ObserveDB("GetFeed", err, time.Since(start))
 		}(time.Now())
 	}
 
 	var feedID uuid.UUID
-	feedID, err = uuid.Parse(arg.ID)
+	feedID, err = uuid.Parse(id)
 	if err != nil {
 		return Feed{}, err
 	}
 
-	var updated sqlc.Feed
-	updated, err = s.queries.UpdateFeedCrawlState(ctx, sqlc.UpdateFeedCrawlStateParams{
-		ID:           feedID,
-		Etag:         arg.ETag,
-		LastModified: arg.LastModified,
-		LastCrawled:  arg.LastCrawled,
-		NewTitle:     arg.Title,
-	})
+	var row sqlc.Feed
+	row, err = s.queries.GetFeed(ctx, feedID)
 	if err != nil {
 		return Feed{}, err
 	}
-	feed = mapFeed(updated)
+	feed = mapFeed(row)
 	return feed, nil
 }
 
-type Item struct {
-	ID          string         `json:"id"`
-	FeedID      string         `json:"feed_id"`
-	FeedTitle   string         `json:"feed_title"`
-	GUID        sql.NullString `json:"guid"`
-	URL         string         `json:"url"`
-	Title       string         `json:"title"`
-	Author      sql.NullString `json:"author"`
-	ContentHTML string         `json:"content_html"`
-	ContentText string         `json:"content_text"`
-	PublishedAt sql.NullTime   `json:"published_at"`
-	RetrievedAt time.Time      `json:"retrieved_at"`
-}
-
-type UpsertItemParams struct {
-	FeedID      string
-	GUID        sql.NullString
-	URL         string
-	Title       string
-	Author      sql.NullString
-	ContentHTML string
-	ContentText string
-	PublishedAt sql.NullTime
-	RetrievedAt sql.NullTime
-	ContentHash []byte
-}
-
-type UpsertItemResult struct {
-	Item    Item
-	Fresh   bool
-	Indexed bool
-}
-
-func (s *Store) UpsertItem(ctx context.Context, arg UpsertItemParams) (result UpsertItemResult, err error) {
+func (s *Store) GetFeedByURL(ctx context.Context, url string) (feed Feed, err error) {
 	if s.metrics != nil {
 		defer func(start time.Time) {
-			s.metrics.ObserveDB("UpsertItem", err, time.Since(start))
+			s.metrics.ObserveDB("GetFeedByURL", err, time.Since(start))
 		}(time.Now())
 	}
 
-	var feedID uuid.UUID
-	feedID, err = uuid.Parse(arg.FeedID)
-	if err != nil {
-		return UpsertItemResult{}, err
-	}
-
-	var row sqlc.UpsertItemRow
-	row, err = s.queries.UpsertItem(ctx, sqlc.UpsertItemParams{
-		FeedID:      feedID,
-		Guid:        arg.GUID,
-		Url:         arg.URL,
-		Title:       arg.Title,
-		Author:      arg.Author,
-		ContentHtml: arg.ContentHTML,
-		ContentText: arg.ContentText,
-		PublishedAt: arg.PublishedAt,
-		RetrievedAt: nullTimeArg(arg.RetrievedAt),
-		ContentHash: arg.ContentHash,
+	var row sqlc.Feed
+	row, err = s.queries.GetFeedByURL(ctx, sqlc.GetFeedByURLParams{
+		Url:          url,
+		CanonicalUrl: urlcanon.Normalize(url),
 	})
 	if err != nil {
-		return UpsertItemResult{}, err
+		return Feed{}, err
 	}
-
-	item := mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt)
-
-	indexed := row.Indexed.Valid && row.Indexed.Bool
-
-	result = UpsertItemResult{Item: item, Fresh: row.Inserted, Indexed: indexed}
-	return result, nil
-}
-
-type ListRecentParams struct {
-	Limit  int32
-	Offset int32
+	feed = mapFeed(row)
+	return feed, nil
 }
 
-func (s *Store) ListRecent(ctx context.Context, arg ListRecentParams) (items []Item, err error) {
+func (s *Store) ListFeeds(ctx context.Context, active bool) (feeds []Feed, err error) {
 	if s.metrics != nil {
 		defer func(start time.Time) {
-			s.metrics.ObserveDB("ListRecent", err, time.Since(start))
+			s.metrics.ObserveDB("ListFeeds", err, time.Since(start))
 		}(time.Now())
 	}
 
-	var rows []sqlc.ListRecentRow
-	rows, err = s.queries.ListRecent(ctx, sqlc.ListRecentParams{
-		Limit:  arg.Limit,
-		Offset: arg.Offset,
-	})
+	rows, err := s.queries.ListFeeds(ctx, active)
 	if err != nil {
 		return nil, err
 	}
-
-	items = make([]Item, 0, len(rows))
-	for _, row := range rows {
-		items = append(items, mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt))
+	feeds = make([]Feed, 0, len(rows))
+	for _, f := range rows {
+		feeds = append(feeds, mapFeed(f))
 	}
-	return items, nil
+	return feeds, nil
 }
 
-func (s *Store) ListRecentFiltered(ctx context.Context, feedIDs []string, direction SortDirection, limit, offset int32) (items []Item, err error) {
+func (s *Store) ListDueFeeds(ctx context.Context, now time.Time) (feeds []Feed, err error) {
 	if s.metrics != nil {
 		defer func(start time.Time) {
-			s.metrics.ObserveDB("ListRecentFiltered", err, time.Since(start))
+			s.metrics.ObserveDB("ListDueFeeds", err, time.Since(start))
 		}(time.Now())
 	}
 
-	sortDirection := direction
-	if sortDirection == "" {
-		sortDirection = SortDirectionDesc
-	}
-
-	if sortDirection != SortDirectionAsc && sortDirection != SortDirectionDesc {
-		err = fmt.Errorf("store: invalid sort direction %q", sortDirection)
+	rows, err := s.queries.ListDueFeeds(ctx, sql.NullTime{Valid: true, Time: now})
+	if err != nil {
 		return nil, err
 	}
+	feeds = make([]Feed, 0, len(rows))
+	for _, f := range rows {
+		feeds = append(feeds, mapFeed(f))
+	}
+	return feeds, nil
+}
 
-	var parsedIDs []uuid.UUID
-	if len(feedIDs) > 0 {
-		parsedIDs = make([]uuid.UUID, len(feedIDs))
-		for i, id := range feedIDs {
-			parsedIDs[i], err = uuid.Parse(id)
-			if err != nil {
-				return nil, err
-			}
-		}
+func (s *Store) ScheduleFeedCrawl(ctx context.Context, id string, next time.Time) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ScheduleFeedCrawl", err, time.Since(start))
+		}(time.Now())
 	}
 
-	var rows []sqlc.ListRecentFilteredRow
-	rows, err = s.queries.ListRecentFiltered(ctx, sqlc.ListRecentFilteredParams{
-		FeedIds:       parsedIDs,
-		SortDirection: string(sortDirection),
-		ResultLimit:   limit,
-		ResultOffset:  offset,
-	})
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(id)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	items = make([]Item, 0, len(rows))
-	for _, row := range rows {
-		items = append(items, mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt))
-	}
-	return items, nil
+	err = s.queries.ScheduleFeedCrawl(ctx, sqlc.ScheduleFeedCrawlParams{
+		ID:          feedID,
+		NextCrawlAt: sql.NullTime{Valid: true, Time: next},
+	})
+	return err
 }
 
-func (s *Store) ListByFeed(ctx context.Context, feedID string, limit, offset int32) (items []Item, err error) {
+// ListFeedPublishTimes returns up to limit publish timestamps for the feed's
+// items, newest first.
+func (s *Store) ListFeedPublishTimes(ctx context.Context, feedID string, limit int32) (times []time.Time, err error) {
 	if s.metrics != nil {
 		defer func(start time.Time) {
-			s.metrics.ObserveDB("ListByFeed", err, time.Since(start))
+			s.metrics.ObserveDB("ListFeedPublishTimes", err, time.Since(start))
 		}(time.Now())
 	}
 
@@ -302,53 +441,2200 @@ func (s *Store) ListByFeed(ctx context.Context, feedID string, limit, offset int
 		return nil, err
 	}
 
-	var rows []sqlc.ListByFeedRow
-	rows, err = s.queries.ListByFeed(ctx, sqlc.ListByFeedParams{
-		FeedID: id,
-		Limit:  limit,
-		Offset: offset,
+	var rows []sql.NullTime
+	rows, err = s.queries.ListFeedPublishTimes(ctx, sqlc.ListFeedPublishTimesParams{
+		FeedID:      id,
+		ResultLimit: limit,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	items = make([]Item, 0, len(rows))
+	times = make([]time.Time, 0, len(rows))
 	for _, row := range rows {
-		items = append(items, mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt))
+		if row.Valid {
+			times = append(times, row.Time)
+		}
 	}
-	return items, nil
+	return times, nil
 }
 
-func (s *Store) FilterItems(ctx context.Context, arg FilterItemsParams) (result FilterItemsResult, err error) {
+type UpdateFeedCrawlStateParams struct {
+	ID              string
+	ETag            sql.NullString
+	LastModified    sql.NullString
+	LastCrawled     sql.NullTime
+	Title           string
+	RefreshInterval time.Duration
+	SkipHours       uint32
+	SkipDays        uint8
+}
+
+func (s *Store) UpdateFeedCrawlState(ctx context.Context, arg UpdateFeedCrawlStateParams) (feed Feed, err error) {
 	if s.metrics != nil {
 		defer func(start time.Time) {
-			s.metrics.ObserveDB("FilterItems", err, time.Since(start))
+			s.metrics.ObserveDB("UpdateFeedCrawlState", err, time.Since(start))
 		}(time.Now())
 	}
 
-	sortField := arg.SortField
-	if sortField == "" {
-		sortField = ItemSortFieldPublishedAt
-	}
-	sortDirection := arg.SortDirection
-	if sortDirection == "" {
-		sortDirection = SortDirectionDesc
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(arg.ID)
+	if err != nil {
+		return Feed{}, err
 	}
 
-	orderColumn := map[ItemSortField]string{
-		ItemSortFieldPublishedAt: "i.published_at",
-		ItemSortFieldRetrievedAt: "i.retrieved_at",
+	var updated sqlc.Feed
+	updated, err = s.queries.UpdateFeedCrawlState(ctx, sqlc.UpdateFeedCrawlStateParams{
+		ID:                     feedID,
+		Etag:                   arg.ETag,
+		LastModified:           arg.LastModified,
+		LastCrawled:            arg.LastCrawled,
+		NewTitle:               arg.Title,
+		RefreshIntervalSeconds: int32(arg.RefreshInterval / time.Second),
+		SkipHours:              int32(arg.SkipHours),
+		SkipDays:               int16(arg.SkipDays),
+	})
+	if err != nil {
+		return Feed{}, err
 	}
-	column, ok := orderColumn[sortField]
-	if !ok {
-		err = fmt.Errorf("store: invalid sort field %q", sortField)
-		return FilterItemsResult{}, err
+	feed = mapFeed(updated)
+	return feed, nil
+}
+
+// ListBackedOffFeeds returns active feeds whose backoff is still in effect at
+// now.
+func (s *Store) ListBackedOffFeeds(ctx context.Context, now time.Time) (feeds []Feed, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListBackedOffFeeds", err, time.Since(start))
+		}(time.Now())
 	}
 
-	dirSQL := "DESC"
-	switch sortDirection {
-	case SortDirectionAsc:
-		dirSQL = "ASC"
+	rows, err := s.queries.ListBackedOffFeeds(ctx, sql.NullTime{Valid: true, Time: now})
+	if err != nil {
+		return nil, err
+	}
+	feeds = make([]Feed, 0, len(rows))
+	for _, f := range rows {
+		feeds = append(feeds, mapFeed(f))
+	}
+	return feeds, nil
+}
+
+type UpdateFeedBackoffParams struct {
+	ID       string
+	Until    time.Time
+	Duration time.Duration
+	Reason   string
+	Failures int32
+}
+
+func (s *Store) UpdateFeedBackoff(ctx context.Context, arg UpdateFeedBackoffParams) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("UpdateFeedBackoff", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(arg.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.queries.UpdateFeedBackoff(ctx, sqlc.UpdateFeedBackoffParams{
+		ID:              feedID,
+		BackoffUntil:    sql.NullTime{Valid: true, Time: arg.Until},
+		BackoffSeconds:  int32(arg.Duration / time.Second),
+		BackoffReason:   sql.NullString{Valid: arg.Reason != "", String: arg.Reason},
+		BackoffFailures: arg.Failures,
+	})
+	return err
+}
+
+func (s *Store) ClearFeedBackoff(ctx context.Context, id string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ClearFeedBackoff", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.queries.ClearFeedBackoff(ctx, feedID)
+	return err
+}
+
+// UpdateFeedURL moves the feed to url and records the URL it replaces in
+// feed_url_history. It returns ErrFeedExists when another feed already uses
+// url, now or previously.
+func (s *Store) UpdateFeedURL(ctx context.Context, id, url string) (feed Feed, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("UpdateFeedURL", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(id)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Feed{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	var current sqlc.Feed
+	current, err = q.GetFeed(ctx, feedID)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	var updated sqlc.Feed
+	updated, err = moveFeedURL(ctx, q, current, url)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Feed{}, err
+	}
+	feed = mapFeed(updated)
+	return feed, nil
+}
+
+// moveFeedURL points current at url and records the URL it leaves in the
+// feed's history.
+func moveFeedURL(ctx context.Context, q *sqlc.Queries, current sqlc.Feed, url string) (sqlc.Feed, error) {
+	if current.Url == url {
+		return current, nil
+	}
+
+	// A feed moving back to one of its own earlier URLs takes it out of
+	// the history again.
+	err := q.DeleteFeedURLHistory(ctx, sqlc.DeleteFeedURLHistoryParams{Url: url, FeedID: current.ID})
+	if err != nil {
+		return sqlc.Feed{}, err
+	}
+
+	updated, err := q.UpdateFeedURL(ctx, sqlc.UpdateFeedURLParams{
+		Url:          url,
+		CanonicalUrl: urlcanon.Normalize(url),
+		ID:           current.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrFeedExists
+		}
+		return sqlc.Feed{}, err
+	}
+
+	err = q.InsertFeedURLHistory(ctx, sqlc.InsertFeedURLHistoryParams{
+		Url:        current.Url,
+		FeedID:     current.ID,
+		ReplacedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return sqlc.Feed{}, err
+	}
+	return updated, nil
+}
+
+// moveSubscription points userID's subscription to current at the feed
+// for url, adding that feed when nobody follows it yet, and deletes current
+// once it has no subscribers left. It returns the ID of the feed now
+// subscribed to, or ErrFeedExists when the user already follows it under
+// another subscription.
+func moveSubscription(ctx context.Context, q *sqlc.Queries, userID uuid.UUID, current sqlc.Feed, url string) (uuid.UUID, error) {
+	canonical := urlcanon.Normalize(url)
+	target, err := q.InsertFeed(ctx, sqlc.InsertFeedParams{
+		Url:          url,
+		CanonicalUrl: canonical,
+		Title:        current.Title,
+	})
+	targetID := target.ID
+	if errors.Is(err, sql.ErrNoRows) {
+		targetID, err = q.FindFeedID(ctx, sqlc.FindFeedIDParams{Url: url, CanonicalUrl: canonical})
+	}
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if targetID == current.ID {
+		return current.ID, nil
+	}
+
+	if _, err := q.LockFeed(ctx, current.ID); err != nil {
+		return uuid.UUID{}, err
+	}
+	_, err = q.GetUserFeed(ctx, sqlc.GetUserFeedParams{UserID: userID, FeedID: targetID})
+	switch {
+	case err == nil:
+		return uuid.UUID{}, ErrFeedExists
+	case !errors.Is(err, sql.ErrNoRows):
+		return uuid.UUID{}, err
+	}
+	_, err = q.MoveSubscription(ctx, sqlc.MoveSubscriptionParams{
+		NewFeedID: targetID,
+		UserID:    userID,
+		FeedID:    current.ID,
+	})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if _, err := deleteUnsubscribedFeed(ctx, q, current.ID); err != nil {
+		return uuid.UUID{}, err
+	}
+	return targetID, nil
+}
+
+// UpdateFeedParams lists the changes to apply to a feed; nil fields are left
+// as they are.
+type UpdateFeedParams struct {
+	// UserID applies every change to the user's subscription only; other
+	// subscribers keep the feed as it was.
+	UserID string
+	ID     string
+	// Title renames the feed and keeps crawls from overwriting the name.
+	// An empty title goes back to the name the feed declares.
+	Title *string
+	// Active pauses or resumes crawling. A feed is crawled while any
+	// subscriber keeps it active; resuming also revives a feed the fetcher
+	// deactivated, which is crawled on the next tick.
+	Active *bool
+	// URL moves the subscription to the feed at that URL, added when no
+	// one follows it yet, and returns that feed. The old feed is deleted
+	// when nobody else subscribes to it. Without a UserID the feed itself
+	// moves.
+	URL *string
+	// Category moves the feed to another folder; an empty category
+	// removes it from its folder.
+	Category *string
+}
+
+// FeedPausedReason is the deactivation reason recorded for feeds paused
+// through UpdateFeed.
+const FeedPausedReason = "paused"
+
+func (s *Store) UpdateFeed(ctx context.Context, arg UpdateFeedParams) (feed Feed, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("UpdateFeed", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(arg.ID)
+	if err != nil {
+		return Feed{}, err
+	}
+	var userID uuid.NullUUID
+	userID, err = parseUserID(arg.UserID)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Feed{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	var current sqlc.Feed
+	if userID.Valid {
+		var sub sqlc.GetUserFeedRow
+		sub, err = q.GetUserFeed(ctx, sqlc.GetUserFeedParams{UserID: userID.UUID, FeedID: feedID})
+		current = sub.Feed
+	} else {
+		current, err = q.GetFeed(ctx, feedID)
+	}
+	if err != nil {
+		return Feed{}, err
+	}
+
+	if arg.URL != nil {
+		if userID.Valid {
+			feedID, err = moveSubscription(ctx, q, userID.UUID, current, *arg.URL)
+			if err != nil {
+				return Feed{}, err
+			}
+			if feedID != current.ID {
+				current, err = q.GetFeed(ctx, feedID)
+				if err != nil {
+					return Feed{}, err
+				}
+			}
+		} else if _, err = moveFeedURL(ctx, q, current, *arg.URL); err != nil {
+			return Feed{}, err
+		}
+	}
+	if arg.Title != nil {
+		if userID.Valid {
+			_, err = q.UpdateSubscriptionTitle(ctx, sqlc.UpdateSubscriptionTitleParams{
+				Title:  sql.NullString{Valid: *arg.Title != "", String: *arg.Title},
+				UserID: userID.UUID,
+				FeedID: feedID,
+			})
+		} else {
+			err = q.UpdateFeedTitle(ctx, sqlc.UpdateFeedTitleParams{Title: *arg.Title, ID: feedID})
+		}
+		if err != nil {
+			return Feed{}, err
+		}
+	}
+	if arg.Category != nil {
+		category := sql.NullString{Valid: *arg.Category != "", String: *arg.Category}
+		if userID.Valid {
+			_, err = q.UpdateSubscriptionCategory(ctx, sqlc.UpdateSubscriptionCategoryParams{
+				Category: category,
+				UserID:   userID.UUID,
+				FeedID:   feedID,
+			})
+		} else {
+			err = q.UpdateFeedCategory(ctx, sqlc.UpdateFeedCategoryParams{Category: category, ID: feedID})
+		}
+		if err != nil {
+			return Feed{}, err
+		}
+	}
+	if arg.Active != nil {
+		if userID.Valid {
+			_, err = q.UpdateSubscriptionActive(ctx, sqlc.UpdateSubscriptionActiveParams{
+				Active: *arg.Active,
+				UserID: userID.UUID,
+				FeedID: feedID,
+			})
+			// The pause is the user's own, but a feed the fetcher gave
+			// up on is revived for everyone when resumed.
+			if err == nil && *arg.Active && !current.Active {
+				err = q.ActivateFeed(ctx, feedID)
+			}
+		} else if *arg.Active != current.Active {
+			if *arg.Active {
+				err = q.ActivateFeed(ctx, feedID)
+			} else {
+				err = q.DeactivateFeed(ctx, sqlc.DeactivateFeedParams{
+					ID:            feedID,
+					DeactivatedAt: sql.NullTime{Valid: true, Time: time.Now().UTC()},
+					Reason:        sql.NullString{Valid: true, String: FeedPausedReason},
+				})
+			}
+		}
+		if err != nil {
+			return Feed{}, err
+		}
+	}
+
+	if userID.Valid {
+		var sub sqlc.GetUserFeedRow
+		sub, err = q.GetUserFeed(ctx, sqlc.GetUserFeedParams{UserID: userID.UUID, FeedID: feedID})
+		if err != nil {
+			return Feed{}, err
+		}
+		feed = mapUserFeed(sub.Feed, sub.SubscriptionTitle, sub.SubscriptionCategory, sub.SubscriptionActive)
+	} else {
+		var updated sqlc.Feed
+		updated, err = q.GetFeed(ctx, feedID)
+		if err != nil {
+			return Feed{}, err
+		}
+		feed = mapFeed(updated)
+	}
+	if err = tx.Commit(); err != nil {
+		return Feed{}, err
+	}
+	return feed, nil
+}
+
+// DeleteFeed removes a feed together with its items and history, and
+// records the deletion for the fetcher to clear the feed's search
+// documents. It returns sql.ErrNoRows when the feed does not exist.
+func (s *Store) DeleteFeed(ctx context.Context, id string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("DeleteFeed", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	var deleted int64
+	deleted, err = q.DeleteFeed(ctx, feedID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		err = sql.ErrNoRows
+		return err
+	}
+	if err = q.RecordFeedDeletion(ctx, feedID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListUserFeeds returns the feeds userID subscribes to, named and filed as
+// the user chose. An empty userID lists every feed, as ListFeeds does.
+func (s *Store) ListUserFeeds(ctx context.Context, userID string, active bool) (feeds []Feed, err error) {
+	if userID == "" {
+		return s.ListFeeds(ctx, active)
+	}
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListUserFeeds", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.UUID
+	user, err = uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queries.ListUserFeeds(ctx, sqlc.ListUserFeedsParams{UserID: user, Active: active})
+	if err != nil {
+		return nil, err
+	}
+	feeds = make([]Feed, 0, len(rows))
+	for _, row := range rows {
+		f := mapUserFeed(row.Feed, row.SubscriptionTitle, row.SubscriptionCategory, row.SubscriptionActive)
+		f.Seq = row.SubscriptionSeq
+		feeds = append(feeds, f)
+	}
+	return feeds, nil
+}
+
+// GetUserFeed returns a feed as userID sees it. It returns sql.ErrNoRows
+// when the user is not subscribed to the feed. An empty userID returns the
+// feed as GetFeed does.
+func (s *Store) GetUserFeed(ctx context.Context, userID, id string) (feed Feed, err error) {
+	if userID == "" {
+		return s.GetFeed(ctx, id)
+	}
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetUserFeed", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user, feedID uuid.UUID
+	user, err = uuid.Parse(userID)
+	if err != nil {
+		return Feed{}, err
+	}
+	feedID, err = uuid.Parse(id)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	var row sqlc.GetUserFeedRow
+	row, err = s.queries.GetUserFeed(ctx, sqlc.GetUserFeedParams{UserID: user, FeedID: feedID})
+	if err != nil {
+		return Feed{}, err
+	}
+	feed = mapUserFeed(row.Feed, row.SubscriptionTitle, row.SubscriptionCategory, row.SubscriptionActive)
+	feed.Seq = row.SubscriptionSeq
+	return feed, nil
+}
+
+// ListSubscribedFeedIDs returns the IDs of the feeds userID subscribes to.
+func (s *Store) ListSubscribedFeedIDs(ctx context.Context, userID string) (ids []string, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListSubscribedFeedIDs", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.UUID
+	user, err = uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []uuid.UUID
+	rows, err = s.queries.ListSubscribedFeedIDs(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	ids = make([]string, 0, len(rows))
+	for _, id := range rows {
+		ids = append(ids, id.String())
+	}
+	return ids, nil
+}
+
+// Unsubscribe removes userID's subscription to a feed. When nobody else
+// subscribes, the feed and its items are deleted in the same transaction
+// and the deletion is recorded as DeleteFeed does; feedDeleted reports
+// whether that happened. It returns sql.ErrNoRows when the user is not
+// subscribed.
+func (s *Store) Unsubscribe(ctx context.Context, userID, feedID string) (feedDeleted bool, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("Unsubscribe", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user, id uuid.UUID
+	user, err = uuid.Parse(userID)
+	if err != nil {
+		return false, err
+	}
+	id, err = uuid.Parse(feedID)
+	if err != nil {
+		return false, err
+	}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	if _, err = q.LockFeed(ctx, id); err != nil {
+		return false, err
+	}
+	var deleted int64
+	deleted, err = q.DeleteSubscription(ctx, sqlc.DeleteSubscriptionParams{UserID: user, FeedID: id})
+	if err != nil {
+		return false, err
+	}
+	if deleted == 0 {
+		err = sql.ErrNoRows
+		return false, err
+	}
+	feedDeleted, err = deleteUnsubscribedFeed(ctx, q, id)
+	if err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return feedDeleted, nil
+}
+
+// deleteUnsubscribedFeed deletes a feed nobody subscribes to any more and
+// records the deletion. Callers hold the feed's lock from LockFeed.
+func deleteUnsubscribedFeed(ctx context.Context, q *sqlc.Queries, id uuid.UUID) (bool, error) {
+	deleted, err := q.DeleteUnsubscribedFeed(ctx, id)
+	if err != nil || deleted == 0 {
+		return false, err
+	}
+	if err := q.RecordFeedDeletion(ctx, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListFeedDeletions returns the IDs of up to limit deleted feeds whose
+// search documents have not been cleared yet, oldest first.
+func (s *Store) ListFeedDeletions(ctx context.Context, limit int32) (ids []string, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListFeedDeletions", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var rows []uuid.UUID
+	rows, err = s.queries.ListFeedDeletions(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids = make([]string, 0, len(rows))
+	for _, id := range rows {
+		ids = append(ids, id.String())
+	}
+	return ids, nil
+}
+
+// ClearFeedDeletions forgets deleted feeds once their search documents are
+// gone.
+func (s *Store) ClearFeedDeletions(ctx context.Context, ids []string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ClearFeedDeletions", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedIDs []uuid.UUID
+	feedIDs, err = parseUUIDs(ids)
+	if err != nil {
+		return err
+	}
+	return s.queries.ClearFeedDeletions(ctx, feedIDs)
+}
+
+// AdoptFeeds subscribes userID to every feed and hands it the read state
+// and tags recorded by the open API, so the first account of an existing
+// install keeps its subscriptions. It returns the number of new
+// subscriptions.
+func (s *Store) AdoptFeeds(ctx context.Context, userID string) (subscribed int64, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("AdoptFeeds", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.UUID
+	user, err = uuid.Parse(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	subscribed, err = q.SubscribeToAllFeeds(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = q.ClaimItemStates(ctx, user); err != nil {
+		return 0, err
+	}
+	if _, err = q.ClaimTags(ctx, user); err != nil {
+		return 0, err
+	}
+	if _, err = q.ClaimSubscriptionTags(ctx, user); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return subscribed, nil
+}
+
+// GetFeedByCanonicalURL returns the feed whose URL canonicalizes to the same
+// form as url.
+func (s *Store) GetFeedByCanonicalURL(ctx context.Context, url string) (feed Feed, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetFeedByCanonicalURL", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var row sqlc.Feed
+	row, err = s.queries.GetFeedByCanonicalURL(ctx, sql.NullString{Valid: true, String: urlcanon.Normalize(url)})
+	if err != nil {
+		return Feed{}, err
+	}
+	feed = mapFeed(row)
+	return feed, nil
+}
+
+// ListFeedsWithoutCanonicalURL returns feeds stored before URLs were
+// canonicalized, active and recently healthy feeds first.
+func (s *Store) ListFeedsWithoutCanonicalURL(ctx context.Context) (feeds []Feed, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListFeedsWithoutCanonicalURL", err, time.Since(start))
+		}(time.Now())
+	}
+
+	rows, err := s.queries.ListFeedsWithoutCanonicalURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feeds = make([]Feed, 0, len(rows))
+	for _, f := range rows {
+		feeds = append(feeds, mapFeed(f))
+	}
+	return feeds, nil
+}
+
+// SetFeedCanonicalURL stores the canonical form of url, the feed's current
+// URL, on the feed.
+func (s *Store) SetFeedCanonicalURL(ctx context.Context, id, url string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("SetFeedCanonicalURL", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.queries.SetFeedCanonicalURL(ctx, sqlc.SetFeedCanonicalURLParams{
+		CanonicalUrl: sql.NullString{Valid: true, String: urlcanon.Normalize(url)},
+		ID:           feedID,
+	})
+	return err
+}
+
+// RejectFeedRelocation remembers a relocation target that failed verification
+// so it is not fetched again on every crawl.
+func (s *Store) RejectFeedRelocation(ctx context.Context, id, url string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("RejectFeedRelocation", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.queries.RejectFeedRelocation(ctx, sqlc.RejectFeedRelocationParams{
+		ID:  feedID,
+		Url: sql.NullString{Valid: url != "", String: url},
+	})
+	return err
+}
+
+type MergeFeedsResult struct {
+	// Moved are the items that now belong to the kept feed.
+	Moved []Item
+	// Dropped are the IDs of items deleted because the kept feed already
+	// had them.
+	Dropped []string
+}
+
+// MergeFeeds folds dropID into keepID: items the kept feed does not already
+// have move over, the rest are deleted, the dropped feed's URLs and
+// subscribers join the kept feed, and the dropped feed is removed.
+func (s *Store) MergeFeeds(ctx context.Context, keepID, dropID string) (result MergeFeedsResult, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("MergeFeeds", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var keep, drop uuid.UUID
+	keep, err = uuid.Parse(keepID)
+	if err != nil {
+		return MergeFeedsResult{}, err
+	}
+	drop, err = uuid.Parse(dropID)
+	if err != nil {
+		return MergeFeedsResult{}, err
+	}
+	if keep == drop {
+		err = fmt.Errorf("store: cannot merge feed %s into itself", keepID)
+		return MergeFeedsResult{}, err
+	}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return MergeFeedsResult{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	var dropped sqlc.Feed
+	dropped, err = q.GetFeed(ctx, drop)
+	if err != nil {
+		return MergeFeedsResult{}, err
+	}
+
+	var duplicates []uuid.UUID
+	duplicates, err = q.DeleteDuplicateFeedItems(ctx, sqlc.DeleteDuplicateFeedItemsParams{DropFeedID: drop, KeepFeedID: keep})
+	if err != nil {
+		return MergeFeedsResult{}, err
+	}
+
+	var moved []sqlc.MoveFeedItemsRow
+	moved, err = q.MoveFeedItems(ctx, sqlc.MoveFeedItemsParams{DropFeedID: drop, KeepFeedID: keep})
+	if err != nil {
+		return MergeFeedsResult{}, err
+	}
+
+	err = q.MoveFeedURLHistory(ctx, sqlc.MoveFeedURLHistoryParams{KeepFeedID: keep, DropFeedID: drop})
+	if err != nil {
+		return MergeFeedsResult{}, err
+	}
+
+	err = q.MoveFeedSubscriptions(ctx, sqlc.MoveFeedSubscriptionsParams{KeepFeedID: keep, DropFeedID: drop})
+	if err != nil {
+		return MergeFeedsResult{}, err
+	}
+
+	if _, err = q.DeleteFeed(ctx, drop); err != nil {
+		return MergeFeedsResult{}, err
+	}
+
+	err = q.InsertFeedURLHistory(ctx, sqlc.InsertFeedURLHistoryParams{
+		Url:        dropped.Url,
+		FeedID:     keep,
+		ReplacedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return MergeFeedsResult{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return MergeFeedsResult{}, err
+	}
+
+	result.Dropped = make([]string, 0, len(duplicates))
+	for _, id := range duplicates {
+		result.Dropped = append(result.Dropped, id.String())
+	}
+	result.Moved = make([]Item, 0, len(moved))
+	for _, row := range moved {
+		result.Moved = append(result.Moved, mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt))
+	}
+	return result, nil
+}
+
+// DeactivateFeed stops the fetcher from crawling the feed and records why.
+func (s *Store) DeactivateFeed(ctx context.Context, id, reason string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("DeactivateFeed", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.queries.DeactivateFeed(ctx, sqlc.DeactivateFeedParams{
+		ID:            feedID,
+		DeactivatedAt: sql.NullTime{Valid: true, Time: time.Now().UTC()},
+		Reason:        sql.NullString{Valid: reason != "", String: reason},
+	})
+	return err
+}
+
+// FeedFetch is one entry of a feed's fetch history.
+type FeedFetch struct {
+	FeedID    string         `json:"feed_id"`
+	FetchedAt time.Time      `json:"fetched_at"`
+	Status    sql.NullInt32  `json:"status"`
+	Items     int32          `json:"items"`
+	Error     sql.NullString `json:"error"`
+	Duration  time.Duration  `json:"duration"`
+}
+
+type RecordFeedFetchParams struct {
+	FeedID    string
+	FetchedAt time.Time
+	Status    int
+	Items     int
+	Err       string
+	Duration  time.Duration
+	// Keep is how many log entries to retain for the feed; older entries are
+	// pruned. Zero keeps everything.
+	Keep int32
+}
+
+// RecordFeedFetch updates the feed's health columns and appends the attempt to
+// feed_fetch_log. An attempt with a non-empty Err counts as a failure.
+func (s *Store) RecordFeedFetch(ctx context.Context, arg RecordFeedFetchParams) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("RecordFeedFetch", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(arg.FeedID)
+	if err != nil {
+		return err
+	}
+
+	status := sql.NullInt32{Valid: arg.Status != 0, Int32: int32(arg.Status)}
+	fetchedAt := sql.NullTime{Valid: true, Time: arg.FetchedAt}
+	errText := sql.NullString{Valid: arg.Err != "", String: arg.Err}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	if errText.Valid {
+		err = q.RecordFeedFailure(ctx, sqlc.RecordFeedFailureParams{
+			ID:         feedID,
+			LastError:  errText,
+			FetchedAt:  fetchedAt,
+			LastStatus: status,
+		})
+	} else {
+		err = q.RecordFeedSuccess(ctx, sqlc.RecordFeedSuccessParams{
+			ID:         feedID,
+			FetchedAt:  fetchedAt,
+			LastStatus: status,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	err = q.InsertFeedFetchLog(ctx, sqlc.InsertFeedFetchLogParams{
+		FeedID:     feedID,
+		FetchedAt:  arg.FetchedAt,
+		Status:     status,
+		Items:      int32(arg.Items),
+		Error:      errText,
+		DurationMs: int32(arg.Duration / time.Millisecond),
+	})
+	if err != nil {
+		return err
+	}
+
+	if arg.Keep > 0 {
+		err = q.PruneFeedFetchLog(ctx, sqlc.PruneFeedFetchLogParams{FeedID: feedID, Keep: arg.Keep})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	return err
+}
+
+// ListFeedFetchLog returns up to limit recent fetch attempts for the feed,
+// newest first.
+func (s *Store) ListFeedFetchLog(ctx context.Context, feedID string, limit int32) (fetches []FeedFetch, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListFeedFetchLog", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var id uuid.UUID
+	id, err = uuid.Parse(feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.FeedFetchLog
+	rows, err = s.queries.ListFeedFetchLog(ctx, sqlc.ListFeedFetchLogParams{
+		FeedID:      id,
+		ResultLimit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fetches = make([]FeedFetch, 0, len(rows))
+	for _, row := range rows {
+		fetches = append(fetches, FeedFetch{
+			FeedID:    row.FeedID.String(),
+			FetchedAt: row.FetchedAt,
+			Status:    row.Status,
+			Items:     row.Items,
+			Error:     row.Error,
+			Duration:  time.Duration(row.DurationMs) * time.Millisecond,
+		})
+	}
+	return fetches, nil
+}
+
+// Tag is a user-defined label that groups feeds. Each user has their own
+// tags, applied to their own subscriptions. FeedCount is only set by
+// ListTags.
+type Tag struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+	FeedCount int64
+}
+
+// CreateTag adds a tag for userID, or for the open API when userID is
+// empty. It returns ErrTagExists when the owner already has a tag with the
+// same name, ignoring case.
+func (s *Store) CreateTag(ctx context.Context, userID, name string) (tag Tag, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("CreateTag", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return Tag{}, err
+	}
+
+	row, err := s.queries.CreateTag(ctx, sqlc.CreateTagParams{UserID: user, Name: name})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrTagExists
+		}
+		return Tag{}, err
+	}
+	tag = mapTag(row)
+	return tag, nil
+}
+
+// GetTag returns one of userID's tags. It returns sql.ErrNoRows when the
+// tag does not exist or belongs to someone else.
+func (s *Store) GetTag(ctx context.Context, userID, id string) (tag Tag, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetTag", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return Tag{}, err
+	}
+	var tagID uuid.UUID
+	tagID, err = uuid.Parse(id)
+	if err != nil {
+		return Tag{}, err
+	}
+
+	var row sqlc.Tag
+	row, err = s.queries.GetTag(ctx, sqlc.GetTagParams{ID: tagID, UserID: user})
+	if err != nil {
+		return Tag{}, err
+	}
+	tag = mapTag(row)
+	return tag, nil
+}
+
+// ListTags returns userID's tags ordered by name, with the number of
+// subscriptions carrying each.
+func (s *Store) ListTags(ctx context.Context, userID string) (tags []Tag, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListTags", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queries.ListTags(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	tags = make([]Tag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, Tag{
+			ID:        row.ID.String(),
+			Name:      row.Name,
+			CreatedAt: row.CreatedAt,
+			FeedCount: row.FeedCount,
+		})
+	}
+	return tags, nil
+}
+
+// RenameTag changes the name of one of userID's tags. It returns
+// ErrTagExists when another of their tags already uses the name and
+// sql.ErrNoRows when the tag does not exist.
+func (s *Store) RenameTag(ctx context.Context, userID, id, name string) (tag Tag, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("RenameTag", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return Tag{}, err
+	}
+	var tagID uuid.UUID
+	tagID, err = uuid.Parse(id)
+	if err != nil {
+		return Tag{}, err
+	}
+
+	var row sqlc.Tag
+	row, err = s.queries.RenameTag(ctx, sqlc.RenameTagParams{Name: name, ID: tagID, UserID: user})
+	if errors.Is(err, sql.ErrNoRows) {
+		// The guard against name clashes also yields no rows; tell the
+		// two apart so callers can report a conflict.
+		if _, lookupErr := s.queries.GetTag(ctx, sqlc.GetTagParams{ID: tagID, UserID: user}); lookupErr == nil {
+			err = ErrTagExists
+		}
+	}
+	if err != nil {
+		return Tag{}, err
+	}
+	tag = mapTag(row)
+	return tag, nil
+}
+
+// DeleteTag removes one of userID's tags from their subscriptions and
+// deletes it. It returns sql.ErrNoRows when the tag does not exist.
+func (s *Store) DeleteTag(ctx context.Context, userID, id string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("DeleteTag", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return err
+	}
+	var tagID uuid.UUID
+	tagID, err = uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	var deleted int64
+	deleted, err = s.queries.DeleteTag(ctx, sqlc.DeleteTagParams{ID: tagID, UserID: user})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+// ListFeedTags returns the tags userID put on a feed, ordered by name.
+func (s *Store) ListFeedTags(ctx context.Context, userID, feedID string) (tags []Tag, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListFeedTags", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var id uuid.UUID
+	id, err = uuid.Parse(feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.Tag
+	rows, err = s.queries.ListFeedTags(ctx, sqlc.ListFeedTagsParams{FeedID: id, UserID: user})
+	if err != nil {
+		return nil, err
+	}
+	tags = make([]Tag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, mapTag(row))
+	}
+	return tags, nil
+}
+
+// SetFeedTags replaces the tags userID put on a feed with the named tags,
+// creating those the user does not have yet, and returns the feed's tags.
+// It returns sql.ErrNoRows when the user is not subscribed to the feed, or
+// without a userID, when the feed does not exist.
+func (s *Store) SetFeedTags(ctx context.Context, userID, feedID string, names []string) (tags []Tag, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("SetFeedTags", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var id uuid.UUID
+	id, err = uuid.Parse(feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	if user.Valid {
+		_, err = q.GetUserFeed(ctx, sqlc.GetUserFeedParams{UserID: user.UUID, FeedID: id})
+	} else {
+		_, err = q.GetFeed(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = q.DeleteSubscriptionTags(ctx, sqlc.DeleteSubscriptionTagsParams{FeedID: id, UserID: user}); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		var tag sqlc.Tag
+		tag, err = q.CreateTag(ctx, sqlc.CreateTagParams{UserID: user, Name: name})
+		if errors.Is(err, sql.ErrNoRows) {
+			tag, err = q.GetTagByName(ctx, sqlc.GetTagByNameParams{Name: name, UserID: user})
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = q.InsertSubscriptionTag(ctx, sqlc.InsertSubscriptionTagParams{UserID: user, FeedID: id, TagID: tag.ID})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []sqlc.Tag
+	rows, err = q.ListFeedTags(ctx, sqlc.ListFeedTagsParams{FeedID: id, UserID: user})
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	tags = make([]Tag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, mapTag(row))
+	}
+	return tags, nil
+}
+
+// ListTaggedFeedIDs returns the IDs of the feeds userID put any of the
+// named tags on. Names match case-insensitively.
+func (s *Store) ListTaggedFeedIDs(ctx context.Context, userID string, names []string) (ids []string, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListTaggedFeedIDs", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []uuid.UUID
+	rows, err = s.queries.ListTaggedFeedIDs(ctx, sqlc.ListTaggedFeedIDsParams{UserID: user, Names: lowerStrings(names)})
+	if err != nil {
+		return nil, err
+	}
+	ids = make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.String())
+	}
+	return ids, nil
+}
+
+// User is an account that can sign in to the API.
+type User struct {
+	ID           string
+	Username     string
+	PasswordHash string
+	Admin        bool
+	CreatedAt    time.Time
+}
+
+// APIKey is a long-lived token a user created for scripts. Only a hash of
+// the token is stored.
+type APIKey struct {
+	ID         string
+	UserID     string
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	// Username and Admin describe the key's owner; only UseAPIKey sets
+	// them.
+	Username string
+	Admin    bool
+}
+
+func (s *Store) CountUsers(ctx context.Context) (count int64, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("CountUsers", err, time.Since(start))
+		}(time.Now())
+	}
+
+	count, err = s.queries.CountUsers(ctx)
+	return count, err
+}
+
+type CreateUserParams struct {
+	Username     string
+	PasswordHash string
+	Admin        bool
+}
+
+// CreateUser adds an account. It returns ErrUserExists when the username is
+// taken, ignoring case.
+func (s *Store) CreateUser(ctx context.Context, arg CreateUserParams) (user User, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("CreateUser", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var row sqlc.User
+	row, err = s.queries.CreateUser(ctx, sqlc.CreateUserParams{
+		Username:     arg.Username,
+		PasswordHash: arg.PasswordHash,
+		IsAdmin:      arg.Admin,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrUserExists
+		}
+		return User{}, err
+	}
+	user = mapUser(row)
+	return user, nil
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (user User, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetUserByUsername", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var row sqlc.User
+	row, err = s.queries.GetUserByUsername(ctx, username)
+	if err != nil {
+		return User{}, err
+	}
+	user = mapUser(row)
+	return user, nil
+}
+
+// CreateSession stores a session for the user under the hash of its token.
+// Expired sessions are cleared out on the way.
+func (s *Store) CreateSession(ctx context.Context, userID string, tokenHash []byte, expiresAt time.Time) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("CreateSession", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var id uuid.UUID
+	id, err = uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.queries.DeleteExpiredSessions(ctx); err != nil {
+		return err
+	}
+	err = s.queries.CreateSession(ctx, sqlc.CreateSessionParams{
+		TokenHash: tokenHash,
+		UserID:    id,
+		ExpiresAt: expiresAt,
+	})
+	return err
+}
+
+func (s *Store) DeleteSession(ctx context.Context, tokenHash []byte) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("DeleteSession", err, time.Since(start))
+		}(time.Now())
+	}
+
+	err = s.queries.DeleteSession(ctx, tokenHash)
+	return err
+}
+
+// GetSessionUser returns the user holding an unexpired session. It returns
+// sql.ErrNoRows for unknown or expired sessions.
+func (s *Store) GetSessionUser(ctx context.Context, tokenHash []byte) (user User, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetSessionUser", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var row sqlc.User
+	row, err = s.queries.GetSessionUser(ctx, tokenHash)
+	if err != nil {
+		return User{}, err
+	}
+	user = mapUser(row)
+	return user, nil
+}
+
+type CreateAPIKeyParams struct {
+	UserID    string
+	Name      string
+	TokenHash []byte
+	Scopes    []string
+}
+
+func (s *Store) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (key APIKey, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("CreateAPIKey", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var userID uuid.UUID
+	userID, err = uuid.Parse(arg.UserID)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	var row sqlc.ApiKey
+	row, err = s.queries.CreateAPIKey(ctx, sqlc.CreateAPIKeyParams{
+		UserID:    userID,
+		Name:      arg.Name,
+		TokenHash: arg.TokenHash,
+		Scopes:    arg.Scopes,
+	})
+	if err != nil {
+		return APIKey{}, err
+	}
+	key = mapAPIKey(row)
+	return key, nil
+}
+
+// ListAPIKeys returns a user's API keys, oldest first.
+func (s *Store) ListAPIKeys(ctx context.Context, userID string) (keys []APIKey, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListAPIKeys", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var id uuid.UUID
+	id, err = uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.ApiKey
+	rows, err = s.queries.ListAPIKeys(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	keys = make([]APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, mapAPIKey(row))
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey revokes one of a user's API keys. It returns sql.ErrNoRows
+// when the user has no such key.
+func (s *Store) DeleteAPIKey(ctx context.Context, userID, id string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("DeleteAPIKey", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var owner, keyID uuid.UUID
+	owner, err = uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	keyID, err = uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	var deleted int64
+	deleted, err = s.queries.DeleteAPIKey(ctx, sqlc.DeleteAPIKeyParams{ID: keyID, UserID: owner})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+// UseAPIKey looks up the key stored under tokenHash together with its owner
+// and records that it was used. It returns sql.ErrNoRows for unknown keys.
+func (s *Store) UseAPIKey(ctx context.Context, tokenHash []byte) (key APIKey, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("UseAPIKey", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var row sqlc.UseAPIKeyRow
+	row, err = s.queries.UseAPIKey(ctx, tokenHash)
+	if err != nil {
+		return APIKey{}, err
+	}
+	key = APIKey{
+		ID:         row.ID.String(),
+		UserID:     row.UserID.String(),
+		Name:       row.Name,
+		Scopes:     row.Scopes,
+		CreatedAt:  row.CreatedAt,
+		LastUsedAt: row.LastUsedAt,
+		Username:   row.Username,
+		Admin:      row.IsAdmin,
+	}
+	return key, nil
+}
+
+// SetFeverKey stores the hash of a user's Fever API key, replacing any key
+// set before.
+func (s *Store) SetFeverKey(ctx context.Context, userID string, keyHash []byte) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("SetFeverKey", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var id uuid.UUID
+	id, err = uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.queries.SetFeverKey(ctx, sqlc.SetFeverKeyParams{UserID: id, KeyHash: keyHash})
+	return err
+}
+
+// DeleteFeverKey turns off Fever access for a user. It returns
+// sql.ErrNoRows when no key was set.
+func (s *Store) DeleteFeverKey(ctx context.Context, userID string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("DeleteFeverKey", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var id uuid.UUID
+	id, err = uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+
+	var deleted int64
+	deleted, err = s.queries.DeleteFeverKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+// GetFeverKeyUser returns the user a Fever API key belongs to. It returns
+// sql.ErrNoRows for unknown keys.
+func (s *Store) GetFeverKeyUser(ctx context.Context, keyHash []byte) (user User, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetFeverKeyUser", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var row sqlc.User
+	row, err = s.queries.GetFeverKeyUser(ctx, keyHash)
+	if err != nil {
+		return User{}, err
+	}
+	user = mapUser(row)
+	return user, nil
+}
+
+type Item struct {
+	ID          string         `json:"id"`
+	FeedID      string         `json:"feed_id"`
+	FeedTitle   string         `json:"feed_title"`
+	GUID        sql.NullString `json:"guid"`
+	URL         string         `json:"url"`
+	Title       string         `json:"title"`
+	Author      sql.NullString `json:"author"`
+	ContentHTML string         `json:"content_html"`
+	ContentText string         `json:"content_text"`
+	PublishedAt sql.NullTime   `json:"published_at"`
+	RetrievedAt time.Time      `json:"retrieved_at"`
+	// Read and Starred are only loaded by FilterItems and GetItem.
+	Read    bool `json:"read"`
+	Starred bool `json:"starred"`
+	// Seq is the item's integer ID for clients that cannot take UUIDs. It
+	// is only loaded by FilterItems.
+	Seq int64 `json:"-"`
+}
+
+type UpsertItemParams struct {
+	FeedID      string
+	GUID        sql.NullString
+	URL         string
+	Title       string
+	Author      sql.NullString
+	ContentHTML string
+	ContentText string
+	PublishedAt sql.NullTime
+	RetrievedAt sql.NullTime
+	ContentHash []byte
+}
+
+type UpsertItemResult struct {
+	Item    Item
+	Fresh   bool
+	Indexed bool
+}
+
+func (s *Store) UpsertItem(ctx context.Context, arg UpsertItemParams) (result UpsertItemResult, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("UpsertItem", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var feedID uuid.UUID
+	feedID, err = uuid.Parse(arg.FeedID)
+	if err != nil {
+		return UpsertItemResult{}, err
+	}
+
+	var row sqlc.UpsertItemRow
+	row, err = s.queries.UpsertItem(ctx, sqlc.UpsertItemParams{
+		FeedID:      feedID,
+		Guid:        arg.GUID,
+		Url:         arg.URL,
+		Title:       arg.Title,
+		Author:      arg.Author,
+		ContentHtml: arg.ContentHTML,
+		ContentText: arg.ContentText,
+		PublishedAt: arg.PublishedAt,
+		RetrievedAt: nullTimeArg(arg.RetrievedAt),
+		ContentHash: arg.ContentHash,
+	})
+	if err != nil {
+		return UpsertItemResult{}, err
+	}
+
+	item := mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt)
+
+	indexed := row.Indexed.Valid && row.Indexed.Bool
+
+	result = UpsertItemResult{Item: item, Fresh: row.Inserted, Indexed: indexed}
+	return result, nil
+}
+
+type ListRecentParams struct {
+	Limit  int32
+	Offset int32
+}
+
+func (s *Store) ListRecent(ctx context.Context, arg ListRecentParams) (items []Item, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListRecent", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var rows []sqlc.ListRecentRow
+	rows, err = s.queries.ListRecent(ctx, sqlc.ListRecentParams{
+		Limit:  arg.Limit,
+		Offset: arg.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items = make([]Item, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt))
+	}
+	return items, nil
+}
+
+func (s *Store) ListRecentFiltered(ctx context.Context, feedIDs []string, direction SortDirection, limit, offset int32) (items []Item, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListRecentFiltered", err, time.Since(start))
+		}(time.Now())
+	}
+
+	sortDirection := direction
+	if sortDirection == "" {
+		sortDirection = SortDirectionDesc
+	}
+
+	if sortDirection != SortDirectionAsc && sortDirection != SortDirectionDesc {
+		err = fmt.Errorf("store: invalid sort direction %q", sortDirection)
+		return nil, err
+	}
+
+	var parsedIDs []uuid.UUID
+	if len(feedIDs) > 0 {
+		parsedIDs = make([]uuid.UUID, len(feedIDs))
+		for i, id := range feedIDs {
+			parsedIDs[i], err = uuid.Parse(id)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var rows []sqlc.ListRecentFilteredRow
+	rows, err = s.queries.ListRecentFiltered(ctx, sqlc.ListRecentFilteredParams{
+		FeedIds:       parsedIDs,
+		SortDirection: string(sortDirection),
+		ResultLimit:   limit,
+		ResultOffset:  offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items = make([]Item, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt))
+	}
+	return items, nil
+}
+
+func (s *Store) ListByFeed(ctx context.Context, feedID string, limit, offset int32) (items []Item, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListByFeed", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var id uuid.UUID
+	id, err = uuid.Parse(feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.ListByFeedRow
+	rows, err = s.queries.ListByFeed(ctx, sqlc.ListByFeedParams{
+		FeedID: id,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items = make([]Item, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt))
+	}
+	return items, nil
+}
+
+// GetItem returns a single item with its feed title and the read state of
+// userID. It returns sql.ErrNoRows when the item does not exist or, for a
+// non-empty userID, belongs to a feed the user is not subscribed to.
+func (s *Store) GetItem(ctx context.Context, userID, id string) (item Item, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetItem", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var itemID uuid.UUID
+	itemID, err = uuid.Parse(id)
+	if err != nil {
+		return Item{}, err
+	}
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return Item{}, err
+	}
+
+	var row sqlc.GetItemRow
+	row, err = s.queries.GetItem(ctx, sqlc.GetItemParams{UserID: user, ID: itemID})
+	if err != nil {
+		return Item{}, err
+	}
+	item = mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt)
+	item.Read = row.Read
+	item.Starred = row.Starred
+	return item, nil
+}
+
+// ItemNeighbors holds the IDs of the items listed around an item, newest
+// first, in the global timeline and within its feed. IDs are empty at
+// either end of a listing.
+type ItemNeighbors struct {
+	Previous     string
+	Next         string
+	FeedPrevious string
+	FeedNext     string
+}
+
+// GetItemNeighbors returns the items listed before and after an item in the
+// default order of FilterItems. The timeline covers userID's subscriptions,
+// or every feed when userID is empty. It returns sql.ErrNoRows when the
+// item does not exist.
+func (s *Store) GetItemNeighbors(ctx context.Context, userID, id string) (neighbors ItemNeighbors, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetItemNeighbors", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var itemID uuid.UUID
+	itemID, err = uuid.Parse(id)
+	if err != nil {
+		return ItemNeighbors{}, err
+	}
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return ItemNeighbors{}, err
+	}
+
+	var row sqlc.GetItemNeighborsRow
+	row, err = s.queries.GetItemNeighbors(ctx, sqlc.GetItemNeighborsParams{ID: itemID, UserID: user})
+	if err != nil {
+		return ItemNeighbors{}, err
+	}
+	neighbors = ItemNeighbors{
+		Previous:     nullUUIDString(row.PreviousID),
+		Next:         nullUUIDString(row.NextID),
+		FeedPrevious: nullUUIDString(row.FeedPreviousID),
+		FeedNext:     nullUUIDString(row.FeedNextID),
+	}
+	return neighbors, nil
+}
+
+// UpdateItemStateParams lists the state changes to apply to an item; nil
+// fields are left as they are.
+type UpdateItemStateParams struct {
+	// UserID owns the state; an empty UserID changes the shared state of
+	// the open API.
+	UserID  string
+	ID      string
+	Read    *bool
+	Starred *bool
+}
+
+// UpdateItemState marks an item read or unread and starred or unstarred and
+// returns the updated item. It returns sql.ErrNoRows when the item does not
+// exist or is not in the user's subscriptions.
+func (s *Store) UpdateItemState(ctx context.Context, arg UpdateItemStateParams) (item Item, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("UpdateItemState", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var itemID uuid.UUID
+	itemID, err = uuid.Parse(arg.ID)
+	if err != nil {
+		return Item{}, err
+	}
+	var userID uuid.NullUUID
+	userID, err = parseUserID(arg.UserID)
+	if err != nil {
+		return Item{}, err
+	}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Item{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	if _, err = q.GetItem(ctx, sqlc.GetItemParams{UserID: userID, ID: itemID}); err != nil {
+		return Item{}, err
+	}
+	if arg.Read != nil {
+		err = q.SetItemRead(ctx, sqlc.SetItemReadParams{ItemID: itemID, UserID: userID, Read: *arg.Read, ReadAt: time.Now().UTC()})
+		if err != nil {
+			return Item{}, err
+		}
+	}
+	if arg.Starred != nil {
+		err = q.SetItemStarred(ctx, sqlc.SetItemStarredParams{ItemID: itemID, UserID: userID, Starred: *arg.Starred})
+		if err != nil {
+			return Item{}, err
+		}
+	}
+
+	var row sqlc.GetItemRow
+	row, err = q.GetItem(ctx, sqlc.GetItemParams{UserID: userID, ID: itemID})
+	if err != nil {
+		return Item{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return Item{}, err
+	}
+	item = mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt)
+	item.Read = row.Read
+	item.Starred = row.Starred
+	return item, nil
+}
+
+// MarkFeedRead marks every item of a feed retrieved at or before before as
+// read for userID and returns how many items changed.
+func (s *Store) MarkFeedRead(ctx context.Context, userID, feedID string, before time.Time) (marked int64, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("MarkFeedRead", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var id uuid.UUID
+	id, err = uuid.Parse(feedID)
+	if err != nil {
+		return 0, err
+	}
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	marked, err = s.queries.MarkFeedRead(ctx, sqlc.MarkFeedReadParams{
+		UserID: user,
+		ReadAt: time.Now().UTC(),
+		FeedID: id,
+		Before: before,
+	})
+	return marked, err
+}
+
+// ListUnreadCounts returns the number of items per feed ID that userID has
+// not read, across the user's subscriptions. Feeds without unread items are
+// left out.
+func (s *Store) ListUnreadCounts(ctx context.Context, userID string) (counts map[string]int64, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListUnreadCounts", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.ListUnreadCountsRow
+	rows, err = s.queries.ListUnreadCounts(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	counts = make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.FeedID.String()] = row.Unread
+	}
+	return counts, nil
+}
+
+// ListItemsBySeqParams selects a user's items by sequence number. Zero
+// bounds are open.
+type ListItemsBySeqParams struct {
+	UserID   string
+	SinceSeq int64
+	// MaxSeq lists items numbered below it, newest first. Otherwise items
+	// are listed oldest first.
+	MaxSeq int64
+	// Seqs, when set, keeps only the listed items.
+	Seqs  []int64
+	Limit int32
+}
+
+// ListItemsBySeq lists items from userID's subscriptions in sequence order,
+// with the user's read state.
+func (s *Store) ListItemsBySeq(ctx context.Context, arg ListItemsBySeqParams) (items []Item, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListItemsBySeq", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.UUID
+	user, err = uuid.Parse(arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.ListItemsBySeqRow
+	rows, err = s.queries.ListItemsBySeq(ctx, sqlc.ListItemsBySeqParams{
+		UserID:      user,
+		SinceSeq:    sql.NullInt64{Int64: arg.SinceSeq, Valid: arg.SinceSeq > 0},
+		MaxSeq:      sql.NullInt64{Int64: arg.MaxSeq, Valid: arg.MaxSeq > 0},
+		Seqs:        arg.Seqs,
+		ResultLimit: arg.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	items = make([]Item, 0, len(rows))
+	for _, row := range rows {
+		it := mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt)
+		it.Seq = row.Seq
+		it.Read = row.Read
+		it.Starred = row.Starred
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// CountUserItems returns how many items userID's subscriptions hold.
+func (s *Store) CountUserItems(ctx context.Context, userID string) (count int64, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("CountUserItems", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.UUID
+	user, err = uuid.Parse(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err = s.queries.CountUserItems(ctx, user)
+	return count, err
+}
+
+// ListUnreadItemSeqs returns the sequence numbers of every item userID has
+// not read, in ascending order.
+func (s *Store) ListUnreadItemSeqs(ctx context.Context, userID string) (seqs []int64, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListUnreadItemSeqs", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.UUID
+	user, err = uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seqs, err = s.queries.ListUnreadItemSeqs(ctx, user)
+	return seqs, err
+}
+
+// ListStarredItemSeqs returns the sequence numbers of every item userID has
+// starred, in ascending order.
+func (s *Store) ListStarredItemSeqs(ctx context.Context, userID string) (seqs []int64, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListStarredItemSeqs", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.UUID
+	user, err = uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seqs, err = s.queries.ListStarredItemSeqs(ctx, user)
+	return seqs, err
+}
+
+// itemHostSQL extracts the host of an item's URL without a leading "www.".
+// It must match the expression of the items_host_idx index.
+const itemHostSQL = `substring(lower(i.url) from '^[a-z][a-z0-9+.-]*://(?:www\.)?([^/:?#]+)')`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (s *Store) FilterItems(ctx context.Context, arg FilterItemsParams) (result FilterItemsResult, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("FilterItems", err, time.Since(start))
+		}(time.Now())
+	}
+
+	sortField := arg.SortField
+	if sortField == "" {
+		sortField = ItemSortFieldPublishedAt
+	}
+	sortDirection := arg.SortDirection
+	if sortDirection == "" {
+		sortDirection = SortDirectionDesc
+	}
+
+	orderColumn := map[ItemSortField]string{
+		ItemSortFieldPublishedAt: "i.published_at",
+		ItemSortFieldRetrievedAt: "i.retrieved_at",
+	}
+	column, ok := orderColumn[sortField]
+	if !ok {
+		err = fmt.Errorf("store: invalid sort field %q", sortField)
+		return FilterItemsResult{}, err
+	}
+
+	dirSQL := "DESC"
+	switch sortDirection {
+	case SortDirectionAsc:
+		dirSQL = "ASC"
 	case SortDirectionDesc:
 		dirSQL = "DESC"
 	default:
@@ -356,90 +2642,1193 @@ func (s *Store) FilterItems(ctx context.Context, arg FilterItemsParams) (result
 		return FilterItemsResult{}, err
 	}
 
-	var builder strings.Builder
-	builder.WriteString("SELECT i.id, i.feed_id, f.title AS feed_title, i.guid, i.url, i.title, i.author, i.content_html, i.content_text, i.published_at, i.retrieved_at, COUNT(*) OVER () AS total FROM items i JOIN feeds f ON f.id = i.feed_id")
+	// Counting every match is what makes deep pages slow, so cursor pages
+	// skip it.
+	totalSQL := "COUNT(*) OVER ()"
+	if arg.After != nil {
+		if arg.After.SortField != sortField || arg.After.SortDirection != sortDirection {
+			err = ErrInvalidCursor
+			return FilterItemsResult{}, err
+		}
+		totalSQL = "0::bigint"
+	}
+
+	var userID uuid.NullUUID
+	userID, err = parseUserID(arg.UserID)
+	if err != nil {
+		return FilterItemsResult{}, err
+	}
+
+	args := make([]any, 0, len(arg.FeedIDs)+len(arg.Tags)+6)
+	placeholder := 1
+	var builder strings.Builder
+	if userID.Valid {
+		builder.WriteString("SELECT i.id, i.feed_id, COALESCE(sub.title, f.title) AS feed_title, i.guid, i.url, i.title, i.author, i.content_html, i.content_text, i.published_at, i.retrieved_at, COALESCE(s.read, false), COALESCE(s.starred, false), i.seq, ")
+		builder.WriteString(totalSQL)
+		builder.WriteString(" AS total FROM items i JOIN feeds f ON f.id = i.feed_id JOIN subscriptions sub ON sub.feed_id = i.feed_id AND sub.user_id = $1 LEFT JOIN item_states s ON s.item_id = i.id AND s.user_id = $1")
+		placeholder++
+		args = append(args, userID.UUID)
+	} else {
+		builder.WriteString("SELECT i.id, i.feed_id, f.title AS feed_title, i.guid, i.url, i.title, i.author, i.content_html, i.content_text, i.published_at, i.retrieved_at, COALESCE(s.read, false), COALESCE(s.starred, false), i.seq, ")
+		builder.WriteString(totalSQL)
+		builder.WriteString(" AS total FROM items i JOIN feeds f ON f.id = i.feed_id LEFT JOIN item_states s ON s.item_id = i.id AND s.user_id IS NULL")
+	}
+	var conditions []string
+	if len(arg.FeedIDs) > 0 {
+		ids := make([]uuid.UUID, 0, len(arg.FeedIDs))
+		for _, id := range arg.FeedIDs {
+			var parsed uuid.UUID
+			parsed, err = uuid.Parse(id)
+			if err != nil {
+				return FilterItemsResult{}, err
+			}
+			ids = append(ids, parsed)
+		}
+
+		placeholders := make([]string, 0, len(ids))
+		for _, id := range ids {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", placeholder))
+			placeholder++
+			args = append(args, id)
+		}
+		conditions = append(conditions, fmt.Sprintf("i.feed_id IN (%s)", strings.Join(placeholders, ", ")))
+	}
+	if len(arg.Tags) > 0 {
+		placeholders := make([]string, 0, len(arg.Tags))
+		for _, tag := range arg.Tags {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", placeholder))
+			placeholder++
+			args = append(args, strings.ToLower(tag))
+		}
+		// Tags are the user's own, so only their subscriptions match.
+		owner := "st.user_id IS NULL"
+		if userID.Valid {
+			owner = "st.user_id = $1"
+		}
+		conditions = append(conditions, fmt.Sprintf("i.feed_id IN (SELECT st.feed_id FROM subscription_tags st JOIN tags t ON t.id = st.tag_id WHERE %s AND lower(t.name) IN (%s))", owner, strings.Join(placeholders, ", ")))
+	}
+	addCondition := func(format string, value any) {
+		conditions = append(conditions, fmt.Sprintf(format, placeholder))
+		placeholder++
+		args = append(args, value)
+	}
+	if arg.Category != "" {
+		if userID.Valid {
+			addCondition("sub.category = $%d", arg.Category)
+		} else {
+			addCondition("f.category = $%d", arg.Category)
+		}
+	}
+	if len(arg.Seqs) > 0 {
+		addCondition("i.seq = ANY($%d)", pq.Array(arg.Seqs))
+	}
+	if len(arg.IDs) > 0 {
+		ids := make([]string, 0, len(arg.IDs))
+		for _, id := range arg.IDs {
+			var parsed uuid.UUID
+			parsed, err = uuid.Parse(id)
+			if err != nil {
+				return FilterItemsResult{}, err
+			}
+			ids = append(ids, parsed.String())
+		}
+		addCondition("i.id = ANY($%d::uuid[])", pq.Array(ids))
+	}
+	if !arg.PublishedAfter.IsZero() {
+		addCondition("i.published_at >= $%d", arg.PublishedAfter)
+	}
+	if !arg.PublishedBefore.IsZero() {
+		addCondition("i.published_at < $%d", arg.PublishedBefore)
+	}
+	if !arg.RetrievedAfter.IsZero() {
+		addCondition("i.retrieved_at >= $%d", arg.RetrievedAfter)
+	}
+	if !arg.RetrievedBefore.IsZero() {
+		addCondition("i.retrieved_at < $%d", arg.RetrievedBefore)
+	}
+	if arg.Author != "" {
+		addCondition("lower(i.author) = $%d", strings.ToLower(arg.Author))
+	}
+	if arg.Title != "" {
+		addCondition("lower(i.title) LIKE $%d", "%"+likeEscaper.Replace(strings.ToLower(arg.Title))+"%")
+	}
+	if arg.Host != "" {
+		addCondition(itemHostSQL+" = $%d", strings.TrimPrefix(strings.ToLower(arg.Host), "www."))
+	}
+	if arg.Unread != nil {
+		addCondition("COALESCE(s.read, false) <> $%d", *arg.Unread)
+	}
+	if arg.Starred != nil {
+		addCondition("COALESCE(s.starred, false) = $%d", *arg.Starred)
+	}
+	if arg.After != nil {
+		var afterID uuid.UUID
+		afterID, err = uuid.Parse(arg.After.ID)
+		if err != nil {
+			err = ErrInvalidCursor
+			return FilterItemsResult{}, err
+		}
+		cmp := "<"
+		if sortDirection == SortDirectionAsc {
+			cmp = ">"
+		}
+		tail := fmt.Sprintf("(i.retrieved_at, i.id) %s ($%d, $%d)", cmp, placeholder, placeholder+1)
+		placeholder += 2
+		args = append(args, arg.After.RetrievedAt, afterID)
+		switch {
+		case sortField == ItemSortFieldRetrievedAt:
+			conditions = append(conditions, tail)
+		case arg.After.PublishedAt.Valid:
+			// Undated items sort last in either direction.
+			conditions = append(conditions, fmt.Sprintf("(i.published_at %s $%d OR (i.published_at = $%d AND %s) OR i.published_at IS NULL)", cmp, placeholder, placeholder, tail))
+			placeholder++
+			args = append(args, arg.After.PublishedAt.Time)
+		default:
+			conditions = append(conditions, fmt.Sprintf("(i.published_at IS NULL AND %s)", tail))
+		}
+	}
+	if len(conditions) > 0 {
+		builder.WriteString(" WHERE ")
+		builder.WriteString(strings.Join(conditions, " AND "))
+	}
+
+	builder.WriteString(" ORDER BY ")
+	if sortField == ItemSortFieldPublishedAt {
+		builder.WriteString(fmt.Sprintf("%s %s NULLS LAST, i.retrieved_at %s, i.id %s", column, dirSQL, dirSQL, dirSQL))
+	} else {
+		builder.WriteString(fmt.Sprintf("%s %s, i.id %s", column, dirSQL, dirSQL))
+	}
+
+	offset := arg.Offset
+	if arg.After != nil {
+		offset = 0
+	}
+	// One extra row tells whether another page follows.
+	builder.WriteString(fmt.Sprintf(" LIMIT $%d OFFSET $%d", placeholder, placeholder+1))
+	args = append(args, arg.Limit+1, offset)
+
+	rows, err := s.db.QueryContext(ctx, builder.String(), args...)
+	if err != nil {
+		return FilterItemsResult{}, err
+	}
+	defer rows.Close()
+
+	items := make([]Item, 0)
+	var total int64
+	for rows.Next() {
+		var (
+			id          uuid.UUID
+			feedID      uuid.UUID
+			feedTitle   string
+			guid        sql.NullString
+			url         string
+			title       string
+			author      sql.NullString
+			contentHTML string
+			contentText string
+			publishedAt sql.NullTime
+			retrievedAt time.Time
+			read        bool
+			starred     bool
+			seq         int64
+			rowTotal    int64
+		)
+		if err = rows.Scan(&id, &feedID, &feedTitle, &guid, &url, &title, &author, &contentHTML, &contentText, &publishedAt, &retrievedAt, &read, &starred, &seq, &rowTotal); err != nil {
+			return FilterItemsResult{}, err
+		}
+		total = rowTotal
+		it := mapItem(id, feedID, feedTitle, guid, url, title, author, contentHTML, contentText, publishedAt, retrievedAt)
+		it.Read = read
+		it.Starred = starred
+		it.Seq = seq
+		items = append(items, it)
+	}
+	if err = rows.Err(); err != nil {
+		return FilterItemsResult{}, err
+	}
+
+	result = FilterItemsResult{Items: items, Total: total}
+	if len(items) > int(arg.Limit) {
+		result.Items = items[:arg.Limit]
+		if arg.Limit > 0 {
+			last := result.Items[len(result.Items)-1]
+			result.NextCursor = &ItemCursor{
+				SortField:     sortField,
+				SortDirection: sortDirection,
+				PublishedAt:   last.PublishedAt,
+				RetrievedAt:   last.RetrievedAt,
+				ID:            last.ID,
+			}
+		}
+	}
+	return result, nil
+}
+
+// SavedSearch is a stored query. The fetcher checks newly indexed items
+// against it and records the ones that match. MatchCount and NewCount,
+// the matches found since LastViewedAt, are not set by
+// ListSavedSearchQueries.
+type SavedSearch struct {
+	ID     string
+	UserID string
+	Name   string
+	Query  string
+	// FeedIDs and Tags narrow the search to the listed feeds and to feeds
+	// carrying any of the tags; empty lists leave it open.
+	FeedIDs      []string
+	Tags         []string
+	CreatedAt    time.Time
+	LastViewedAt time.Time
+	MatchCount   int64
+	NewCount     int64
+}
+
+type CreateSavedSearchParams struct {
+	// UserID owns the search; empty stands for the open API.
+	UserID  string
+	Name    string
+	Query   string
+	FeedIDs []string
+	Tags    []string
+}
+
+// CreateSavedSearch stores a saved search. Tags are kept in lower case.
+func (s *Store) CreateSavedSearch(ctx context.Context, arg CreateSavedSearchParams) (saved SavedSearch, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("CreateSavedSearch", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(arg.UserID)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	var feedIDs []uuid.UUID
+	feedIDs, err = parseUUIDs(arg.FeedIDs)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+
+	var row sqlc.SavedSearch
+	row, err = s.queries.CreateSavedSearch(ctx, sqlc.CreateSavedSearchParams{
+		UserID:  user,
+		Name:    arg.Name,
+		Query:   arg.Query,
+		FeedIds: feedIDs,
+		Tags:    lowerStrings(arg.Tags),
+	})
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	saved = mapSavedSearch(sqlc.GetSavedSearchRow{
+		ID:           row.ID,
+		UserID:       row.UserID,
+		Name:         row.Name,
+		Query:        row.Query,
+		FeedIds:      row.FeedIds,
+		Tags:         row.Tags,
+		CreatedAt:    row.CreatedAt,
+		LastViewedAt: row.LastViewedAt,
+	})
+	return saved, nil
+}
+
+// GetSavedSearch returns one of userID's saved searches with its match
+// counts, which leave out matches from feeds the user no longer follows.
+// It returns sql.ErrNoRows when the user has no such search.
+func (s *Store) GetSavedSearch(ctx context.Context, userID, id string) (saved SavedSearch, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetSavedSearch", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	var searchID uuid.UUID
+	searchID, err = uuid.Parse(id)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+
+	var row sqlc.GetSavedSearchRow
+	row, err = s.queries.GetSavedSearch(ctx, sqlc.GetSavedSearchParams{ID: searchID, UserID: user})
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	saved = mapSavedSearch(row)
+	return saved, nil
+}
+
+// ListSavedSearches returns userID's saved searches ordered by name, with
+// their match counts as GetSavedSearch reports them.
+func (s *Store) ListSavedSearches(ctx context.Context, userID string) (searches []SavedSearch, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListSavedSearches", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.ListSavedSearchesRow
+	rows, err = s.queries.ListSavedSearches(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	searches = make([]SavedSearch, 0, len(rows))
+	for _, row := range rows {
+		searches = append(searches, mapSavedSearch(sqlc.GetSavedSearchRow(row)))
+	}
+	return searches, nil
+}
+
+// UpdateSavedSearchParams lists the changes to a saved search; nil fields
+// are left alone. An empty, non-nil FeedIDs or Tags clears that filter.
+type UpdateSavedSearchParams struct {
+	UserID  string
+	ID      string
+	Name    *string
+	Query   *string
+	FeedIDs []string
+	Tags    []string
+}
+
+// UpdateSavedSearch changes one of userID's saved searches. Matches found
+// so far are dropped when the query or filters change, since they no
+// longer describe the search. It returns sql.ErrNoRows when the user has no
+// such search.
+func (s *Store) UpdateSavedSearch(ctx context.Context, arg UpdateSavedSearchParams) (saved SavedSearch, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("UpdateSavedSearch", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(arg.UserID)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	var searchID uuid.UUID
+	searchID, err = uuid.Parse(arg.ID)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+
+	var tx *sql.Tx
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	q := s.queries.WithTx(tx)
+
+	var current sqlc.GetSavedSearchRow
+	current, err = q.GetSavedSearch(ctx, sqlc.GetSavedSearchParams{ID: searchID, UserID: user})
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	params := sqlc.UpdateSavedSearchParams{
+		Name:    current.Name,
+		Query:   current.Query,
+		FeedIds: current.FeedIds,
+		Tags:    current.Tags,
+		ID:      searchID,
+		UserID:  user,
+	}
+	changed := false
+	if arg.Name != nil {
+		params.Name = *arg.Name
+	}
+	if arg.Query != nil && *arg.Query != current.Query {
+		params.Query = *arg.Query
+		changed = true
+	}
+	if arg.FeedIDs != nil {
+		params.FeedIds, err = parseUUIDs(arg.FeedIDs)
+		if err != nil {
+			return SavedSearch{}, err
+		}
+		changed = true
+	}
+	if arg.Tags != nil {
+		params.Tags = lowerStrings(arg.Tags)
+		changed = true
+	}
+	// A nil slice would be written as NULL.
+	if params.FeedIds == nil {
+		params.FeedIds = []uuid.UUID{}
+	}
+	if params.Tags == nil {
+		params.Tags = []string{}
+	}
+
+	if _, err = q.UpdateSavedSearch(ctx, params); err != nil {
+		return SavedSearch{}, err
+	}
+	if changed {
+		if err = q.DeleteSavedSearchMatches(ctx, searchID); err != nil {
+			return SavedSearch{}, err
+		}
+	}
+	var row sqlc.GetSavedSearchRow
+	row, err = q.GetSavedSearch(ctx, sqlc.GetSavedSearchParams{ID: searchID, UserID: user})
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return SavedSearch{}, err
+	}
+	saved = mapSavedSearch(row)
+	return saved, nil
+}
+
+// DeleteSavedSearch removes one of userID's saved searches and its
+// matches. It returns sql.ErrNoRows when the user has no such search.
+func (s *Store) DeleteSavedSearch(ctx context.Context, userID, id string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("DeleteSavedSearch", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return err
+	}
+	var searchID uuid.UUID
+	searchID, err = uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	var deleted int64
+	deleted, err = s.queries.DeleteSavedSearch(ctx, sqlc.DeleteSavedSearchParams{ID: searchID, UserID: user})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+// MarkSavedSearchViewed clears a saved search's new matches. It returns
+// sql.ErrNoRows when userID has no such search.
+func (s *Store) MarkSavedSearchViewed(ctx context.Context, userID, id string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("MarkSavedSearchViewed", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return err
+	}
+	var searchID uuid.UUID
+	searchID, err = uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+
+	var marked int64
+	marked, err = s.queries.MarkSavedSearchViewed(ctx, sqlc.MarkSavedSearchViewedParams{ID: searchID, UserID: user})
+	if err != nil {
+		return err
+	}
+	if marked == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+// SavedSearchMatch is an item a saved search matched. New is set for
+// matches found since the search was last viewed.
+type SavedSearchMatch struct {
+	Item
+	MatchedAt time.Time
+	New       bool
+}
+
+type ListSavedSearchMatchesParams struct {
+	UserID string
+	ID     string
+	// New keeps only the matches found since the search was last viewed.
+	New    bool
+	Limit  int32
+	Offset int32
+}
+
+// ListSavedSearchMatches lists the items a saved search matched, most
+// recently matched first. It returns sql.ErrNoRows when the user has no
+// such search.
+func (s *Store) ListSavedSearchMatches(ctx context.Context, arg ListSavedSearchMatchesParams) (matches []SavedSearchMatch, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListSavedSearchMatches", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var searchID uuid.UUID
+	searchID, err = uuid.Parse(arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.queries.GetSavedSearch(ctx, sqlc.GetSavedSearchParams{ID: searchID, UserID: user}); err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.ListSavedSearchMatchesRow
+	rows, err = s.queries.ListSavedSearchMatches(ctx, sqlc.ListSavedSearchMatchesParams{
+		SavedSearchID: searchID,
+		OnlyNew:       arg.New,
+		ResultLimit:   arg.Limit,
+		ResultOffset:  arg.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	matches = make([]SavedSearchMatch, 0, len(rows))
+	for _, row := range rows {
+		it := mapItem(row.ID, row.FeedID, row.FeedTitle, row.Guid, row.Url, row.Title, row.Author, row.ContentHtml, row.ContentText, row.PublishedAt, row.RetrievedAt)
+		it.Read = row.Read
+		it.Starred = row.Starred
+		matches = append(matches, SavedSearchMatch{Item: it, MatchedAt: row.MatchedAt, New: row.IsNew})
+	}
+	return matches, nil
+}
+
+// ListSavedSearchQueries returns the ID and query of every saved search,
+// across all users, for the fetcher to evaluate.
+func (s *Store) ListSavedSearchQueries(ctx context.Context) (searches []SavedSearch, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListSavedSearchQueries", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var rows []sqlc.ListSavedSearchQueriesRow
+	rows, err = s.queries.ListSavedSearchQueries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	searches = make([]SavedSearch, 0, len(rows))
+	for _, row := range rows {
+		searches = append(searches, SavedSearch{ID: row.ID.String(), Query: row.Query})
+	}
+	return searches, nil
+}
+
+// AddSavedSearchMatches records the items among itemIDs that a saved
+// search matched. Items outside the owner's subscriptions or the search's
+// feed and tag filters are skipped, as are items already recorded. It
+// returns how many matches were added.
+func (s *Store) AddSavedSearchMatches(ctx context.Context, id string, itemIDs []string) (added int64, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("AddSavedSearchMatches", err, time.Since(start))
+		}(time.Now())
+	}
 
-	args := make([]any, 0, len(arg.FeedIDs)+2)
-	placeholder := 1
-	if len(arg.FeedIDs) > 0 {
-		ids := make([]uuid.UUID, 0, len(arg.FeedIDs))
-		for _, id := range arg.FeedIDs {
-			var parsed uuid.UUID
-			parsed, err = uuid.Parse(id)
-			if err != nil {
-				return FilterItemsResult{}, err
-			}
-			ids = append(ids, parsed)
-		}
+	var searchID uuid.UUID
+	searchID, err = uuid.Parse(id)
+	if err != nil {
+		return 0, err
+	}
+	var items []uuid.UUID
+	items, err = parseUUIDs(itemIDs)
+	if err != nil {
+		return 0, err
+	}
 
-		builder.WriteString(" WHERE i.feed_id IN (")
-		for i, id := range ids {
-			if i > 0 {
-				builder.WriteString(", ")
-			}
-			builder.WriteString(fmt.Sprintf("$%d", placeholder))
-			placeholder++
-			args = append(args, id)
+	added, err = s.queries.AddSavedSearchMatches(ctx, sqlc.AddSavedSearchMatchesParams{ItemIds: items, SavedSearchID: searchID})
+	return added, err
+}
+
+// Webhook posts items to URL as the fetcher inserts them or sees their
+// content change. Items outside the owner's subscriptions or the webhook's
+// filters are not sent. Only ID and Keywords are set by ListActiveWebhooks.
+type Webhook struct {
+	ID     string
+	UserID string
+	URL    string
+	// Secret keys the HMAC-SHA256 signature sent with every payload.
+	Secret string
+	// FeedIDs narrows the webhook to the listed feeds and Keywords to items
+	// mentioning any of the keywords; empty lists leave it open.
+	FeedIDs   []string
+	Keywords  []string
+	Active    bool
+	CreatedAt time.Time
+}
+
+type CreateWebhookParams struct {
+	// UserID owns the webhook; empty stands for the open API.
+	UserID   string
+	URL      string
+	Secret   string
+	FeedIDs  []string
+	Keywords []string
+}
+
+// CreateWebhook stores an active webhook.
+func (s *Store) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (hook Webhook, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("CreateWebhook", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(arg.UserID)
+	if err != nil {
+		return Webhook{}, err
+	}
+	var feedIDs []uuid.UUID
+	feedIDs, err = parseUUIDs(arg.FeedIDs)
+	if err != nil {
+		return Webhook{}, err
+	}
+	keywords := arg.Keywords
+	if keywords == nil {
+		keywords = []string{}
+	}
+
+	var row sqlc.Webhook
+	row, err = s.queries.CreateWebhook(ctx, sqlc.CreateWebhookParams{
+		UserID:   user,
+		Url:      arg.URL,
+		Secret:   arg.Secret,
+		FeedIds:  feedIDs,
+		Keywords: keywords,
+	})
+	if err != nil {
+		return Webhook{}, err
+	}
+	hook = mapWebhook(row)
+	return hook, nil
+}
+
+// GetWebhook returns one of userID's webhooks. It returns sql.ErrNoRows
+// when the user has no such webhook.
+func (s *Store) GetWebhook(ctx context.Context, userID, id string) (hook Webhook, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("GetWebhook", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return Webhook{}, err
+	}
+	var hookID uuid.UUID
+	hookID, err = uuid.Parse(id)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	var row sqlc.Webhook
+	row, err = s.queries.GetWebhook(ctx, sqlc.GetWebhookParams{ID: hookID, UserID: user})
+	if err != nil {
+		return Webhook{}, err
+	}
+	hook = mapWebhook(row)
+	return hook, nil
+}
+
+// ListWebhooks returns userID's webhooks, oldest first.
+func (s *Store) ListWebhooks(ctx context.Context, userID string) (hooks []Webhook, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListWebhooks", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.Webhook
+	rows, err = s.queries.ListWebhooks(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	hooks = make([]Webhook, 0, len(rows))
+	for _, row := range rows {
+		hooks = append(hooks, mapWebhook(row))
+	}
+	return hooks, nil
+}
+
+// UpdateWebhookParams lists the changes to a webhook; nil fields are left
+// alone. An empty, non-nil FeedIDs or Keywords clears that filter.
+type UpdateWebhookParams struct {
+	UserID   string
+	ID       string
+	URL      *string
+	Secret   *string
+	FeedIDs  []string
+	Keywords []string
+	Active   *bool
+}
+
+// UpdateWebhook changes one of userID's webhooks. Deliveries already
+// queued keep the URL and secret current when they are sent. It returns
+// sql.ErrNoRows when the user has no such webhook.
+func (s *Store) UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (hook Webhook, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("UpdateWebhook", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(arg.UserID)
+	if err != nil {
+		return Webhook{}, err
+	}
+	var hookID uuid.UUID
+	hookID, err = uuid.Parse(arg.ID)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	// Nil slices are written as NULL, which keeps the stored filter.
+	params := sqlc.UpdateWebhookParams{Keywords: arg.Keywords, ID: hookID, UserID: user}
+	if arg.URL != nil {
+		params.Url = sql.NullString{String: *arg.URL, Valid: true}
+	}
+	if arg.Secret != nil {
+		params.Secret = sql.NullString{String: *arg.Secret, Valid: true}
+	}
+	if arg.FeedIDs != nil {
+		params.FeedIds, err = parseUUIDs(arg.FeedIDs)
+		if err != nil {
+			return Webhook{}, err
 		}
-		builder.WriteString(")")
+	}
+	if arg.Active != nil {
+		params.Active = sql.NullBool{Bool: *arg.Active, Valid: true}
 	}
 
-	builder.WriteString(" ORDER BY ")
-	if sortField == ItemSortFieldPublishedAt {
-		builder.WriteString(fmt.Sprintf("%s %s NULLS LAST, i.retrieved_at DESC", column, dirSQL))
-	} else {
-		builder.WriteString(fmt.Sprintf("%s %s, i.id DESC", column, dirSQL))
+	var row sqlc.Webhook
+	row, err = s.queries.UpdateWebhook(ctx, params)
+	if err != nil {
+		return Webhook{}, err
 	}
+	hook = mapWebhook(row)
+	return hook, nil
+}
 
-	builder.WriteString(fmt.Sprintf(" LIMIT $%d OFFSET $%d", placeholder, placeholder+1))
-	args = append(args, arg.Limit, arg.Offset)
+// DeleteWebhook removes one of userID's webhooks and its deliveries. It
+// returns sql.ErrNoRows when the user has no such webhook.
+func (s *Store) DeleteWebhook(ctx context.Context, userID, id string) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("DeleteWebhook", err, time.Since(start))
+		}(time.Now())
+	}
 
-	rows, err := s.db.QueryContext(ctx, builder.String(), args...)
+	var user uuid.NullUUID
+	user, err = parseUserID(userID)
 	if err != nil {
-		return FilterItemsResult{}, err
+		return err
+	}
+	var hookID uuid.UUID
+	hookID, err = uuid.Parse(id)
+	if err != nil {
+		return err
 	}
-	defer rows.Close()
 
-	items := make([]Item, 0)
-	var total int64
-	for rows.Next() {
-		var (
-			id          uuid.UUID
-			feedID      uuid.UUID
-			feedTitle   string
-			guid        sql.NullString
-			url         string
-			title       string
-			author      sql.NullString
-			contentHTML string
-			contentText string
-			publishedAt sql.NullTime
-			retrievedAt time.Time
-			rowTotal    int64
-		)
-		if err = rows.Scan(&id, &feedID, &feedTitle, &guid, &url, &title, &author, &contentHTML, &contentText, &publishedAt, &retrievedAt, &rowTotal); err != nil {
-			return FilterItemsResult{}, err
+	var deleted int64
+	deleted, err = s.queries.DeleteWebhook(ctx, sqlc.DeleteWebhookParams{ID: hookID, UserID: user})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+// Webhook delivery statuses. Pending deliveries are retried until they
+// succeed or run out of attempts.
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// WebhookDelivery is a queued webhook payload and the outcome of its
+// latest attempt.
+type WebhookDelivery struct {
+	ID        string
+	WebhookID string
+	// ItemID is empty once the item has been deleted.
+	ItemID         string
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	ResponseStatus sql.NullInt32
+	LastError      sql.NullString
+	CreatedAt      time.Time
+}
+
+type ListWebhookDeliveriesParams struct {
+	UserID string
+	ID     string
+	// Status keeps only deliveries in that status when set.
+	Status string
+	Limit  int32
+	Offset int32
+}
+
+// ListWebhookDeliveries lists a webhook's deliveries, newest first. It
+// returns sql.ErrNoRows when the user has no such webhook.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) (deliveries []WebhookDelivery, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListWebhookDeliveries", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var user uuid.NullUUID
+	user, err = parseUserID(arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var hookID uuid.UUID
+	hookID, err = uuid.Parse(arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.queries.GetWebhook(ctx, sqlc.GetWebhookParams{ID: hookID, UserID: user}); err != nil {
+		return nil, err
+	}
+
+	var rows []sqlc.WebhookDelivery
+	rows, err = s.queries.ListWebhookDeliveries(ctx, sqlc.ListWebhookDeliveriesParams{
+		WebhookID:    hookID,
+		Status:       sql.NullString{String: arg.Status, Valid: arg.Status != ""},
+		ResultLimit:  arg.Limit,
+		ResultOffset: arg.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	deliveries = make([]WebhookDelivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, WebhookDelivery{
+			ID:             row.ID.String(),
+			WebhookID:      row.WebhookID.String(),
+			ItemID:         nullUUIDString(row.ItemID),
+			Event:          row.Event,
+			Payload:        row.Payload,
+			Status:         row.Status,
+			Attempts:       row.Attempts,
+			NextAttemptAt:  row.NextAttemptAt,
+			LastAttemptAt:  row.LastAttemptAt,
+			ResponseStatus: row.ResponseStatus,
+			LastError:      row.LastError,
+			CreatedAt:      row.CreatedAt,
+		})
+	}
+	return deliveries, nil
+}
+
+// ListActiveWebhooks returns the ID and keywords of every active webhook,
+// across all users, for the fetcher to queue deliveries for.
+func (s *Store) ListActiveWebhooks(ctx context.Context) (hooks []Webhook, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ListActiveWebhooks", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var rows []sqlc.ListActiveWebhooksRow
+	rows, err = s.queries.ListActiveWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hooks = make([]Webhook, 0, len(rows))
+	for _, row := range rows {
+		hooks = append(hooks, Webhook{ID: row.ID.String(), Keywords: row.Keywords, Active: true})
+	}
+	return hooks, nil
+}
+
+// WebhookEvent is a payload to queue for an item.
+type WebhookEvent struct {
+	ItemID  string
+	Event   string
+	Payload string
+}
+
+// EnqueueWebhookDeliveries queues a webhook's deliveries for events. Items
+// outside the owner's subscriptions or the webhook's feed filter are
+// skipped. It returns how many deliveries were queued.
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, id string, events []WebhookEvent) (queued int64, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("EnqueueWebhookDeliveries", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var hookID uuid.UUID
+	hookID, err = uuid.Parse(id)
+	if err != nil {
+		return 0, err
+	}
+	params := sqlc.EnqueueWebhookDeliveriesParams{
+		ItemIds:   make([]uuid.UUID, 0, len(events)),
+		Events:    make([]string, 0, len(events)),
+		Payloads:  make([]string, 0, len(events)),
+		WebhookID: hookID,
+	}
+	for _, ev := range events {
+		var itemID uuid.UUID
+		itemID, err = uuid.Parse(ev.ItemID)
+		if err != nil {
+			return 0, err
 		}
-		total = rowTotal
-		items = append(items, mapItem(id, feedID, feedTitle, guid, url, title, author, contentHTML, contentText, publishedAt, retrievedAt))
+		params.ItemIds = append(params.ItemIds, itemID)
+		params.Events = append(params.Events, ev.Event)
+		params.Payloads = append(params.Payloads, ev.Payload)
 	}
-	if err = rows.Err(); err != nil {
-		return FilterItemsResult{}, err
+
+	queued, err = s.queries.EnqueueWebhookDeliveries(ctx, params)
+	return queued, err
+}
+
+// ClaimedWebhookDelivery is a due delivery along with where to send it.
+type ClaimedWebhookDelivery struct {
+	ID        string
+	WebhookID string
+	Event     string
+	Payload   string
+	// Attempts counts the attempts made before this one.
+	Attempts int32
+	URL      string
+	Secret   string
+}
+
+// ClaimWebhookDeliveries takes up to limit pending deliveries of active
+// webhooks that are due at now. They are not handed out again until lease
+// has passed, so a delivery whose attempt is never recorded is retried.
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int32) (claimed []ClaimedWebhookDelivery, err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("ClaimWebhookDeliveries", err, time.Since(start))
+		}(time.Now())
 	}
 
-	result = FilterItemsResult{Items: items, Total: total}
-	return result, nil
+	var rows []sqlc.ClaimWebhookDeliveriesRow
+	rows, err = s.queries.ClaimWebhookDeliveries(ctx, sqlc.ClaimWebhookDeliveriesParams{
+		LeaseUntil:  now.Add(lease),
+		DueBefore:   now,
+		ResultLimit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	claimed = make([]ClaimedWebhookDelivery, 0, len(rows))
+	for _, row := range rows {
+		claimed = append(claimed, ClaimedWebhookDelivery{
+			ID:        row.ID.String(),
+			WebhookID: row.WebhookID.String(),
+			Event:     row.Event,
+			Payload:   row.Payload,
+			Attempts:  row.Attempts,
+			URL:       row.Url,
+			Secret:    row.Secret,
+		})
+	}
+	return claimed, nil
+}
+
+type RecordWebhookAttemptParams struct {
+	ID string
+	// Status is WebhookDelivered, WebhookFailed, or WebhookPending to retry
+	// at NextAttemptAt.
+	Status         string
+	AttemptedAt    time.Time
+	NextAttemptAt  time.Time
+	ResponseStatus int
+	Error          string
+}
+
+// RecordWebhookAttempt stores the outcome of a delivery attempt. A zero
+// ResponseStatus or empty Error is stored as NULL.
+func (s *Store) RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) (err error) {
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.ObserveDB("RecordWebhookAttempt", err, time.Since(start))
+		}(time.Now())
+	}
+
+	var deliveryID uuid.UUID
+	deliveryID, err = uuid.Parse(arg.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.queries.RecordWebhookAttempt(ctx, sqlc.RecordWebhookAttemptParams{
+		Status:         arg.Status,
+		NextAttemptAt:  arg.NextAttemptAt,
+		AttemptedAt:    sql.NullTime{Time: arg.AttemptedAt, Valid: true},
+		ResponseStatus: sql.NullInt32{Int32: int32(arg.ResponseStatus), Valid: arg.ResponseStatus != 0},
+		LastError:      sql.NullString{String: arg.Error, Valid: arg.Error != ""},
+		ID:             deliveryID,
+	})
+	return err
 }
 
 func mapFeed(f sqlc.Feed) Feed {
 	return Feed{
-		ID:           f.ID.String(),
-		URL:          f.Url,
-		Title:        f.Title,
-		ETag:         f.Etag,
-		LastModified: f.LastModified,
-		LastCrawled:  f.LastCrawled,
-		Active:       f.Active,
+		ID:                  f.ID.String(),
+		URL:                 f.Url,
+		Title:               f.Title,
+		ETag:                f.Etag,
+		LastModified:        f.LastModified,
+		LastCrawled:         f.LastCrawled,
+		Active:              f.Active,
+		NextCrawlAt:         f.NextCrawlAt,
+		RefreshInterval:     time.Duration(f.RefreshIntervalSeconds) * time.Second,
+		SkipHours:           uint32(f.SkipHours),
+		SkipDays:            uint8(f.SkipDays),
+		BackoffUntil:        f.BackoffUntil,
+		BackoffDuration:     time.Duration(f.BackoffSeconds) * time.Second,
+		BackoffReason:       f.BackoffReason,
+		BackoffFailures:     f.BackoffFailures,
+		ConsecutiveFailures: f.ConsecutiveFailures,
+		LastError:           f.LastError,
+		LastErrorAt:         f.LastErrorAt,
+		LastSuccessAt:       f.LastSuccessAt,
+		LastStatus:          f.LastStatus,
+		ConsecutiveNotFound: f.ConsecutiveNotFound,
+		DeactivatedAt:       f.DeactivatedAt,
+		DeactivatedReason:   f.DeactivatedReason,
+		RejectedRelocation:  f.RejectedRelocationUrl,
+		CanonicalURL:        f.CanonicalUrl,
+		TitleLocked:         f.TitleLocked,
+		Category:            f.Category,
+	}
+}
+
+// mapUserFeed applies a subscription's name, folder and pause to its feed.
+func mapUserFeed(f sqlc.Feed, title, category sql.NullString, active bool) Feed {
+	feed := mapFeed(f)
+	feed.TitleLocked = title.Valid
+	if title.Valid {
+		feed.Title = title.String
+	}
+	feed.Category = category
+	if !active && feed.Active {
+		feed.Active = false
+		feed.DeactivatedReason = sql.NullString{Valid: true, String: FeedPausedReason}
+	}
+	return feed
+}
+
+func mapTag(t sqlc.Tag) Tag {
+	return Tag{
+		ID:        t.ID.String(),
+		Name:      t.Name,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+func mapSavedSearch(row sqlc.GetSavedSearchRow) SavedSearch {
+	feedIDs := make([]string, 0, len(row.FeedIds))
+	for _, id := range row.FeedIds {
+		feedIDs = append(feedIDs, id.String())
+	}
+	tags := row.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return SavedSearch{
+		ID:           row.ID.String(),
+		UserID:       nullUUIDString(row.UserID),
+		Name:         row.Name,
+		Query:        row.Query,
+		FeedIDs:      feedIDs,
+		Tags:         tags,
+		CreatedAt:    row.CreatedAt,
+		LastViewedAt: row.LastViewedAt,
+		MatchCount:   row.MatchCount,
+		NewCount:     row.NewCount,
+	}
+}
+
+func mapWebhook(row sqlc.Webhook) Webhook {
+	feedIDs := make([]string, 0, len(row.FeedIds))
+	for _, id := range row.FeedIds {
+		feedIDs = append(feedIDs, id.String())
+	}
+	keywords := row.Keywords
+	if keywords == nil {
+		keywords = []string{}
+	}
+	return Webhook{
+		ID:        row.ID.String(),
+		UserID:    nullUUIDString(row.UserID),
+		URL:       row.Url,
+		Secret:    row.Secret,
+		FeedIDs:   feedIDs,
+		Keywords:  keywords,
+		Active:    row.Active,
+		CreatedAt: row.CreatedAt,
+	}
+}
+
+func mapUser(u sqlc.User) User {
+	return User{
+		ID:           u.ID.String(),
+		Username:     u.Username,
+		PasswordHash: u.PasswordHash,
+		Admin:        u.IsAdmin,
+		CreatedAt:    u.CreatedAt,
+	}
+}
+
+func mapAPIKey(k sqlc.ApiKey) APIKey {
+	return APIKey{
+		ID:         k.ID.String(),
+		UserID:     k.UserID.String(),
+		Name:       k.Name,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
 	}
 }
 
@@ -459,6 +3848,46 @@ func mapItem(id uuid.UUID, feedID uuid.UUID, feedTitle string, guid sql.NullStri
 	}
 }
 
+// parseUserID parses the ID of the user a call acts for; an empty ID stands
+// for the open API.
+func parseUserID(id string) (uuid.NullUUID, error) {
+	if id == "" {
+		return uuid.NullUUID{}, nil
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: parsed, Valid: true}, nil
+}
+
+func parseUUIDs(ids []string) ([]uuid.UUID, error) {
+	parsed := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		u, err := uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, u)
+	}
+	return parsed, nil
+}
+
+func lowerStrings(values []string) []string {
+	lowered := make([]string, 0, len(values))
+	for _, v := range values {
+		lowered = append(lowered, strings.ToLower(v))
+	}
+	return lowered
+}
+
+func nullUUIDString(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.UUID.String()
+}
+
 func nullTimeArg(t sql.NullTime) interface{} {
 	if t.Valid {
 		return t.Time
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// openTestStore returns a Store on a fresh schema of the database named by
+// COURIER_TEST_DSN, with every migration applied. Tests using it are
+// skipped when the variable is unset.
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	dsn := os.Getenv("COURIER_TEST_DSN")
+	if dsn == "" {
+		t.Skip("COURIER_TEST_DSN not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// One connection keeps the search_path below for every query.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatalf("schema name: %v", err)
+	}
+	schema := "courier_test_" + hex.EncodeToString(buf)
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { _, _ = db.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE") })
+	if _, err := db.ExecContext(ctx, "SET search_path TO "+schema+", public"); err != nil {
+		t.Fatalf("set search_path: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "db", "migrations", "*.sql"))
+	if err != nil {
+		t.Fatalf("list migrations: %v", err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		up, _, _ := strings.Cut(string(raw), "-- +goose Down")
+		if _, err := db.ExecContext(ctx, up); err != nil {
+			t.Fatalf("apply %s: %v", filepath.Base(file), err)
+		}
+	}
+	return New(db, nil)
+}
+
+func createTestUser(t *testing.T, s *Store, name string) string {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), CreateUserParams{Username: name, PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("create user %s: %v", name, err)
+	}
+	return user.ID
+}
+
+func TestUpdateFeedOnlyChangesCallersSubscription(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+
+	shared, err := s.InsertFeed(ctx, InsertFeedParams{UserID: alice, URL: "https://example.com/feed.xml", Title: "Example"})
+	if err != nil {
+		t.Fatalf("alice subscribe: %v", err)
+	}
+	if _, err := s.InsertFeed(ctx, InsertFeedParams{UserID: bob, URL: "https://example.com/feed.xml"}); err != nil {
+		t.Fatalf("bob subscribe: %v", err)
+	}
+
+	paused := false
+	updated, err := s.UpdateFeed(ctx, UpdateFeedParams{UserID: alice, ID: shared.ID, Active: &paused})
+	if err != nil {
+		t.Fatalf("alice pause: %v", err)
+	}
+	if updated.Active || updated.DeactivatedReason.String != FeedPausedReason {
+		t.Fatalf("expected alice's subscription to be paused, got %+v", updated)
+	}
+	due, err := s.ListDueFeeds(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("list due feeds: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != shared.ID {
+		t.Fatalf("expected bob's subscription to keep the feed crawled, got %+v", due)
+	}
+
+	moved := "https://example.org/other.xml"
+	updated, err = s.UpdateFeed(ctx, UpdateFeedParams{UserID: alice, ID: shared.ID, URL: &moved})
+	if err != nil {
+		t.Fatalf("alice move: %v", err)
+	}
+	if updated.ID == shared.ID || updated.URL != moved {
+		t.Fatalf("expected alice to move to a new feed, got %s %s", updated.ID, updated.URL)
+	}
+	if updated.Active {
+		t.Fatalf("expected the moved subscription to stay paused")
+	}
+
+	bobs, err := s.GetUserFeed(ctx, bob, shared.ID)
+	if err != nil {
+		t.Fatalf("bob feed: %v", err)
+	}
+	if bobs.URL != shared.URL || !bobs.Active {
+		t.Fatalf("expected bob's feed to be unchanged, got %s active=%v", bobs.URL, bobs.Active)
+	}
+	if _, err := s.GetUserFeed(ctx, alice, shared.ID); err != sql.ErrNoRows {
+		t.Fatalf("expected alice to have left the shared feed, got %v", err)
+	}
+}
+
+func TestTagsArePerUser(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+
+	shared, err := s.InsertFeed(ctx, InsertFeedParams{UserID: alice, URL: "https://example.com/feed.xml"})
+	if err != nil {
+		t.Fatalf("alice subscribe: %v", err)
+	}
+	if _, err := s.InsertFeed(ctx, InsertFeedParams{UserID: bob, URL: "https://example.com/feed.xml"}); err != nil {
+		t.Fatalf("bob subscribe: %v", err)
+	}
+
+	tags, err := s.SetFeedTags(ctx, alice, shared.ID, []string{"Go"})
+	if err != nil || len(tags) != 1 {
+		t.Fatalf("alice tag: %v %+v", err, tags)
+	}
+	if _, err := s.CreateTag(ctx, bob, "go"); err != nil {
+		t.Fatalf("expected bob to have his own go tag, got %v", err)
+	}
+
+	if _, err := s.GetTag(ctx, bob, tags[0].ID); err != sql.ErrNoRows {
+		t.Fatalf("expected alice's tag to be hidden from bob, got %v", err)
+	}
+	if _, err := s.RenameTag(ctx, bob, tags[0].ID, "Rust"); err != sql.ErrNoRows {
+		t.Fatalf("expected bob not to rename alice's tag, got %v", err)
+	}
+	if err := s.DeleteTag(ctx, bob, tags[0].ID); err != sql.ErrNoRows {
+		t.Fatalf("expected bob not to delete alice's tag, got %v", err)
+	}
+	bobTags, err := s.ListFeedTags(ctx, bob, shared.ID)
+	if err != nil || len(bobTags) != 0 {
+		t.Fatalf("expected bob's subscription to be untagged, got %v %+v", err, bobTags)
+	}
+	ids, err := s.ListTaggedFeedIDs(ctx, bob, []string{"go"})
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("expected alice's tag not to match for bob, got %v %v", err, ids)
+	}
+	ids, err = s.ListTaggedFeedIDs(ctx, alice, []string{"GO"})
+	if err != nil || len(ids) != 1 || ids[0] != shared.ID {
+		t.Fatalf("expected alice's tagged feed, got %v %v", err, ids)
+	}
+
+	if _, err := s.Unsubscribe(ctx, alice, shared.ID); err != nil {
+		t.Fatalf("alice unsubscribe: %v", err)
+	}
+	listed, err := s.ListTags(ctx, alice)
+	if err != nil || len(listed) != 1 || listed[0].FeedCount != 0 {
+		t.Fatalf("expected unsubscribing to untag the feed, got %v %+v", err, listed)
+	}
+}
+
+func TestSavedSearchCountsSkipUnsubscribedFeeds(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+
+	var itemIDs []string
+	var feedIDs []string
+	for _, url := range []string{"https://example.com/a.xml", "https://example.com/b.xml"} {
+		f, err := s.InsertFeed(ctx, InsertFeedParams{UserID: alice, URL: url})
+		if err != nil {
+			t.Fatalf("subscribe %s: %v", url, err)
+		}
+		feedIDs = append(feedIDs, f.ID)
+		res, err := s.UpsertItem(ctx, UpsertItemParams{
+			FeedID:      f.ID,
+			URL:         url + "#1",
+			Title:       "Generics in Go",
+			ContentHash: []byte(url),
+		})
+		if err != nil {
+			t.Fatalf("upsert item: %v", err)
+		}
+		itemIDs = append(itemIDs, res.Item.ID)
+	}
+	// Bob keeps the first feed alive after alice leaves it.
+	if _, err := s.InsertFeed(ctx, InsertFeedParams{UserID: bob, URL: "https://example.com/a.xml"}); err != nil {
+		t.Fatalf("bob subscribe: %v", err)
+	}
+
+	saved, err := s.CreateSavedSearch(ctx, CreateSavedSearchParams{UserID: alice, Name: "Go", Query: "generics"})
+	if err != nil {
+		t.Fatalf("create saved search: %v", err)
+	}
+	if added, err := s.AddSavedSearchMatches(ctx, saved.ID, itemIDs); err != nil || added != 2 {
+		t.Fatalf("expected 2 matches, got %d (%v)", added, err)
+	}
+
+	if _, err := s.Unsubscribe(ctx, alice, feedIDs[0]); err != nil {
+		t.Fatalf("alice unsubscribe: %v", err)
+	}
+	got, err := s.GetSavedSearch(ctx, alice, saved.ID)
+	if err != nil {
+		t.Fatalf("get saved search: %v", err)
+	}
+	if got.MatchCount != 1 || got.NewCount != 1 {
+		t.Fatalf("expected the left feed's match to be uncounted, got %d/%d", got.MatchCount, got.NewCount)
+	}
+	listed, err := s.ListSavedSearches(ctx, alice)
+	if err != nil || len(listed) != 1 || listed[0].MatchCount != 1 {
+		t.Fatalf("expected listed counts to match, got %v %+v", err, listed)
+	}
+}
@@ -0,0 +1,285 @@
+package syndication
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Feed is a listing of entries to publish as Atom 1.0, RSS 2.0 or JSON
+// Feed 1.1.
+type Feed struct {
+	Title       string
+	Description string
+	// FeedURL is where the document is served from. It doubles as the
+	// feed's ID.
+	FeedURL string
+	// HomeURL is the page the feed belongs to; FeedURL stands in when it
+	// is empty.
+	HomeURL string
+	// Updated is when the listing last changed. Zero falls back to the
+	// Unix epoch so documents stay stable.
+	Updated time.Time
+	Entries []Entry
+}
+
+// Entry is a published item. ID must be stable across renders.
+type Entry struct {
+	ID          string
+	Title       string
+	URL         string
+	Author      string
+	ContentHTML string
+	ContentText string
+	// Published may be zero for undated items; Updated may not.
+	Published time.Time
+	Updated   time.Time
+}
+
+func (f Feed) homeURL() string {
+	if f.HomeURL != "" {
+		return f.HomeURL
+	}
+	return f.FeedURL
+}
+
+func (f Feed) updated() time.Time {
+	if f.Updated.IsZero() {
+		return time.Unix(0, 0).UTC()
+	}
+	return f.Updated.UTC()
+}
+
+// entryID turns an item ID into a URI, as Atom requires.
+func entryID(id string) string {
+	return "urn:uuid:" + id
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Links     []atomLink  `xml:"link"`
+	Published string      `xml:"published,omitempty"`
+	Updated   string      `xml:"updated"`
+	Author    *atomPerson `xml:"author"`
+	Summary   *atomText   `xml:"summary"`
+	Content   *atomText   `xml:"content"`
+}
+
+// WriteAtom encodes f as an Atom 1.0 document.
+func WriteAtom(w io.Writer, f Feed) error {
+	doc := atomFeed{
+		ID:      f.FeedURL,
+		Title:   f.Title,
+		Updated: f.updated().Format(time.RFC3339),
+		Author:  atomPerson{Name: f.Title},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.FeedURL},
+			{Rel: "alternate", Type: "text/html", Href: f.homeURL()},
+		},
+	}
+	for _, e := range f.Entries {
+		entry := atomEntry{
+			ID:      entryID(e.ID),
+			Title:   e.Title,
+			Updated: e.Updated.UTC().Format(time.RFC3339),
+		}
+		if e.URL != "" {
+			entry.Links = []atomLink{{Rel: "alternate", Type: "text/html", Href: e.URL}}
+		}
+		if !e.Published.IsZero() {
+			entry.Published = e.Published.UTC().Format(time.RFC3339)
+		}
+		if e.Author != "" {
+			entry.Author = &atomPerson{Name: e.Author}
+		}
+		if e.ContentText != "" {
+			entry.Summary = &atomText{Type: "text", Body: e.ContentText}
+		}
+		if e.ContentHTML != "" {
+			entry.Content = &atomText{Type: "html", Body: e.ContentHTML}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return writeXML(w, doc)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          rssLink   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	Creator     string  `xml:"dc:creator,omitempty"`
+	Description string  `xml:"description"`
+}
+
+// WriteRSS encodes f as an RSS 2.0 document. Authors are written as
+// dc:creator since RSS expects an email address in author.
+func WriteRSS(w io.Writer, f Feed) error {
+	description := f.Description
+	if description == "" {
+		description = f.Title
+	}
+	doc := rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.homeURL(),
+			Description:   description,
+			Self:          rssLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: f.updated().Format(time.RFC1123Z),
+		},
+	}
+	for _, e := range f.Entries {
+		item := rssItem{
+			Title:       e.Title,
+			Link:        e.URL,
+			GUID:        rssGUID{IsPermaLink: "false", Value: entryID(e.ID)},
+			Creator:     e.Author,
+			Description: e.ContentHTML,
+		}
+		if item.Description == "" {
+			item.Description = e.ContentText
+		}
+		date := e.Published
+		if date.IsZero() {
+			date = e.Updated
+		}
+		item.PubDate = date.UTC().Format(time.RFC1123Z)
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url"`
+	FeedURL     string     `json:"feed_url"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url,omitempty"`
+	Title         string       `json:"title"`
+	ContentHTML   string       `json:"content_html,omitempty"`
+	ContentText   string       `json:"content_text,omitempty"`
+	DatePublished string       `json:"date_published,omitempty"`
+	DateModified  string       `json:"date_modified"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+	// Author is the JSON Feed 1.0 field, kept for older readers.
+	Author *jsonAuthor `json:"author,omitempty"`
+}
+
+// WriteJSON encodes f as a JSON Feed 1.1 document.
+func WriteJSON(w io.Writer, f Feed) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.homeURL(),
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Items:       make([]jsonItem, 0, len(f.Entries)),
+	}
+	for _, e := range f.Entries {
+		item := jsonItem{
+			ID:           e.ID,
+			URL:          e.URL,
+			Title:        e.Title,
+			ContentHTML:  e.ContentHTML,
+			ContentText:  e.ContentText,
+			DateModified: e.Updated.UTC().Format(time.RFC3339),
+		}
+		// Items need content of one kind or the other.
+		if item.ContentHTML == "" && item.ContentText == "" {
+			item.ContentText = e.Title
+		}
+		if !e.Published.IsZero() {
+			item.DatePublished = e.Published.UTC().Format(time.RFC3339)
+		}
+		if e.Author != "" {
+			item.Authors = []jsonAuthor{{Name: e.Author}}
+			item.Author = &item.Authors[0]
+		}
+		doc.Items = append(doc.Items, item)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,96 @@
+package syndication
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+var sample = Feed{
+	Title:   "Courier",
+	FeedURL: "https://courier.example/feeds.atom?tag=go",
+	Updated: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+	Entries: []Entry{
+		{
+			ID:          "22222222-2222-2222-2222-222222222222",
+			Title:       "Go & generics",
+			URL:         "https://go.dev/blog/generics",
+			Author:      "Jane Doe",
+			ContentHTML: "<p>Type parameters</p>",
+			ContentText: "Type parameters",
+			Published:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Updated:     time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:          "33333333-3333-3333-3333-333333333333",
+			Title:       "Undated",
+			URL:         "https://go.dev/blog/undated",
+			ContentText: "No date",
+			Updated:     time.Date(2024, 5, 2, 7, 0, 0, 0, time.UTC),
+		},
+	},
+}
+
+func TestWriteRoundTrips(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		write    func(io.Writer, Feed) error
+		feedType string
+	}{
+		{"atom", WriteAtom, "atom"},
+		{"rss", WriteRSS, "rss"},
+		{"json", WriteJSON, "json"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.write(&buf, sample); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			parsed, err := gofeed.NewParser().Parse(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("parse: %v\n%s", err, buf.String())
+			}
+			if parsed.FeedType != tc.feedType || parsed.Title != "Courier" || parsed.FeedLink != sample.FeedURL {
+				t.Fatalf("unexpected feed %s %q %q", parsed.FeedType, parsed.Title, parsed.FeedLink)
+			}
+			if len(parsed.Items) != 2 {
+				t.Fatalf("expected 2 items, got %d", len(parsed.Items))
+			}
+			first := parsed.Items[0]
+			if first.Title != "Go & generics" || first.Link != "https://go.dev/blog/generics" || !strings.Contains(first.GUID, sample.Entries[0].ID) {
+				t.Fatalf("unexpected item: %+v", first)
+			}
+			if first.PublishedParsed == nil || !first.PublishedParsed.Equal(sample.Entries[0].Published) {
+				t.Fatalf("expected the publication date to survive, got %v", first.PublishedParsed)
+			}
+			if first.Author == nil || first.Author.Name != "Jane Doe" {
+				t.Fatalf("expected the author to survive, got %+v", first.Author)
+			}
+			if !strings.Contains(first.Content+first.Description, "<p>Type parameters</p>") {
+				t.Fatalf("expected the HTML content to survive, got %q / %q", first.Content, first.Description)
+			}
+		})
+	}
+}
+
+func TestWriteJSONIsValidJSONFeed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, Feed{Title: "Empty", FeedURL: "https://courier.example/feeds.json"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc["version"] != "https://jsonfeed.org/version/1.1" || doc["home_page_url"] != "https://courier.example/feeds.json" {
+		t.Fatalf("unexpected document: %v", doc)
+	}
+	if items, ok := doc["items"].([]any); !ok || len(items) != 0 {
+		t.Fatalf("expected an empty items list, got %v", doc["items"])
+	}
+}